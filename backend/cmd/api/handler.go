@@ -1,18 +1,32 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"time"
 
 	authUsecase "ga03-backend/internal/auth/usecase"
 	emailDelivery "ga03-backend/internal/email/delivery"
 	emailRepo "ga03-backend/internal/email/repository"
+	emailScheduler "ga03-backend/internal/email/scheduler"
 	emailUsecasePkg "ga03-backend/internal/email/usecase"
+	promptDelivery "ga03-backend/internal/prompt/delivery"
+	promptRepo "ga03-backend/internal/prompt/repository"
+	promptUsecasePkg "ga03-backend/internal/prompt/usecase"
 	taskDelivery "ga03-backend/internal/task/delivery"
 	taskRepo "ga03-backend/internal/task/repository"
 	taskUsecasePkg "ga03-backend/internal/task/usecase"
+	usageDelivery "ga03-backend/internal/usage/delivery"
+	usageRepo "ga03-backend/internal/usage/repository"
+	usageUsecasePkg "ga03-backend/internal/usage/usecase"
 	"ga03-backend/pkg/ai"
 	"ga03-backend/pkg/chroma"
+	"ga03-backend/pkg/pgvector"
 	"ga03-backend/pkg/config"
+	"ga03-backend/pkg/embedding"
+	"ga03-backend/pkg/jobqueue"
 	"ga03-backend/pkg/sse"
 
 	"github.com/gin-gonic/gin"
@@ -26,6 +40,14 @@ type Handler struct {
 	config         *config.Config
 	summaryHandler *emailDelivery.SummaryHandler
 	taskHandler    *taskDelivery.TaskHandler
+	promptHandler  *promptDelivery.PromptHandler
+	promptUsecase  promptUsecasePkg.PromptUsecase
+	usageHandler   *usageDelivery.UsageHandler
+	jobQueue       *jobqueue.Queue
+
+	aiChain         *ai.FallbackService
+	snoozeScheduler *emailScheduler.SnoozeScheduler
+	server          *http.Server
 }
 
 // emailFetcherAdapter adapts EmailUsecase to TaskUsecase.EmailFetcher interface
@@ -33,17 +55,20 @@ type emailFetcherAdapter struct {
 	emailUc emailUsecasePkg.EmailUsecase
 }
 
-func (a *emailFetcherAdapter) GetEmailByID(userID, id string) (subject, body string, err error) {
+func (a *emailFetcherAdapter) GetEmailByID(userID, id string) (subject, body string, receivedAt time.Time, err error) {
 	email, err := a.emailUc.GetEmailByID(userID, id)
 	if err != nil {
-		return "", "", err
+		return "", "", time.Time{}, err
 	}
-	return email.Subject, email.Body, nil
+	if email == nil {
+		return "", "", time.Time{}, errors.New("email not found")
+	}
+	return email.Subject, email.Body, email.ReceivedAt, nil
 }
 
-func NewHandler(authUc authUsecase.AuthUsecase, emailUc emailUsecasePkg.EmailUsecase, taskUc taskUsecasePkg.TaskUsecase, sseManager *sse.Manager, cfg *config.Config, summaryRepo emailRepo.EmailSummaryRepository, taskRepository taskRepo.TaskRepository) *Handler {
+func NewHandler(authUc authUsecase.AuthUsecase, emailUc emailUsecasePkg.EmailUsecase, taskUc taskUsecasePkg.TaskUsecase, sseManager *sse.Manager, cfg *config.Config, summaryRepo emailRepo.EmailSummaryRepository, taskRepository taskRepo.TaskRepository, promptRepository promptRepo.PromptRepository, usageRepository usageRepo.UsageRepository, jobQueue *jobqueue.Queue, embedder embedding.Embedder, pgVectorStore *pgvector.Client) *Handler {
 	// Initialize runtime config for settings API
-	InitRuntimeConfig(cfg.OllamaBaseURL, cfg.OllamaModel)
+	InitRuntimeConfig(cfg.OllamaBaseURL, cfg.OllamaModel, cfg.OpenAIBaseURL, cfg.OpenAIModel, cfg.OpenAIAPIKey)
 
 	// Initialize AI service with dynamic config getters for runtime updates
 	aiCfg := ai.DynamicConfig{
@@ -51,22 +76,73 @@ func NewHandler(authUc authUsecase.AuthUsecase, emailUc emailUsecasePkg.EmailUse
 		GeminiAPIKey:     cfg.GeminiApiKey,
 		GetOllamaBaseURL: GetRuntimeOllamaBaseURL,
 		GetOllamaModel:   GetRuntimeOllamaModel,
+		GetOpenAIBaseURL: GetRuntimeOpenAIBaseURL,
+		GetOpenAIModel:   GetRuntimeOpenAIModel,
+		GetOpenAIAPIKey:  GetRuntimeOpenAIAPIKey,
+		OpenAIJSONMode:   cfg.OpenAIJSONMode,
+		Chain: ai.ChainConfig{
+			Orders: map[ai.Operation][]string{
+				ai.OperationSummarize:    cfg.AIChainSummarize,
+				ai.OperationExtractTasks: cfg.AIChainExtractTasks,
+				ai.OperationSynonyms:     cfg.AIChainSynonyms,
+				ai.OperationDraftReply:   cfg.AIChainDraftReply,
+				ai.OperationClassify:     cfg.AIChainClassify,
+			},
+			FailureThreshold: cfg.AIBreakerFailures,
+			Cooldown:         cfg.AIBreakerCooldown,
+			MaxAttempts:      cfg.AIRetryAttempts,
+			BaseBackoff:      cfg.AIRetryBaseDelay,
+		},
 	}
 	aiService, err := ai.NewSummarizerServiceWithDynamicConfig(aiCfg)
 	if err != nil {
 		log.Printf("Warning: Failed to initialize AI service: %v", err)
 	} else {
-		log.Printf("AI service initialized with provider: %s (dynamic config enabled)", cfg.AIProvider)
+		log.Printf("AI service initialized with provider: %s (dynamic config enabled, orders: %v)", cfg.AIProvider, aiService.Orders())
+	}
+
+	// Prompt registry: built-in templates, optional overrides on disk, versions activated in the database
+	promptRegistry, err := ai.NewPromptRegistry(cfg.PromptTemplatesDir)
+	if err != nil {
+		log.Printf("Warning: %v. Using built-in prompt templates.", err)
+		promptRegistry, _ = ai.NewPromptRegistry("")
+	}
+	promptUc := promptUsecasePkg.NewPromptUsecase(promptRepository, promptRegistry)
+	promptUc.Start(cfg.PromptReloadInterval)
+	if aiService != nil {
+		aiService.SetPromptRegistry(promptRegistry)
+		aiService.SetPromptRecorder(promptUc)
+	}
+	promptHandler := promptDelivery.NewPromptHandler(promptUc)
+
+	// Per-user AI usage and daily quotas: users over quota fall back to the exempt (local) providers
+	usageUc := usageUsecasePkg.NewUsageUsecase(usageRepository, usageUsecasePkg.QuotaConfig{
+		DailyTokens:     int64(cfg.AIDailyTokenQuota),
+		DailyRequests:   int64(cfg.AIDailyRequestQuota),
+		ExemptProviders: cfg.AIQuotaExemptProviders,
+	})
+	if aiService != nil {
+		aiService.SetUsageTracker(usageUc)
 	}
+	usageHandler := usageDelivery.NewUsageHandler(usageUc)
 
 	// Set AI service vào emailUsecase qua interface
 	if aiService != nil {
 		emailUc.SetAIService(aiService)
 	}
 
-	// Initialize Chroma client for vector search
-	if cfg.ChromaAPIKey != "" {
-		chromaClient, err := chroma.NewChromaClient(cfg)
+	// Initialize vector search: pgvector (self-hosted) or Chroma Cloud
+	if cfg.VectorBackend == "pgvector" {
+		if pgVectorStore != nil {
+			emailUc.SetVectorSearchService(pgVectorStore)
+			log.Println("pgvector store initialized successfully")
+		} else {
+			log.Println("Warning: pgvector store not available. Semantic search will not be available.")
+		}
+	} else if embedder == nil {
+		log.Println("Warning: embedding model not available. Semantic search will not be available.")
+	} else if cfg.ChromaAPIKey != "" {
+		chromaClient, err := chroma.NewChromaClient(cfg, embedder)
 		if err != nil {
 			log.Printf("Warning: Failed to initialize Chroma client: %v. Semantic search will not be available.", err)
 		} else {
@@ -77,13 +153,21 @@ func NewHandler(authUc authUsecase.AuthUsecase, emailUc emailUsecasePkg.EmailUse
 		log.Println("Warning: CHROMA_API_KEY not set. Semantic search will not be available.")
 	}
 
+	// Re-embed the vectors of a previous embedding model in the background
+	go func() {
+		if _, err := emailUc.QueueStaleEmbeddings(); err != nil {
+			log.Printf("Warning: Failed to queue re-embedding: %v", err)
+		}
+	}()
+
 	// Initialize SummaryWorkerService for background AI summaries
-	summaryWorker := emailUsecasePkg.NewSummaryWorkerService(summaryRepo, sseManager, 3)
+	summaryWorker := emailUsecasePkg.NewSummaryWorkerService(summaryRepo, sseManager, jobQueue, 3)
 	if aiService != nil {
 		summaryWorker.SetGeminiService(aiService)
 	}
-	summaryWorker.Start()
-	log.Println("Summary worker service started")
+	summaryWorker.SetEmailSource(emailUc)
+	summaryWorker.StartStaleRefresh(cfg.SummaryRefreshInterval, cfg.SummaryRefreshBatch)
+	log.Println("Summary worker service registered on the job queue")
 
 	// Create SummaryHandler
 	summaryHandler := emailDelivery.NewSummaryHandler(summaryWorker, emailUc)
@@ -106,9 +190,18 @@ func NewHandler(authUc authUsecase.AuthUsecase, emailUc emailUsecasePkg.EmailUse
 		config:         cfg,
 		summaryHandler: summaryHandler,
 		taskHandler:    taskHandler,
+		promptHandler:  promptHandler,
+		promptUsecase:  promptUc,
+		usageHandler:   usageHandler,
+		jobQueue:       jobQueue,
+		aiChain:        aiService,
 	}
 }
 
+// SetSnoozeScheduler exposes the snooze scheduler metrics through the API
+func (h *Handler) SetSnoozeScheduler(s *emailScheduler.SnoozeScheduler) {
+	h.snoozeScheduler = s
+}
 
 func (h *Handler) Start(addr string) error {
 	r := gin.Default()
@@ -136,8 +229,23 @@ func (h *Handler) Start(addr string) error {
 	})
 
 	// Setup routes
-	SetupRoutes(r, h.authUsecase, h.emailUsecase, h.sseManager, h.config, h.summaryHandler, h.taskHandler)
+	SetupRoutes(r, h.authUsecase, h.emailUsecase, h.sseManager, h.config, h.summaryHandler, h.taskHandler, h.snoozeScheduler, h.aiChain, h.promptHandler, h.usageHandler, h.jobQueue)
 
-	return r.Run(addr)
+	h.server = &http.Server{
+		Addr:    addr,
+		Handler: r,
+	}
+	return h.server.ListenAndServe()
+}
+
+// Shutdown gracefully stops the HTTP server, waiting for in-flight requests
+func (h *Handler) Shutdown(ctx context.Context) error {
+	if h.promptUsecase != nil {
+		h.promptUsecase.Stop()
+	}
+	if h.server == nil {
+		return nil
+	}
+	return h.server.Shutdown(ctx)
 }
 
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ga03-backend/pkg/jobqueue"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDeadJobsLimit = 50
+	maxDeadJobsLimit     = 500
+)
+
+// GetJobQueueStats returns the depth, retries and dead letters of every queue
+// GET /api/settings/jobs
+func GetJobQueueStats(queue *jobqueue.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats, err := queue.Stats()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"queues": stats})
+	}
+}
+
+// GetDeadJobs lists dead-lettered jobs, most recent first
+// GET /api/settings/jobs/dead?queue=summary&limit=50
+func GetDeadJobs(queue *jobqueue.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultDeadJobsLimit
+		if l := c.Query("limit"); l != "" {
+			parsed, err := strconv.Atoi(l)
+			if err != nil || parsed <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+				return
+			}
+			limit = parsed
+		}
+		if limit > maxDeadJobsLimit {
+			limit = maxDeadJobsLimit
+		}
+
+		jobs, err := queue.DeadJobs(c.Query("queue"), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+	}
+}
+
+// RetryDeadJob puts a dead-lettered job back in its queue
+// POST /api/settings/jobs/dead/:id/retry
+func RetryDeadJob(queue *jobqueue.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := queue.RetryDeadJob(c.Param("id")); err != nil {
+			if errors.Is(err, jobqueue.ErrJobNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Job requeued"})
+	}
+}
+
+// PurgeDeadJobs deletes dead-lettered jobs of a queue (all queues when omitted)
+// DELETE /api/settings/jobs/dead?queue=summary
+func PurgeDeadJobs(queue *jobqueue.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deleted, err := queue.PurgeDeadJobs(c.Query("queue"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+	}
+}
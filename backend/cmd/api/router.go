@@ -4,16 +4,21 @@ import (
 	"ga03-backend/internal/auth/delivery"
 	authUsecase "ga03-backend/internal/auth/usecase"
 	emailDelivery "ga03-backend/internal/email/delivery"
+	emailScheduler "ga03-backend/internal/email/scheduler"
 	emailUsecase "ga03-backend/internal/email/usecase"
+	promptDelivery "ga03-backend/internal/prompt/delivery"
 	taskDelivery "ga03-backend/internal/task/delivery"
+	usageDelivery "ga03-backend/internal/usage/delivery"
+	"ga03-backend/pkg/ai"
 	"ga03-backend/pkg/config"
+	"ga03-backend/pkg/jobqueue"
 	"ga03-backend/pkg/sse"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, authUsecase authUsecase.AuthUsecase, emailUsecase emailUsecase.EmailUsecase, sseManager *sse.Manager, cfg *config.Config, summaryHandler *emailDelivery.SummaryHandler, taskHandler *taskDelivery.TaskHandler) {
+func SetupRoutes(r *gin.Engine, authUsecase authUsecase.AuthUsecase, emailUsecase emailUsecase.EmailUsecase, sseManager *sse.Manager, cfg *config.Config, summaryHandler *emailDelivery.SummaryHandler, taskHandler *taskDelivery.TaskHandler, snoozeScheduler *emailScheduler.SnoozeScheduler, aiChain *ai.FallbackService, promptHandler *promptDelivery.PromptHandler, usageHandler *usageDelivery.UsageHandler, jobQueue *jobqueue.Queue) {
 	authHandler := delivery.NewAuthHandler(authUsecase)
 	emailHandler := emailDelivery.NewEmailHandler(emailUsecase)
 
@@ -24,6 +29,32 @@ func SetupRoutes(r *gin.Engine, authUsecase authUsecase.AuthUsecase, emailUsecas
 			c.JSON(http.StatusOK, gin.H{"status": "ok"})
 		})
 
+		// Scheduler metrics (admins only)
+		api.GET("/metrics/snooze", delivery.AuthMiddleware(authUsecase), delivery.AdminMiddleware(cfg.AdminEmails), func(c *gin.Context) {
+			if snoozeScheduler == nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "snooze scheduler not running"})
+				return
+			}
+			c.JSON(http.StatusOK, snoozeScheduler.Metrics())
+		})
+
+		// AI provider metrics: breaker state, latency and error counters per provider (admins only).
+		// Last error messages are left out: provider errors echo response bodies (see the server logs).
+		api.GET("/metrics/ai", delivery.AuthMiddleware(authUsecase), delivery.AdminMiddleware(cfg.AdminEmails), func(c *gin.Context) {
+			if aiChain == nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service not initialized"})
+				return
+			}
+			stats := aiChain.Stats()
+			for i := range stats {
+				stats[i].LastError = ""
+			}
+			c.JSON(http.StatusOK, gin.H{"providers": stats})
+		})
+
+		// AI usage of the current user and their daily quota
+		api.GET("/ai/usage", delivery.AuthMiddleware(authUsecase), usageHandler.GetUsage)
+
 		// SSE endpoint
 		api.GET("/events", delivery.AuthMiddleware(authUsecase), func(c *gin.Context) {
 			userID := c.GetString("userID")
@@ -41,6 +72,8 @@ func SetupRoutes(r *gin.Engine, authUsecase authUsecase.AuthUsecase, emailUsecas
 			auth.GET("/me", delivery.AuthMiddleware(authUsecase), authHandler.Me)
 			auth.POST("/logout", authHandler.Logout)
 			auth.POST("/set-password", delivery.AuthMiddleware(authUsecase), authHandler.SetPassword)
+			auth.PUT("/language", delivery.AuthMiddleware(authUsecase), authHandler.UpdateLanguage)
+			auth.PUT("/timezone", delivery.AuthMiddleware(authUsecase), authHandler.UpdateTimeZone)
 		}
 		
 		// FCM routes (protected)
@@ -61,6 +94,10 @@ func SetupRoutes(r *gin.Engine, authUsecase authUsecase.AuthUsecase, emailUsecas
 			emails.GET("/status/:status", emailHandler.GetEmailsByStatus) // Kanban status API
 			emails.GET("/:id", emailHandler.GetEmailByID)
 			emails.GET("/:id/summary", emailHandler.SummarizeEmail)
+			emails.POST("/:id/summary/regenerate", summaryHandler.RegenerateSummary)
+			emails.POST("/:id/ai-reply", emailHandler.DraftAIReply)
+			emails.POST("/:id/triage", emailHandler.TriageEmail)
+			emails.GET("/:id/related", emailHandler.GetRelatedEmails)
 			emails.GET("/:id/attachments/:attachmentId", emailHandler.GetAttachment)
 			emails.PATCH("/:id/read", emailHandler.MarkAsRead)
 			emails.PATCH("/:id/unread", emailHandler.MarkAsUnread)
@@ -68,6 +105,9 @@ func SetupRoutes(r *gin.Engine, authUsecase authUsecase.AuthUsecase, emailUsecas
 			emails.PATCH("/:id/mailbox", emailHandler.MoveEmailToMailbox)
 			emails.POST("/:id/snooze", emailHandler.SnoozeEmail)
 			emails.POST("/:id/unsnooze", emailHandler.UnsnoozeEmail)
+			emails.POST("/:id/follow-up", emailHandler.ScheduleFollowUp)
+			emails.GET("/follow-ups", emailHandler.GetFollowUpReminders)
+			emails.DELETE("/follow-ups/:reminder_id", emailHandler.CancelFollowUp)
 			emails.POST("/send", emailHandler.SendEmail)
 			emails.POST("/:id/trash", emailHandler.TrashEmail)
 			emails.POST("/:id/archive", emailHandler.ArchiveEmail)
@@ -82,7 +122,12 @@ func SetupRoutes(r *gin.Engine, authUsecase authUsecase.AuthUsecase, emailUsecas
 		search.Use(delivery.AuthMiddleware(authUsecase))
 		{
 			search.POST("/semantic", emailHandler.SemanticSearch)
+			search.POST("/hybrid", emailHandler.HybridSearch)
 			search.GET("/suggestions", emailHandler.GetSearchSuggestions)
+			search.GET("/saved", emailHandler.ListSavedSearches)
+			search.POST("/saved", emailHandler.CreateSavedSearch)
+			search.PUT("/saved/:id", emailHandler.UpdateSavedSearch)
+			search.DELETE("/saved/:id", emailHandler.DeleteSavedSearch)
 		}
 
 		// Kanban routes (protected)
@@ -94,7 +139,16 @@ func SetupRoutes(r *gin.Engine, authUsecase authUsecase.AuthUsecase, emailUsecas
 			kanban.PUT("/columns/:column_id", emailHandler.UpdateKanbanColumn)
 			kanban.DELETE("/columns/:column_id", emailHandler.DeleteKanbanColumn)
 			kanban.PUT("/columns/orders", emailHandler.UpdateKanbanColumnOrders)
+			kanban.GET("/export", emailHandler.ExportKanban) // ?format=json|csv
+			kanban.POST("/import", emailHandler.ImportKanban) // ?mode=merge|replace
+			kanban.GET("/stats", emailHandler.GetKanbanStats)
+			kanban.GET("/triage/settings", emailHandler.GetTriageSettings)
+			kanban.PUT("/triage/settings", emailHandler.UpdateTriageSettings)
+			kanban.GET("/triage/suggestions", emailHandler.GetTriageSuggestions) // ?status=pending|all
+			kanban.POST("/triage/suggestions/:suggestion_id/accept", emailHandler.AcceptTriageSuggestion)
+			kanban.POST("/triage/suggestions/:suggestion_id/reject", emailHandler.RejectTriageSuggestion)
 			kanban.POST("/summarize", summaryHandler.QueueSummaries) // Background AI summary generation
+			kanban.POST("/summarize/refresh", summaryHandler.RefreshStaleSummaries)
 		}
 
 		// Task routes (protected) - AI task extraction and management
@@ -112,12 +166,37 @@ func SetupRoutes(r *gin.Engine, authUsecase authUsecase.AuthUsecase, emailUsecas
 			}
 		}
 
-		// Settings routes (public) - Runtime configuration
+		// Settings routes (admins only) - Runtime configuration of the AI providers shared by all users
 		settings := api.Group("/settings")
+		settings.Use(delivery.AuthMiddleware(authUsecase), delivery.AdminMiddleware(cfg.AdminEmails))
 		{
 			settings.GET("/ollama", GetOllamaSettings)
 			settings.PUT("/ollama", UpdateOllamaSettings)
 			settings.POST("/ollama/test", TestOllamaConnection)
+			settings.GET("/openai", GetOpenAISettings)
+			settings.PUT("/openai", UpdateOpenAISettings)
+			settings.POST("/openai/test", TestOpenAIConnection)
+			settings.GET("/ai/chain", GetAIChainSettings(aiChain))
+			settings.PUT("/ai/chain", UpdateAIChainSettings(aiChain))
+
+			// Prompt templates: versions, A/B tests and their results
+			prompts := settings.Group("/ai/prompts")
+			{
+				prompts.GET("", promptHandler.ListPrompts)
+				prompts.POST("/preview", promptHandler.PreviewPrompt)
+				prompts.POST("/:name", promptHandler.CreatePrompt)
+				prompts.PUT("/:name/active", promptHandler.ActivatePrompt)
+				prompts.GET("/:name/experiment", promptHandler.GetExperiment)
+			}
+
+			// Background job queue: depth, retries and dead letters
+			jobs := settings.Group("/jobs")
+			{
+				jobs.GET("", GetJobQueueStats(jobQueue))
+				jobs.GET("/dead", GetDeadJobs(jobQueue))
+				jobs.POST("/dead/:id/retry", RetryDeadJob(jobQueue))
+				jobs.DELETE("/dead", PurgeDeadJobs(jobQueue))
+			}
 		}
 	}
 }
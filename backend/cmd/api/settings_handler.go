@@ -1,9 +1,13 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 
+	"ga03-backend/pkg/ai"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,6 +15,9 @@ import (
 type RuntimeConfig struct {
 	OllamaBaseURL string `json:"ollama_base_url"`
 	OllamaModel   string `json:"ollama_model,omitempty"`
+	OpenAIBaseURL string `json:"openai_base_url"`
+	OpenAIModel   string `json:"openai_model,omitempty"`
+	OpenAIAPIKey  string `json:"-"`
 }
 
 var (
@@ -19,12 +26,15 @@ var (
 )
 
 // InitRuntimeConfig initializes runtime config from static config
-func InitRuntimeConfig(ollamaBaseURL, ollamaModel string) {
+func InitRuntimeConfig(ollamaBaseURL, ollamaModel, openAIBaseURL, openAIModel, openAIAPIKey string) {
 	runtimeConfigLock.Lock()
 	defer runtimeConfigLock.Unlock()
 	runtimeConfig = RuntimeConfig{
 		OllamaBaseURL: ollamaBaseURL,
 		OllamaModel:   ollamaModel,
+		OpenAIBaseURL: openAIBaseURL,
+		OpenAIModel:   openAIModel,
+		OpenAIAPIKey:  openAIAPIKey,
 	}
 }
 
@@ -42,6 +52,27 @@ func GetRuntimeOllamaModel() string {
 	return runtimeConfig.OllamaModel
 }
 
+// GetRuntimeOpenAIBaseURL returns the current runtime OpenAI-compatible base URL
+func GetRuntimeOpenAIBaseURL() string {
+	runtimeConfigLock.RLock()
+	defer runtimeConfigLock.RUnlock()
+	return runtimeConfig.OpenAIBaseURL
+}
+
+// GetRuntimeOpenAIModel returns the current runtime OpenAI-compatible model
+func GetRuntimeOpenAIModel() string {
+	runtimeConfigLock.RLock()
+	defer runtimeConfigLock.RUnlock()
+	return runtimeConfig.OpenAIModel
+}
+
+// GetRuntimeOpenAIAPIKey returns the current runtime OpenAI-compatible API key
+func GetRuntimeOpenAIAPIKey() string {
+	runtimeConfigLock.RLock()
+	defer runtimeConfigLock.RUnlock()
+	return runtimeConfig.OpenAIAPIKey
+}
+
 // UpdateOllamaSettingsRequest represents the request body for updating Ollama settings
 type UpdateOllamaSettingsRequest struct {
 	OllamaBaseURL string `json:"ollama_base_url" binding:"required"`
@@ -121,3 +152,178 @@ func TestOllamaConnection(c *gin.Context) {
 		"ollama_base_url": req.OllamaBaseURL,
 	})
 }
+
+// UpdateOpenAISettingsRequest represents the request body for updating the OpenAI-compatible provider.
+// An empty base URL disables the provider in the fallback chain.
+type UpdateOpenAISettingsRequest struct {
+	OpenAIBaseURL string  `json:"openai_base_url"`
+	OpenAIModel   string  `json:"openai_model,omitempty"`
+	OpenAIAPIKey  *string `json:"openai_api_key,omitempty"` // nil = keep current key
+}
+
+// GetOpenAISettings returns current OpenAI-compatible configuration (the API key is never returned)
+// GET /api/settings/openai
+func GetOpenAISettings(c *gin.Context) {
+	runtimeConfigLock.RLock()
+	defer runtimeConfigLock.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"openai_base_url":    runtimeConfig.OpenAIBaseURL,
+		"openai_model":       runtimeConfig.OpenAIModel,
+		"openai_api_key_set": runtimeConfig.OpenAIAPIKey != "",
+	})
+}
+
+// UpdateOpenAISettings updates OpenAI-compatible configuration at runtime
+// PUT /api/settings/openai
+func UpdateOpenAISettings(c *gin.Context) {
+	var req UpdateOpenAISettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	runtimeConfigLock.Lock()
+	runtimeConfig.OpenAIBaseURL = strings.TrimRight(req.OpenAIBaseURL, "/")
+	if req.OpenAIModel != "" {
+		runtimeConfig.OpenAIModel = req.OpenAIModel
+	}
+	if req.OpenAIAPIKey != nil {
+		runtimeConfig.OpenAIAPIKey = *req.OpenAIAPIKey
+	}
+	baseURL, model := runtimeConfig.OpenAIBaseURL, runtimeConfig.OpenAIModel
+	runtimeConfigLock.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "OpenAI-compatible settings updated successfully",
+		"openai_base_url": baseURL,
+		"openai_model":    model,
+	})
+}
+
+// TestOpenAIConnection tests if the OpenAI-compatible server is reachable.
+// Without openai_base_url, the stored base URL is tested with the stored key. A base URL given in the
+// request is only sent the key given in the same request: the stored key never leaves for another server.
+// POST /api/settings/openai/test
+func TestOpenAIConnection(c *gin.Context) {
+	var req struct {
+		OpenAIBaseURL string `json:"openai_base_url"`
+		OpenAIAPIKey  string `json:"openai_api_key"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	apiKey := req.OpenAIAPIKey
+	if req.OpenAIBaseURL == "" {
+		req.OpenAIBaseURL = GetRuntimeOpenAIBaseURL()
+		if apiKey == "" {
+			apiKey = GetRuntimeOpenAIAPIKey()
+		}
+	}
+	if req.OpenAIBaseURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"connected": false, "error": "openai_base_url is not configured"})
+		return
+	}
+
+	// Test connection by listing models (GET /models is part of the OpenAI API)
+	httpReq, err := http.NewRequestWithContext(c.Request.Context(), "GET", strings.TrimRight(req.OpenAIBaseURL, "/")+"/models", nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"connected": false, "error": err.Error()})
+		return
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"connected": false,
+			"error":     err.Error(),
+		})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"connected":   false,
+			"status_code": resp.StatusCode,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connected":       true,
+		"openai_base_url": req.OpenAIBaseURL,
+	})
+}
+
+// UpdateAIChainRequest sets the provider order of one or more operations
+type UpdateAIChainRequest struct {
+	Orders map[ai.Operation][]string `json:"orders" binding:"required"`
+}
+
+// GetAIChainSettings returns the provider order per operation and the registered providers
+// GET /api/settings/ai/chain
+func GetAIChainSettings(chain *ai.FallbackService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if chain == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service not initialized"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"providers": chain.Providers(),
+			"orders":    chain.Orders(),
+		})
+	}
+}
+
+// UpdateAIChainSettings changes provider orders at runtime, e.g. {"orders": {"summarize": ["openai", "gemini"]}}
+// PUT /api/settings/ai/chain
+func UpdateAIChainSettings(chain *ai.FallbackService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if chain == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service not initialized"})
+			return
+		}
+
+		var req UpdateAIChainRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Validate every order before applying any of them
+		providers := make(map[string]bool)
+		for _, name := range chain.Providers() {
+			providers[name] = true
+		}
+		current := chain.Orders()
+		for op, names := range req.Orders {
+			if _, ok := current[op]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown operation: %s", op)})
+				return
+			}
+			if len(names) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("order for %s must contain at least one provider", op)})
+				return
+			}
+			for _, name := range names {
+				if !providers[name] {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown provider: %s", name)})
+					return
+				}
+			}
+		}
+		for op, names := range req.Orders {
+			if err := chain.SetOrder(op, names); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "AI provider chain updated successfully",
+			"orders":  chain.Orders(),
+		})
+	}
+}
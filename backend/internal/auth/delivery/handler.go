@@ -1,6 +1,7 @@
 package delivery
 
 import (
+	"errors"
 	"net/http"
 
 	authdto "ga03-backend/internal/auth/dto"
@@ -100,6 +101,56 @@ func (h *AuthHandler) SetPassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "password set successfully"})
 }
 
+// PUT /auth/language
+func (h *AuthHandler) UpdateLanguage(c *gin.Context) {
+	var req authdto.UpdateLanguageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	user, err := h.authUsecase.UpdateLanguage(userID, req.Language)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// PUT /auth/timezone
+func (h *AuthHandler) UpdateTimeZone(c *gin.Context) {
+	var req authdto.UpdateTimeZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	user, err := h.authUsecase.UpdateTimeZone(userID, req.TimeZone)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidTimeZone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
 func (h *AuthHandler) GoogleSignIn(c *gin.Context) {
 	var req authdto.GoogleSignInRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"ga03-backend/internal/auth/domain"
 	"ga03-backend/internal/auth/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -44,3 +45,32 @@ func AuthMiddleware(authUsecase usecase.AuthUsecase) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminMiddleware only lets the users listed in adminEmails through (must run after AuthMiddleware).
+// It fails closed: with no admin configured, every request is denied.
+func AdminMiddleware(adminEmails []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		if len(admins) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access is disabled: no admin configured (ADMIN_EMAILS)"})
+			return
+		}
+		user, exists := c.Get("user")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		userData, ok := user.(*domain.User)
+		if !ok || !admins[strings.ToLower(userData.Email)] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
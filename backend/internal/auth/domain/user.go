@@ -21,6 +21,12 @@ type User struct {
 	// Email sync status
 	EmailsSynced bool `json:"emails_synced" gorm:"default:false"` // Whether all emails have been synced to vector DB
 
+	// Language of AI-generated content (summaries, drafts...), e.g. "vi" or "en"; empty = default prompts
+	Language string `json:"language,omitempty"`
+
+	// IANA time zone of the user, e.g. "Asia/Ho_Chi_Minh"; relative dates in emails resolve in it (empty = UTC)
+	TimeZone string `json:"time_zone,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -33,6 +33,14 @@ type SetPasswordRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+type UpdateLanguageRequest struct {
+	Language string `json:"language" binding:"required,max=16"`
+}
+
+type UpdateTimeZoneRequest struct {
+	TimeZone string `json:"time_zone" binding:"required,max=64"`
+}
+
 type TokenResponse struct {
 	AccessToken      string              `json:"access_token"`
 	RefreshToken     string              `json:"refresh_token"`
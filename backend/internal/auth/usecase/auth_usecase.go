@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	authdomain "ga03-backend/internal/auth/domain"
@@ -158,6 +159,49 @@ func (u *authUsecase) SetPassword(userID string, password string) error {
 	return u.userRepo.Update(user)
 }
 
+func (u *authUsecase) UpdateLanguage(userID string, language string) (*authdomain.User, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	user.Language = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+	if err := u.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ErrInvalidTimeZone is returned when a time zone is not a known IANA name
+var ErrInvalidTimeZone = errors.New("invalid time zone")
+
+func (u *authUsecase) UpdateTimeZone(userID string, timeZone string) (*authdomain.User, error) {
+	timeZone = strings.TrimSpace(timeZone)
+	if timeZone == "" || strings.EqualFold(timeZone, "Local") {
+		return nil, ErrInvalidTimeZone
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	user.TimeZone = timeZone
+	if err := u.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (u *authUsecase) Register(req *authdto.RegisterRequest) (*authdto.TokenResponse, error) {
 	existing, err := u.userRepo.FindByEmail(req.Email)
 	if err != nil {
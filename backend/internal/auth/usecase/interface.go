@@ -18,6 +18,8 @@ type AuthUsecase interface {
 	Logout(refreshToken string) error
 	ValidateToken(tokenString string) (*authdomain.User, error)
 	SetPassword(userID string, password string) error
+	UpdateLanguage(userID string, language string) (*authdomain.User, error)
+	UpdateTimeZone(userID string, timeZone string) (*authdomain.User, error)
 	SetEmailSyncCallback(callback EmailSyncCallback)
 	
 	// FCM Token Management
@@ -1,9 +1,12 @@
 package delivery
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
@@ -16,6 +19,7 @@ import (
 	emaildomain "ga03-backend/internal/email/domain"
 	emaildto "ga03-backend/internal/email/dto"
 	"ga03-backend/internal/email/usecase"
+	"ga03-backend/pkg/ai"
 
 	"github.com/gin-gonic/gin"
 )
@@ -77,6 +81,10 @@ func (h *EmailHandler) CreateKanbanColumn(c *gin.Context) {
 
 	err := h.emailUsecase.CreateKanbanColumn(userData.ID, &req)
 	if err != nil {
+		if errors.Is(err, usecase.ErrSavedSearchNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -107,6 +115,10 @@ func (h *EmailHandler) UpdateKanbanColumn(c *gin.Context) {
 	req.ColumnID = columnID // Ensure column_id matches URL param
 	err := h.emailUsecase.UpdateKanbanColumn(userData.ID, &req)
 	if err != nil {
+		if errors.Is(err, usecase.ErrSavedSearchNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -167,6 +179,380 @@ func (h *EmailHandler) UpdateKanbanColumnOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "column orders updated"})
 }
 
+// GET /kanban/export?format=json|csv
+func (h *EmailHandler) ExportKanban(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	filename := "kanban-" + time.Now().Format("20060102")
+
+	switch format {
+	case "json":
+		board, err := h.emailUsecase.ExportKanbanBoard(userData.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", buildAttachmentContentDisposition(filename+".json"))
+		c.JSON(http.StatusOK, board)
+	case "csv":
+		cards, err := h.emailUsecase.ExportKanbanCards(userData.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		var buf bytes.Buffer
+		if err := emaildto.WriteKanbanCardsCSV(&buf, cards); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", buildAttachmentContentDisposition(filename+".csv"))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+	}
+}
+
+// POST /kanban/import?mode=merge|replace&format=json|csv
+// Accepts the file as raw body or as multipart field "file".
+// JSON restores columns and cards; CSV only restores cards into existing columns.
+func (h *EmailHandler) ImportKanban(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	mode := emaildomain.KanbanImportMode(strings.ToLower(c.DefaultQuery("mode", string(emaildomain.KanbanImportMerge))))
+
+	var body io.Reader = c.Request.Body
+	format := strings.ToLower(c.Query("format"))
+	filename := ""
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+		filename = strings.ToLower(fileHeader.Filename)
+	}
+	if format == "" {
+		if strings.HasSuffix(filename, ".csv") || strings.Contains(c.ContentType(), "csv") {
+			format = "csv"
+		} else {
+			format = "json"
+		}
+	}
+
+	var (
+		result *emaildomain.KanbanImportResult
+		err    error
+	)
+	switch format {
+	case "json":
+		var board emaildomain.KanbanBoardExport
+		if decodeErr := json.NewDecoder(body).Decode(&board); decodeErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json: " + decodeErr.Error()})
+			return
+		}
+		result, err = h.emailUsecase.ImportKanbanBoard(userData.ID, &board, mode)
+	case "csv":
+		assignments, parseErr := emaildto.ParseKanbanCardsCSV(body)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": parseErr.Error()})
+			return
+		}
+		result, err = h.emailUsecase.ImportKanbanCards(userData.ID, assignments, mode)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidKanbanImport) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// maxKanbanStatsDays caps the date range of GET /kanban/stats
+const maxKanbanStatsDays = 366
+
+// GET /kanban/stats?from=YYYY-MM-DD&to=YYYY-MM-DD&tz=Asia/Ho_Chi_Minh&done_column=done
+// Both dates are inclusive and interpreted in tz (default UTC). Default range: last 30 days.
+func (h *EmailHandler) GetKanbanStats(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz"})
+		return
+	}
+
+	now := time.Now().In(loc)
+	toDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if raw := c.Query("to"); raw != "" {
+		if toDay, err = time.ParseInLocation("2006-01-02", raw, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+			return
+		}
+	}
+	fromDay := toDay.AddDate(0, 0, -29)
+	if raw := c.Query("from"); raw != "" {
+		if fromDay, err = time.ParseInLocation("2006-01-02", raw, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+			return
+		}
+	}
+	if toDay.Before(fromDay) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	to := toDay.AddDate(0, 0, 1) // Exclusive upper bound
+	if to.Sub(fromDay) > maxKanbanStatsDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("date range must not exceed %d days", maxKanbanStatsDays)})
+		return
+	}
+
+	stats, err := h.emailUsecase.GetKanbanStats(userData.ID, fromDay, to, loc, c.DefaultQuery("done_column", "done"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// maxTriageSuggestionsLimit caps GET /kanban/triage/suggestions
+const maxTriageSuggestionsLimit = 200
+
+// triageErrorStatus maps triage usecase errors to HTTP status codes
+func triageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrTriageUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ai.ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, usecase.ErrInvalidTriageSettings):
+		return http.StatusBadRequest
+	case errors.Is(err, usecase.ErrTriageSuggestionNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GET /kanban/triage/settings
+func (h *EmailHandler) GetTriageSettings(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	settings, err := h.emailUsecase.GetTriageSettings(userData.ID)
+	if err != nil {
+		c.JSON(triageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+// PUT /kanban/triage/settings
+// Body: {"enabled": true, "auto_move_threshold": 0.85, "suggest_threshold": 0.5}
+// Only emails received after auto-triage is enabled are classified automatically.
+func (h *EmailHandler) UpdateTriageSettings(c *gin.Context) {
+	var req emaildto.TriageSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	current, err := h.emailUsecase.GetTriageSettings(userData.ID)
+	if err != nil {
+		c.JSON(triageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	autoMove, suggest := current.AutoMoveThreshold, current.SuggestThreshold
+	if req.AutoMoveThreshold != nil {
+		autoMove = *req.AutoMoveThreshold
+	}
+	if req.SuggestThreshold != nil {
+		suggest = *req.SuggestThreshold
+	}
+
+	settings, err := h.emailUsecase.UpdateTriageSettings(userData.ID, req.Enabled, autoMove, suggest)
+	if err != nil {
+		c.JSON(triageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+// GET /kanban/triage/suggestions?status=pending&limit=50
+// status defaults to pending; status=all lists every suggestion
+func (h *EmailHandler) GetTriageSuggestions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	status := emaildomain.TriageSuggestionStatus(c.DefaultQuery("status", string(emaildomain.TriageStatusPending)))
+	if status == "all" {
+		status = ""
+	}
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > maxTriageSuggestionsLimit {
+		limit = maxTriageSuggestionsLimit
+	}
+
+	suggestions, err := h.emailUsecase.ListTriageSuggestions(userData.ID, status, limit)
+	if err != nil {
+		c.JSON(triageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// POST /kanban/triage/suggestions/:suggestion_id/accept
+// Body (optional): {"column_id": "..."} to file the email somewhere else than suggested
+func (h *EmailHandler) AcceptTriageSuggestion(c *gin.Context) {
+	suggestionID := c.Param("suggestion_id")
+	var req emaildto.AcceptTriageRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	suggestion, err := h.emailUsecase.AcceptTriageSuggestion(userData.ID, suggestionID, req.ColumnID)
+	if err != nil {
+		c.JSON(triageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "suggestion accepted", "suggestion": suggestion})
+}
+
+// POST /kanban/triage/suggestions/:suggestion_id/reject
+func (h *EmailHandler) RejectTriageSuggestion(c *gin.Context) {
+	suggestionID := c.Param("suggestion_id")
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	if err := h.emailUsecase.RejectTriageSuggestion(userData.ID, suggestionID); err != nil {
+		c.JSON(triageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "suggestion rejected"})
+}
+
+// POST /emails/:id/triage
+// Classifies one email now, even if auto-triage is disabled; thresholds still decide the move
+func (h *EmailHandler) TriageEmail(c *gin.Context) {
+	id := c.Param("id")
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	suggestion, err := h.emailUsecase.TriageEmail(userData.ID, id)
+	if err != nil {
+		c.JSON(triageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestion": suggestion})
+}
+
 // GET /emails/:id/summary
 func (h *EmailHandler) SummarizeEmail(c *gin.Context) {
 	id := c.Param("id")
@@ -181,12 +567,68 @@ func (h *EmailHandler) SummarizeEmail(c *gin.Context) {
 	ctx = context.WithValue(ctx, "userID", userID)
 	summary, err := h.emailUsecase.SummarizeEmail(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, ai.ErrQuotaExceeded) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"summary": summary})
 }
 
+// POST /emails/:id/ai-reply
+// Body: {"tone": "...", "instruction": "...", "stream": false}
+// With stream=true the response is 202 with the draft skeleton; tokens arrive on the SSE
+// stream as ai_reply_delta events, then ai_reply_done (or ai_reply_error) with the same draft id.
+func (h *EmailHandler) DraftAIReply(c *gin.Context) {
+	id := c.Param("id")
+	var req emaildto.AIReplyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	opts := emaildomain.ReplyDraftOptions{Tone: req.Tone, Instruction: req.Instruction}
+
+	var draft *emaildomain.ReplyDraft
+	var err error
+	if req.Stream {
+		draft, err = h.emailUsecase.StreamDraftReply(userData.ID, id, opts)
+	} else {
+		draft, err = h.emailUsecase.DraftReply(c.Request.Context(), userData.ID, id, opts)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, usecase.ErrReplyDraftingUnavailable) {
+			status = http.StatusServiceUnavailable
+		} else if errors.Is(err, ai.ErrQuotaExceeded) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Stream {
+		c.JSON(http.StatusAccepted, gin.H{"draft": draft, "streaming": true})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"draft": draft})
+}
+
 // PATCH /emails/:id/mailbox
 func (h *EmailHandler) MoveEmailToMailbox(c *gin.Context) {
 	id := c.Param("id")
@@ -276,6 +718,82 @@ func (h *EmailHandler) UnsnoozeEmail(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "email unsnoozed", "target_column": targetColumn})
 }
 
+// POST /emails/:id/follow-up
+// Schedules a "remind me if no reply in N days" reminder for an existing sent email
+func (h *EmailHandler) ScheduleFollowUp(c *gin.Context) {
+	id := c.Param("id")
+	var req emaildto.FollowUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing days"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+	userID := userData.ID
+
+	reminder, err := h.emailUsecase.ScheduleFollowUp(userID, id, req.Days, req.ColumnID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "follow-up scheduled", "follow_up": reminder})
+}
+
+// GET /emails/follow-ups?status=pending
+func (h *EmailHandler) GetFollowUpReminders(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+	userID := userData.ID
+
+	status := emaildomain.FollowUpStatus(c.Query("status"))
+	reminders, err := h.emailUsecase.GetFollowUpReminders(userID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"follow_ups": reminders})
+}
+
+// DELETE /emails/follow-ups/:reminder_id
+func (h *EmailHandler) CancelFollowUp(c *gin.Context) {
+	reminderID := c.Param("reminder_id")
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+	userID := userData.ID
+
+	if err := h.emailUsecase.CancelFollowUp(userID, reminderID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "follow-up cancelled"})
+}
+
 func NewEmailHandler(emailUsecase usecase.EmailUsecase) *EmailHandler {
 	return &EmailHandler{
 		emailUsecase: emailUsecase,
@@ -543,12 +1061,30 @@ func (h *EmailHandler) SendEmail(c *gin.Context) {
 	// Combine files: regular attachments + inline images (with Content-ID set)
 	allFiles := append(req.Files, req.InlineImages...)
 
-	if err := h.emailUsecase.SendEmail(userID, req.To, req.Cc, req.Bcc, req.Subject, req.Body, allFiles); err != nil {
+	sent, err := h.emailUsecase.SendEmail(userID, req.To, req.Cc, req.Bcc, req.Subject, req.Body, allFiles)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "email sent successfully"})
+	response := gin.H{"message": "email sent successfully"}
+	if sent != nil {
+		response["email"] = sent
+	}
+
+	// Optional "remind me if no reply in N days"
+	// The email is already sent, so a failure here is reported without failing the request
+	if req.FollowUpDays > 0 {
+		reminder, err := h.emailUsecase.ScheduleFollowUpForSentEmail(userID, sent, req.To, req.Subject, req.FollowUpDays, req.FollowUpColumnID)
+		if err != nil {
+			log.Printf("Failed to schedule follow-up reminder: %v", err)
+			response["follow_up_error"] = err.Error()
+		} else {
+			response["follow_up"] = reminder
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *EmailHandler) TrashEmail(c *gin.Context) {
@@ -806,9 +1342,16 @@ func (h *EmailHandler) FuzzySearch(c *gin.Context) {
 
 	emails, total, err := h.emailUsecase.FuzzySearch(userID, query, limit, offset)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidSearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if offset == 0 {
+		h.emailUsecase.RecordSearchQuery(userID, query) // First page only: paging is not a new search
+	}
 
 	c.JSON(http.StatusOK, emaildto.EmailsResponse{
 		Emails: emails,
@@ -863,6 +1406,9 @@ func (h *EmailHandler) SemanticSearch(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if offset == 0 {
+		h.emailUsecase.RecordSearchQuery(userID, req.Query)
+	}
 
 	c.JSON(http.StatusOK, emaildto.EmailsResponse{
 		Emails: emails,
@@ -872,9 +1418,99 @@ func (h *EmailHandler) SemanticSearch(c *gin.Context) {
 	})
 }
 
+// GET /emails/:id/related?limit=10
+// GetRelatedEmails returns the emails most similar to an email ("more like this"), excluding its thread
+func (h *EmailHandler) GetRelatedEmails(c *gin.Context) {
+	id := c.Param("id")
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	related, err := h.emailUsecase.GetRelatedEmails(userData.ID, id, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrRelatedEmailsUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrRelatedEmailNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"related": related})
+}
+
+// HybridSearch ranks emails with a keyword (BM25) query and a semantic query fused with reciprocal rank fusion.
+// Each hit reports its rank and score in every source that found it.
+// POST /api/search/hybrid
+func (h *EmailHandler) HybridSearch(c *gin.Context) {
+	var req emaildto.HybridSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid query parameter"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	result, err := h.emailUsecase.HybridSearch(userData.ID, req.Query, limit, offset)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidSearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if offset == 0 {
+		h.emailUsecase.RecordSearchQuery(userData.ID, req.Query)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // GET /api/search/suggestions?q=query
-// GetSearchSuggestions returns suggestions for auto-complete
-// Suggestions are populated from relevant data (sender names, subject keywords) from inbox
+// GetSearchSuggestions returns suggestions for auto-complete, by category (past queries, contacts, subjects),
+// plus "suggestions": the values of all categories in that order
 // This does NOT use semantic search - semantic search is only used when user presses Enter or clicks suggestion
 func (h *EmailHandler) GetSearchSuggestions(c *gin.Context) {
 	query := c.Query("q")
@@ -895,15 +1531,146 @@ func (h *EmailHandler) GetSearchSuggestions(c *gin.Context) {
 	}
 	userID := userData.ID
 
-	// Get suggestions from inbox data (sender names, subject keywords)
-	// Limit to 5 suggestions for UI
+	// Limit to 5 suggestions per category for UI
 	suggestions, err := h.emailUsecase.GetSearchSuggestions(userID, query, 5)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+	values := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, group := range [][]*emaildomain.SearchSuggestion{suggestions.Queries, suggestions.Contacts, suggestions.Subjects} {
+		for _, suggestion := range group {
+			if key := strings.ToLower(suggestion.Value); !seen[key] {
+				seen[key] = true
+				values = append(values, suggestion.Value)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": values,
+		"queries":     suggestions.Queries,
+		"contacts":    suggestions.Contacts,
+		"subjects":    suggestions.Subjects,
+	})
+}
+
+// GET /api/search/saved
+// ListSavedSearches returns the saved searches of the user
+func (h *EmailHandler) ListSavedSearches(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	searches, err := h.emailUsecase.ListSavedSearches(userData.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"saved_searches": searches})
+}
+
+// POST /api/search/saved
+// CreateSavedSearch saves a search; it shows up as a virtual mailbox in GET /api/emails/mailboxes
+func (h *EmailHandler) CreateSavedSearch(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	var req emaildto.SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	search := req.ToSavedSearch()
+	if err := h.emailUsecase.CreateSavedSearch(userData.ID, search); err != nil {
+		if errors.Is(err, usecase.ErrInvalidSavedSearch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"saved_search": search})
+}
+
+// PUT /api/search/saved/:id
+// UpdateSavedSearch replaces the name, query, mode and alert flag of a saved search
+func (h *EmailHandler) UpdateSavedSearch(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	var req emaildto.SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	search := req.ToSavedSearch()
+	search.ID = c.Param("id")
+	updated, err := h.emailUsecase.UpdateSavedSearch(userData.ID, search)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrSavedSearchNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrInvalidSavedSearch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"saved_search": updated})
+}
+
+// DELETE /api/search/saved/:id
+// DeleteSavedSearch removes a saved search
+func (h *EmailHandler) DeleteSavedSearch(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	if err := h.emailUsecase.DeleteSavedSearch(userData.ID, c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrSavedSearchNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "saved search deleted"})
 }
 
 // POST /api/emails/bulk
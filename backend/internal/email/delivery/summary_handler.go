@@ -1,14 +1,20 @@
 package delivery
 
 import (
+	"errors"
 	"net/http"
 
 	authdomain "ga03-backend/internal/auth/domain"
 	"ga03-backend/internal/email/usecase"
+	"ga03-backend/pkg/ai"
+	"ga03-backend/pkg/jobqueue"
 
 	"github.com/gin-gonic/gin"
 )
 
+// maxUserSummaryRefresh bounds the stale summaries a user can queue per request
+const maxUserSummaryRefresh = 100
+
 // SummaryHandler handles email summary API endpoints
 type SummaryHandler struct {
 	summaryWorker *usecase.SummaryWorkerService
@@ -30,7 +36,7 @@ type QueueSummaryRequest struct {
 
 // POST /api/kanban/summarize
 // QueueSummaries queues emails for background AI summary generation
-// Returns cached summaries immediately; rest will arrive via SSE "summary_update" events
+// Returns cached summaries immediately; rest will stream via SSE "summary_delta" events, then "summary_update" with the final text
 func (h *SummaryHandler) QueueSummaries(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -76,12 +82,14 @@ func (h *SummaryHandler) QueueSummaries(c *gin.Context) {
 		}
 
 		job := usecase.SummaryJob{
-			UserID:  userID,
-			EmailID: email.ID,
-			Subject: email.Subject,
-			Body:    email.Body,
+			UserID:   userID,
+			EmailID:  email.ID,
+			Subject:  email.Subject,
+			Body:     email.Body,
+			Language: userData.Language,
 		}
-		if h.summaryWorker.QueueJob(job) {
+		// Summaries requested by the board are on screen: they go before backfills and refreshes
+		if h.summaryWorker.QueueJob(job, jobqueue.PriorityHigh) {
 			queuedCount++
 		}
 	}
@@ -91,3 +99,75 @@ func (h *SummaryHandler) QueueSummaries(c *gin.Context) {
 		"queued":    queuedCount,
 	})
 }
+
+// POST /api/emails/:id/summary/regenerate
+// RegenerateSummary summarizes an email again with the current model and prompt, replacing the cached summary.
+// Text streams via SSE "summary_delta" events; the new summary (with how it was generated) is returned.
+func (h *SummaryHandler) RegenerateSummary(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	email, err := h.emailUsecase.GetEmailByID(userData.ID, c.Param("id"))
+	if err != nil || email == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "email not found"})
+		return
+	}
+
+	summary, err := h.summaryWorker.Regenerate(usecase.SummaryJob{
+		UserID:   userData.ID,
+		EmailID:  email.ID,
+		Subject:  email.Subject,
+		Body:     email.Body,
+		Language: userData.Language,
+	})
+	if err != nil {
+		if errors.Is(err, usecase.ErrSummaryUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ai.ErrQuotaExceeded) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to regenerate summary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"summary": summary})
+}
+
+// POST /api/kanban/summarize/refresh
+// RefreshStaleSummaries queues the user's summaries generated by an older model or prompt version.
+// Refreshed summaries arrive via SSE "summary_update" events.
+func (h *SummaryHandler) RefreshStaleSummaries(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	queued, err := h.summaryWorker.RefreshStaleSummaries(userData.ID, maxUserSummaryRefresh)
+	if err != nil {
+		if errors.Is(err, usecase.ErrSummaryUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh summaries"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"queued": queued})
+}
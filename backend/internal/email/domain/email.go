@@ -1,16 +1,27 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Mailbox struct {
 	ID    string `json:"id"`
 	Name  string `json:"name"`
 	Type  string `json:"type"`  // "inbox", "sent", "drafts", etc.
 	Count int    `json:"count"` // unread count for inbox
+	// Saved search of a virtual mailbox (Type MailboxTypeSavedSearch)
+	SavedSearch *SavedSearch `json:"saved_search,omitempty"`
 }
 
 type Email struct {
 	ID          string       `json:"id"`
+	ThreadID    string       `json:"thread_id,omitempty"`   // Provider thread ID (Gmail)
+	MessageID   string       `json:"message_id,omitempty"`  // RFC 5322 Message-ID header
+	InReplyTo   string       `json:"in_reply_to,omitempty"` // In-Reply-To header, used for reply detection
 	UserID      string       `json:"user_id,omitempty"`
 	MailboxID   string       `json:"mailbox_id"`
 	Status      string       `json:"status"` // inbox, todo, done, snoozed
@@ -29,6 +40,7 @@ type Email struct {
 	ReceivedAt  time.Time    `json:"received_at"`
 	CreatedAt   time.Time    `json:"created_at"`
 	SnoozedUntil *time.Time  `json:"snoozed_until,omitempty"`
+	Highlight    *SearchHighlight `json:"highlight,omitempty"` // Passage that matched a semantic search
 }
 
 type Attachment struct {
@@ -39,3 +51,19 @@ type Attachment struct {
 	URL       string `json:"url,omitempty"`
 	ContentID string `json:"content_id,omitempty"`
 }
+
+// SentEmail identifies a message right after it has been sent
+type SentEmail struct {
+	ID        string `json:"id"`         // Provider message ID (empty for SMTP until the Sent folder is synced)
+	ThreadID  string `json:"thread_id"`  // Provider thread ID (Gmail)
+	MessageID string `json:"message_id"` // RFC 5322 Message-ID header
+}
+
+// NewMessageID generates a unique RFC 5322 Message-ID for an outgoing email
+func NewMessageID(fromEmail string) string {
+	domain := "localhost"
+	if idx := strings.LastIndex(fromEmail, "@"); idx >= 0 && idx < len(fromEmail)-1 {
+		domain = fromEmail[idx+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", uuid.New().String(), domain)
+}
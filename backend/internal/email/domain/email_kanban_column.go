@@ -5,12 +5,12 @@ import "time"
 // EmailKanbanColumn represents the mapping between an email and a Kanban column
 // This is used to track which emails belong to which custom Kanban columns
 type EmailKanbanColumn struct {
-	ID               string    `json:"id" gorm:"primaryKey"`
-	UserID           string    `json:"user_id" gorm:"index:idx_user_email_column;not null"`
-	EmailID          string    `json:"email_id" gorm:"index:idx_user_email_column;not null"`
-	ColumnID         string    `json:"column_id" gorm:"index:idx_user_email_column;not null"` // Kanban column ID (custom or default)
-	PreviousColumnID string     `json:"previous_column_id"`                                    // Column before snooze (for restore)
-	SnoozedUntil     *time.Time `json:"snoozed_until,omitempty"`                               // Expiration time for snooze
+	ID               string     `json:"id" gorm:"primaryKey"`
+	UserID           string     `json:"user_id" gorm:"index:idx_user_email_column;index:idx_user_column_updated,priority:1;not null"`
+	EmailID          string     `json:"email_id" gorm:"index:idx_user_email_column;not null"`
+	ColumnID         string     `json:"column_id" gorm:"index:idx_user_email_column;index:idx_user_column_updated,priority:2;index:idx_column_snoozed_until,priority:1;not null"` // Kanban column ID (custom or default)
+	PreviousColumnID string     `json:"previous_column_id"`                                                                                                                       // Column before snooze (for restore)
+	SnoozedUntil     *time.Time `json:"snoozed_until,omitempty" gorm:"index:idx_column_snoozed_until,priority:2"`                                                                 // Expiration time for snooze
 	CreatedAt        time.Time  `json:"created_at"`
-	UpdatedAt        time.Time  `json:"updated_at"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"index:idx_user_column_updated,priority:3"`
 }
@@ -9,9 +9,25 @@ type EmailSummary struct {
 	EmailID   string    `json:"email_id" gorm:"index:idx_user_email;uniqueIndex:idx_user_email_unique;not null"`
 	Summary   string    `json:"summary" gorm:"type:text"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// How the summary was generated; a summary is stale when the model or prompt version
+	// no longer serves traffic (empty = generated before tracking). A summary of content that changed
+	// is only replaced when a summary of the email is requested again (ContentHash differs).
+	Provider      string `json:"provider,omitempty" gorm:"index:idx_summary_generation;not null;default:''"`
+	Model         string `json:"model,omitempty" gorm:"index:idx_summary_generation;not null;default:''"`
+	Language      string `json:"language,omitempty" gorm:"not null;default:''"` // Language of the prompt template used
+	PromptVersion int    `json:"prompt_version" gorm:"not null;default:0"`
+	ContentHash   string `json:"content_hash,omitempty" gorm:"not null;default:''"` // SHA-256 of the subject and body summarized
 }
 
 // TableName specifies the table name for GORM
 func (EmailSummary) TableName() string {
 	return "email_summaries"
 }
+
+// SummaryGeneration describes what currently generates summaries: the model of each provider and,
+// per prompt language, the prompt versions serving traffic. Summaries made otherwise are stale.
+type SummaryGeneration struct {
+	Models         map[string]string // provider => model
+	PromptVersions map[string][]int  // language => versions
+}
@@ -5,10 +5,29 @@ import "time"
 // EmailSyncHistory tracks which emails have been synced to the vector database
 // This helps avoid unnecessary API calls to Chroma/Gemini
 type EmailSyncHistory struct {
-	ID        string    `json:"id" gorm:"primaryKey"`
-	UserID    string    `json:"user_id" gorm:"index:idx_user_email;not null"`
-	EmailID   string    `json:"email_id" gorm:"index:idx_user_email;not null;uniqueIndex:idx_user_email_unique"`
-	SyncedAt  time.Time `json:"synced_at"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       string    `json:"id" gorm:"primaryKey"`
+	UserID   string    `json:"user_id" gorm:"index:idx_user_email;not null"`
+	EmailID  string    `json:"email_id" gorm:"index:idx_user_email;not null;uniqueIndex:idx_user_email_unique"`
+	SyncedAt time.Time `json:"synced_at"`
+	// Model and dimension of the stored vector (rows synced before they were recorded used Gemini text-embedding-004)
+	EmbeddingModel string    `json:"embedding_model" gorm:"index;default:'gemini:text-embedding-004'"`
+	EmbeddingDim   int       `json:"embedding_dim" gorm:"default:768"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// AttachmentSyncHistory tracks which attachments have been extracted and embedded for attachment search.
+// Attachments without text (scanned PDFs) are recorded with no chunks, and those whose extraction failed
+// with the error, so they are not downloaded again until the extractor version changes.
+type AttachmentSyncHistory struct {
+	ID               string    `json:"id" gorm:"primaryKey"`
+	UserID           string    `json:"user_id" gorm:"uniqueIndex:idx_user_email_attachment;not null"`
+	EmailID          string    `json:"email_id" gorm:"uniqueIndex:idx_user_email_attachment;not null"`
+	AttachmentID     string    `json:"attachment_id" gorm:"uniqueIndex:idx_user_email_attachment;not null"`
+	Filename         string    `json:"filename"`
+	ChunkCount       int       `json:"chunk_count"`
+	EmbeddingModel   string    `json:"embedding_model" gorm:"index"`
+	ExtractorVersion int       `json:"extractor_version" gorm:"default:0"` // textextract.Version (0 for rows recorded before it existed)
+	ExtractError     string    `json:"extract_error,omitempty" gorm:"default:''"`
+	SyncedAt         time.Time `json:"synced_at"`
 }
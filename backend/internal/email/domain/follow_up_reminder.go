@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// FollowUpStatus represents the lifecycle state of a follow-up reminder
+type FollowUpStatus string
+
+const (
+	FollowUpStatusPending   FollowUpStatus = "pending"   // Waiting for a reply or the deadline
+	FollowUpStatusReplied   FollowUpStatus = "replied"   // A reply arrived before the deadline
+	FollowUpStatusTriggered FollowUpStatus = "triggered" // Deadline passed without reply, email resurfaced
+	FollowUpStatusCancelled FollowUpStatus = "cancelled" // Cancelled by the user
+)
+
+// FollowUpReminder tracks a sent email that should be resurfaced if nobody replies by RemindAt
+type FollowUpReminder struct {
+	ID             string         `json:"id" gorm:"primaryKey"`
+	UserID         string         `json:"user_id" gorm:"index:idx_follow_up_user;not null"`
+	EmailID        string         `json:"email_id" gorm:"index:idx_follow_up_user"` // Provider message ID (may be resolved later for IMAP)
+	ThreadID       string         `json:"thread_id" gorm:"index"`                   // Gmail thread ID (root Message-ID for IMAP)
+	MessageID      string         `json:"message_id" gorm:"index"`                  // RFC 5322 Message-ID of the sent email
+	Subject        string         `json:"subject"`
+	To             string         `json:"to"`
+	TargetColumnID string         `json:"target_column_id"` // Kanban column to resurface the email into
+	SentAt         time.Time      `json:"sent_at"`
+	RemindAt       time.Time      `json:"remind_at" gorm:"index:idx_follow_up_due"`
+	Status         FollowUpStatus `json:"status" gorm:"type:varchar(20);default:'pending';index:idx_follow_up_due"`
+	RepliedAt      *time.Time     `json:"replied_at,omitempty"`
+	TriggeredAt    *time.Time     `json:"triggered_at,omitempty"`
+	ClaimedUntil   *time.Time     `json:"-"` // Lease of the scheduler instance handling the due reminder
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// KanbanBoardExportVersion is the current version of the JSON board export format
+const KanbanBoardExportVersion = 1
+
+// KanbanImportMode controls how an import is applied to the existing board
+type KanbanImportMode string
+
+const (
+	KanbanImportMerge   KanbanImportMode = "merge"   // Upsert imported columns/cards, keep everything else
+	KanbanImportReplace KanbanImportMode = "replace" // Board ends up exactly as imported (default columns are kept)
+)
+
+// KanbanBoardExport is a full-fidelity snapshot of a user's Kanban board
+type KanbanBoardExport struct {
+	Version     int                      `json:"version"`
+	ExportedAt  time.Time                `json:"exported_at"`
+	Columns     []KanbanColumnExport     `json:"columns"`
+	Assignments []KanbanAssignmentExport `json:"assignments"`
+}
+
+// KanbanColumnExport is a column definition with its order, Gmail label mapping and saved search source
+type KanbanColumnExport struct {
+	ColumnID       string   `json:"column_id"`
+	Name           string   `json:"name"`
+	Order          int      `json:"order"`
+	GmailLabelID   string   `json:"gmail_label_id"`
+	RemoveLabelIDs []string `json:"remove_label_ids"`
+	Description    string   `json:"description,omitempty"`
+	Examples       []string `json:"examples,omitempty"`
+	SavedSearchID  string   `json:"saved_search_id,omitempty"`
+}
+
+// KanbanAssignmentExport places an email in a column (with snooze state if any)
+type KanbanAssignmentExport struct {
+	EmailID          string     `json:"email_id"`
+	ColumnID         string     `json:"column_id"`
+	PreviousColumnID string     `json:"previous_column_id,omitempty"`
+	SnoozedUntil     *time.Time `json:"snoozed_until,omitempty"`
+}
+
+// KanbanCard is a card row of the CSV export
+type KanbanCard struct {
+	EmailID      string
+	Subject      string
+	From         string
+	ColumnID     string
+	SnoozedUntil *time.Time
+}
+
+// KanbanImportResult summarizes what an import changed
+type KanbanImportResult struct {
+	Mode                KanbanImportMode `json:"mode"`
+	ColumnsCreated      int              `json:"columns_created"`
+	ColumnsUpdated      int              `json:"columns_updated"`
+	ColumnsDeleted      int              `json:"columns_deleted"`
+	AssignmentsImported int              `json:"assignments_imported"`
+	AssignmentsRemoved  int              `json:"assignments_removed"`
+}
@@ -48,6 +48,9 @@ type KanbanColumn struct {
 	Order          int         `json:"order" gorm:"column:display_order;not null;default:0"` // Display order
 	GmailLabelID   string      `json:"gmail_label_id,omitempty" gorm:"default:''"`           // Gmail label ID to add when moving here (e.g., "STARRED")
 	RemoveLabelIDs StringArray `json:"remove_label_ids,omitempty" gorm:"type:text"`          // Gmail label IDs to remove when moving here (e.g., ["INBOX"])
+	Description    string      `json:"description,omitempty" gorm:"type:text;default:''"`    // What belongs here, used by AI auto-triage
+	Examples       StringArray `json:"examples,omitempty" gorm:"type:text"`                  // Example subjects/phrases of emails that belong here (AI auto-triage)
+	SavedSearchID  string      `json:"saved_search_id,omitempty" gorm:"default:''"`          // Saved search listing the emails of the column (smart column)
 	CreatedAt      time.Time   `json:"created_at"`
 	UpdatedAt      time.Time   `json:"updated_at"`
 }
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// KanbanColumnTransition records one move of an email between Kanban columns.
+// Used for analytics (cycle time, throughput); the current column lives in EmailKanbanColumn.
+type KanbanColumnTransition struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	UserID     string    `json:"user_id" gorm:"index:idx_transition_user_to_moved,priority:1;index:idx_transition_user_email,priority:1;not null"`
+	EmailID    string    `json:"email_id" gorm:"index:idx_transition_user_email,priority:2;not null"`
+	FromColumn string    `json:"from_column"` // Empty when the email had no column yet
+	ToColumn   string    `json:"to_column" gorm:"index:idx_transition_user_to_moved,priority:2;not null"`
+	MovedAt    time.Time `json:"moved_at" gorm:"index:idx_transition_user_to_moved,priority:3;not null"`
+}
@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// KanbanStats is the analytics view of a user's Kanban board
+type KanbanStats struct {
+	From            time.Time               `json:"from"`
+	To              time.Time               `json:"to"`
+	Timezone        string                  `json:"timezone"`
+	DoneColumn      string                  `json:"done_column"`
+	TotalCards      int64                   `json:"total_cards"`
+	Columns         []KanbanColumnStats     `json:"columns"`
+	AgeDistribution []KanbanAgeBucket       `json:"age_distribution"`
+	TimeToDone      KanbanCycleTime         `json:"time_to_done"`
+	Throughput      []KanbanDailyThroughput `json:"throughput"`
+}
+
+// KanbanColumnStats describes the cards currently sitting in one column.
+// Age = time since the card was last moved into the column.
+type KanbanColumnStats struct {
+	ColumnID        string            `json:"column_id"`
+	Name            string            `json:"name"`
+	Count           int64             `json:"count"`
+	AvgAgeHours     float64           `json:"avg_age_hours"`
+	OldestAgeHours  float64           `json:"oldest_age_hours"`
+	AgeDistribution []KanbanAgeBucket `json:"age_distribution"`
+}
+
+// KanbanAgeBucket counts cards whose age falls in a bucket (e.g. "1d-3d")
+type KanbanAgeBucket struct {
+	Bucket string `json:"bucket"`
+	Count  int64  `json:"count"`
+}
+
+// KanbanCycleTime summarizes how long cards took to reach the done column
+type KanbanCycleTime struct {
+	SampleSize  int64    `json:"sample_size"`
+	MedianHours *float64 `json:"median_hours"`
+	P90Hours    *float64 `json:"p90_hours"`
+	AvgHours    *float64 `json:"avg_hours"`
+}
+
+// KanbanDailyThroughput is the number of cards moved to the done column on a day
+type KanbanDailyThroughput struct {
+	Date  string `json:"date"` // YYYY-MM-DD in the requested timezone
+	Count int64  `json:"count"`
+}
@@ -2,11 +2,16 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"mime/multipart"
 
 	"golang.org/x/oauth2"
 )
 
+// ErrEmailNotFound is wrapped by providers when the mail server confirms an email does not exist
+// (deleted or bad ID), as opposed to a temporary failure
+var ErrEmailNotFound = errors.New("email not found")
+
 // TokenUpdateFunc is a callback function that handles token updates
 type TokenUpdateFunc func(token *oauth2.Token) error
 
@@ -14,9 +19,11 @@ type TokenUpdateFunc func(token *oauth2.Token) error
 type MailProvider interface {
 	GetMailboxes(ctx context.Context, accessToken, refreshToken string, onTokenRefresh TokenUpdateFunc) ([]*Mailbox, error)
 	GetEmails(ctx context.Context, accessToken, refreshToken, mailboxID string, limit, offset int, query string, onTokenRefresh TokenUpdateFunc) ([]*Email, int, error)
+	// GetEmailsExcluding lists a mailbox without the given emails, paginated and counted after exclusion
+	GetEmailsExcluding(ctx context.Context, accessToken, refreshToken, mailboxID string, excludeIDs []string, limit, offset int, onTokenRefresh TokenUpdateFunc) ([]*Email, int, error)
 	GetEmailByID(ctx context.Context, accessToken, refreshToken, messageID string, onTokenRefresh TokenUpdateFunc) (*Email, error)
 	GetAttachment(ctx context.Context, accessToken, refreshToken, messageID, attachmentID string, onTokenRefresh TokenUpdateFunc) (*Attachment, []byte, error)
-	SendEmail(ctx context.Context, accessToken, refreshToken, fromName, fromEmail, to, cc, bcc, subject, body string, files []*multipart.FileHeader, onTokenRefresh TokenUpdateFunc) error
+	SendEmail(ctx context.Context, accessToken, refreshToken, fromName, fromEmail, to, cc, bcc, subject, body string, files []*multipart.FileHeader, onTokenRefresh TokenUpdateFunc) (*SentEmail, error)
 	TrashEmail(ctx context.Context, accessToken, refreshToken, emailID string, onTokenRefresh TokenUpdateFunc) error
 	ArchiveEmail(ctx context.Context, accessToken, refreshToken, emailID string, onTokenRefresh TokenUpdateFunc) error
 	PermanentDeleteEmail(ctx context.Context, accessToken, refreshToken, emailID string, onTokenRefresh TokenUpdateFunc) error
@@ -26,5 +33,6 @@ type MailProvider interface {
 	ModifyMessageLabels(ctx context.Context, accessToken, refreshToken, messageID string, addLabelIDs, removeLabelIDs []string, onTokenRefresh TokenUpdateFunc) error
 	Watch(ctx context.Context, accessToken, refreshToken string, topicName string, onTokenRefresh TokenUpdateFunc) error
 	Stop(ctx context.Context, accessToken, refreshToken string, onTokenRefresh TokenUpdateFunc) error
+	GetThreadEmails(ctx context.Context, accessToken, refreshToken, threadID string, onTokenRefresh TokenUpdateFunc) ([]*Email, error)
 	ValidateToken(ctx context.Context, accessToken, refreshToken string, onTokenRefresh TokenUpdateFunc) error
 }
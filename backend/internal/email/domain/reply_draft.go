@@ -0,0 +1,18 @@
+package domain
+
+// ReplyDraftOptions steers the AI-generated reply
+type ReplyDraftOptions struct {
+	Tone        string // e.g. "formal", "friendly", "short and direct"
+	Instruction string // What the reply should say
+}
+
+// ReplyDraft is an AI-generated reply, ready to be edited and sent with /emails/send
+type ReplyDraft struct {
+	ID        string   `json:"id"`
+	EmailID   string   `json:"email_id"`
+	ThreadID  string   `json:"thread_id,omitempty"`
+	InReplyTo string   `json:"in_reply_to,omitempty"` // Message-ID of the email being answered
+	To        []string `json:"to"`
+	Subject   string   `json:"subject"`
+	Body      string   `json:"body"` // Plain text; empty until generation finishes when streamed
+}
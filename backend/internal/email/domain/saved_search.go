@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// SavedSearchMode is the search run by a saved search
+type SavedSearchMode string
+
+const (
+	SavedSearchModeFuzzy    SavedSearchMode = "fuzzy"    // Keyword search with operators (FuzzySearch)
+	SavedSearchModeSemantic SavedSearchMode = "semantic" // Vector search (SemanticSearch, without AI query expansion)
+	SavedSearchModeHybrid   SavedSearchMode = "hybrid"   // Keyword + vector (HybridSearch)
+)
+
+// IsValid reports whether the mode is a known search mode
+func (m SavedSearchMode) IsValid() bool {
+	return m == SavedSearchModeFuzzy || m == SavedSearchModeSemantic || m == SavedSearchModeHybrid
+}
+
+// MailboxTypeSavedSearch is the type of the virtual mailbox of a saved search ("smart folder")
+const MailboxTypeSavedSearch = "saved_search"
+
+// savedSearchMailboxPrefix prefixes the ID of a saved search to make the ID of its virtual mailbox
+const savedSearchMailboxPrefix = "search:"
+
+// SavedSearch is a named query of a user, shown as a virtual mailbox and usable as a Kanban column source
+type SavedSearch struct {
+	ID          string          `json:"id" gorm:"primaryKey"`
+	UserID      string          `json:"user_id" gorm:"index;not null"`
+	Name        string          `json:"name" gorm:"not null"`
+	Query       string          `json:"query" gorm:"type:text;not null"`
+	Mode        SavedSearchMode `json:"mode" gorm:"type:varchar(16);not null;default:'fuzzy'"`
+	Alert       bool            `json:"alert" gorm:"not null;default:false"` // Notify (SSE and push) when a new email matches
+	AlertsSince *time.Time      `json:"-"`                                   // Emails received earlier never alert: alerts were off (nil: since creation)
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// SavedSearchAlert records that an email was checked against a saved search with alerts,
+// so that each email alerts at most once per saved search, whichever path sees it first
+type SavedSearchAlert struct {
+	SavedSearchID string `gorm:"primaryKey"`
+	EmailID       string `gorm:"primaryKey"`
+	UserID        string `gorm:"index;not null"`
+	CreatedAt     time.Time
+}
+
+// MailboxID returns the ID of the virtual mailbox of the saved search
+func (s *SavedSearch) MailboxID() string {
+	return savedSearchMailboxPrefix + s.ID
+}
+
+// SavedSearchIDFromMailbox returns the saved search of a virtual mailbox ID ("search:<id>")
+func SavedSearchIDFromMailbox(mailboxID string) (string, bool) {
+	if !strings.HasPrefix(mailboxID, savedSearchMailboxPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(mailboxID, savedSearchMailboxPrefix), true
+}
+
+// SavedSearchMatch is sent (SSE "saved_search_match") when a new email matches a saved search with alerts
+type SavedSearchMatch struct {
+	SavedSearch *SavedSearch `json:"saved_search"`
+	Email       *Email       `json:"email"`
+}
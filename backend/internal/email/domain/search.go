@@ -0,0 +1,62 @@
+package domain
+
+// Sources of a hybrid search
+const (
+	SearchSourceKeyword  = "keyword"
+	SearchSourceSemantic = "semantic"
+)
+
+// Sources of a search highlight
+const (
+	HighlightSourceBody       = "body"
+	HighlightSourceAttachment = "attachment"
+)
+
+// SearchHighlight is the passage that made an email match a semantic search
+type SearchHighlight struct {
+	Source       string  `json:"source"` // HighlightSourceBody or HighlightSourceAttachment
+	AttachmentID string  `json:"attachment_id,omitempty"`
+	Filename     string  `json:"filename,omitempty"`
+	MimeType     string  `json:"mime_type,omitempty"`
+	Snippet      string  `json:"snippet"`
+	Distance     float64 `json:"distance"` // Lower = more similar (metric of the vector backend)
+}
+
+// RelatedEmail is an email similar to another one ("more like this")
+type RelatedEmail struct {
+	Email    *Email  `json:"email"`
+	Distance float64 `json:"distance"` // Lower = more similar (metric of the vector backend)
+}
+
+// KeywordScore is the rank of an email in the keyword (BM25) results
+type KeywordScore struct {
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"` // BM25
+}
+
+// SemanticScore is the rank of an email in the vector results
+type SemanticScore struct {
+	Rank     int     `json:"rank"`
+	Distance float64 `json:"distance"` // Lower = more similar (metric of the vector backend)
+}
+
+// HybridSearchHit is an email of a hybrid search with its fused score and per-source scores
+type HybridSearchHit struct {
+	Email    *Email         `json:"email"`
+	Score    float64        `json:"score"` // Reciprocal rank fusion
+	Keyword  *KeywordScore  `json:"keyword,omitempty"`
+	Semantic *SemanticScore `json:"semantic,omitempty"`
+}
+
+// HybridSearchResult is a page of a hybrid search.
+// Total counts every matching email; only the best Ranked of them can be paged, and pages of the
+// same query come from the same ranking.
+type HybridSearchResult struct {
+	Hits           []*HybridSearchHit `json:"hits"`
+	Total          int                `json:"total"`
+	TotalEstimated bool               `json:"total_estimated"` // Keyword candidates were capped, so Total is an estimate
+	Ranked         int                `json:"ranked"`
+	Limit          int                `json:"limit"`
+	Offset         int                `json:"offset"`
+	Sources        []string           `json:"sources"` // Sources that ran (semantic is skipped when vector search is unavailable)
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// EmailSearchDocument is an email in the full-text search index (table email_search_index).
+// The *Folded fields hold the text as fuzzy.Fold returns it (lowercase words without accents),
+// so that "canh" finds "cảnh báo"; the other fields are what search results display.
+type EmailSearchDocument struct {
+	UserID           string    `json:"user_id"`
+	EmailID          string    `json:"email_id"`
+	MailboxID        string    `json:"mailbox_id"`
+	ThreadID         string    `json:"thread_id"`
+	Subject          string    `json:"subject"`
+	FromAddress      string    `json:"from"`
+	FromName         string    `json:"from_name"`
+	Recipients       string    `json:"recipients"` // To and Cc, comma separated
+	Preview          string    `json:"preview"`
+	SubjectFolded    string    `json:"-"`
+	SenderFolded     string    `json:"-"`
+	RecipientsFolded string    `json:"-"`
+	BodyFolded       string    `json:"-"`
+	HasAttachment    bool      `json:"has_attachment"`
+	IsRead           bool      `json:"is_read"`
+	IsStarred        bool      `json:"is_starred"`
+	Size             int64     `json:"size"`
+	ReceivedAt       time.Time `json:"received_at"`
+	IndexedAt        time.Time `json:"indexed_at"`
+}
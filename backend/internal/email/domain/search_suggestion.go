@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// Kinds of search suggestions
+const (
+	SuggestionKindContact = "contact" // Sender of an email (name, address)
+	SuggestionKindSubject = "subject" // Subject of an email, without "Re:"/"Fwd:" prefixes
+	SuggestionKindQuery   = "query"   // Query the user searched for
+)
+
+// SearchSuggestion is an auto-complete entry of a user. Entries are ranked by frecency: Score counts
+// the uses of the entry, each one halved every SuggestionHalfLife, as of LastUsedAt.
+type SearchSuggestion struct {
+	ID         string    `json:"-" gorm:"primaryKey"`
+	UserID     string    `json:"-" gorm:"uniqueIndex:idx_search_suggestion_key;not null"`
+	Kind       string    `json:"kind" gorm:"uniqueIndex:idx_search_suggestion_key;not null"`
+	Key        string    `json:"-" gorm:"column:suggestion_key;uniqueIndex:idx_search_suggestion_key;not null"` // Address of a contact, folded subject or query
+	Value      string    `json:"value" gorm:"not null"`                                                         // Text shown and searched when picked
+	Detail     string    `json:"detail,omitempty"`                                                              // Address of a contact
+	Folded     string    `json:"-" gorm:"not null"`                                                             // Words of the entry folded with fuzzy.Fold (prefix matching)
+	Count      int       `json:"count" gorm:"not null;default:0"`
+	Score      float64   `json:"-" gorm:"not null;default:0"`
+	LastUsedAt time.Time `json:"last_used_at" gorm:"not null"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+}
+
+// SuggestionHalfLife is the time after which a use of a suggestion counts half
+const SuggestionHalfLife = 30 * 24 * time.Hour
+
+// AddUse counts one use of the suggestion at use.LastUsedAt: the score decays to the later of the two
+// uses and gains one. A use older than the last one (emails synced out of order) adds its decayed weight
+// without moving the last use or the text back.
+func (s *SearchSuggestion) AddUse(use *SearchSuggestion) {
+	s.Count++
+	if use.LastUsedAt.Before(s.LastUsedAt) {
+		s.Score += suggestionDecay(s.LastUsedAt.Sub(use.LastUsedAt))
+		return
+	}
+	s.Score = s.Score*suggestionDecay(use.LastUsedAt.Sub(s.LastUsedAt)) + 1
+	s.LastUsedAt = use.LastUsedAt
+	s.Value = use.Value
+	s.Detail = use.Detail
+	s.Folded = use.Folded
+}
+
+// suggestionDecay is the weight of a use after elapsed time
+func suggestionDecay(elapsed time.Duration) float64 {
+	return math.Pow(0.5, elapsed.Hours()/SuggestionHalfLife.Hours())
+}
+
+// SearchSuggestionSource records the emails already counted in the suggestions of a user,
+// so that an email fetched again is not counted twice
+type SearchSuggestionSource struct {
+	UserID    string `gorm:"primaryKey"`
+	EmailID   string `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+// SearchSuggestions are the suggestions matching a prefix by kind, best first
+type SearchSuggestions struct {
+	Queries  []*SearchSuggestion `json:"queries"`
+	Contacts []*SearchSuggestion `json:"contacts"`
+	Subjects []*SearchSuggestion `json:"subjects"`
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSearchSuggestionAddUse(t *testing.T) {
+	last := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		score     float64
+		usedAt    time.Time
+		wantScore float64
+		wantLast  time.Time
+		wantValue string
+	}{
+		{"first use", 0, last, 1, last, "new"},
+		{"same time", 1, last, 2, last, "new"},
+		{"one half-life later", 2, last.Add(SuggestionHalfLife), 2, last.Add(SuggestionHalfLife), "new"},
+		{"two half-lives later", 4, last.Add(2 * SuggestionHalfLife), 2, last.Add(2 * SuggestionHalfLife), "new"},
+		{"older use adds its decayed weight", 1, last.Add(-SuggestionHalfLife), 1.5, last, "old"},
+		{"much older use barely counts", 1, last.Add(-20 * SuggestionHalfLife), 1 + math.Pow(0.5, 20), last, "old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SearchSuggestion{Value: "old", Count: 3, Score: tt.score, LastUsedAt: last}
+			s.AddUse(&SearchSuggestion{Value: "new", LastUsedAt: tt.usedAt})
+			if math.Abs(s.Score-tt.wantScore) > 1e-9 {
+				t.Errorf("Score = %v, want %v", s.Score, tt.wantScore)
+			}
+			if !s.LastUsedAt.Equal(tt.wantLast) || s.Value != tt.wantValue || s.Count != 4 {
+				t.Errorf("LastUsedAt, Value, Count = %v, %q, %d; want %v, %q, 4", s.LastUsedAt, s.Value, s.Count, tt.wantLast, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestSearchSuggestionFrecency(t *testing.T) {
+	// A suggestion used often long ago ranks below one used a few times recently
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := &SearchSuggestion{}
+	for i := 0; i < 10; i++ {
+		old.AddUse(&SearchSuggestion{LastUsedAt: start.Add(time.Duration(i) * time.Hour)})
+	}
+	recent := &SearchSuggestion{}
+	for i := 0; i < 3; i++ {
+		recent.AddUse(&SearchSuggestion{LastUsedAt: start.Add(4*SuggestionHalfLife + time.Duration(i)*time.Hour)})
+	}
+
+	now := start.Add(4*SuggestionHalfLife + time.Hour)
+	rank := func(s *SearchSuggestion) float64 {
+		return s.Score * suggestionDecay(now.Sub(s.LastUsedAt))
+	}
+	if rank(old) >= rank(recent) {
+		t.Errorf("rank of 10 old uses = %v, want below rank of 3 recent uses %v", rank(old), rank(recent))
+	}
+	if old.Count != 10 || recent.Count != 3 {
+		t.Errorf("counts = %d, %d; want 10, 3", old.Count, recent.Count)
+	}
+}
@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// TriageSettings are a user's AI auto-triage preferences
+type TriageSettings struct {
+	UserID            string     `json:"-" gorm:"primaryKey"`
+	Enabled           bool       `json:"enabled" gorm:"default:false"`
+	AutoMoveThreshold float64    `json:"auto_move_threshold" gorm:"default:0.85"` // Confidence at or above which the email is moved automatically
+	SuggestThreshold  float64    `json:"suggest_threshold" gorm:"default:0.5"`    // Confidence at or above which a suggestion is shown
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`                    // Only emails received after this are triaged automatically
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Default triage thresholds
+const (
+	DefaultTriageAutoMoveThreshold = 0.85
+	DefaultTriageSuggestThreshold  = 0.5
+)
+
+// TriageSuggestionStatus represents the lifecycle state of a triage suggestion
+type TriageSuggestionStatus string
+
+const (
+	TriageStatusPending       TriageSuggestionStatus = "pending"        // Shown to the user, waiting for accept/reject
+	TriageStatusAutoMoved     TriageSuggestionStatus = "auto_moved"     // Confidence was high enough to move the email
+	TriageStatusAccepted      TriageSuggestionStatus = "accepted"       // User accepted (or moved the email to the suggested column)
+	TriageStatusRejected      TriageSuggestionStatus = "rejected"       // User rejected (or moved the email somewhere else)
+	TriageStatusOverridden    TriageSuggestionStatus = "overridden"     // User moved an auto-moved email to another column
+	TriageStatusLowConfidence TriageSuggestionStatus = "low_confidence" // Below the suggestion threshold, kept so the email is not classified again
+	TriageStatusKept          TriageSuggestionStatus = "kept"           // Predicted column is the current one, nothing to do
+)
+
+// TriageSuggestion is the AI classification of one email into a Kanban column
+type TriageSuggestion struct {
+	ID         string                 `json:"id" gorm:"primaryKey"`
+	UserID     string                 `json:"user_id" gorm:"uniqueIndex:idx_triage_user_email,priority:1;index:idx_triage_user_status,priority:1;not null"`
+	EmailID    string                 `json:"email_id" gorm:"uniqueIndex:idx_triage_user_email,priority:2;not null"`
+	ColumnID   string                 `json:"column_id" gorm:"not null"`
+	Confidence float64                `json:"confidence"`
+	Reason     string                 `json:"reason,omitempty"`
+	Subject    string                 `json:"subject"` // For display without refetching the email
+	From       string                 `json:"from" gorm:"column:from_address"`
+	Status     TriageSuggestionStatus `json:"status" gorm:"type:varchar(20);index:idx_triage_user_status,priority:2;not null"`
+	ResolvedAt *time.Time             `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+// TriageExample is a manual move remembered as a few-shot example for the classifier
+type TriageExample struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"index:idx_triage_example_user_column,priority:1;not null"`
+	ColumnID  string    `json:"column_id" gorm:"index:idx_triage_example_user_column,priority:2;not null"`
+	EmailID   string    `json:"email_id"`
+	Subject   string    `json:"subject"`
+	From      string    `json:"from" gorm:"column:from_address"`
+	Snippet   string    `json:"snippet"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_triage_example_user_column,priority:3"`
+}
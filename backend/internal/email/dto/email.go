@@ -29,6 +29,34 @@ type SendEmailRequest struct {
 	// Inline images with Content-ID for embedding in HTML body
 	InlineImages     []*multipart.FileHeader `form:"inline_images"`
 	InlineImagesMeta string                  `form:"inline_images_meta"` // JSON: [{"filename":"x","content_id":"y"}]
+	// Follow-up reminder: resurface the email if nobody replies within N days (0 = disabled)
+	FollowUpDays     int    `form:"follow_up_days"`
+	FollowUpColumnID string `form:"follow_up_column_id"` // Defaults to FOLLOW_UP_DEFAULT_COLUMN
+}
+
+// FollowUpRequest schedules a follow-up reminder for an existing sent email
+type FollowUpRequest struct {
+	Days     int    `json:"days" binding:"required"`
+	ColumnID string `json:"column_id"`
+}
+
+// AIReplyRequest asks the AI to draft a reply to an email
+type AIReplyRequest struct {
+	Tone        string `json:"tone" binding:"max=100"`
+	Instruction string `json:"instruction" binding:"max=1000"`
+	Stream      bool   `json:"stream"` // Push tokens over SSE instead of waiting for the full draft
+}
+
+// TriageSettingsRequest updates AI auto-triage settings; omitted thresholds keep their current value
+type TriageSettingsRequest struct {
+	Enabled           bool     `json:"enabled"`
+	AutoMoveThreshold *float64 `json:"auto_move_threshold"`
+	SuggestThreshold  *float64 `json:"suggest_threshold"`
+}
+
+// AcceptTriageRequest accepts a triage suggestion, optionally into a different column
+type AcceptTriageRequest struct {
+	ColumnID string `json:"column_id"`
 }
 
 // ValidateEmailList validates a comma-separated list of email addresses
@@ -65,3 +93,28 @@ type BulkOperationRequest struct {
 	EmailIDs []string `json:"email_ids" binding:"required"`
 	Action   string   `json:"action" binding:"required"` // mark_read, mark_unread, trash, permanent_delete
 }
+
+// HybridSearchRequest for keyword + semantic search
+type HybridSearchRequest struct {
+	Query  string `json:"query" binding:"required"`
+	Limit  int    `json:"limit,omitempty"`
+	Offset int    `json:"offset,omitempty"` // Pages after the first reuse the ranking of the first page
+}
+
+// SavedSearchRequest creates or replaces a saved search
+type SavedSearchRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Query string `json:"query" binding:"required,max=1000"`
+	Mode  string `json:"mode,omitempty"`  // fuzzy (default), semantic or hybrid
+	Alert bool   `json:"alert,omitempty"` // SSE/FCM alert when a new email matches
+}
+
+// ToSavedSearch converts the request to a saved search
+func (r *SavedSearchRequest) ToSavedSearch() *emaildomain.SavedSearch {
+	return &emaildomain.SavedSearch{
+		Name:  r.Name,
+		Query: r.Query,
+		Mode:  emaildomain.SavedSearchMode(strings.ToLower(strings.TrimSpace(r.Mode))),
+		Alert: r.Alert,
+	}
+}
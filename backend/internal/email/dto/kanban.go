@@ -0,0 +1,105 @@
+package dto
+
+import (
+	"encoding/csv"
+	"fmt"
+	emaildomain "ga03-backend/internal/email/domain"
+	"io"
+	"strings"
+	"time"
+)
+
+// kanbanCSVHeader is the column layout of the CSV card export
+var kanbanCSVHeader = []string{"email_id", "subject", "from", "column", "snoozed_until"}
+
+// WriteKanbanCardsCSV writes cards as CSV (snoozed_until in RFC 3339)
+func WriteKanbanCardsCSV(w io.Writer, cards []*emaildomain.KanbanCard) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(kanbanCSVHeader); err != nil {
+		return err
+	}
+
+	for _, card := range cards {
+		snoozedUntil := ""
+		if card.SnoozedUntil != nil {
+			snoozedUntil = card.SnoozedUntil.UTC().Format(time.RFC3339)
+		}
+		record := []string{card.EmailID, card.Subject, card.From, card.ColumnID, snoozedUntil}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ParseKanbanCardsCSV reads card assignments from a CSV file. Columns are matched by header name;
+// only email_id and column are required, subject and from are ignored on import.
+func ParseKanbanCardsCSV(r io.Reader) ([]emaildomain.KanbanAssignmentExport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csv file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		// Strip a UTF-8 BOM left by spreadsheet tools
+		name = strings.TrimPrefix(name, "\ufeff")
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	// Accept column_id as an alias of column
+	if _, ok := index["column"]; !ok {
+		if i, ok := index["column_id"]; ok {
+			index["column"] = i
+		}
+	}
+	for _, required := range []string{"email_id", "column"} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("csv header is missing the %q column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var assignments []emaildomain.KanbanAssignmentExport
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue // Blank line
+		}
+
+		assignment := emaildomain.KanbanAssignmentExport{
+			EmailID:  field(record, "email_id"),
+			ColumnID: field(record, "column"),
+		}
+		if raw := field(record, "snoozed_until"); raw != "" {
+			until, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: snoozed_until must be RFC 3339, got %q", line, raw)
+			}
+			assignment.SnoozedUntil = &until
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
@@ -1,6 +1,10 @@
 package repository
 
-import "time"
+import (
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+)
 
 // EmailKanbanColumnRepository defines the interface for email-kanban column mapping repository
 type EmailKanbanColumnRepository interface {
@@ -19,21 +23,46 @@ type EmailKanbanColumnRepository interface {
 	// GetEmailsByColumn gets all email IDs for a specific column
 	GetEmailsByColumn(userID, columnID string) ([]string, error)
 
+	// GetEmailsByColumnPaginated gets one page of email IDs in a column (most recently moved first)
+	// together with the total number of emails in that column. Backed by idx_user_column_updated.
+	GetEmailsByColumnPaginated(userID, columnID string, limit, offset int) ([]string, int64, error)
+
+	// GetEmailIDsOutsideColumn gets IDs of emails mapped to any column other than columnID
+	GetEmailIDsOutsideColumn(userID, columnID string) ([]string, error)
+
 	// GetEmailColumnMap returns a map of emailID -> columnID for a user.
 	// Used to ensure an email doesn't appear in multiple columns in Kanban mode.
 	GetEmailColumnMap(userID string) (map[string]string, error)
 	
+	// GetMappingsByUserID gets all email-column mappings of a user (used by board export)
+	GetMappingsByUserID(userID string) ([]*emaildomain.EmailKanbanColumn, error)
+
+	// ImportMappings upserts mappings in one transaction. With replace, mappings of emails
+	// not in the import are deleted; returns how many were removed that way and the
+	// column each imported email was in before (emailID -> columnID).
+	ImportMappings(userID string, mappings []*emaildomain.EmailKanbanColumn, replace bool) (int, map[string]string, error)
+
 	// RemoveEmailColumn removes the column mapping for an email
 	RemoveEmailColumn(userID, emailID string) error
 	
 	// RemoveEmailColumnMapping removes a specific email-column mapping
 	RemoveEmailColumnMapping(userID, emailID, columnID string) error
 	
-	// GetAllSnoozedMappings gets all email mappings in snoozed column (for auto-unsnooze)
-	GetAllSnoozedMappings() ([]SnoozedEmailMapping, error)
+	// ClaimDueSnoozes atomically restores up to limit expired snoozes to their previous column.
+	// Rows are claimed with SELECT ... FOR UPDATE SKIP LOCKED so concurrent instances never wake the same email twice.
+	ClaimDueSnoozes(now time.Time, limit int) ([]SnoozedEmailMapping, error)
+
+	// GetSnoozeBacklog returns how many snoozes are due but not yet woken, and the oldest due time
+	GetSnoozeBacklog(now time.Time) (int64, *time.Time, error)
+
+	// Analytics, aggregated in SQL (see email_kanban_column_stats.go)
+	GetColumnCardStats(userID string, now time.Time) ([]ColumnCardStats, error)
+	GetCardAgeBuckets(userID string, now time.Time) ([]ColumnAgeBucketCount, error)
+	GetTimeToColumnStats(userID, columnID string, from, to time.Time) (*CycleTimeStats, error)
+	GetDailyArrivals(userID, columnID string, from, to time.Time, timezone string) ([]DailyCount, error)
 }
 
-// SnoozedEmailMapping contains info needed for auto-unsnooze
+// SnoozedEmailMapping contains info about a snooze restored by ClaimDueSnoozes
 type SnoozedEmailMapping struct {
 	UserID           string
 	EmailID          string
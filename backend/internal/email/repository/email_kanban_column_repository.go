@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type emailKanbanColumnRepository struct {
@@ -18,26 +19,40 @@ func NewEmailKanbanColumnRepository(db *gorm.DB) EmailKanbanColumnRepository {
 	return &emailKanbanColumnRepository{db: db}
 }
 
-// SetEmailColumn sets the column for an email (creates or updates)
+// SetEmailColumn sets the column for an email (creates or updates) and records the transition
 func (r *emailKanbanColumnRepository) SetEmailColumn(userID, emailID, columnID string) error {
-	var count int64
-	r.db.Model(&emaildomain.EmailKanbanColumn{}).Where("user_id = ? AND email_id = ?", userID, emailID).Count(&count)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		fromColumn, found, err := currentColumn(tx, userID, emailID)
+		if err != nil {
+			return err
+		}
 
-	if count == 0 {
-		// Create new mapping
-		mapping := emaildomain.EmailKanbanColumn{
-			ID:       uuid.New().String(),
-			UserID:   userID,
-			EmailID:  emailID,
-			ColumnID: columnID,
+		if !found {
+			// Create new mapping
+			mapping := emaildomain.EmailKanbanColumn{
+				ID:       uuid.New().String(),
+				UserID:   userID,
+				EmailID:  emailID,
+				ColumnID: columnID,
+			}
+			if err := tx.Create(&mapping).Error; err != nil {
+				return err
+			}
+		} else {
+			// Update existing mapping(s) - handle potential duplicates by updating all.
+			// Rows already in the column are left alone so a no-op move keeps their updated_at.
+			err := tx.Model(&emaildomain.EmailKanbanColumn{}).
+				Where("user_id = ? AND email_id = ? AND column_id <> ?", userID, emailID, columnID).
+				Update("column_id", columnID).Error
+			if err != nil {
+				return err
+			}
 		}
-		return r.db.Create(&mapping).Error
-	}
 
-	// Update existing mapping(s) - handle potential duplicates by updating all
-	return r.db.Model(&emaildomain.EmailKanbanColumn{}).
-		Where("user_id = ? AND email_id = ?", userID, emailID).
-		Update("column_id", columnID).Error
+		return recordTransitions(tx, []emaildomain.KanbanColumnTransition{{
+			UserID: userID, EmailID: emailID, FromColumn: fromColumn, ToColumn: columnID, MovedAt: time.Now(),
+		}})
+	})
 }
 
 // GetEmailColumn gets the column ID for an email
@@ -68,6 +83,41 @@ func (r *emailKanbanColumnRepository) GetEmailsByColumn(userID, columnID string)
 	return emailIDs, nil
 }
 
+// GetEmailsByColumnPaginated gets one page of email IDs in a column plus the column total
+func (r *emailKanbanColumnRepository) GetEmailsByColumnPaginated(userID, columnID string, limit, offset int) ([]string, int64, error) {
+	query := r.db.Model(&emaildomain.EmailKanbanColumn{}).Where("user_id = ? AND column_id = ?", userID, columnID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 || int64(offset) >= total {
+		return []string{}, total, nil
+	}
+
+	var emailIDs []string
+	err := query.Order("updated_at DESC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Pluck("email_id", &emailIDs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return emailIDs, total, nil
+}
+
+// GetEmailIDsOutsideColumn gets IDs of emails mapped to any other column
+func (r *emailKanbanColumnRepository) GetEmailIDsOutsideColumn(userID, columnID string) ([]string, error) {
+	var emailIDs []string
+	err := r.db.Model(&emaildomain.EmailKanbanColumn{}).
+		Where("user_id = ? AND column_id <> ?", userID, columnID).
+		Pluck("email_id", &emailIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return emailIDs, nil
+}
+
 // GetEmailColumnMap returns a map of emailID -> columnID for a user.
 func (r *emailKanbanColumnRepository) GetEmailColumnMap(userID string) (map[string]string, error) {
 	type row struct {
@@ -90,6 +140,102 @@ func (r *emailKanbanColumnRepository) GetEmailColumnMap(userID string) (map[stri
 	return result, nil
 }
 
+// GetMappingsByUserID gets all email-column mappings of a user
+func (r *emailKanbanColumnRepository) GetMappingsByUserID(userID string) ([]*emaildomain.EmailKanbanColumn, error) {
+	var mappings []*emaildomain.EmailKanbanColumn
+	err := r.db.Where("user_id = ?", userID).Order("column_id ASC, updated_at DESC, id ASC").Find(&mappings).Error
+	if err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// importBatchSize bounds the number of bind parameters per statement during imports
+const importBatchSize = 500
+
+// ImportMappings upserts mappings in one transaction (one row per email), optionally dropping the rest
+func (r *emailKanbanColumnRepository) ImportMappings(userID string, mappings []*emaildomain.EmailKanbanColumn, replace bool) (removed int, previous map[string]string, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		removed, previous, err = importMappings(tx, userID, mappings, replace)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return removed, previous, nil
+}
+
+// importMappings replaces the mappings of the imported emails within tx and records their transitions.
+// Returns the number of mappings removed by replace and the previous column of each imported email.
+func importMappings(tx *gorm.DB, userID string, mappings []*emaildomain.EmailKanbanColumn, replace bool) (int, map[string]string, error) {
+	removed := 0
+
+	emailIDs := make([]string, len(mappings))
+	for i, m := range mappings {
+		emailIDs[i] = m.EmailID
+	}
+
+	if replace {
+		query := tx.Where("user_id = ?", userID)
+		if len(emailIDs) > 0 {
+			query = query.Where("email_id NOT IN ?", emailIDs)
+		}
+		res := query.Delete(&emaildomain.EmailKanbanColumn{})
+		if res.Error != nil {
+			return 0, nil, res.Error
+		}
+		removed = int(res.RowsAffected)
+	}
+
+	// Drop existing rows (including duplicates) of the imported emails, then insert fresh ones
+	previous := make(map[string]string, len(emailIDs))
+	for start := 0; start < len(emailIDs); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(emailIDs) {
+			end = len(emailIDs)
+		}
+
+		var existing []emaildomain.EmailKanbanColumn
+		err := tx.Select("email_id, column_id").
+			Where("user_id = ? AND email_id IN ?", userID, emailIDs[start:end]).
+			Find(&existing).Error
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, e := range existing {
+			previous[e.EmailID] = e.ColumnID
+		}
+
+		err = tx.Where("user_id = ? AND email_id IN ?", userID, emailIDs[start:end]).
+			Delete(&emaildomain.EmailKanbanColumn{}).Error
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	now := time.Now()
+	transitions := make([]emaildomain.KanbanColumnTransition, 0, len(mappings))
+	for _, m := range mappings {
+		m.ID = uuid.New().String()
+		m.UserID = userID
+		m.CreatedAt = now
+		m.UpdatedAt = now
+		transitions = append(transitions, emaildomain.KanbanColumnTransition{
+			UserID: userID, EmailID: m.EmailID, FromColumn: previous[m.EmailID], ToColumn: m.ColumnID, MovedAt: now,
+		})
+	}
+	if len(mappings) == 0 {
+		return removed, previous, nil
+	}
+	if err := tx.CreateInBatches(mappings, importBatchSize).Error; err != nil {
+		return 0, nil, err
+	}
+	if err := recordTransitions(tx, transitions); err != nil {
+		return 0, nil, err
+	}
+	return removed, previous, nil
+}
+
 // RemoveEmailColumn removes the column mapping for an email
 func (r *emailKanbanColumnRepository) RemoveEmailColumn(userID, emailID string) error {
 	return r.db.Where("user_id = ? AND email_id = ?", userID, emailID).Delete(&emaildomain.EmailKanbanColumn{}).Error
@@ -102,30 +248,46 @@ func (r *emailKanbanColumnRepository) RemoveEmailColumnMapping(userID, emailID,
 
 // SnoozeEmailToColumn moves email to snoozed column and saves previous column
 func (r *emailKanbanColumnRepository) SnoozeEmailToColumn(userID, emailID, previousColumnID string, snoozedUntil time.Time) error {
-	var count int64
-	r.db.Model(&emaildomain.EmailKanbanColumn{}).Where("user_id = ? AND email_id = ?", userID, emailID).Count(&count)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		fromColumn, found, err := currentColumn(tx, userID, emailID)
+		if err != nil {
+			return err
+		}
 
-	if count == 0 {
-		// Create new mapping
-		mapping := emaildomain.EmailKanbanColumn{
-			ID:               uuid.New().String(),
-			UserID:           userID,
-			EmailID:          emailID,
-			ColumnID:         "snoozed",
-			PreviousColumnID: previousColumnID,
-			SnoozedUntil:     &snoozedUntil,
+		if !found {
+			// Create new mapping
+			mapping := emaildomain.EmailKanbanColumn{
+				ID:               uuid.New().String(),
+				UserID:           userID,
+				EmailID:          emailID,
+				ColumnID:         "snoozed",
+				PreviousColumnID: previousColumnID,
+				SnoozedUntil:     &snoozedUntil,
+			}
+			if err := tx.Create(&mapping).Error; err != nil {
+				return err
+			}
+		} else {
+			// Update existing mapping(s) - handle potential duplicates by updating all
+			err := tx.Model(&emaildomain.EmailKanbanColumn{}).
+				Where("user_id = ? AND email_id = ?", userID, emailID).
+				Updates(map[string]interface{}{
+					"column_id":          "snoozed",
+					"previous_column_id": previousColumnID,
+					"snoozed_until":      snoozedUntil,
+				}).Error
+			if err != nil {
+				return err
+			}
 		}
-		return r.db.Create(&mapping).Error
-	}
 
-	// Update existing mapping(s) - handle potential duplicates by updating all
-	return r.db.Model(&emaildomain.EmailKanbanColumn{}).
-		Where("user_id = ? AND email_id = ?", userID, emailID).
-		Updates(map[string]interface{}{
-			"column_id":          "snoozed",
-			"previous_column_id": previousColumnID,
-			"snoozed_until":      snoozedUntil,
-		}).Error
+		if fromColumn == "" {
+			fromColumn = previousColumnID
+		}
+		return recordTransitions(tx, []emaildomain.KanbanColumnTransition{{
+			UserID: userID, EmailID: emailID, FromColumn: fromColumn, ToColumn: "snoozed", MovedAt: time.Now(),
+		}})
+	})
 }
 
 // GetPreviousColumn gets the previous column ID for a snoozed email
@@ -144,26 +306,107 @@ func (r *emailKanbanColumnRepository) GetPreviousColumn(userID, emailID string)
 	return mapping.PreviousColumnID, nil
 }
 
-// GetAllSnoozedMappings gets all email mappings in snoozed column (for auto-unsnooze)
-func (r *emailKanbanColumnRepository) GetAllSnoozedMappings() ([]SnoozedEmailMapping, error) {
-	var mappings []emaildomain.EmailKanbanColumn
-	err := r.db.Where("column_id = ?", "snoozed").Find(&mappings).Error
+// ClaimDueSnoozes claims expired snoozes with FOR UPDATE SKIP LOCKED and restores them in the same transaction
+func (r *emailKanbanColumnRepository) ClaimDueSnoozes(now time.Time, limit int) ([]SnoozedEmailMapping, error) {
+	var result []SnoozedEmailMapping
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var mappings []emaildomain.EmailKanbanColumn
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("column_id = ? AND snoozed_until IS NOT NULL AND snoozed_until <= ?", "snoozed", now).
+			Order("snoozed_until ASC").
+			Limit(limit).
+			Find(&mappings).Error
+		if err != nil {
+			return err
+		}
+		if len(mappings) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(mappings))
+		for i, m := range mappings {
+			ids[i] = m.ID
+		}
+
+		// Restore previous column (default inbox) and clear the expiration
+		err = tx.Model(&emaildomain.EmailKanbanColumn{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"column_id":     gorm.Expr("COALESCE(NULLIF(previous_column_id, ''), 'inbox')"),
+				"snoozed_until": nil,
+				"updated_at":    now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		result = make([]SnoozedEmailMapping, len(mappings))
+		transitions := make([]emaildomain.KanbanColumnTransition, len(mappings))
+		for i, m := range mappings {
+			prevCol := m.PreviousColumnID
+			if prevCol == "" {
+				prevCol = "inbox"
+			}
+			result[i] = SnoozedEmailMapping{
+				UserID:           m.UserID,
+				EmailID:          m.EmailID,
+				PreviousColumnID: prevCol,
+				SnoozedUntil:     m.SnoozedUntil,
+			}
+			transitions[i] = emaildomain.KanbanColumnTransition{
+				UserID: m.UserID, EmailID: m.EmailID, FromColumn: "snoozed", ToColumn: prevCol, MovedAt: now,
+			}
+		}
+		return recordTransitions(tx, transitions)
+	})
 	if err != nil {
 		return nil, err
 	}
-	
-	result := make([]SnoozedEmailMapping, len(mappings))
-	for i, m := range mappings {
-		prevCol := m.PreviousColumnID
-		if prevCol == "" {
-			prevCol = "inbox"
+	return result, nil
+}
+
+// GetSnoozeBacklog counts due snoozes that have not been woken yet
+func (r *emailKanbanColumnRepository) GetSnoozeBacklog(now time.Time) (int64, *time.Time, error) {
+	var row struct {
+		Count  int64
+		Oldest *time.Time
+	}
+	err := r.db.Model(&emaildomain.EmailKanbanColumn{}).
+		Select("COUNT(*) AS count, MIN(snoozed_until) AS oldest").
+		Where("column_id = ? AND snoozed_until IS NOT NULL AND snoozed_until <= ?", "snoozed", now).
+		Scan(&row).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	return row.Count, row.Oldest, nil
+}
+
+// currentColumn returns the column an email is mapped to, if any
+func currentColumn(tx *gorm.DB, userID, emailID string) (string, bool, error) {
+	var mapping emaildomain.EmailKanbanColumn
+	err := tx.Select("column_id").Where("user_id = ? AND email_id = ?", userID, emailID).Take(&mapping).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", false, nil
 		}
-		result[i] = SnoozedEmailMapping{
-			UserID:           m.UserID,
-			EmailID:          m.EmailID,
-			PreviousColumnID: prevCol,
-			SnoozedUntil:     m.SnoozedUntil,
+		return "", false, err
+	}
+	return mapping.ColumnID, true, nil
+}
+
+// recordTransitions stores column moves for analytics, skipping no-op moves within the same column
+func recordTransitions(tx *gorm.DB, transitions []emaildomain.KanbanColumnTransition) error {
+	rows := make([]emaildomain.KanbanColumnTransition, 0, len(transitions))
+	for _, t := range transitions {
+		if t.FromColumn == t.ToColumn {
+			continue
 		}
+		t.ID = uuid.New().String()
+		rows = append(rows, t)
 	}
-	return result, nil
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, importBatchSize).Error
 }
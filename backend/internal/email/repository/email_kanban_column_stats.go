@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CardAgeBucket is an age range used by the card age distribution (MaxAge 0 = open-ended)
+type CardAgeBucket struct {
+	Label  string
+	MaxAge time.Duration
+}
+
+// CardAgeBuckets lists the age buckets in ascending order
+var CardAgeBuckets = []CardAgeBucket{
+	{Label: "<1d", MaxAge: 24 * time.Hour},
+	{Label: "1d-3d", MaxAge: 3 * 24 * time.Hour},
+	{Label: "3d-7d", MaxAge: 7 * 24 * time.Hour},
+	{Label: "7d-14d", MaxAge: 14 * 24 * time.Hour},
+	{Label: "14d-30d", MaxAge: 30 * 24 * time.Hour},
+	{Label: ">30d"},
+}
+
+// ColumnCardStats is the aggregated state of the cards in one column
+type ColumnCardStats struct {
+	ColumnID      string
+	Count         int64
+	AvgAgeSeconds float64
+	MaxAgeSeconds float64
+}
+
+// ColumnAgeBucketCount is the number of cards of a column in one age bucket
+type ColumnAgeBucketCount struct {
+	ColumnID string
+	Bucket   string
+	Count    int64
+}
+
+// CycleTimeStats summarizes durations (in seconds) until cards reached a column
+type CycleTimeStats struct {
+	SampleSize    int64
+	MedianSeconds *float64
+	P90Seconds    *float64
+	AvgSeconds    *float64
+}
+
+// DailyCount is a per-day counter
+type DailyCount struct {
+	Day   time.Time
+	Count int64
+}
+
+// GetColumnCardStats counts cards per column with their average and maximum age
+func (r *emailKanbanColumnRepository) GetColumnCardStats(userID string, now time.Time) ([]ColumnCardStats, error) {
+	var rows []ColumnCardStats
+	err := r.db.Raw(`
+		SELECT column_id,
+		       COUNT(*) AS count,
+		       COALESCE(AVG(EXTRACT(EPOCH FROM (CAST(@now AS timestamptz) - updated_at))), 0) AS avg_age_seconds,
+		       COALESCE(MAX(EXTRACT(EPOCH FROM (CAST(@now AS timestamptz) - updated_at))), 0) AS max_age_seconds
+		FROM email_kanban_columns
+		WHERE user_id = @user
+		GROUP BY column_id`,
+		map[string]interface{}{"user": userID, "now": now},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// GetCardAgeBuckets counts cards per column and CardAgeBuckets bucket, based on the time of the last move
+func (r *emailKanbanColumnRepository) GetCardAgeBuckets(userID string, now time.Time) ([]ColumnAgeBucketCount, error) {
+	params := map[string]interface{}{"user": userID}
+
+	var caseExpr strings.Builder
+	caseExpr.WriteString("CASE")
+	last := ""
+	for i, bucket := range CardAgeBuckets {
+		if bucket.MaxAge == 0 {
+			last = bucket.Label
+			continue
+		}
+		name := fmt.Sprintf("t%d", i)
+		params[name] = now.Add(-bucket.MaxAge)
+		fmt.Fprintf(&caseExpr, " WHEN updated_at > @%s THEN '%s'", name, bucket.Label)
+	}
+	fmt.Fprintf(&caseExpr, " ELSE '%s' END", last)
+
+	var rows []ColumnAgeBucketCount
+	err := r.db.Raw(`
+		SELECT column_id, `+caseExpr.String()+` AS bucket, COUNT(*) AS count
+		FROM email_kanban_columns
+		WHERE user_id = @user
+		GROUP BY 1, 2`,
+		params,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// GetTimeToColumnStats measures how long cards that reached columnID within [from, to) took to get there:
+// from their first move into any other column to their first move into columnID.
+// Cards without recorded transitions (moved before history existed) fall back to updated_at - created_at of their mapping.
+func (r *emailKanbanColumnRepository) GetTimeToColumnStats(userID, columnID string, from, to time.Time) (*CycleTimeStats, error) {
+	var row CycleTimeStats
+	err := r.db.Raw(`
+		WITH arrived AS (
+			SELECT email_id, MIN(moved_at) AS arrived_at
+			FROM kanban_column_transitions
+			WHERE user_id = @user AND to_column = @column AND moved_at >= @from AND moved_at < @to
+			GROUP BY email_id
+		), started AS (
+			SELECT t.email_id, MIN(t.moved_at) AS started_at
+			FROM kanban_column_transitions t
+			JOIN arrived a ON a.email_id = t.email_id
+			WHERE t.user_id = @user AND t.to_column <> @column AND t.moved_at < a.arrived_at
+			GROUP BY t.email_id
+		), durations AS (
+			SELECT EXTRACT(EPOCH FROM (a.arrived_at - s.started_at)) AS seconds
+			FROM arrived a
+			JOIN started s ON s.email_id = a.email_id
+			UNION ALL
+			SELECT EXTRACT(EPOCH FROM (m.updated_at - m.created_at))
+			FROM email_kanban_columns m
+			WHERE m.user_id = @user AND m.column_id = @column
+			  AND m.updated_at >= @from AND m.updated_at < @to AND m.updated_at > m.created_at
+			  AND NOT EXISTS (
+				SELECT 1 FROM kanban_column_transitions t
+				WHERE t.user_id = m.user_id AND t.email_id = m.email_id
+			  )
+		)
+		SELECT COUNT(*) AS sample_size,
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds) AS median_seconds,
+		       percentile_cont(0.9) WITHIN GROUP (ORDER BY seconds) AS p90_seconds,
+		       AVG(seconds) AS avg_seconds
+		FROM durations`,
+		map[string]interface{}{"user": userID, "column": columnID, "from": from, "to": to},
+	).Scan(&row).Error
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// GetDailyArrivals counts cards moved into columnID per day of [from, to) in the given IANA timezone.
+// Days without arrivals are returned with a zero count.
+func (r *emailKanbanColumnRepository) GetDailyArrivals(userID, columnID string, from, to time.Time, timezone string) ([]DailyCount, error) {
+	var rows []DailyCount
+	err := r.db.Raw(`
+		WITH days AS (
+			SELECT CAST(d AS date) AS day
+			FROM generate_series(
+				CAST(CAST(@from AS timestamptz) AT TIME ZONE @tz AS date),
+				CAST(CAST(@to AS timestamptz) AT TIME ZONE @tz - interval '1 microsecond' AS date),
+				interval '1 day'
+			) AS d
+		), arrivals AS (
+			SELECT CAST(moved_at AT TIME ZONE @tz AS date) AS day, COUNT(DISTINCT email_id) AS count
+			FROM kanban_column_transitions
+			WHERE user_id = @user AND to_column = @column AND moved_at >= @from AND moved_at < @to
+			GROUP BY 1
+			UNION ALL
+			SELECT CAST(m.updated_at AT TIME ZONE @tz AS date) AS day, COUNT(*) AS count
+			FROM email_kanban_columns m
+			WHERE m.user_id = @user AND m.column_id = @column AND m.updated_at >= @from AND m.updated_at < @to
+			  AND NOT EXISTS (
+				SELECT 1 FROM kanban_column_transitions t
+				WHERE t.user_id = m.user_id AND t.email_id = m.email_id
+			  )
+			GROUP BY 1
+		)
+		SELECT days.day, COALESCE(SUM(arrivals.count), 0) AS count
+		FROM days
+		LEFT JOIN arrivals ON arrivals.day = days.day
+		GROUP BY days.day
+		ORDER BY days.day`,
+		map[string]interface{}{"user": userID, "column": columnID, "from": from, "to": to, "tz": timezone},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
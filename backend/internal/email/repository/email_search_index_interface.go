@@ -0,0 +1,30 @@
+package repository
+
+import (
+	emaildomain "ga03-backend/internal/email/domain"
+)
+
+// EmailSearchIndexRepository defines the interface of the full-text search index (Postgres tsvector)
+type EmailSearchIndexRepository interface {
+	// Upsert adds or refreshes documents in one statement. A shorter body (e.g. a preview) never
+	// replaces a longer one already indexed.
+	Upsert(docs []*emaildomain.EmailSearchDocument) error
+
+	// Search returns one page of a user's documents matching the condition (named parameters, see
+	// search.Query.SQL), ranked by rankQuery (a tsquery, newest first when empty), and the number of matches
+	Search(userID, where string, args map[string]interface{}, rankQuery string, limit, offset int) ([]*emaildomain.EmailSearchDocument, int64, error)
+
+	// SetRead and ToggleStarred keep the flags of an indexed email up to date
+	SetRead(userID, emailID string, read bool) error
+	ToggleStarred(userID, emailID string) error
+	// CountUnread counts the unread emails among emailIDs (emails not indexed are not counted)
+	CountUnread(userID string, emailIDs []string) (int64, error)
+
+	// Delete removes an email from the index
+	Delete(userID, emailID string) error
+
+	// MarkUserIndexed records that the whole mailbox of a user has been indexed
+	MarkUserIndexed(userID string) error
+	// IsUserIndexed reports whether the whole mailbox of a user has been indexed
+	IsUserIndexed(userID string) (bool, error)
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	emailSearchIndexTable      = "email_search_index"
+	emailSearchIndexUsersTable = "email_search_index_users"
+)
+
+// emailSearchIndexColumns are the columns read by Search (everything but the tsvector)
+const emailSearchIndexColumns = "user_id, email_id, mailbox_id, thread_id, subject, from_address, from_name, recipients, preview, " +
+	"subject_folded, sender_folded, recipients_folded, body_folded, has_attachment, is_read, is_starred, size, received_at, indexed_at"
+
+// MigrateEmailSearchIndex creates the full-text search index. The text is folded in Go (fuzzy.Fold)
+// before it is stored, so the tsvector uses the 'simple' configuration and needs no unaccent extension.
+// Subject words rank above sender, recipients and body words.
+func MigrateEmailSearchIndex(db *gorm.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS email_search_index (
+			user_id           text NOT NULL,
+			email_id          text NOT NULL,
+			mailbox_id        text NOT NULL DEFAULT '',
+			thread_id         text NOT NULL DEFAULT '',
+			subject           text NOT NULL DEFAULT '',
+			from_address      text NOT NULL DEFAULT '',
+			from_name         text NOT NULL DEFAULT '',
+			recipients        text NOT NULL DEFAULT '',
+			preview           text NOT NULL DEFAULT '',
+			subject_folded    text NOT NULL DEFAULT '',
+			sender_folded     text NOT NULL DEFAULT '',
+			recipients_folded text NOT NULL DEFAULT '',
+			body_folded       text NOT NULL DEFAULT '',
+			has_attachment    boolean NOT NULL DEFAULT false,
+			is_read           boolean NOT NULL DEFAULT false,
+			is_starred        boolean NOT NULL DEFAULT false,
+			size              bigint NOT NULL DEFAULT 0,
+			received_at       timestamptz,
+			indexed_at        timestamptz NOT NULL DEFAULT now(),
+			document          tsvector GENERATED ALWAYS AS (
+				setweight(to_tsvector('simple', subject_folded), 'A') ||
+				setweight(to_tsvector('simple', sender_folded), 'B') ||
+				setweight(to_tsvector('simple', recipients_folded), 'C') ||
+				setweight(to_tsvector('simple', body_folded), 'D')
+			) STORED,
+			PRIMARY KEY (user_id, email_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_email_search_index_document ON email_search_index USING gin (document)`,
+		`CREATE INDEX IF NOT EXISTS idx_email_search_index_received ON email_search_index (user_id, received_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS email_search_index_users (
+			user_id    text PRIMARY KEY,
+			indexed_at timestamptz NOT NULL DEFAULT now()
+		)`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to migrate search index: %w", err)
+		}
+	}
+	return nil
+}
+
+// emailSearchIndexRepository implements EmailSearchIndexRepository interface
+type emailSearchIndexRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailSearchIndexRepository creates a new instance of emailSearchIndexRepository
+func NewEmailSearchIndexRepository(db *gorm.DB) EmailSearchIndexRepository {
+	return &emailSearchIndexRepository{
+		db: db,
+	}
+}
+
+// Upsert adds or refreshes documents in one statement
+func (r *emailSearchIndexRepository) Upsert(docs []*emaildomain.EmailSearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]string, 0, len(docs))
+	args := make([]interface{}, 0, len(docs)*19)
+	for _, doc := range docs {
+		rows = append(rows, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, doc.UserID, doc.EmailID, doc.MailboxID, doc.ThreadID, doc.Subject, doc.FromAddress, doc.FromName,
+			doc.Recipients, doc.Preview, doc.SubjectFolded, doc.SenderFolded, doc.RecipientsFolded, doc.BodyFolded,
+			doc.HasAttachment, doc.IsRead, doc.IsStarred, doc.Size, doc.ReceivedAt, now)
+	}
+
+	// List views only carry a preview: keep the full body indexed from the detail view
+	stmt := `INSERT INTO email_search_index (user_id, email_id, mailbox_id, thread_id, subject, from_address, from_name,
+			recipients, preview, subject_folded, sender_folded, recipients_folded, body_folded,
+			has_attachment, is_read, is_starred, size, received_at, indexed_at)
+		VALUES ` + strings.Join(rows, ", ") + `
+		ON CONFLICT (user_id, email_id) DO UPDATE SET
+			mailbox_id = EXCLUDED.mailbox_id,
+			thread_id = EXCLUDED.thread_id,
+			subject = EXCLUDED.subject,
+			from_address = EXCLUDED.from_address,
+			from_name = EXCLUDED.from_name,
+			recipients = EXCLUDED.recipients,
+			preview = EXCLUDED.preview,
+			subject_folded = EXCLUDED.subject_folded,
+			sender_folded = EXCLUDED.sender_folded,
+			recipients_folded = EXCLUDED.recipients_folded,
+			body_folded = CASE WHEN length(EXCLUDED.body_folded) >= length(email_search_index.body_folded)
+				THEN EXCLUDED.body_folded ELSE email_search_index.body_folded END,
+			has_attachment = EXCLUDED.has_attachment OR email_search_index.has_attachment,
+			is_read = EXCLUDED.is_read,
+			is_starred = EXCLUDED.is_starred,
+			size = GREATEST(EXCLUDED.size, email_search_index.size),
+			received_at = EXCLUDED.received_at,
+			indexed_at = EXCLUDED.indexed_at`
+	return r.db.Exec(stmt, args...).Error
+}
+
+// Search returns one page of a user's documents matching the condition and the number of matches.
+// A limit <= 0 returns every match.
+func (r *emailSearchIndexRepository) Search(userID, where string, args map[string]interface{}, rankQuery string, limit, offset int) ([]*emaildomain.EmailSearchDocument, int64, error) {
+	query := r.db.Table(emailSearchIndexTable).Where("user_id = ?", userID)
+	if where != "" {
+		query = query.Where(where, args)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 || (limit > 0 && int64(offset) >= total) {
+		return []*emaildomain.EmailSearchDocument{}, total, nil
+	}
+
+	if rankQuery != "" {
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "ts_rank(document, to_tsquery('simple', ?)) DESC, received_at DESC",
+			Vars: []interface{}{rankQuery},
+		}})
+	} else {
+		query = query.Order("received_at DESC")
+	}
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var docs []*emaildomain.EmailSearchDocument
+	if err := query.Select(emailSearchIndexColumns).Find(&docs).Error; err != nil {
+		return nil, 0, err
+	}
+	return docs, total, nil
+}
+
+// SetRead updates the read flag of an indexed email
+func (r *emailSearchIndexRepository) SetRead(userID, emailID string, read bool) error {
+	return r.db.Table(emailSearchIndexTable).
+		Where("user_id = ? AND email_id = ?", userID, emailID).
+		Update("is_read", read).Error
+}
+
+// CountUnread counts the unread emails among emailIDs
+func (r *emailSearchIndexRepository) CountUnread(userID string, emailIDs []string) (int64, error) {
+	if len(emailIDs) == 0 {
+		return 0, nil
+	}
+	var count int64
+	err := r.db.Table(emailSearchIndexTable).
+		Where("user_id = ? AND email_id IN ? AND NOT is_read", userID, emailIDs).
+		Count(&count).Error
+	return count, err
+}
+
+// ToggleStarred flips the starred flag of an indexed email
+func (r *emailSearchIndexRepository) ToggleStarred(userID, emailID string) error {
+	return r.db.Table(emailSearchIndexTable).
+		Where("user_id = ? AND email_id = ?", userID, emailID).
+		Update("is_starred", gorm.Expr("NOT is_starred")).Error
+}
+
+// Delete removes an email from the index
+func (r *emailSearchIndexRepository) Delete(userID, emailID string) error {
+	return r.db.Exec("DELETE FROM email_search_index WHERE user_id = ? AND email_id = ?", userID, emailID).Error
+}
+
+// MarkUserIndexed records that the whole mailbox of a user has been indexed
+func (r *emailSearchIndexRepository) MarkUserIndexed(userID string) error {
+	return r.db.Exec(`INSERT INTO email_search_index_users (user_id, indexed_at) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET indexed_at = EXCLUDED.indexed_at`, userID, time.Now()).Error
+}
+
+// IsUserIndexed reports whether the whole mailbox of a user has been indexed
+func (r *emailSearchIndexRepository) IsUserIndexed(userID string) (bool, error) {
+	var count int64
+	if err := r.db.Table(emailSearchIndexUsersTable).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"strings"
 	"time"
 
 	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/jobqueue"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -15,10 +17,13 @@ type EmailSummaryRepository interface {
 	GetSummary(userID, emailID string) (*emaildomain.EmailSummary, error)
 	// GetSummaries retrieves cached summaries for multiple emails
 	GetSummaries(userID string, emailIDs []string) (map[string]string, error)
-	// SaveSummary saves or updates a summary for an email
-	SaveSummary(userID, emailID, summary string) error
+	// SaveSummary saves or updates a summary for an email (keyed by user and email)
+	SaveSummary(summary *emaildomain.EmailSummary) error
 	// DeleteSummary deletes a summary for an email
 	DeleteSummary(userID, emailID string) error
+	// ListStaleSummaries returns summaries not generated by the current models and prompt versions,
+	// oldest first (empty userID = every user), except those whose job in deadQueue was dead-lettered
+	ListStaleSummaries(userID string, current emaildomain.SummaryGeneration, deadQueue string, limit int) ([]*emaildomain.EmailSummary, error)
 }
 
 // emailSummaryRepository implements EmailSummaryRepository interface
@@ -67,32 +72,65 @@ func (r *emailSummaryRepository) GetSummaries(userID string, emailIDs []string)
 }
 
 // SaveSummary saves or updates a summary for an email
-func (r *emailSummaryRepository) SaveSummary(userID, emailID, summaryText string) error {
+func (r *emailSummaryRepository) SaveSummary(summary *emaildomain.EmailSummary) error {
 	var existing emaildomain.EmailSummary
-	err := r.db.Where("user_id = ? AND email_id = ?", userID, emailID).First(&existing).Error
+	err := r.db.Where("user_id = ? AND email_id = ?", summary.UserID, summary.EmailID).First(&existing).Error
 
-	now := time.Now()
+	summary.CreatedAt = time.Now()
 	if err == gorm.ErrRecordNotFound {
 		// Create new record
-		summary := emaildomain.EmailSummary{
-			ID:        uuid.New().String(),
-			UserID:    userID,
-			EmailID:   emailID,
-			Summary:   summaryText,
-			CreatedAt: now,
+		if summary.ID == "" {
+			summary.ID = uuid.New().String()
 		}
-		return r.db.Create(&summary).Error
+		return r.db.Create(summary).Error
 	} else if err != nil {
 		return err
 	}
 
 	// Update existing record
-	existing.Summary = summaryText
-	existing.CreatedAt = now
-	return r.db.Save(&existing).Error
+	summary.ID = existing.ID
+	return r.db.Save(summary).Error
 }
 
 // DeleteSummary deletes a summary for an email
 func (r *emailSummaryRepository) DeleteSummary(userID, emailID string) error {
 	return r.db.Where("user_id = ? AND email_id = ?", userID, emailID).Delete(&emaildomain.EmailSummary{}).Error
 }
+
+// ListStaleSummaries returns summaries whose provider/model or language/prompt version is not
+// in the current generation. Rows generated before tracking (no provider) are always stale.
+// Summaries with a dead job in deadQueue (dedup key "<user ID>/<email ID>") are skipped in the
+// query, so they do not fill every batch.
+func (r *emailSummaryRepository) ListStaleSummaries(userID string, current emaildomain.SummaryGeneration, deadQueue string, limit int) ([]*emaildomain.EmailSummary, error) {
+	modelConds := []string{"FALSE"}
+	var args []interface{}
+	for provider, model := range current.Models {
+		modelConds = append(modelConds, "(provider = ? AND model = ?)")
+		args = append(args, provider, model)
+	}
+	promptConds := []string{"FALSE"}
+	for language, versions := range current.PromptVersions {
+		if len(versions) == 0 {
+			continue
+		}
+		promptConds = append(promptConds, "(language = ? AND prompt_version IN ?)")
+		args = append(args, language, versions)
+	}
+
+	condition := "NOT ((" + strings.Join(modelConds, " OR ") + ") AND (" + strings.Join(promptConds, " OR ") + "))"
+	query := r.db.Where(condition, args...)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if deadQueue != "" {
+		query = query.Where(`NOT EXISTS (SELECT 1 FROM `+jobqueue.Job{}.TableName()+` j
+			WHERE j.queue = ? AND j.status = ? AND j.dedup_key = email_summaries.user_id || '/' || email_summaries.email_id)`,
+			deadQueue, jobqueue.StatusDead)
+	}
+
+	var summaries []*emaildomain.EmailSummary
+	if err := query.Order("created_at").Limit(limit).Find(&summaries).Error; err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
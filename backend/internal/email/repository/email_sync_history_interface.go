@@ -2,13 +2,24 @@ package repository
 
 // EmailSyncHistoryRepository defines the interface for email sync history operations
 type EmailSyncHistoryRepository interface {
-	// Check if an email has been synced for a user
-	IsEmailSynced(userID, emailID string) (bool, error)
-	// Mark an email as synced
-	MarkEmailAsSynced(userID, emailID string) error
+	// Check if an email has been synced for a user with an embedding model
+	IsEmailSynced(userID, emailID, model string) (bool, error)
+	// Mark an email as synced with the model and dimension of its vector
+	MarkEmailAsSynced(userID, emailID, model string, dims int) error
+	// ListStaleEmbeddings returns emails of a user whose vector comes from another model
+	ListStaleEmbeddings(userID, model string, limit int) ([]string, error)
+	// ListUsersWithStaleEmbeddings returns users having vectors from another model
+	ListUsersWithStaleEmbeddings(model string) ([]string, error)
 	// EnsureEmailSynced checks if email is synced, if not marks it as synced (optimized: 1 query)
 	// Returns: (wasAlreadySynced bool, error)
 	EnsureEmailSynced(userID, emailID string) (bool, error)
 	// Delete sync history for an email (optional, for cleanup)
 	DeleteSyncHistory(userID, emailID string) error
+	// Check if an attachment has been embedded for a user with an embedding model.
+	// Attachments recorded without text by an older extractor version are not synced.
+	IsAttachmentSynced(userID, emailID, attachmentID, model string, extractorVersion int) (bool, error)
+	// Mark an attachment as embedded with the model, the extractor version and the number of chunks stored
+	MarkAttachmentSynced(userID, emailID, attachmentID, filename, model string, extractorVersion, chunks int) error
+	// Mark an attachment whose text could not be extracted, with the reason
+	MarkAttachmentFailed(userID, emailID, attachmentID, filename, model string, extractorVersion int, reason string) error
 }
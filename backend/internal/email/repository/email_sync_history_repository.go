@@ -21,10 +21,10 @@ func NewEmailSyncHistoryRepository(db *gorm.DB) EmailSyncHistoryRepository {
 	}
 }
 
-// IsEmailSynced checks if an email has been synced to vector DB for a user
-func (r *emailSyncHistoryRepository) IsEmailSynced(userID, emailID string) (bool, error) {
+// IsEmailSynced checks if an email has been synced to vector DB for a user with an embedding model
+func (r *emailSyncHistoryRepository) IsEmailSynced(userID, emailID, model string) (bool, error) {
 	var history emaildomain.EmailSyncHistory
-	err := r.db.Where("user_id = ? AND email_id = ?", userID, emailID).First(&history).Error
+	err := r.db.Where("user_id = ? AND email_id = ? AND embedding_model = ?", userID, emailID, model).First(&history).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return false, nil
@@ -34,8 +34,8 @@ func (r *emailSyncHistoryRepository) IsEmailSynced(userID, emailID string) (bool
 	return true, nil
 }
 
-// MarkEmailAsSynced marks an email as synced to vector DB
-func (r *emailSyncHistoryRepository) MarkEmailAsSynced(userID, emailID string) error {
+// MarkEmailAsSynced marks an email as synced to vector DB with the model and dimension of its vector
+func (r *emailSyncHistoryRepository) MarkEmailAsSynced(userID, emailID, model string, dims int) error {
 	var history emaildomain.EmailSyncHistory
 
 	// Try to find existing record
@@ -45,12 +45,14 @@ func (r *emailSyncHistoryRepository) MarkEmailAsSynced(userID, emailID string) e
 	if err == gorm.ErrRecordNotFound {
 		// Create new record
 		history = emaildomain.EmailSyncHistory{
-			ID:        uuid.New().String(),
-			UserID:    userID,
-			EmailID:   emailID,
-			SyncedAt:  now,
-			CreatedAt: now,
-			UpdatedAt: now,
+			ID:             uuid.New().String(),
+			UserID:         userID,
+			EmailID:        emailID,
+			SyncedAt:       now,
+			EmbeddingModel: model,
+			EmbeddingDim:   dims,
+			CreatedAt:      now,
+			UpdatedAt:      now,
 		}
 		return r.db.Create(&history).Error
 	} else if err != nil {
@@ -59,6 +61,8 @@ func (r *emailSyncHistoryRepository) MarkEmailAsSynced(userID, emailID string) e
 
 	// Update existing record
 	history.SyncedAt = now
+	history.EmbeddingModel = model
+	history.EmbeddingDim = dims
 	history.UpdatedAt = now
 	return r.db.Save(&history).Error
 }
@@ -67,7 +71,7 @@ func (r *emailSyncHistoryRepository) MarkEmailAsSynced(userID, emailID string) e
 // Returns: (wasAlreadySynced bool, error)
 func (r *emailSyncHistoryRepository) EnsureEmailSynced(userID, emailID string) (bool, error) {
 	now := time.Now()
-	
+
 	// Use upsert with ON CONFLICT constraint name to handle race conditions atomically
 	// Use Session to suppress GORM logging for this specific query
 	result := r.db.Session(&gorm.Session{Logger: r.db.Logger.LogMode(0)}).Exec(`
@@ -75,18 +79,75 @@ func (r *emailSyncHistoryRepository) EnsureEmailSynced(userID, emailID string) (
 		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT ON CONSTRAINT idx_user_email_unique DO NOTHING
 	`, uuid.New().String(), userID, emailID, now, now, now)
-	
+
 	if result.Error != nil {
 		// Silently handle any remaining errors - not critical for app function
 		return true, nil
 	}
-	
+
 	// If no rows affected, the record already existed (was already synced)
 	wasAlreadySynced := result.RowsAffected == 0
 	return wasAlreadySynced, nil
 }
 
+// ListStaleEmbeddings returns emails of a user whose vector comes from another model, oldest first
+func (r *emailSyncHistoryRepository) ListStaleEmbeddings(userID, model string, limit int) ([]string, error) {
+	var emailIDs []string
+	err := r.db.Model(&emaildomain.EmailSyncHistory{}).
+		Where("user_id = ? AND embedding_model <> ?", userID, model).
+		Order("synced_at ASC").
+		Limit(limit).
+		Pluck("email_id", &emailIDs).Error
+	return emailIDs, err
+}
+
+// ListUsersWithStaleEmbeddings returns users having vectors from another model
+func (r *emailSyncHistoryRepository) ListUsersWithStaleEmbeddings(model string) ([]string, error) {
+	var userIDs []string
+	err := r.db.Model(&emaildomain.EmailSyncHistory{}).
+		Where("embedding_model <> ?", model).
+		Distinct("user_id").
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
 // DeleteSyncHistory deletes sync history for an email (for cleanup purposes)
 func (r *emailSyncHistoryRepository) DeleteSyncHistory(userID, emailID string) error {
 	return r.db.Where("user_id = ? AND email_id = ?", userID, emailID).Delete(&emaildomain.EmailSyncHistory{}).Error
 }
+
+// IsAttachmentSynced checks if an attachment has been embedded for a user with an embedding model.
+// Attachments recorded without text (failed or empty) by an older extractor version are extracted again.
+func (r *emailSyncHistoryRepository) IsAttachmentSynced(userID, emailID, attachmentID, model string, extractorVersion int) (bool, error) {
+	var count int64
+	err := r.db.Model(&emaildomain.AttachmentSyncHistory{}).
+		Where("user_id = ? AND email_id = ? AND attachment_id = ? AND embedding_model = ?", userID, emailID, attachmentID, model).
+		Where("chunk_count > 0 OR extractor_version >= ?", extractorVersion).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// MarkAttachmentSynced records that an attachment has been embedded (upsert on user, email and attachment)
+func (r *emailSyncHistoryRepository) MarkAttachmentSynced(userID, emailID, attachmentID, filename, model string, extractorVersion, chunks int) error {
+	return r.upsertAttachment(userID, emailID, attachmentID, filename, model, extractorVersion, chunks, "")
+}
+
+// MarkAttachmentFailed records that the text of an attachment could not be extracted
+func (r *emailSyncHistoryRepository) MarkAttachmentFailed(userID, emailID, attachmentID, filename, model string, extractorVersion int, reason string) error {
+	return r.upsertAttachment(userID, emailID, attachmentID, filename, model, extractorVersion, 0, reason)
+}
+
+func (r *emailSyncHistoryRepository) upsertAttachment(userID, emailID, attachmentID, filename, model string, extractorVersion, chunks int, reason string) error {
+	return r.db.Exec(`
+		INSERT INTO attachment_sync_histories
+			(id, user_id, email_id, attachment_id, filename, chunk_count, embedding_model, extractor_version, extract_error, synced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, email_id, attachment_id) DO UPDATE SET
+			filename = EXCLUDED.filename, chunk_count = EXCLUDED.chunk_count,
+			embedding_model = EXCLUDED.embedding_model, extractor_version = EXCLUDED.extractor_version,
+			extract_error = EXCLUDED.extract_error, synced_at = EXCLUDED.synced_at
+	`, uuid.New().String(), userID, emailID, attachmentID, filename, chunks, model, extractorVersion, reason, time.Now()).Error
+}
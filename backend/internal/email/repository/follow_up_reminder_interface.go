@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+)
+
+// FollowUpReminderRepository defines the interface for follow-up reminder operations
+type FollowUpReminderRepository interface {
+	// Create a new follow-up reminder
+	Create(reminder *emaildomain.FollowUpReminder) error
+	// Get a reminder by ID (scoped to user)
+	GetByID(userID, id string) (*emaildomain.FollowUpReminder, error)
+	// Get the pending reminder for an email, if any
+	GetPendingByEmailID(userID, emailID string) (*emaildomain.FollowUpReminder, error)
+	// List reminders for a user, optionally filtered by status (empty = all)
+	ListByUserID(userID string, status emaildomain.FollowUpStatus) ([]*emaildomain.FollowUpReminder, error)
+	// FindPendingForReplies finds pending reminders that incoming emails may reply to,
+	// matched by Gmail thread ID or by the In-Reply-To header against the sent Message-ID
+	FindPendingForReplies(userID string, threadIDs, inReplyTos []string) ([]*emaildomain.FollowUpReminder, error)
+	// ClaimDue claims up to limit pending reminders whose deadline has passed for lease:
+	// rows are locked with FOR UPDATE SKIP LOCKED so concurrent instances never fire the same reminder.
+	// A reminder still pending when the lease ends (crashed instance) is claimed again.
+	ClaimDue(now time.Time, lease time.Duration, limit int) ([]*emaildomain.FollowUpReminder, error)
+	// UpdateStatus changes the status of a pending reminder and records when it happened;
+	// false when the reminder is no longer pending (cancelled, replied or triggered meanwhile)
+	UpdateStatus(id string, status emaildomain.FollowUpStatus, at time.Time) (bool, error)
+	// UpdateEmailID stores the resolved provider message ID (IMAP sends)
+	UpdateEmailID(id, emailID string) error
+}
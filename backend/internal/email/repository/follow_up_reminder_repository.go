@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// followUpReminderRepository implements FollowUpReminderRepository interface
+type followUpReminderRepository struct {
+	db *gorm.DB
+}
+
+// NewFollowUpReminderRepository creates a new instance of followUpReminderRepository
+func NewFollowUpReminderRepository(db *gorm.DB) FollowUpReminderRepository {
+	return &followUpReminderRepository{
+		db: db,
+	}
+}
+
+// Create creates a new follow-up reminder
+func (r *followUpReminderRepository) Create(reminder *emaildomain.FollowUpReminder) error {
+	if reminder.ID == "" {
+		reminder.ID = uuid.New().String()
+	}
+	if reminder.Status == "" {
+		reminder.Status = emaildomain.FollowUpStatusPending
+	}
+	return r.db.Create(reminder).Error
+}
+
+// GetByID gets a reminder by ID for a user
+func (r *followUpReminderRepository) GetByID(userID, id string) (*emaildomain.FollowUpReminder, error) {
+	var reminder emaildomain.FollowUpReminder
+	err := r.db.Where("user_id = ? AND id = ?", userID, id).First(&reminder).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+// GetPendingByEmailID gets the pending reminder for an email
+func (r *followUpReminderRepository) GetPendingByEmailID(userID, emailID string) (*emaildomain.FollowUpReminder, error) {
+	var reminder emaildomain.FollowUpReminder
+	err := r.db.Where("user_id = ? AND email_id = ? AND status = ?", userID, emailID, emaildomain.FollowUpStatusPending).
+		First(&reminder).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+// ListByUserID lists reminders for a user, newest deadline first
+func (r *followUpReminderRepository) ListByUserID(userID string, status emaildomain.FollowUpStatus) ([]*emaildomain.FollowUpReminder, error) {
+	var reminders []*emaildomain.FollowUpReminder
+	query := r.db.Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("remind_at DESC").Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
+
+// FindPendingForReplies finds pending reminders matched by thread ID or In-Reply-To
+func (r *followUpReminderRepository) FindPendingForReplies(userID string, threadIDs, inReplyTos []string) ([]*emaildomain.FollowUpReminder, error) {
+	if len(threadIDs) == 0 && len(inReplyTos) == 0 {
+		return nil, nil
+	}
+	// IN () is invalid SQL: an empty side matches nothing
+	if len(threadIDs) == 0 {
+		threadIDs = []string{""}
+	}
+	if len(inReplyTos) == 0 {
+		inReplyTos = []string{""}
+	}
+
+	var reminders []*emaildomain.FollowUpReminder
+	err := r.db.Where("user_id = ? AND status = ?", userID, emaildomain.FollowUpStatusPending).
+		Where("(thread_id <> '' AND thread_id IN ?) OR (message_id <> '' AND message_id IN ?)", threadIDs, inReplyTos).
+		Find(&reminders).Error
+	if err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
+
+// ClaimDue claims due reminders with FOR UPDATE SKIP LOCKED and leases them in the same statement
+func (r *followUpReminderRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*emaildomain.FollowUpReminder, error) {
+	var reminders []*emaildomain.FollowUpReminder
+	err := r.db.Raw(`
+		WITH due AS (
+			SELECT id
+			FROM follow_up_reminders
+			WHERE status = @pending AND remind_at <= @now
+			  AND (claimed_until IS NULL OR claimed_until <= @now)
+			ORDER BY remind_at
+			LIMIT @limit
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE follow_up_reminders
+		SET claimed_until = @until
+		FROM due
+		WHERE follow_up_reminders.id = due.id
+		RETURNING follow_up_reminders.*`,
+		map[string]interface{}{
+			"pending": emaildomain.FollowUpStatusPending,
+			"now":     now,
+			"until":   now.Add(lease),
+			"limit":   limit,
+		},
+	).Scan(&reminders).Error
+	if err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
+
+// UpdateStatus changes the status of a reminder that is still pending (conditional update)
+func (r *followUpReminderRepository) UpdateStatus(id string, status emaildomain.FollowUpStatus, at time.Time) (bool, error) {
+	updates := map[string]interface{}{
+		"status":     status,
+		"updated_at": time.Now(),
+	}
+	switch status {
+	case emaildomain.FollowUpStatusReplied:
+		updates["replied_at"] = at
+	case emaildomain.FollowUpStatusTriggered:
+		updates["triggered_at"] = at
+	}
+	result := r.db.Model(&emaildomain.FollowUpReminder{}).
+		Where("id = ? AND status = ?", id, emaildomain.FollowUpStatusPending).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateEmailID stores the resolved provider message ID
+func (r *followUpReminderRepository) UpdateEmailID(id, emailID string) error {
+	return r.db.Model(&emaildomain.FollowUpReminder{}).Where("id = ?", id).Update("email_id", emailID).Error
+}
@@ -18,4 +18,17 @@ type KanbanColumnRepository interface {
 	DeleteColumnByPK(id string) error
 	// Update column order for multiple columns
 	UpdateColumnOrders(userID string, orders map[string]int) error
+	// Upsert columns by column_id and store email mappings in one transaction. If deleteExcept is non-nil,
+	// every other column whose column_id is not listed is deleted (replace import); replaceMappings
+	// deletes the mappings of emails not in the import.
+	ImportBoard(userID string, columns []*emaildomain.KanbanColumn, deleteExcept []string, mappings []*emaildomain.EmailKanbanColumn, replaceMappings bool) (*BoardImport, error)
+}
+
+// BoardImport summarizes an applied board import
+type BoardImport struct {
+	ColumnsCreated  int
+	ColumnsUpdated  int
+	ColumnsDeleted  int
+	MappingsRemoved int
+	PreviousColumns map[string]string // emailID -> column the email was in before the import
 }
@@ -102,3 +102,78 @@ func (r *kanbanColumnRepository) UpdateColumnOrders(userID string, orders map[st
 	}
 	return nil
 }
+
+// ImportBoard imports columns and email mappings in a single transaction
+func (r *kanbanColumnRepository) ImportBoard(userID string, columns []*emaildomain.KanbanColumn, deleteExcept []string, mappings []*emaildomain.EmailKanbanColumn, replaceMappings bool) (*BoardImport, error) {
+	var imported BoardImport
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		imported.ColumnsCreated, imported.ColumnsUpdated, imported.ColumnsDeleted, err = importColumns(tx, userID, columns, deleteExcept)
+		if err != nil {
+			return err
+		}
+		imported.MappingsRemoved, imported.PreviousColumns, err = importMappings(tx, userID, mappings, replaceMappings)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &imported, nil
+}
+
+// importColumns upserts columns by column_id within tx and optionally deletes the ones not listed
+func importColumns(tx *gorm.DB, userID string, columns []*emaildomain.KanbanColumn, deleteExcept []string) (created, updated, deleted int, err error) {
+	now := time.Now()
+	for _, column := range columns {
+		if column.RemoveLabelIDs == nil {
+			column.RemoveLabelIDs = emaildomain.StringArray{}
+		}
+		if column.Examples == nil {
+			column.Examples = emaildomain.StringArray{}
+		}
+
+		var existing emaildomain.KanbanColumn
+		findErr := tx.Where("user_id = ? AND column_id = ?", userID, column.ColumnID).First(&existing).Error
+		if findErr != nil && findErr != gorm.ErrRecordNotFound {
+			return 0, 0, 0, findErr
+		}
+
+		if findErr == gorm.ErrRecordNotFound {
+			column.ID = uuid.New().String()
+			column.UserID = userID
+			column.CreatedAt = now
+			column.UpdatedAt = now
+			if err := tx.Create(column).Error; err != nil {
+				return 0, 0, 0, err
+			}
+			created++
+			continue
+		}
+
+		existing.Name = column.Name
+		existing.Order = column.Order
+		existing.GmailLabelID = column.GmailLabelID
+		existing.RemoveLabelIDs = column.RemoveLabelIDs
+		existing.Description = column.Description
+		existing.Examples = column.Examples
+		existing.SavedSearchID = column.SavedSearchID
+		existing.UpdatedAt = now
+		if err := tx.Save(&existing).Error; err != nil {
+			return 0, 0, 0, err
+		}
+		updated++
+	}
+
+	if deleteExcept == nil {
+		return created, updated, 0, nil
+	}
+	query := tx.Where("user_id = ?", userID)
+	if len(deleteExcept) > 0 {
+		query = query.Where("column_id NOT IN ?", deleteExcept)
+	}
+	res := query.Delete(&emaildomain.KanbanColumn{})
+	if res.Error != nil {
+		return 0, 0, 0, res.Error
+	}
+	return created, updated, int(res.RowsAffected), nil
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	emaildomain "ga03-backend/internal/email/domain"
+)
+
+// SavedSearchRepository defines the interface for saved searches (smart folders)
+type SavedSearchRepository interface {
+	// Create stores a new saved search
+	Create(search *emaildomain.SavedSearch) error
+	// Update saves the name, query, mode, alert flag and alert start of a saved search
+	Update(search *emaildomain.SavedSearch) error
+	// ClaimAlert records that an email is checked against a saved search; false when it already was
+	ClaimAlert(userID, id, emailID string) (bool, error)
+	// Delete removes a saved search (scoped to user) and its alert records
+	Delete(userID, id string) error
+	// GetByID returns a saved search (scoped to user), nil if not found
+	GetByID(userID, id string) (*emaildomain.SavedSearch, error)
+	// ListByUser returns the saved searches of a user, by name
+	ListByUser(userID string) ([]*emaildomain.SavedSearch, error)
+}
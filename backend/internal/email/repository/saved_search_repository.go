@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// savedSearchRepository implements SavedSearchRepository interface
+type savedSearchRepository struct {
+	db *gorm.DB
+}
+
+// NewSavedSearchRepository creates a new instance of savedSearchRepository
+func NewSavedSearchRepository(db *gorm.DB) SavedSearchRepository {
+	return &savedSearchRepository{
+		db: db,
+	}
+}
+
+// Create stores a new saved search
+func (r *savedSearchRepository) Create(search *emaildomain.SavedSearch) error {
+	if search.ID == "" {
+		search.ID = uuid.New().String()
+	}
+	now := time.Now()
+	search.CreatedAt = now
+	search.UpdatedAt = now
+	return r.db.Create(search).Error
+}
+
+// Update saves the editable fields of a saved search
+func (r *savedSearchRepository) Update(search *emaildomain.SavedSearch) error {
+	search.UpdatedAt = time.Now()
+	return r.db.Model(&emaildomain.SavedSearch{}).
+		Where("id = ? AND user_id = ?", search.ID, search.UserID).
+		Updates(map[string]interface{}{
+			"name":         search.Name,
+			"query":        search.Query,
+			"mode":         search.Mode,
+			"alert":        search.Alert,
+			"alerts_since": search.AlertsSince,
+			"updated_at":   search.UpdatedAt,
+		}).Error
+}
+
+// ClaimAlert inserts the alert record of an email (the primary key makes concurrent checks of the
+// same email claim it once)
+func (r *savedSearchRepository) ClaimAlert(userID, id, emailID string) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&emaildomain.SavedSearchAlert{
+		SavedSearchID: id,
+		EmailID:       emailID,
+		UserID:        userID,
+		CreatedAt:     time.Now(),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete removes a saved search and its alert records
+func (r *savedSearchRepository) Delete(userID, id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("saved_search_id = ? AND user_id = ?", id, userID).Delete(&emaildomain.SavedSearchAlert{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND user_id = ?", id, userID).Delete(&emaildomain.SavedSearch{}).Error
+	})
+}
+
+// GetByID returns a saved search
+func (r *savedSearchRepository) GetByID(userID, id string) (*emaildomain.SavedSearch, error) {
+	var search emaildomain.SavedSearch
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&search).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &search, nil
+}
+
+// ListByUser returns the saved searches of a user
+func (r *savedSearchRepository) ListByUser(userID string) ([]*emaildomain.SavedSearch, error) {
+	var searches []*emaildomain.SavedSearch
+	err := r.db.Where("user_id = ?", userID).Order("name ASC").Find(&searches).Error
+	return searches, err
+}
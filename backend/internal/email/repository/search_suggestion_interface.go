@@ -0,0 +1,17 @@
+package repository
+
+import (
+	emaildomain "ga03-backend/internal/email/domain"
+)
+
+// SearchSuggestionRepository defines the interface of the persistent search suggestions (contacts, subjects, queries)
+type SearchSuggestionRepository interface {
+	// RecordEmail counts the suggestions of an email (its sender and subject), once per email
+	RecordEmail(userID, emailID string, suggestions []*emaildomain.SearchSuggestion) error
+	// Record counts one use of each suggestion (Kind, Key, Value, Detail, Folded and LastUsedAt are set)
+	Record(suggestions []*emaildomain.SearchSuggestion) error
+	// Search returns the best ranked suggestions of a kind whose folded words start with every word
+	Search(userID, kind string, words []string, limit int) ([]*emaildomain.SearchSuggestion, error)
+	// Prune keeps the keepPerKind best ranked suggestions of every kind of a user
+	Prune(userID string, keepPerKind int) error
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// suggestionRank is the frecency of a suggestion now: its score decayed since its last use.
+// The exponent is capped as power() fails on underflow.
+var suggestionRank = fmt.Sprintf(
+	`score * power(0.5, LEAST(EXTRACT(EPOCH FROM (now() - last_used_at)) / %d, 60))`,
+	int64(emaildomain.SuggestionHalfLife/time.Second))
+
+// searchSuggestionRepository implements SearchSuggestionRepository interface
+type searchSuggestionRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchSuggestionRepository creates a new instance of searchSuggestionRepository
+func NewSearchSuggestionRepository(db *gorm.DB) SearchSuggestionRepository {
+	return &searchSuggestionRepository{
+		db: db,
+	}
+}
+
+// RecordEmail counts the suggestions of an email unless the email was counted before
+func (r *searchSuggestionRepository) RecordEmail(userID, emailID string, suggestions []*emaildomain.SearchSuggestion) error {
+	if len(suggestions) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&emaildomain.SearchSuggestionSource{
+			UserID:    userID,
+			EmailID:   emailID,
+			CreatedAt: time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil // Already counted
+		}
+		return record(tx, suggestions)
+	})
+}
+
+// Record counts one use of each suggestion
+func (r *searchSuggestionRepository) Record(suggestions []*emaildomain.SearchSuggestion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return record(tx, suggestions)
+	})
+}
+
+// record counts one use of each suggestion at its LastUsedAt (SearchSuggestion.AddUse). The row is
+// created empty if missing, then locked so that concurrent syncs do not lose uses.
+func record(tx *gorm.DB, suggestions []*emaildomain.SearchSuggestion) error {
+	now := time.Now()
+	for _, s := range suggestions {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&emaildomain.SearchSuggestion{
+			ID:         uuid.New().String(),
+			UserID:     s.UserID,
+			Kind:       s.Kind,
+			Key:        s.Key,
+			Value:      s.Value,
+			Detail:     s.Detail,
+			Folded:     s.Folded,
+			LastUsedAt: s.LastUsedAt,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var current emaildomain.SearchSuggestion
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND kind = ? AND suggestion_key = ?", s.UserID, s.Kind, s.Key).
+			First(&current).Error
+		if err != nil {
+			return err
+		}
+		current.AddUse(s)
+		current.UpdatedAt = now
+		if err := tx.Save(&current).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search returns the best ranked suggestions of a kind matching every word as a word prefix
+func (r *searchSuggestionRepository) Search(userID, kind string, words []string, limit int) ([]*emaildomain.SearchSuggestion, error) {
+	query := r.db.Where("user_id = ? AND kind = ?", userID, kind)
+	for _, word := range words {
+		// Words are letters and digits (fuzzy.Tokenize): no LIKE wildcards to escape
+		query = query.Where("(' ' || folded) LIKE ?", "% "+word+"%")
+	}
+
+	var suggestions []*emaildomain.SearchSuggestion
+	err := query.
+		Order(suggestionRank + " DESC").
+		Order("last_used_at DESC").
+		Limit(limit).
+		Find(&suggestions).Error
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// Prune deletes the suggestions ranked after keepPerKind in their kind
+func (r *searchSuggestionRepository) Prune(userID string, keepPerKind int) error {
+	return r.db.Exec(`
+		DELETE FROM search_suggestions WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY kind ORDER BY `+suggestionRank+` DESC, last_used_at DESC) AS position
+				FROM search_suggestions WHERE user_id = ?
+			) ranked WHERE position > ?
+		)`, userID, keepPerKind).Error
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	emaildomain "ga03-backend/internal/email/domain"
+)
+
+// TriageRepository defines the interface for AI auto-triage settings, suggestions and examples
+type TriageRepository interface {
+	// GetSettings returns the user's triage settings (nil if never saved)
+	GetSettings(userID string) (*emaildomain.TriageSettings, error)
+	// SaveSettings creates or updates the user's triage settings
+	SaveSettings(settings *emaildomain.TriageSettings) error
+
+	// GetSuggestion returns the suggestion for an email, if any
+	GetSuggestion(userID, emailID string) (*emaildomain.TriageSuggestion, error)
+	// GetSuggestionByID returns a suggestion by ID (scoped to user)
+	GetSuggestionByID(userID, id string) (*emaildomain.TriageSuggestion, error)
+	// SaveSuggestion creates or replaces the suggestion of an email (one per user/email)
+	SaveSuggestion(suggestion *emaildomain.TriageSuggestion) error
+	// ListSuggestions lists suggestions, newest first, optionally filtered by status (empty = all)
+	ListSuggestions(userID string, status emaildomain.TriageSuggestionStatus, limit int) ([]*emaildomain.TriageSuggestion, error)
+	// UpdateSuggestionStatus resolves a suggestion
+	UpdateSuggestionStatus(id string, status emaildomain.TriageSuggestionStatus) error
+
+	// AddExample stores a few-shot example, keeping only the newest keepPerColumn per column
+	AddExample(example *emaildomain.TriageExample, keepPerColumn int) error
+	// GetExamples returns up to perColumn newest examples of every column
+	GetExamples(userID string, perColumn int) ([]*emaildomain.TriageExample, error)
+}
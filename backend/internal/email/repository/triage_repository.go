@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// triageRepository implements TriageRepository interface
+type triageRepository struct {
+	db *gorm.DB
+}
+
+// NewTriageRepository creates a new instance of triageRepository
+func NewTriageRepository(db *gorm.DB) TriageRepository {
+	return &triageRepository{
+		db: db,
+	}
+}
+
+// GetSettings returns the user's triage settings
+func (r *triageRepository) GetSettings(userID string) (*emaildomain.TriageSettings, error) {
+	var settings emaildomain.TriageSettings
+	err := r.db.Where("user_id = ?", userID).First(&settings).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveSettings creates or updates the user's triage settings
+func (r *triageRepository) SaveSettings(settings *emaildomain.TriageSettings) error {
+	now := time.Now()
+	if settings.CreatedAt.IsZero() {
+		settings.CreatedAt = now
+	}
+	settings.UpdatedAt = now
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "auto_move_threshold", "suggest_threshold", "enabled_at", "updated_at"}),
+	}).Create(settings).Error
+}
+
+// GetSuggestion returns the suggestion for an email
+func (r *triageRepository) GetSuggestion(userID, emailID string) (*emaildomain.TriageSuggestion, error) {
+	var suggestion emaildomain.TriageSuggestion
+	err := r.db.Where("user_id = ? AND email_id = ?", userID, emailID).First(&suggestion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// GetSuggestionByID returns a suggestion by ID for a user
+func (r *triageRepository) GetSuggestionByID(userID, id string) (*emaildomain.TriageSuggestion, error) {
+	var suggestion emaildomain.TriageSuggestion
+	err := r.db.Where("user_id = ? AND id = ?", userID, id).First(&suggestion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// SaveSuggestion creates or replaces the suggestion of an email
+func (r *triageRepository) SaveSuggestion(suggestion *emaildomain.TriageSuggestion) error {
+	now := time.Now()
+	if suggestion.ID == "" {
+		suggestion.ID = uuid.New().String()
+	}
+	if suggestion.CreatedAt.IsZero() {
+		suggestion.CreatedAt = now
+	}
+	suggestion.UpdatedAt = now
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "email_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"column_id", "confidence", "reason", "subject", "from_address", "status", "resolved_at", "updated_at",
+		}),
+	}).Create(suggestion).Error
+}
+
+// ListSuggestions lists suggestions for a user, newest first
+func (r *triageRepository) ListSuggestions(userID string, status emaildomain.TriageSuggestionStatus, limit int) ([]*emaildomain.TriageSuggestion, error) {
+	var suggestions []*emaildomain.TriageSuggestion
+	query := r.db.Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Order("created_at DESC").Find(&suggestions).Error; err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// UpdateSuggestionStatus resolves a suggestion
+func (r *triageRepository) UpdateSuggestionStatus(id string, status emaildomain.TriageSuggestionStatus) error {
+	now := time.Now()
+	return r.db.Model(&emaildomain.TriageSuggestion{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":      status,
+		"resolved_at": now,
+		"updated_at":  now,
+	}).Error
+}
+
+// AddExample stores a few-shot example and prunes the oldest ones of its column
+func (r *triageRepository) AddExample(example *emaildomain.TriageExample, keepPerColumn int) error {
+	if example.ID == "" {
+		example.ID = uuid.New().String()
+	}
+	if example.CreatedAt.IsZero() {
+		example.CreatedAt = time.Now()
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// The same email moved again replaces its previous example
+		if err := tx.Where("user_id = ? AND email_id = ?", example.UserID, example.EmailID).
+			Delete(&emaildomain.TriageExample{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(example).Error; err != nil {
+			return err
+		}
+		if keepPerColumn <= 0 {
+			return nil
+		}
+		return tx.Exec(`
+			DELETE FROM triage_examples
+			WHERE user_id = ? AND column_id = ? AND id NOT IN (
+				SELECT id FROM triage_examples
+				WHERE user_id = ? AND column_id = ?
+				ORDER BY created_at DESC
+				LIMIT ?
+			)`, example.UserID, example.ColumnID, example.UserID, example.ColumnID, keepPerColumn).Error
+	})
+}
+
+// GetExamples returns the newest examples of every column
+func (r *triageRepository) GetExamples(userID string, perColumn int) ([]*emaildomain.TriageExample, error) {
+	var examples []*emaildomain.TriageExample
+	err := r.db.Raw(`
+		SELECT id, user_id, column_id, email_id, subject, from_address, snippet, created_at
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY column_id ORDER BY created_at DESC) AS rn
+			FROM triage_examples
+			WHERE user_id = ?
+		) ranked
+		WHERE rn <= ?
+		ORDER BY column_id, created_at DESC`, userID, perColumn).Scan(&examples).Error
+	if err != nil {
+		return nil, err
+	}
+	return examples, nil
+}
@@ -0,0 +1,176 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"ga03-backend/internal/email/repository"
+	"ga03-backend/internal/email/usecase"
+	"ga03-backend/pkg/fcm"
+	"log"
+	"time"
+
+	authrepo "ga03-backend/internal/auth/repository"
+)
+
+const (
+	// followUpBatchSize is the number of due reminders claimed at once
+	followUpBatchSize = 50
+	// followUpClaimLease is how long a claimed reminder is reserved for the instance handling it
+	followUpClaimLease = 5 * time.Minute
+)
+
+// FollowUpReminderScheduler resurfaces sent emails that got no reply by their deadline
+type FollowUpReminderScheduler struct {
+	followUpRepo repository.FollowUpReminderRepository
+	emailUsecase usecase.EmailUsecase
+	fcmRepo      authrepo.FCMTokenRepository
+	fcmClient    *fcm.Client
+	interval     time.Duration
+	stopChan     chan struct{}
+}
+
+// NewFollowUpReminderScheduler creates a new scheduler
+func NewFollowUpReminderScheduler(
+	followUpRepo repository.FollowUpReminderRepository,
+	emailUsecase usecase.EmailUsecase,
+	fcmRepo authrepo.FCMTokenRepository,
+	fcmClient *fcm.Client,
+) *FollowUpReminderScheduler {
+	return &FollowUpReminderScheduler{
+		followUpRepo: followUpRepo,
+		emailUsecase: emailUsecase,
+		fcmRepo:      fcmRepo,
+		fcmClient:    fcmClient,
+		interval:     1 * time.Minute, // Check every minute
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// Start begins the scheduler loop
+func (s *FollowUpReminderScheduler) Start() {
+	if s.fcmClient == nil {
+		log.Println("[FollowUpScheduler] FCM client not available, reminders will only be sent via SSE")
+	}
+
+	log.Println("[FollowUpScheduler] Starting follow-up reminder scheduler (interval: 1 minute)")
+
+	go func() {
+		// Run immediately on start
+		s.checkDueFollowUps()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.checkDueFollowUps()
+			case <-s.stopChan:
+				log.Println("[FollowUpScheduler] Scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop gracefully stops the scheduler
+func (s *FollowUpReminderScheduler) Stop() {
+	close(s.stopChan)
+}
+
+// checkDueFollowUps resurfaces emails whose follow-up deadline passed without a reply.
+// Reminders are claimed batch by batch, so several instances can run side by side.
+func (s *FollowUpReminderScheduler) checkDueFollowUps() {
+	for {
+		// Stop between batches when shutting down
+		select {
+		case <-s.stopChan:
+			return
+		default:
+		}
+
+		reminders, err := s.followUpRepo.ClaimDue(time.Now(), followUpClaimLease, followUpBatchSize)
+		if err != nil {
+			log.Printf("[FollowUpScheduler] Error claiming due follow-ups: %v", err)
+			return
+		}
+		if len(reminders) == 0 {
+			return
+		}
+
+		log.Printf("[FollowUpScheduler] Claimed %d due follow-ups", len(reminders))
+
+		for _, reminder := range reminders {
+			resurfaced, err := s.emailUsecase.ResurfaceFollowUp(reminder)
+			if err != nil {
+				// Still pending: claimed again once the lease ends
+				log.Printf("[FollowUpScheduler] Error resurfacing follow-up %s: %v", reminder.ID, err)
+				continue
+			}
+			if !resurfaced {
+				log.Printf("[FollowUpScheduler] Follow-up %s got a reply, nothing to do", reminder.ID)
+				continue
+			}
+
+			s.sendPushNotification(reminder.UserID, reminder.ID, reminder.EmailID, reminder.Subject, reminder.To, reminder.SentAt)
+		}
+
+		if len(reminders) < followUpBatchSize {
+			return
+		}
+	}
+}
+
+// sendPushNotification notifies all devices of the user that an email got no reply
+func (s *FollowUpReminderScheduler) sendPushNotification(userID, reminderID, emailID, subject, to string, sentAt time.Time) {
+	if s.fcmClient == nil {
+		return
+	}
+
+	tokens, err := s.fcmRepo.GetTokensByUserID(userID)
+	if err != nil {
+		log.Printf("[FollowUpScheduler] Error getting FCM tokens for user %s: %v", userID, err)
+		return
+	}
+	if len(tokens) == 0 {
+		return
+	}
+
+	var tokenStrings []string
+	for _, t := range tokens {
+		tokenStrings = append(tokenStrings, t.Token)
+	}
+
+	if subject == "" {
+		subject = "(Không có tiêu đề)"
+	}
+	days := int(time.Since(sentAt).Hours() / 24)
+
+	clickAction := "/inbox"
+	if emailID != "" {
+		clickAction = fmt.Sprintf("/inbox/%s", emailID)
+	}
+
+	notification := fcm.NotificationData{
+		Title: "⏰ Chưa có phản hồi: " + subject,
+		Body:  fmt.Sprintf("%s chưa trả lời email của bạn sau %d ngày", to, days),
+		Data: map[string]string{
+			"type":         "follow_up_reminder",
+			"reminder_id":  reminderID,
+			"email_id":     emailID,
+			"click_action": clickAction,
+		},
+	}
+
+	failedTokens, err := s.fcmClient.SendToDevices(context.Background(), tokenStrings, notification)
+	if err != nil {
+		log.Printf("[FollowUpScheduler] Error sending reminder %s: %v", reminderID, err)
+	} else {
+		log.Printf("[FollowUpScheduler] Sent follow-up reminder '%s' to %d devices", subject, len(tokenStrings)-len(failedTokens))
+	}
+
+	// Cleanup failed tokens
+	for _, token := range failedTokens {
+		s.fcmRepo.DeleteToken(token)
+	}
+}
@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"ga03-backend/internal/email/repository"
+	"ga03-backend/internal/email/usecase"
+	"log"
+	"sync"
+	"time"
+)
+
+// snoozeBatchSize is the max number of snoozes claimed per transaction
+const snoozeBatchSize = 100
+
+// SnoozeSchedulerMetrics exposes the health and lag of the snooze scheduler
+type SnoozeSchedulerMetrics struct {
+	Running           bool      `json:"running"`
+	Interval          string    `json:"interval"`
+	LastRunAt         time.Time `json:"last_run_at"`
+	LastRunDurationMs int64     `json:"last_run_duration_ms"`
+	LastRunWoken      int       `json:"last_run_woken"`
+	WokenTotal        int64     `json:"woken_total"`
+	ErrorsTotal       int64     `json:"errors_total"`
+	// Lag = time between snoozed_until and the actual wake-up
+	LastMaxLagSeconds float64 `json:"last_max_lag_seconds"`
+	AvgLagSeconds     float64 `json:"avg_lag_seconds"`
+	// Backlog = snoozes already due but not woken yet (measured after each run)
+	BacklogCount            int64   `json:"backlog_count"`
+	OldestBacklogLagSeconds float64 `json:"oldest_backlog_lag_seconds"`
+}
+
+// SnoozeScheduler wakes snoozed emails when their snooze expires.
+// State lives in the database, so wake-ups survive restarts and several
+// instances can run side by side (rows are claimed with SKIP LOCKED).
+type SnoozeScheduler struct {
+	emailKanbanColumnRepo repository.EmailKanbanColumnRepository
+	emailUsecase          usecase.EmailUsecase
+	interval              time.Duration
+	stopChan              chan struct{}
+	doneChan              chan struct{}
+	stopOnce              sync.Once
+
+	metricsMu sync.RWMutex
+	metrics   SnoozeSchedulerMetrics
+}
+
+// NewSnoozeScheduler creates a new scheduler
+func NewSnoozeScheduler(
+	emailKanbanColumnRepo repository.EmailKanbanColumnRepository,
+	emailUsecase usecase.EmailUsecase,
+	interval time.Duration,
+) *SnoozeScheduler {
+	if interval <= 0 {
+		interval = 1 * time.Minute
+	}
+	return &SnoozeScheduler{
+		emailKanbanColumnRepo: emailKanbanColumnRepo,
+		emailUsecase:          emailUsecase,
+		interval:              interval,
+		stopChan:              make(chan struct{}),
+		doneChan:              make(chan struct{}),
+		metrics:               SnoozeSchedulerMetrics{Interval: interval.String()},
+	}
+}
+
+// Start begins the scheduler loop
+func (s *SnoozeScheduler) Start() {
+	log.Printf("[SnoozeScheduler] Starting snooze scheduler (interval: %s)", s.interval)
+
+	s.metricsMu.Lock()
+	s.metrics.Running = true
+	s.metricsMu.Unlock()
+
+	go func() {
+		defer close(s.doneChan)
+
+		// Run immediately on start to catch up on snoozes that expired while we were down
+		s.wakeDueSnoozes()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.wakeDueSnoozes()
+			case <-s.stopChan:
+				s.metricsMu.Lock()
+				s.metrics.Running = false
+				s.metricsMu.Unlock()
+				log.Println("[SnoozeScheduler] Scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop gracefully stops the scheduler, waiting for the batch in progress to finish
+func (s *SnoozeScheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+	<-s.doneChan
+}
+
+// Metrics returns a snapshot of the scheduler metrics
+func (s *SnoozeScheduler) Metrics() SnoozeSchedulerMetrics {
+	s.metricsMu.RLock()
+	defer s.metricsMu.RUnlock()
+	return s.metrics
+}
+
+// wakeDueSnoozes claims and restores due snoozes batch by batch until none are left
+func (s *SnoozeScheduler) wakeDueSnoozes() {
+	start := time.Now()
+	woken := 0
+	var maxLag, sumLag float64
+	var errCount int64
+
+	for {
+		// Stop between batches when shutting down
+		select {
+		case <-s.stopChan:
+			return
+		default:
+		}
+
+		now := time.Now()
+		mappings, err := s.emailKanbanColumnRepo.ClaimDueSnoozes(now, snoozeBatchSize)
+		if err != nil {
+			log.Printf("[SnoozeScheduler] Error claiming due snoozes: %v", err)
+			errCount++
+			break
+		}
+
+		for _, mapping := range mappings {
+			if mapping.SnoozedUntil != nil {
+				lag := now.Sub(*mapping.SnoozedUntil).Seconds()
+				sumLag += lag
+				if lag > maxLag {
+					maxLag = lag
+				}
+			}
+			s.emailUsecase.HandleSnoozeExpired(mapping.UserID, mapping.EmailID, mapping.PreviousColumnID)
+		}
+		woken += len(mappings)
+
+		if len(mappings) < snoozeBatchSize {
+			break
+		}
+	}
+
+	backlogCount, oldest, err := s.emailKanbanColumnRepo.GetSnoozeBacklog(time.Now())
+	if err != nil {
+		log.Printf("[SnoozeScheduler] Error measuring snooze backlog: %v", err)
+		errCount++
+	}
+
+	if woken > 0 {
+		log.Printf("[SnoozeScheduler] Woke %d snoozed emails (max lag %.1fs)", woken, maxLag)
+	}
+
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+	// Running average over all wake-ups so far
+	if woken > 0 {
+		total := float64(s.metrics.WokenTotal) + float64(woken)
+		s.metrics.AvgLagSeconds = (s.metrics.AvgLagSeconds*float64(s.metrics.WokenTotal) + sumLag) / total
+	}
+	s.metrics.LastRunAt = start
+	s.metrics.LastRunDurationMs = time.Since(start).Milliseconds()
+	s.metrics.LastRunWoken = woken
+	s.metrics.WokenTotal += int64(woken)
+	s.metrics.ErrorsTotal += errCount
+	s.metrics.LastMaxLagSeconds = maxLag
+	if err == nil {
+		s.metrics.BacklogCount = backlogCount
+		s.metrics.OldestBacklogLagSeconds = 0
+		if oldest != nil {
+			s.metrics.OldestBacklogLagSeconds = time.Since(*oldest).Seconds()
+		}
+	}
+}
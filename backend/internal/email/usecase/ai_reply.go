@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/ai"
+
+	"github.com/google/uuid"
+)
+
+// ErrReplyDraftingUnavailable is returned when no configured AI provider can draft replies
+var ErrReplyDraftingUnavailable = errors.New("AI reply drafting is not available")
+
+const (
+	maxReplyThreadMessages = 5    // Earlier thread messages given as context
+	maxReplyEmailRunes     = 6000 // Body of the email being answered
+	maxReplyThreadRunes    = 2000 // Body of each earlier thread message
+	replyStreamTimeout     = 2 * time.Minute
+)
+
+// DraftReply writes a reply to an email, grounded in the email and its thread
+func (u *emailUsecase) DraftReply(ctx context.Context, userID, emailID string, opts emaildomain.ReplyDraftOptions) (*emaildomain.ReplyDraft, error) {
+	draft, req, drafter, err := u.prepareReplyDraft(ctx, userID, emailID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := drafter.DraftReply(u.withUserPrompt(ctx, userID), req, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to draft reply: %w", err)
+	}
+	draft.Body = body
+	return draft, nil
+}
+
+// StreamDraftReply starts drafting in the background and returns the draft skeleton right away.
+// Tokens are pushed to the user's SSE channel as "ai_reply_delta" events, followed by
+// "ai_reply_done" (with the full draft) or "ai_reply_error".
+func (u *emailUsecase) StreamDraftReply(userID, emailID string, opts emaildomain.ReplyDraftOptions) (*emaildomain.ReplyDraft, error) {
+	if u.eventService == nil {
+		return nil, fmt.Errorf("event service not configured")
+	}
+
+	draft, req, drafter, err := u.prepareReplyDraft(context.Background(), userID, emailID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	go func(draft emaildomain.ReplyDraft) {
+		ctx, cancel := context.WithTimeout(u.withUserPrompt(context.Background(), userID), replyStreamTimeout)
+		defer cancel()
+
+		seq := 0
+		body, err := drafter.DraftReply(ctx, req, func(delta string) {
+			seq++
+			u.eventService.SendToUser(userID, "ai_reply_delta", map[string]interface{}{
+				"draft_id": draft.ID,
+				"email_id": draft.EmailID,
+				"seq":      seq,
+				"delta":    delta,
+			})
+		})
+		if err != nil {
+			log.Printf("[AIReply] Failed to draft reply for email %s: %v", draft.EmailID, err)
+			u.eventService.SendToUser(userID, "ai_reply_error", map[string]string{
+				"draft_id": draft.ID,
+				"email_id": draft.EmailID,
+				"error":    "failed to draft reply",
+			})
+			return
+		}
+
+		draft.Body = body
+		u.eventService.SendToUser(userID, "ai_reply_done", draft)
+	}(*draft)
+
+	return draft, nil
+}
+
+// prepareReplyDraft loads the email and its thread and builds the provider request
+func (u *emailUsecase) prepareReplyDraft(ctx context.Context, userID, emailID string, opts emaildomain.ReplyDraftOptions) (*emaildomain.ReplyDraft, ai.DraftReplyRequest, ai.ReplyDrafter, error) {
+	var req ai.DraftReplyRequest
+
+	drafter, ok := u.aiService.(ai.ReplyDrafter)
+	if u.aiService == nil || !ok {
+		return nil, req, nil, ErrReplyDraftingUnavailable
+	}
+
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, req, nil, err
+	}
+	if user == nil {
+		return nil, req, nil, fmt.Errorf("user not found")
+	}
+
+	email, err := u.GetEmailByID(userID, emailID)
+	if err != nil || email == nil {
+		return nil, req, nil, fmt.Errorf("email not found")
+	}
+
+	req = ai.DraftReplyRequest{
+		Email:       toDraftMessage(email, maxReplyEmailRunes),
+		Thread:      u.replyThreadContext(ctx, userID, user.Provider, email),
+		Tone:        opts.Tone,
+		Instruction: opts.Instruction,
+		SenderName:  user.Name,
+	}
+
+	draft := &emaildomain.ReplyDraft{
+		ID:        uuid.New().String(),
+		EmailID:   email.ID,
+		ThreadID:  email.ThreadID,
+		InReplyTo: email.MessageID,
+		To:        []string{email.From},
+		Subject:   replySubject(email.Subject),
+	}
+	return draft, req, drafter, nil
+}
+
+// replyThreadContext returns the messages preceding the email in its Gmail thread (oldest first).
+// Thread context is best effort: on failure the reply is drafted from the email alone.
+func (u *emailUsecase) replyThreadContext(ctx context.Context, userID, provider string, email *emaildomain.Email) []ai.DraftMessage {
+	if provider == "imap" || email.ThreadID == "" || u.mailProvider == nil {
+		return nil
+	}
+	accessToken, refreshToken, err := u.getUserTokens(userID)
+	if err != nil || accessToken == "" {
+		return nil
+	}
+
+	emails, err := u.mailProvider.GetThreadEmails(ctx, accessToken, refreshToken, email.ThreadID, u.makeTokenUpdateCallback(userID))
+	if err != nil {
+		log.Printf("[AIReply] Failed to load thread %s: %v", email.ThreadID, err)
+		return nil
+	}
+
+	var earlier []*emaildomain.Email
+	for _, e := range emails {
+		if e == nil || e.ID == email.ID || e.ReceivedAt.After(email.ReceivedAt) {
+			continue
+		}
+		earlier = append(earlier, e)
+	}
+	sort.SliceStable(earlier, func(i, j int) bool { return earlier[i].ReceivedAt.Before(earlier[j].ReceivedAt) })
+	if len(earlier) > maxReplyThreadMessages {
+		earlier = earlier[len(earlier)-maxReplyThreadMessages:]
+	}
+
+	thread := make([]ai.DraftMessage, 0, len(earlier))
+	for _, e := range earlier {
+		thread = append(thread, toDraftMessage(e, maxReplyThreadRunes))
+	}
+	return thread
+}
+
+// toDraftMessage converts an email to plain-text prompt context, truncated to maxRunes
+func toDraftMessage(email *emaildomain.Email, maxRunes int) ai.DraftMessage {
+	from := email.From
+	if email.FromName != "" && email.FromName != email.From {
+		from = fmt.Sprintf("%s <%s>", email.FromName, email.From)
+	}
+
+	body := email.Body
+	if email.IsHTML {
+		body = cleanHTMLForEmbedding(body)
+	}
+	if body == "" {
+		body = email.Preview
+	}
+	if runes := []rune(body); len(runes) > maxRunes {
+		body = string(runes[:maxRunes]) + "..."
+	}
+
+	return ai.DraftMessage{
+		From:    from,
+		To:      email.To,
+		Subject: email.Subject,
+		Date:    email.ReceivedAt,
+		Body:    body,
+	}
+}
+
+// replySubject prefixes the subject with "Re: " unless it already is a reply
+func replySubject(subject string) string {
+	trimmed := strings.TrimSpace(subject)
+	if strings.HasPrefix(strings.ToLower(trimmed), "re:") {
+		return trimmed
+	}
+	return "Re: " + trimmed
+}
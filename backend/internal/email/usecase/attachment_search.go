@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/embedding"
+	"ga03-backend/pkg/jobqueue"
+	"ga03-backend/pkg/textextract"
+)
+
+// AttachmentIndexQueue is the job queue of attachments waiting to be extracted and embedded
+const AttachmentIndexQueue = "attachment_index"
+
+// attachmentIndexWorkerCount is the number of attachment workers per instance (downloads are large)
+const attachmentIndexWorkerCount = 2
+
+const (
+	// attachmentChunkRunes is the size of an embedded passage (a few paragraphs)
+	attachmentChunkRunes = 1500
+	// maxAttachmentChunks bounds the passages embedded per attachment (the first ~100 pages of text)
+	maxAttachmentChunks = 200
+)
+
+// AttachmentIndexJob extracts and embeds the text of one attachment (stored as the job payload)
+type AttachmentIndexJob struct {
+	UserID       string `json:"user_id"`
+	EmailID      string `json:"email_id"`
+	AttachmentID string `json:"attachment_id"`
+	Filename     string `json:"filename"`
+	MimeType     string `json:"mime_type"`
+}
+
+// queueAttachmentIndexing queues the attachments of an email whose text can be extracted
+func (u *emailUsecase) queueAttachmentIndexing(userID string, email *emaildomain.Email) {
+	maxBytes := int64(u.config.AttachmentIndexMaxBytes)
+	if maxBytes <= 0 {
+		return
+	}
+	for _, attachment := range email.Attachments {
+		if attachment.ID == "" || attachment.Size > maxBytes || !textextract.Supported(attachment.MimeType, attachment.Name) {
+			continue
+		}
+		job := AttachmentIndexJob{
+			UserID:       userID,
+			EmailID:      email.ID,
+			AttachmentID: attachment.ID,
+			Filename:     attachment.Name,
+			MimeType:     attachment.MimeType,
+		}
+		err := u.jobQueue.Enqueue(AttachmentIndexQueue, job, jobqueue.EnqueueOptions{
+			UserID:   userID,
+			Priority: jobqueue.PriorityLow,
+			DedupKey: userID + "/" + email.ID + "/" + attachment.ID,
+		})
+		if err != nil {
+			log.Printf("[AttachmentIndex] Failed to queue attachment %s of email %s: %v", attachment.Name, email.ID, err)
+		}
+	}
+}
+
+// handleAttachmentIndexJob downloads an attachment, extracts its text and embeds it in chunks.
+// Download and embedding failures are retried by the queue; attachments without text and extraction
+// failures are recorded so they are not downloaded again until the extractor changes.
+func (u *emailUsecase) handleAttachmentIndexJob(ctx context.Context, queued *jobqueue.Job) error {
+	if u.vectorSearchService == nil {
+		return nil
+	}
+
+	var job AttachmentIndexJob
+	if err := json.Unmarshal([]byte(queued.Payload), &job); err != nil {
+		return jobqueue.Permanent(fmt.Errorf("invalid attachment index job: %w", err))
+	}
+
+	model, _ := u.vectorSearchService.EmbeddingModel()
+	synced, err := u.emailSyncHistoryRepo.IsAttachmentSynced(job.UserID, job.EmailID, job.AttachmentID, model, textextract.Version)
+	if err != nil {
+		return fmt.Errorf("failed to check attachment sync status: %w", err)
+	}
+	if synced {
+		return nil
+	}
+
+	attachment, data, err := u.GetAttachment(job.UserID, job.EmailID, job.AttachmentID)
+	if err != nil {
+		return fmt.Errorf("failed to download attachment %s: %w", job.Filename, err)
+	}
+	if attachment == nil || data == nil {
+		// Provider without attachment download (local storage)
+		return nil
+	}
+	if maxBytes := u.config.AttachmentIndexMaxBytes; maxBytes > 0 && len(data) > maxBytes {
+		return u.emailSyncHistoryRepo.MarkAttachmentFailed(job.UserID, job.EmailID, job.AttachmentID, job.Filename, model, textextract.Version,
+			fmt.Sprintf("attachment too large (%d bytes)", len(data)))
+	}
+
+	mimeType := job.MimeType
+	if attachment.MimeType != "" {
+		mimeType = attachment.MimeType
+	}
+	text, err := textextract.Extract(mimeType, job.Filename, data)
+	if err != nil {
+		// Corrupt or unsupported documents will not get better with retries
+		log.Printf("[AttachmentIndex] Failed to extract text of %s (email %s): %v", job.Filename, job.EmailID, err)
+		return u.emailSyncHistoryRepo.MarkAttachmentFailed(job.UserID, job.EmailID, job.AttachmentID, job.Filename, model, textextract.Version, err.Error())
+	}
+
+	passages := textextract.Chunk(text, attachmentChunkRunes)
+	if len(passages) > maxAttachmentChunks {
+		passages = passages[:maxAttachmentChunks]
+	}
+	chunks := make([]embedding.Chunk, len(passages))
+	for i, passage := range passages {
+		chunks[i] = embedding.Chunk{
+			EmailID:      job.EmailID,
+			AttachmentID: job.AttachmentID,
+			Filename:     job.Filename,
+			MimeType:     mimeType,
+			Index:        i,
+			Text:         passage,
+		}
+	}
+
+	if len(chunks) > 0 {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		err = u.vectorSearchService.UpsertAttachmentChunks(ctx, job.UserID, job.EmailID, job.AttachmentID, chunks)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to embed attachment %s: %w", job.Filename, err)
+		}
+	}
+
+	log.Printf("[AttachmentIndex] Indexed %s of email %s (%d chunks)", job.Filename, job.EmailID, len(chunks))
+	return u.emailSyncHistoryRepo.MarkAttachmentSynced(job.UserID, job.EmailID, job.AttachmentID, job.Filename, model, textextract.Version, len(chunks))
+}
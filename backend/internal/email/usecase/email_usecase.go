@@ -2,6 +2,8 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	authrepo "ga03-backend/internal/auth/repository"
 	emaildomain "ga03-backend/internal/email/domain"
@@ -10,12 +12,13 @@ import (
 	"ga03-backend/pkg/config"
 	"ga03-backend/pkg/fuzzy"
 	"ga03-backend/pkg/imap"
+	"ga03-backend/pkg/jobqueue"
+	"ga03-backend/pkg/textextract"
 	"ga03-backend/pkg/utils/crypto"
 	"log"
 	"mime/multipart"
 	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -32,23 +35,20 @@ type emailUsecase struct {
 	emailSyncHistoryRepo  repository.EmailSyncHistoryRepository
 	kanbanColumnRepo      repository.KanbanColumnRepository
 	emailKanbanColumnRepo repository.EmailKanbanColumnRepository
+	followUpRepo          repository.FollowUpReminderRepository
+	triageRepo            repository.TriageRepository
+	searchIndexRepo       repository.EmailSearchIndexRepository // Full-text index of FuzzySearch
+	suggestionRepo        repository.SearchSuggestionRepository // Auto-complete of the search bar
+	savedSearchRepo       repository.SavedSearchRepository      // Smart folders and Kanban sources
 	userRepo              authrepo.UserRepository
 	mailProvider          emaildomain.MailProvider // Gmail Provider
 	imapProvider          *imap.IMAPService        // IMAP Provider
 	config                *config.Config
 	topicName             string
 	aiService             ai.SummarizerService
-	kanbanStatus          map[string]string // emailID -> status
-	kanbanStatusMu        sync.RWMutex      // Mutex to protect kanbanStatus map
 	vectorSearchService   VectorSearchService
-	syncJobQueue          chan EmailSyncJob // Job queue for email sync workers
-	workerWg              sync.WaitGroup    // Wait group for workers
-
-	// Suggestion cache: stores FromName and Subject for fast auto-suggest
-	// Key: userID, Value: map of unique suggestions (FromName or Subject)
-	suggestionCache   map[string]map[string]struct{}
-	suggestionCacheMu sync.RWMutex
-	eventService      EventService // injected event service
+	jobQueue              *jobqueue.Queue // Persistent queue of vector sync, attachment and triage jobs
+	eventService          EventService    // injected event service
 }
 
 // SetEventService allows wiring EventService after creation
@@ -56,12 +56,28 @@ func (u *emailUsecase) SetEventService(svc EventService) {
 	u.eventService = svc
 }
 
-// EmailSyncJob represents a job to sync an email to vector DB
+// VectorSyncQueue is the job queue of emails waiting to be indexed in the vector DB
+const VectorSyncQueue = "vector_sync"
+
+// vectorSyncWorkerCount is the number of vector sync workers per instance
+const vectorSyncWorkerCount = 5
+
+// EmailSyncJob represents a job to sync an email to vector DB (stored as the job payload).
+// It only carries IDs: the email is loaded by the worker, so no content is kept in the queue.
 type EmailSyncJob struct {
-	UserID  string
-	EmailID string
-	Subject string
-	Body    string
+	UserID  string `json:"user_id"`
+	EmailID string `json:"email_id"`
+}
+
+// SetJobQueue registers the vector sync, attachment and triage workers on the persistent job queue
+// (they run once the queue is started)
+func (u *emailUsecase) SetJobQueue(queue *jobqueue.Queue) {
+	u.jobQueue = queue
+	queue.Register(VectorSyncQueue, vectorSyncWorkerCount, u.handleVectorSyncJob)
+	queue.Register(VectorReembedQueue, vectorReembedWorkerCount, u.handleVectorReembedJob)
+	queue.Register(AttachmentIndexQueue, attachmentIndexWorkerCount, u.handleAttachmentIndexJob)
+	queue.Register(TriageQueue, triageWorkerCount, u.handleTriageJob)
+	queue.Register(SavedSearchAlertQueue, savedSearchAlertWorkerCount, u.handleSavedSearchAlertJob)
 }
 
 // SetAIService allows wiring AI Service after creation
@@ -75,144 +91,99 @@ func (u *emailUsecase) SetVectorSearchService(svc VectorSearchService) {
 }
 
 // NewEmailUsecase creates a new instance of emailUsecase
-func NewEmailUsecase(emailRepo repository.EmailRepository, emailSyncHistoryRepo repository.EmailSyncHistoryRepository, kanbanColumnRepo repository.KanbanColumnRepository, emailKanbanColumnRepo repository.EmailKanbanColumnRepository, userRepo authrepo.UserRepository, mailProvider emaildomain.MailProvider, imapProvider *imap.IMAPService, cfg *config.Config, topicName string) EmailUsecase {
+func NewEmailUsecase(emailRepo repository.EmailRepository, emailSyncHistoryRepo repository.EmailSyncHistoryRepository, kanbanColumnRepo repository.KanbanColumnRepository, emailKanbanColumnRepo repository.EmailKanbanColumnRepository, followUpRepo repository.FollowUpReminderRepository, triageRepo repository.TriageRepository, searchIndexRepo repository.EmailSearchIndexRepository, suggestionRepo repository.SearchSuggestionRepository, savedSearchRepo repository.SavedSearchRepository, userRepo authrepo.UserRepository, mailProvider emaildomain.MailProvider, imapProvider *imap.IMAPService, cfg *config.Config, topicName string) EmailUsecase {
 	// GeminiService cần được truyền vào khi khởi tạo
 	uc := &emailUsecase{
 		emailRepo:             emailRepo,
 		emailSyncHistoryRepo:  emailSyncHistoryRepo,
 		kanbanColumnRepo:      kanbanColumnRepo,
 		emailKanbanColumnRepo: emailKanbanColumnRepo,
+		followUpRepo:          followUpRepo,
+		triageRepo:            triageRepo,
+		searchIndexRepo:       searchIndexRepo,
+		suggestionRepo:        suggestionRepo,
+		savedSearchRepo:       savedSearchRepo,
 		userRepo:              userRepo,
 		mailProvider:          mailProvider,
 		imapProvider:          imapProvider,
 		config:                cfg,
 		topicName:             topicName,
 		aiService:             nil, // cần set sau
-		kanbanStatus:          make(map[string]string),
-		syncJobQueue:          make(chan EmailSyncJob, 1000), // Buffered channel for jobs
-		suggestionCache:       make(map[string]map[string]struct{}),
 	}
-	uc.startSnoozeChecker()
-	uc.startSyncWorkers(5) // Start 5 worker goroutines
 	return uc
 }
 
-func (u *emailUsecase) startSnoozeChecker() {
-	ticker := time.NewTicker(1 * time.Minute)
-	go func() {
-		for range ticker.C {
-			u.checkSnoozedEmails()
-		}
-	}()
-}
-
-// startSyncWorkers starts worker goroutines to process email sync jobs
-func (u *emailUsecase) startSyncWorkers(workerCount int) {
-	for i := 0; i < workerCount; i++ {
-		u.workerWg.Add(1)
-		go u.syncWorker(i)
+// handleVectorSyncJob indexes an email in the vector DB. Failures are retried by the queue.
+func (u *emailUsecase) handleVectorSyncJob(ctx context.Context, queued *jobqueue.Job) error {
+	if u.vectorSearchService == nil {
+		return nil
 	}
-}
-
-// syncWorker processes email sync jobs from the queue
-func (u *emailUsecase) syncWorker(workerID int) {
-	defer u.workerWg.Done()
-
-	for job := range u.syncJobQueue {
-		if u.vectorSearchService == nil {
-			continue
-		}
 
-		// Check if email has already been synced (read-only check, doesn't insert)
-		alreadySynced, err := u.emailSyncHistoryRepo.IsEmailSynced(job.UserID, job.EmailID)
-		if err != nil {
-			log.Printf("[VectorSync] Worker %d: Error checking sync status for %s: %v", workerID, job.EmailID, err)
-			continue
-		}
-
-		if alreadySynced {
-			// Email already synced, skip silently (don't log to avoid spam)
-			continue
-		}
-
-		log.Printf("[VectorSync] Worker %d: Syncing email %s to vector DB", workerID, job.EmailID)
-
-		// Email not synced yet, upsert to vector DB
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		err = u.UpsertEmailEmbedding(ctx, job.UserID, job.EmailID, job.Subject, job.Body)
-		cancel()
-
-		if err != nil {
-			// Log embedding error - will retry next time email is fetched
-			log.Printf("[VectorSync] Worker %d: Failed to sync email %s: %v", workerID, job.EmailID, err)
-			continue // Don't mark as synced so it can retry
-		}
-
-		// Mark as synced ONLY after successful embedding
-		if markErr := u.emailSyncHistoryRepo.MarkEmailAsSynced(job.UserID, job.EmailID); markErr != nil {
-			log.Printf("[VectorSync] Worker %d: Failed to mark email %s as synced: %v", workerID, job.EmailID, markErr)
-		} else {
-			log.Printf("[VectorSync] Worker %d: Successfully synced email %s", workerID, job.EmailID)
-		}
+	var job EmailSyncJob
+	if err := json.Unmarshal([]byte(queued.Payload), &job); err != nil {
+		return jobqueue.Permanent(fmt.Errorf("invalid vector sync job: %w", err))
 	}
-}
 
-func (u *emailUsecase) checkSnoozedEmails() {
-	// Get snoozed mappings to know previous columns and userIDs and expiration
-	snoozedMappings, err := u.emailKanbanColumnRepo.GetAllSnoozedMappings()
+	// Check if email has already been synced (read-only check, doesn't insert)
+	model, _ := u.vectorSearchService.EmbeddingModel()
+	alreadySynced, err := u.emailSyncHistoryRepo.IsEmailSynced(job.UserID, job.EmailID, model)
 	if err != nil {
-		log.Printf("Failed to get snoozed mappings: %v", err)
-		return
+		return fmt.Errorf("failed to check sync status: %w", err)
+	}
+	if alreadySynced {
+		// Email already synced, skip silently (don't log to avoid spam)
+		return nil
 	}
 
-	now := time.Now()
-	for _, mapping := range snoozedMappings {
-		// Calculate if snooze has expired
-		// Default to 1 hour from now if no time set (legacy data)
-		var expired bool
-		if mapping.SnoozedUntil == nil {
-			// For legacy data without time, we can't check unless we fetch email
-			// But for now, let's assume if it's in DB without time, it might be stuck or manual only.
-			// Ideally we should fix legacy data.
-			continue
-		} else {
-			expired = mapping.SnoozedUntil.Before(now)
-		}
+	email, err := u.loadEmail(job.UserID, job.EmailID)
+	if errors.Is(err, emaildomain.ErrEmailNotFound) || (err == nil && email == nil) {
+		return jobqueue.Permanent(fmt.Errorf("email %s not found", job.EmailID))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load email %s: %w", job.EmailID, err)
+	}
+	if email.Subject == "" && email.Body == "" {
+		return nil
+	}
 
-		if expired {
-			// Wake up! Restore to previous column
-			targetColumn := mapping.PreviousColumnID
-			if targetColumn == "" {
-				targetColumn = "inbox"
-			}
+	log.Printf("[VectorSync] Syncing email %s to vector DB", job.EmailID)
 
-			// Update in-memory status if user is active (optional, but good for consistency)
-			u.kanbanStatusMu.Lock()
-			u.kanbanStatus[mapping.EmailID] = targetColumn
-			u.kanbanStatusMu.Unlock()
+	// Email not synced yet, upsert to vector DB (HTML cleaned, paragraphs kept for chunking)
+	if err := u.embedEmail(ctx, job.UserID, job.EmailID, email.Subject, textextract.EmailText(email.Body)); err != nil {
+		// Not marked as synced: the queue retries it with backoff
+		return fmt.Errorf("failed to sync email %s: %w", job.EmailID, err)
+	}
 
-			// Update email object in local repo if it exists there
-			email, _ := u.emailRepo.GetEmailByID(mapping.EmailID)
-			if email != nil {
-				email.Status = targetColumn
-				email.SnoozedUntil = nil
-				u.emailRepo.UpdateEmail(email)
-			}
+	// Mark as synced ONLY after successful embedding
+	_, dims := u.vectorSearchService.EmbeddingModel()
+	if markErr := u.emailSyncHistoryRepo.MarkEmailAsSynced(job.UserID, job.EmailID, model, dims); markErr != nil {
+		log.Printf("[VectorSync] Failed to mark email %s as synced: %v", job.EmailID, markErr)
+	} else {
+		log.Printf("[VectorSync] Successfully synced email %s", job.EmailID)
+	}
+	return nil
+}
 
-			// Update DB mapping to restore column
-			u.emailKanbanColumnRepo.SetEmailColumn(mapping.UserID, mapping.EmailID, targetColumn)
+// HandleSnoozeExpired updates local state and notifies the user after the snooze scheduler
+// has restored an email to targetColumn in the database
+func (u *emailUsecase) HandleSnoozeExpired(userID, emailID, targetColumn string) {
+	// Update email object in local repo if it exists there
+	email, _ := u.emailRepo.GetEmailByID(emailID)
+	if email != nil {
+		email.Status = targetColumn
+		email.SnoozedUntil = nil
+		u.emailRepo.UpdateEmail(email)
+	}
 
-			log.Printf("Email %s woken up from snooze, restored to %s", mapping.EmailID, targetColumn)
+	log.Printf("Email %s woken up from snooze, restored to %s", emailID, targetColumn)
 
-			// Notify user via SSE to refresh UI
-			if u.eventService != nil {
-				u.eventService.SendToUser(mapping.UserID, "email_update", map[string]string{
-					"email_id": mapping.EmailID,
-					"action":   "unsnooze",
-					"column":   targetColumn,
-				})
-			}
-		}
+	// Notify user via SSE to refresh UI
+	if u.eventService != nil {
+		u.eventService.SendToUser(userID, "email_update", map[string]string{
+			"email_id": emailID,
+			"action":   "unsnooze",
+			"column":   targetColumn,
+		})
 	}
 }
 
@@ -223,11 +194,6 @@ func (u *emailUsecase) SnoozeEmail(userID, emailID string, snoozeUntil time.Time
 		previousColumn = "inbox" // Default to inbox if no column or already snoozed
 	}
 
-	// Update local status with lock
-	u.kanbanStatusMu.Lock()
-	u.kanbanStatus[emailID] = "snoozed"
-	u.kanbanStatusMu.Unlock()
-
 	// Fetch the full email object (from provider or repo)
 	email, err := u.GetEmailByID(userID, emailID)
 	if err != nil {
@@ -267,11 +233,6 @@ func (u *emailUsecase) UnsnoozeEmail(userID, emailID string) (string, error) {
 		previousColumn = "inbox"
 	}
 
-	// Update local status with lock
-	u.kanbanStatusMu.Lock()
-	u.kanbanStatus[emailID] = previousColumn
-	u.kanbanStatusMu.Unlock()
-
 	// Update email object in repository
 	email, err := u.emailRepo.GetEmailByID(emailID)
 	if err == nil && email != nil {
@@ -334,8 +295,18 @@ func (u *emailUsecase) SummarizeEmail(ctx context.Context, emailID string) (stri
 	if u.aiService == nil {
 		return "", fmt.Errorf("AI service not configured")
 	}
-	prompt := "Hãy tóm tắt nội dung email sau bằng tiếng Việt, chỉ nêu ý chính, không thêm nhận xét cá nhân: " + email.Body
-	return u.aiService.SummarizeEmail(ctx, prompt)
+	ctx = ai.WithPromptContext(ctx, ai.PromptContext{Language: user.Language, UserID: user.ID})
+	return u.aiService.SummarizeEmail(ctx, email.Body)
+}
+
+// withUserPrompt renders the AI prompts of ctx in the user's language.
+// Without a user, the default prompts are used.
+func (u *emailUsecase) withUserPrompt(ctx context.Context, userID string) context.Context {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return ai.WithPromptContext(ctx, ai.PromptContext{UserID: userID})
+	}
+	return ai.WithPromptContext(ctx, ai.PromptContext{Language: user.Language, UserID: user.ID})
 }
 
 func (u *emailUsecase) getUserTokens(userID string) (string, string, error) {
@@ -370,6 +341,16 @@ func (u *emailUsecase) makeTokenUpdateCallback(userID string) emaildomain.TokenU
 }
 
 func (u *emailUsecase) GetAllMailboxes(userID string) ([]*emaildomain.Mailbox, error) {
+	mailboxes, err := u.providerMailboxes(userID)
+	if err != nil {
+		return nil, err
+	}
+	// Saved searches follow the mailboxes of the provider as smart folders
+	return append(mailboxes, u.savedSearchMailboxes(userID)...), nil
+}
+
+// providerMailboxes returns the mailboxes of the mail provider (or local storage) of the user
+func (u *emailUsecase) providerMailboxes(userID string) ([]*emaildomain.Mailbox, error) {
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return nil, err
@@ -407,6 +388,10 @@ func (u *emailUsecase) GetMailboxByID(id string) (*emaildomain.Mailbox, error) {
 }
 
 func (u *emailUsecase) GetEmailsByMailbox(userID, mailboxID string, limit, offset int, query string) ([]*emaildomain.Email, int, error) {
+	if id, ok := emaildomain.SavedSearchIDFromMailbox(mailboxID); ok {
+		return u.getSavedSearchMailboxEmails(userID, id, limit, offset, query)
+	}
+
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return nil, 0, err
@@ -439,9 +424,8 @@ func (u *emailUsecase) GetEmailsByMailbox(userID, mailboxID string, limit, offse
 	emails, total, err := u.mailProvider.GetEmails(ctx, accessToken, refreshToken, mailboxID, limit, offset, query, u.makeTokenUpdateCallback(userID))
 	if err == nil {
 		// Sync emails to vector DB asynchronously (don't block the request)
-		for _, email := range emails {
-			u.SyncEmailToVectorDB(userID, email)
-		}
+		u.syncEmails(userID, emails)
+		u.DetectFollowUpReplies(userID, emails)
 	}
 	return emails, total, err
 }
@@ -461,6 +445,16 @@ func (u *emailUsecase) GetAttachment(userID, messageID, attachmentID string) (*e
 }
 
 func (u *emailUsecase) GetEmailByID(userID, id string) (*emaildomain.Email, error) {
+	email, err := u.loadEmail(userID, id)
+	if err == nil && email != nil {
+		// Sync email to vector DB asynchronously
+		u.SyncEmailToVectorDB(userID, email)
+	}
+	return email, err
+}
+
+// loadEmail fetches an email from the provider of the user (or local storage), without side effects
+func (u *emailUsecase) loadEmail(userID, id string) (*emaildomain.Email, error) {
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return nil, err
@@ -475,12 +469,7 @@ func (u *emailUsecase) GetEmailByID(userID, id string) (*emaildomain.Email, erro
 		if err != nil {
 			return nil, fmt.Errorf("failed to decrypt password: %w", err)
 		}
-		email, err := u.imapProvider.GetEmailByID(context.Background(), user.ImapServer, user.ImapPort, user.Email, decryptedPass, id)
-		if err == nil && email != nil {
-			// Sync email to vector DB asynchronously
-			u.SyncEmailToVectorDB(userID, email)
-		}
-		return email, err
+		return u.imapProvider.GetEmailByID(context.Background(), user.ImapServer, user.ImapPort, user.Email, decryptedPass, id)
 	}
 
 	// Gmail Handler
@@ -495,15 +484,16 @@ func (u *emailUsecase) GetEmailByID(userID, id string) (*emaildomain.Email, erro
 	}
 
 	ctx := context.Background()
-	email, err := u.mailProvider.GetEmailByID(ctx, accessToken, refreshToken, id, u.makeTokenUpdateCallback(userID))
-	if err == nil && email != nil {
-		// Sync email to vector DB asynchronously
-		u.SyncEmailToVectorDB(userID, email)
-	}
-	return email, err
+	return u.mailProvider.GetEmailByID(ctx, accessToken, refreshToken, id, u.makeTokenUpdateCallback(userID))
 }
 
-func (u *emailUsecase) MarkEmailAsRead(userID, id string) error {
+func (u *emailUsecase) MarkEmailAsRead(userID, id string) (err error) {
+	defer func() {
+		if err == nil {
+			u.updateSearchIndex(userID, id, func() error { return u.searchIndexRepo.SetRead(userID, id, true) })
+		}
+	}()
+
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return err
@@ -543,7 +533,13 @@ func (u *emailUsecase) MarkEmailAsRead(userID, id string) error {
 	return u.mailProvider.MarkAsRead(ctx, accessToken, refreshToken, id, u.makeTokenUpdateCallback(userID))
 }
 
-func (u *emailUsecase) MarkEmailAsUnread(userID, id string) error {
+func (u *emailUsecase) MarkEmailAsUnread(userID, id string) (err error) {
+	defer func() {
+		if err == nil {
+			u.updateSearchIndex(userID, id, func() error { return u.searchIndexRepo.SetRead(userID, id, false) })
+		}
+	}()
+
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return err
@@ -583,7 +579,13 @@ func (u *emailUsecase) MarkEmailAsUnread(userID, id string) error {
 	return u.mailProvider.MarkAsUnread(ctx, accessToken, refreshToken, id, u.makeTokenUpdateCallback(userID))
 }
 
-func (u *emailUsecase) ToggleStar(userID, id string) error {
+func (u *emailUsecase) ToggleStar(userID, id string) (err error) {
+	defer func() {
+		if err == nil {
+			u.updateSearchIndex(userID, id, func() error { return u.searchIndexRepo.ToggleStarred(userID, id) })
+		}
+	}()
+
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return err
@@ -623,33 +625,40 @@ func (u *emailUsecase) ToggleStar(userID, id string) error {
 	return u.mailProvider.ToggleStar(ctx, accessToken, refreshToken, id, u.makeTokenUpdateCallback(userID))
 }
 
-func (u *emailUsecase) SendEmail(userID, to, cc, bcc, subject, body string, files []*multipart.FileHeader) error {
+func (u *emailUsecase) SendEmail(userID, to, cc, bcc, subject, body string, files []*multipart.FileHeader) (*emaildomain.SentEmail, error) {
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if user == nil {
-		return fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user not found")
 	}
 
 	// IMAP Handler (SMTP)
 	if user.Provider == "imap" {
 		decryptedPass, err := crypto.Decrypt(user.ImapPassword, u.config.EncryptionKey)
 		if err != nil {
-			return fmt.Errorf("failed to decrypt password: %w", err)
+			return nil, fmt.Errorf("failed to decrypt password: %w", err)
 		}
 		return u.imapProvider.SendEmail(context.Background(), user.ImapServer, user.ImapPort, user.Email, decryptedPass, to, subject, body)
 	}
 
 	if user.AccessToken == "" {
-		return nil // Not supported for local storage yet
+		return nil, nil // Not supported for local storage yet
 	}
 
 	ctx := context.Background()
 	return u.mailProvider.SendEmail(ctx, user.AccessToken, user.RefreshToken, user.Name, user.Email, to, cc, bcc, subject, body, files, u.makeTokenUpdateCallback(userID))
 }
 
-func (u *emailUsecase) TrashEmail(userID, id string) error {
+func (u *emailUsecase) TrashEmail(userID, id string) (err error) {
+	defer func() {
+		if err == nil {
+			invalidateSearchCaches(userID)
+			go u.refreshSearchIndex(userID, id)
+		}
+	}()
+
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return err
@@ -681,7 +690,14 @@ func (u *emailUsecase) TrashEmail(userID, id string) error {
 	return u.mailProvider.TrashEmail(ctx, accessToken, refreshToken, id, u.makeTokenUpdateCallback(userID))
 }
 
-func (u *emailUsecase) ArchiveEmail(userID, id string) error {
+func (u *emailUsecase) ArchiveEmail(userID, id string) (err error) {
+	defer func() {
+		if err == nil {
+			invalidateSearchCaches(userID)
+			go u.refreshSearchIndex(userID, id)
+		}
+	}()
+
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return err
@@ -714,7 +730,13 @@ func (u *emailUsecase) ArchiveEmail(userID, id string) error {
 }
 
 // PermanentDeleteEmail permanently deletes an email (for emails in trash)
-func (u *emailUsecase) PermanentDeleteEmail(userID, id string) error {
+func (u *emailUsecase) PermanentDeleteEmail(userID, id string) (err error) {
+	defer func() {
+		if err == nil {
+			u.updateSearchIndex(userID, id, func() error { return u.searchIndexRepo.Delete(userID, id) })
+		}
+	}()
+
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return err
@@ -759,7 +781,26 @@ func (u *emailUsecase) WatchMailbox(userID string) error {
 }
 
 // Move email to another mailbox (Kanban drag & drop)
+// Manual moves also teach the auto-triage classifier (see learnFromManualMove)
 func (u *emailUsecase) MoveEmailToMailbox(userID, emailID, mailboxID, sourceColumnID string) error {
+	if err := u.moveEmail(userID, emailID, mailboxID, sourceColumnID); err != nil {
+		return err
+	}
+	u.learnFromManualMove(userID, emailID, mailboxID)
+	return nil
+}
+
+// moveEmail moves an email to a Kanban column, syncing Gmail labels.
+// Used directly by automatic moves (follow-up resurfacing, auto-triage).
+func (u *emailUsecase) moveEmail(userID, emailID, mailboxID, sourceColumnID string) (err error) {
+	defer func() {
+		if err == nil {
+			// Column labels may have moved the email out of its mailbox, and in: results changed
+			invalidateSearchCaches(userID)
+			go u.refreshSearchIndex(userID, emailID)
+		}
+	}()
+
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return err
@@ -809,47 +850,10 @@ func (u *emailUsecase) MoveEmailToMailbox(userID, emailID, mailboxID, sourceColu
 		}
 
 		// Prepare label IDs to add and remove
-		addLabelIDs := []string{}
-		removeLabelIDs := []string{}
-
-		// Add target column's label (or INBOX if no label configured)
-		if targetColumn != nil && targetColumn.GmailLabelID != "" {
-			addLabelIDs = append(addLabelIDs, targetColumn.GmailLabelID)
-		} else {
-			// If target column has no label mapping, add INBOX to keep email visible
-			addLabelIDs = append(addLabelIDs, "INBOX")
-		}
-
-		// Add target column's remove_label_ids to removeLabelIDs
-		if targetColumn != nil && len(targetColumn.RemoveLabelIDs) > 0 {
-			removeLabelIDs = append(removeLabelIDs, []string(targetColumn.RemoveLabelIDs)...)
-		}
-
-		// Remove source column's label (when leaving the column)
-		if sourceColumn != nil && sourceColumn.GmailLabelID != "" {
-			// Only remove if it's not the same as the target label
-			if targetColumn == nil || sourceColumn.GmailLabelID != targetColumn.GmailLabelID {
-				removeLabelIDs = append(removeLabelIDs, sourceColumn.GmailLabelID)
-			}
-		}
+		addLabelIDs, removeLabelIDs := columnLabelChanges(sourceColumn, targetColumn)
 
 		// Apply label changes via Gmail API
 		if len(addLabelIDs) > 0 || len(removeLabelIDs) > 0 {
-			// Deduplicate: remove labels that appear in both add and remove lists
-			addSet := make(map[string]bool)
-			for _, id := range addLabelIDs {
-				addSet[id] = true
-			}
-
-			// Filter out labels from removeLabelIDs that are also in addLabelIDs
-			var filteredRemove []string
-			for _, id := range removeLabelIDs {
-				if !addSet[id] {
-					filteredRemove = append(filteredRemove, id)
-				}
-			}
-			removeLabelIDs = filteredRemove
-
 			ctx := context.Background()
 			log.Printf("[MoveEmail] Source: %s (label: %v), Target: %s (label: %v)",
 				actualSourceColumnID,
@@ -880,11 +884,6 @@ func (u *emailUsecase) MoveEmailToMailbox(userID, emailID, mailboxID, sourceColu
 		}
 	}
 
-	// Update local Kanban status map with lock
-	u.kanbanStatusMu.Lock()
-	u.kanbanStatus[emailID] = mailboxID
-	u.kanbanStatusMu.Unlock()
-
 	// Save email-column mapping to DB (for both default and custom columns)
 	// This allows us to persist which emails belong to which columns
 	if err := u.emailKanbanColumnRepo.SetEmailColumn(userID, emailID, mailboxID); err != nil {
@@ -895,8 +894,61 @@ func (u *emailUsecase) MoveEmailToMailbox(userID, emailID, mailboxID, sourceColu
 	return nil
 }
 
+// columnLabelChanges returns the Gmail labels to add and remove when an email moves from the
+// source column (nil when unknown or unchanged) to the target column
+func columnLabelChanges(sourceColumn, targetColumn *emaildomain.KanbanColumn) (addLabelIDs, removeLabelIDs []string) {
+	// Add target column's label (or INBOX if no label configured)
+	if targetColumn != nil && targetColumn.GmailLabelID != "" {
+		addLabelIDs = append(addLabelIDs, targetColumn.GmailLabelID)
+	} else {
+		// If target column has no label mapping, add INBOX to keep email visible
+		addLabelIDs = append(addLabelIDs, "INBOX")
+	}
+
+	// Add target column's remove_label_ids to removeLabelIDs
+	if targetColumn != nil && len(targetColumn.RemoveLabelIDs) > 0 {
+		removeLabelIDs = append(removeLabelIDs, []string(targetColumn.RemoveLabelIDs)...)
+	}
+
+	// Remove source column's label (when leaving the column)
+	if sourceColumn != nil && sourceColumn.GmailLabelID != "" {
+		// Only remove if it's not the same as the target label
+		if targetColumn == nil || sourceColumn.GmailLabelID != targetColumn.GmailLabelID {
+			removeLabelIDs = append(removeLabelIDs, sourceColumn.GmailLabelID)
+		}
+	}
+
+	// Deduplicate: remove labels that appear in both add and remove lists
+	addSet := make(map[string]bool)
+	for _, id := range addLabelIDs {
+		addSet[id] = true
+	}
+	var filteredRemove []string
+	for _, id := range removeLabelIDs {
+		if !addSet[id] {
+			filteredRemove = append(filteredRemove, id)
+		}
+	}
+	return addLabelIDs, filteredRemove
+}
+
 // GetEmailsByStatus returns emails by status (for Kanban columns)
 func (u *emailUsecase) GetEmailsByStatus(userID, status string, limit, offset int, isKanban bool) ([]*emaildomain.Email, int, error) {
+	// A column sourced from a saved search lists the results of the search
+	if column, err := u.kanbanColumnRepo.GetColumnByID(userID, status); err == nil && column != nil && column.SavedSearchID != "" {
+		emails, total, err := u.getSavedSearchEmails(userID, column.SavedSearchID, limit, offset)
+		if err == nil {
+			for _, email := range emails {
+				email.Status = status
+			}
+			return emails, total, nil
+		}
+		if !errors.Is(err, ErrSavedSearchNotFound) {
+			return nil, 0, err
+		}
+		// The saved search was deleted: the column lists its own emails again
+	}
+
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
 		return nil, 0, err
@@ -912,67 +964,43 @@ func (u *emailUsecase) GetEmailsByStatus(userID, status string, limit, offset in
 			return nil, 0, fmt.Errorf("failed to decrypt password: %w", err)
 		}
 
-		// For IMAP, we fetch INBOX and filter by local Kanban status
-		// Note: This is inefficient for large mailboxes as we fetch then filter.
-		// A better approach would be to store Kanban status in DB for IMAP users too.
-		emails, total, err := u.imapProvider.GetEmails(context.Background(), user.ImapServer, user.ImapPort, user.Email, decryptedPass, "INBOX", limit, offset)
-		if err != nil {
-			return nil, 0, err
-		}
+		ctx := context.Background()
 
-		// Sync emails to vector DB asynchronously
-		for _, email := range emails {
-			u.SyncEmailToVectorDB(userID, email)
-		}
-
-		var filtered []*emaildomain.Email
-		if isKanban {
-			// In Kanban mode, use DB mapping to guarantee uniqueness across columns.
-			emailToCol, err := u.emailKanbanColumnRepo.GetEmailColumnMap(userID)
+		// Kanban state lives in the DB mapping (same for Kanban and list mode):
+		// - Inbox = INBOX emails not assigned to another column, paginated after exclusion
+		// - Other columns = emails mapped to that column, paginated in SQL
+		if status == "inbox" {
+			excludedIDs, err := u.emailKanbanColumnRepo.GetEmailIDsOutsideColumn(userID, "inbox")
 			if err != nil {
-				log.Printf("[GetEmailsByStatus][IMAP] Failed to get email-column map: %v", err)
-				emailToCol = map[string]string{}
+				return nil, 0, err
 			}
 
-			for _, email := range emails {
-				col := emailToCol[email.ID]
-				switch status {
-				case "inbox":
-					// Inbox shows only unmapped emails or explicitly mapped to inbox.
-					if col == "" || col == "inbox" {
-						filtered = append(filtered, email)
-					}
-				default:
-					// Other columns show only emails mapped to that column.
-					if col == status {
-						filtered = append(filtered, email)
-					}
-				}
+			emails, total, err := u.imapProvider.GetEmailsExcluding(ctx, user.ImapServer, user.ImapPort, user.Email, decryptedPass, "INBOX", excludedIDs, limit, offset)
+			if err != nil {
+				return nil, 0, err
 			}
-			return filtered, len(filtered), nil
+
+			// Sync emails to vector DB asynchronously
+			u.syncEmails(userID, emails)
+			u.DetectFollowUpReplies(userID, emails)
+			u.TriageNewEmails(userID, emails)
+			u.AlertSavedSearches(userID, emails)
+			return emails, total, nil
 		}
 
-		// Non-Kanban fallback: use in-memory status map.
-		if status == "inbox" {
-			for _, email := range emails {
-				u.kanbanStatusMu.RLock()
-				s, ok := u.kanbanStatus[email.ID]
-				u.kanbanStatusMu.RUnlock()
-				if !ok || s == "inbox" {
-					filtered = append(filtered, email)
-				}
-			}
-		} else {
-			for _, email := range emails {
-				u.kanbanStatusMu.RLock()
-				s, ok := u.kanbanStatus[email.ID]
-				u.kanbanStatusMu.RUnlock()
-				if ok && s == status {
-					filtered = append(filtered, email)
-				}
-			}
+		emailIDs, total, err := u.emailKanbanColumnRepo.GetEmailsByColumnPaginated(userID, status, limit, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		emails, err := u.imapProvider.GetEmailsByIDs(ctx, user.ImapServer, user.ImapPort, user.Email, decryptedPass, emailIDs)
+		if err != nil {
+			return nil, 0, err
 		}
-		return filtered, total, nil
+		for _, email := range emails {
+			email.Status = status
+		}
+		return emails, int(total), nil
 	}
 
 	// Gmail Handler
@@ -996,8 +1024,9 @@ func (u *emailUsecase) GetEmailsByStatus(userID, status string, limit, offset in
 	if !isKanban && column != nil && column.GmailLabelID != "" {
 		log.Printf("[GetEmailsByStatus] Column %s mapped to Gmail label %s - fetching from Gmail", status, column.GmailLabelID)
 
-		// Use the Gmail label ID directly to fetch emails
-		emails, total, err := u.mailProvider.GetEmails(ctx, accessToken, refreshToken, column.GmailLabelID, limit, offset, "", u.makeTokenUpdateCallback(userID))
+		// Use the Gmail label ID directly to fetch emails, without snoozed emails (paginated after exclusion)
+		snoozedEmailIDs, _ := u.emailKanbanColumnRepo.GetEmailsByColumn(userID, "snoozed")
+		emails, total, err := u.mailProvider.GetEmailsExcluding(ctx, accessToken, refreshToken, column.GmailLabelID, snoozedEmailIDs, limit, offset, u.makeTokenUpdateCallback(userID))
 		if err != nil {
 			// If label doesn't exist or error, fallback to empty
 			log.Printf("[GetEmailsByStatus] Failed to fetch from label %s: %v", column.GmailLabelID, err)
@@ -1005,50 +1034,20 @@ func (u *emailUsecase) GetEmailsByStatus(userID, status string, limit, offset in
 		}
 
 		// Sync emails to vector DB asynchronously
-		for _, email := range emails {
-			u.SyncEmailToVectorDB(userID, email)
-		}
+		u.syncEmails(userID, emails)
+		u.DetectFollowUpReplies(userID, emails)
 
-		// Exclude snoozed emails from this column
-		snoozedEmailIDs, _ := u.emailKanbanColumnRepo.GetEmailsByColumn(userID, "snoozed")
-		snoozedSet := make(map[string]bool)
-		for _, id := range snoozedEmailIDs {
-			snoozedSet[id] = true
-		}
-
-		var filtered []*emaildomain.Email
-		for _, email := range emails {
-			if !snoozedSet[email.ID] {
-				filtered = append(filtered, email)
-			}
-		}
-
-		return filtered, total, nil
+		return emails, total, nil
 	}
 
 	// Special handling for "snoozed" column - fetch from DB mapping directly
 	if status == "snoozed" {
-		snoozedEmailIDs, err := u.emailKanbanColumnRepo.GetEmailsByColumn(userID, "snoozed")
+		paginatedIDs, total, err := u.emailKanbanColumnRepo.GetEmailsByColumnPaginated(userID, "snoozed", limit, offset)
 		if err != nil {
 			log.Printf("[GetEmailsByStatus] Failed to get snoozed emails from DB: %v", err)
 			return []*emaildomain.Email{}, 0, nil
 		}
 
-		if len(snoozedEmailIDs) == 0 {
-			return []*emaildomain.Email{}, 0, nil
-		}
-
-		// Apply pagination
-		start := offset
-		end := offset + limit
-		if start >= len(snoozedEmailIDs) {
-			return []*emaildomain.Email{}, len(snoozedEmailIDs), nil
-		}
-		if end > len(snoozedEmailIDs) {
-			end = len(snoozedEmailIDs)
-		}
-		paginatedIDs := snoozedEmailIDs[start:end]
-
 		// Fetch full email details from Gmail AND local DB to ensure SnoozedUntil is present
 		var emails []*emaildomain.Email
 		for _, emailID := range paginatedIDs {
@@ -1078,7 +1077,7 @@ func (u *emailUsecase) GetEmailsByStatus(userID, status string, limit, offset in
 			}
 		}
 
-		return emails, len(snoozedEmailIDs), nil
+		return emails, int(total), nil
 	}
 
 	// Kanban mode: ensure no email appears in multiple columns.
@@ -1092,8 +1091,10 @@ func (u *emailUsecase) GetEmailsByStatus(userID, status string, limit, offset in
 			// If column is mapped to a Gmail label, fetch directly from Gmail
 			// This ensures the column reflects the actual state of the label in Gmail
 			if column != nil && column.GmailLabelID != "" {
-				// Fetch directly from Gmail using the label
-				emails, total, err := u.mailProvider.GetEmails(ctx, accessToken, refreshToken, column.GmailLabelID, limit, offset, "", u.makeTokenUpdateCallback(userID))
+				// Fetch directly from Gmail using the label. Snoozed emails only appear in "Snoozed":
+				// they are excluded before pagination, so pages stay full and the total matches
+				snoozedEmailIDs, _ := u.emailKanbanColumnRepo.GetEmailsByColumn(userID, "snoozed")
+				emails, total, err := u.mailProvider.GetEmailsExcluding(ctx, accessToken, refreshToken, column.GmailLabelID, snoozedEmailIDs, limit, offset, u.makeTokenUpdateCallback(userID))
 				if err != nil {
 					log.Printf("[GetEmailsByStatus] Failed to fetch from label %s: %v", column.GmailLabelID, err)
 					return []*emaildomain.Email{}, 0, nil
@@ -1107,79 +1108,18 @@ func (u *emailUsecase) GetEmailsByStatus(userID, status string, limit, offset in
 					// (Simple check - if we just fetched it, it's likely valid, but good practice)
 					
 					for _, em := range e {
-						// Update the mapping in DB
-						// Note: This overrides any previous mapping. 
-						// Ensure we don't accidentally move snoozed items? 
-						// Snoozed items are handled by the 'snoozed' check below in display, 
-						// but in DB they might be marked as 'snoozed'. 
-						// If we mark them as 'todo' here, they might disappear from snoozed?
-						// Safe bet: Check if email is snoozed before updating? 
-						// For performance, we'll just update map. 
-						// Actually, if it's snoozed, it shouldn't show up here if we filter?
-						// But for the DB mapping, if it has the label, it technically 'belongs' here too.
+						// Snoozed emails were excluded above, so their snooze mapping is never overridden
 						_ = u.emailKanbanColumnRepo.SetEmailColumn(userID, em.ID, colID)
 					}
 					// Also update vector DB since we have fresh emails
-					for _, em := range e {
-						u.SyncEmailToVectorDB(userID, em)
-					}
+					u.syncEmails(userID, e)
 				}(emails, status)
 
-				// Exclude snoozed emails from this column
-				// Snoozed emails should only appear in "Snoozed" or be hidden
-				snoozedEmailIDs, _ := u.emailKanbanColumnRepo.GetEmailsByColumn(userID, "snoozed")
-				snoozedSet := make(map[string]bool)
-				for _, id := range snoozedEmailIDs {
-					snoozedSet[id] = true
-				}
-
-				var filtered []*emaildomain.Email
-				for _, email := range emails {
-					if !snoozedSet[email.ID] {
-						filtered = append(filtered, email)
-					}
-				}
-
-				// Recalculate totals if we filtered anything? 
-				// Gmail total is total matched by label. 
-				// If we filter, the page size might shrink. 
-				// Ideally we should loop to fill page like Inbox, but for now filtering is safer correctness-wise.
-				return filtered, total, nil
+				return emails, total, nil
 			}
 
 			// Fallback: Use DB mapping if no Gmail label is configured
-			dbEmailIDs, err := u.emailKanbanColumnRepo.GetEmailsByColumn(userID, status)
-			if err != nil {
-				log.Printf("[GetEmailsByStatus] Failed to get emails for column %s: %v", status, err)
-				return []*emaildomain.Email{}, 0, nil
-			}
-
-			if len(dbEmailIDs) == 0 {
-				return []*emaildomain.Email{}, 0, nil
-			}
-
-			start := offset
-			end := offset + limit
-			if start >= len(dbEmailIDs) {
-				return []*emaildomain.Email{}, len(dbEmailIDs), nil
-			}
-			if end > len(dbEmailIDs) {
-				end = len(dbEmailIDs)
-			}
-			paginatedIDs := dbEmailIDs[start:end]
-
-			var mapped []*emaildomain.Email
-			for _, emailID := range paginatedIDs {
-				email, err := u.mailProvider.GetEmailByID(ctx, accessToken, refreshToken, emailID, u.makeTokenUpdateCallback(userID))
-				if err != nil {
-					log.Printf("[GetEmailsByStatus] Failed to fetch email %s for column %s: %v", emailID, status, err)
-					continue
-				}
-				if email != nil {
-					mapped = append(mapped, email)
-				}
-			}
-			return mapped, len(dbEmailIDs), nil
+			return u.getMappedColumnEmails(ctx, userID, accessToken, refreshToken, status, limit, offset)
 		}
 
 		// status == "inbox"
@@ -1190,190 +1130,82 @@ func (u *emailUsecase) GetEmailsByStatus(userID, status string, limit, offset in
 			targetLabel = inboxCol.GmailLabelID
 		}
 
-		emailToCol, err := u.emailKanbanColumnRepo.GetEmailColumnMap(userID)
+		// Inbox shows emails not assigned to another column (including snoozed),
+		// paginated and counted after exclusion so pages are full and the total is exact
+		excludedIDs, err := u.emailKanbanColumnRepo.GetEmailIDsOutsideColumn(userID, "inbox")
 		if err != nil {
-			log.Printf("[GetEmailsByStatus] Failed to get email-column map: %v", err)
-			emailToCol = map[string]string{}
-		}
-
-		var filtered []*emaildomain.Email
-		currentOffset := offset
-		
-		// Loop to fetch enough emails after filtering
-		// We try up to 5 times or until we find enough valid "inbox" emails
-		maxAttempts := 5
-		batchSize := limit
-		if batchSize < 50 {
-			batchSize = 50 // Fetch at least 50 at a time for efficiency
-		}
-		
-		var lastTotal int
-		
-		for i := 0; i < maxAttempts; i++ {
-			emails, total, err := u.mailProvider.GetEmails(ctx, accessToken, refreshToken, targetLabel, batchSize, currentOffset, "", u.makeTokenUpdateCallback(userID))
-			if err != nil {
-				return nil, 0, err
-			}
-			lastTotal = total
-			
-			if len(emails) == 0 {
-				break
-			}
-
-			// Filter this batch
-			for _, email := range emails {
-				col := emailToCol[email.ID]
-				if col == "" || col == "inbox" {
-					filtered = append(filtered, email)
-				}
-			}
-			
-			currentOffset += len(emails)
-			
-			// If we have satisfied the limit, stop
-			if len(filtered) >= limit {
-				break
-			}
+			return nil, 0, err
 		}
-		
-		// Trim to exact limit if we over-fetched
-		if len(filtered) > limit {
-			filtered = filtered[:limit]
+		emails, total, err := u.mailProvider.GetEmailsExcluding(ctx, accessToken, refreshToken, targetLabel, excludedIDs, limit, offset, u.makeTokenUpdateCallback(userID))
+		if err != nil {
+			return nil, 0, err
 		}
-		
-		return filtered, lastTotal, nil
+
+		u.syncEmails(userID, emails)
+		u.DetectFollowUpReplies(userID, emails)
+		u.TriageNewEmails(userID, emails)
+		u.AlertSavedSearches(userID, emails)
+		return emails, total, nil
 	}
 
-	// Default behavior: Fetch from INBOX with exact limit (avoid over-fetching for performance)
-	emails, _, err := u.mailProvider.GetEmails(ctx, accessToken, refreshToken, "INBOX", limit, offset, "", u.makeTokenUpdateCallback(userID))
+	// Non-inbox columns (default or custom without gmail_label_id) are driven by the DB mapping
+	if status != "inbox" {
+		return u.getMappedColumnEmails(ctx, userID, accessToken, refreshToken, status, limit, offset)
+	}
+
+	// Inbox shows emails not assigned to another column (including snoozed),
+	// paginated and counted after exclusion so pages are full and the total is exact
+	excludedIDs, err := u.emailKanbanColumnRepo.GetEmailIDsOutsideColumn(userID, "inbox")
+	if err != nil {
+		return nil, 0, err
+	}
+	emails, total, err := u.mailProvider.GetEmailsExcluding(ctx, accessToken, refreshToken, "INBOX", excludedIDs, limit, offset, u.makeTokenUpdateCallback(userID))
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Sync emails to vector DB asynchronously
-	for _, email := range emails {
-		u.SyncEmailToVectorDB(userID, email)
-	}
+	u.syncEmails(userID, emails)
+	u.DetectFollowUpReplies(userID, emails)
+
+	// Classify unmapped inbox emails in the background when auto-triage is on
+	u.TriageNewEmails(userID, emails)
+	// Match new emails against saved searches with alerts
+	u.AlertSavedSearches(userID, emails)
+	return emails, total, nil
+}
 
-	// Check if this is a default column or custom column
-	defaultColumns := map[string]bool{
-		"inbox":   true,
-		"todo":    true,
-		"done":    true,
-		"snoozed": true,
+// getMappedColumnEmails returns one page of a column driven purely by the DB mapping,
+// paginated in SQL so page sizes and total are exact
+func (u *emailUsecase) getMappedColumnEmails(ctx context.Context, userID, accessToken, refreshToken, status string, limit, offset int) ([]*emaildomain.Email, int, error) {
+	paginatedIDs, total, err := u.emailKanbanColumnRepo.GetEmailsByColumnPaginated(userID, status, limit, offset)
+	if err != nil {
+		log.Printf("[GetEmailsByStatus] Failed to get emails for column %s: %v", status, err)
+		return []*emaildomain.Email{}, 0, nil
 	}
 
-	var filtered []*emaildomain.Email
-	if defaultColumns[status] {
-		// Default columns: use kanbanStatus map (in-memory) as fallback, but also check DB
-		emailIDSet := make(map[string]bool)
-
-		// Get email IDs from DB mapping first
-		dbEmailIDs, err := u.emailKanbanColumnRepo.GetEmailsByColumn(userID, status)
-		if err == nil {
-			for _, id := range dbEmailIDs {
-				emailIDSet[id] = true
-			}
-		}
-
-		// Get snoozed email IDs to exclude them from other columns
-		snoozedEmailIDs := make(map[string]bool)
-		if status != "snoozed" {
-			snoozedIDs, err := u.emailKanbanColumnRepo.GetEmailsByColumn(userID, "snoozed")
-			if err == nil {
-				for _, id := range snoozedIDs {
-					snoozedEmailIDs[id] = true
-				}
-			}
-		}
-
-		// Filter emails
-		if status == "inbox" {
-			// For inbox: include emails that are in inbox (no mapping) or explicitly mapped to inbox
-			// Exclude snoozed emails
-			for _, email := range emails {
-				// Skip if email is snoozed
-				if snoozedEmailIDs[email.ID] {
-					continue
-				}
-				// Check DB mapping first
-				if emailIDSet[email.ID] {
-					filtered = append(filtered, email)
-					continue
-				}
-				// Fallback to in-memory status
-				u.kanbanStatusMu.RLock()
-				s, ok := u.kanbanStatus[email.ID]
-				u.kanbanStatusMu.RUnlock()
-				if !ok || s == "inbox" {
-					filtered = append(filtered, email)
-				}
-			}
-		} else {
-			// For other default columns: only include if mapped
-			// Exclude snoozed emails
-			for _, email := range emails {
-				// Skip if email is snoozed
-				if snoozedEmailIDs[email.ID] {
-					continue
-				}
-				// Check DB mapping first
-				if emailIDSet[email.ID] {
-					filtered = append(filtered, email)
-					continue
-				}
-				// Fallback to in-memory status
-				u.kanbanStatusMu.RLock()
-				s, ok := u.kanbanStatus[email.ID]
-				u.kanbanStatusMu.RUnlock()
-				if ok && s == status {
-					filtered = append(filtered, email)
-				}
-			}
-		}
-	} else {
-		// Custom column without gmail_label_id: fetch emails directly using DB mapping
-		// Similar to how "snoozed" column works
-		dbEmailIDs, err := u.emailKanbanColumnRepo.GetEmailsByColumn(userID, status)
+	mapped := []*emaildomain.Email{}
+	for _, emailID := range paginatedIDs {
+		email, err := u.mailProvider.GetEmailByID(ctx, accessToken, refreshToken, emailID, u.makeTokenUpdateCallback(userID))
 		if err != nil {
-			log.Printf("[GetEmailsByStatus] Failed to get emails for custom column %s: %v", status, err)
-			return []*emaildomain.Email{}, 0, nil
-		}
-
-		if len(dbEmailIDs) == 0 {
-			return []*emaildomain.Email{}, 0, nil
-		}
-
-		// Apply pagination
-		start := offset
-		end := offset + limit
-		if start >= len(dbEmailIDs) {
-			return []*emaildomain.Email{}, len(dbEmailIDs), nil
+			log.Printf("[GetEmailsByStatus] Failed to fetch email %s for column %s: %v", emailID, status, err)
+			continue
 		}
-		if end > len(dbEmailIDs) {
-			end = len(dbEmailIDs)
+		if email != nil {
+			email.Status = status
+			mapped = append(mapped, email)
 		}
-		paginatedIDs := dbEmailIDs[start:end]
-
-		// Fetch full email details from Gmail
-		for _, emailID := range paginatedIDs {
-			email, err := u.mailProvider.GetEmailByID(ctx, accessToken, refreshToken, emailID, u.makeTokenUpdateCallback(userID))
-			if err != nil {
-				log.Printf("[GetEmailsByStatus] Failed to fetch email %s for column %s: %v", emailID, status, err)
-				continue
-			}
-			if email != nil {
-				filtered = append(filtered, email)
-			}
-		}
-
-		return filtered, len(dbEmailIDs), nil
 	}
-	return filtered, len(filtered), nil
+	return mapped, int(total), nil
 }
 
 // FuzzySearch performs fuzzy search over emails
-// It searches subject, from, from_name fields with typo tolerance and partial matching
+// The query supports Gmail operators (from:, to:, subject:, has:attachment, is:unread, is:starred,
+// before:/after:, larger:/smaller:, in:<kanban column>, "phrases", OR, NOT / -). Operators are sent to
+// the provider's search where possible; free text is matched here with typo tolerance and partial
+// matching on subject, sender and body, accent-insensitively
+// Once the user's mailbox is in the full-text index, the whole query runs there instead (word prefixes,
+// every mailbox, bodies included) without contacting the provider
 // Results are ranked by relevance score (best matches first)
 // Optimized: Progressive fetching - fetch small batches and only fetch more if needed
 func (u *emailUsecase) FuzzySearch(userID, query string, limit, offset int) ([]*emaildomain.Email, int, error) {
@@ -1382,9 +1214,6 @@ func (u *emailUsecase) FuzzySearch(userID, query string, limit, offset int) ([]*
 	if len(query) == 0 {
 		return []*emaildomain.Email{}, 0, nil
 	}
-	if len(query) < 1 {
-		return []*emaildomain.Email{}, 0, fmt.Errorf("query too short")
-	}
 
 	user, err := u.userRepo.FindByID(userID)
 	if err != nil {
@@ -1394,10 +1223,25 @@ func (u *emailUsecase) FuzzySearch(userID, query string, limit, offset int) ([]*
 		return nil, 0, fmt.Errorf("user not found")
 	}
 
-	// Build Gmail search query for pre-filtering (only for Gmail provider)
-	var gmailSearchQuery string
-	if user.Provider != "imap" {
-		gmailSearchQuery = fuzzy.BuildGmailSearchQuery(query)
+	parsed, err := parseSearchQuery(user, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	if parsed.IsEmpty() {
+		return []*emaildomain.Email{}, 0, nil
+	}
+	if u.isSearchIndexed(userID) {
+		return u.searchLocalIndex(userID, parsed, limit, offset)
+	}
+	// Free text is ranked; everything else only filters
+	text, _ := parsed.Split()
+	columns := u.searchColumns(userID, parsed)
+
+	// Compile the operators for the provider's search; the residual query is matched here
+	gmailSearchQuery, residual := parsed.Gmail()
+	imapCriteria, imapResidual := parsed.IMAP()
+	if user.Provider == "imap" {
+		residual = imapResidual
 	}
 
 	// Progressive fetching: start with small batch, fetch more if needed
@@ -1452,7 +1296,7 @@ func (u *emailUsecase) FuzzySearch(userID, query string, limit, offset int) ([]*
 			if err != nil {
 				return nil, 0, fmt.Errorf("failed to decrypt password: %w", err)
 			}
-			batchEmails, _, err = u.imapProvider.GetEmails(context.Background(), user.ImapServer, user.ImapPort, user.Email, decryptedPass, "INBOX", batchSize, currentOffset)
+			batchEmails, _, err = u.imapProvider.SearchEmails(context.Background(), user.ImapServer, user.ImapPort, user.Email, decryptedPass, "INBOX", imapCriteria, batchSize, currentOffset)
 			if err != nil {
 				return nil, 0, err
 			}
@@ -1464,11 +1308,12 @@ func (u *emailUsecase) FuzzySearch(userID, query string, limit, offset int) ([]*
 			}
 
 			if accessToken == "" {
-				// Fallback to local storage
+				// Fallback to local storage: the whole query is matched here
 				batchEmails, _, err = u.emailRepo.GetEmailsByMailbox("INBOX", batchSize, currentOffset)
 				if err != nil {
 					return nil, 0, err
 				}
+				residual = parsed
 			} else {
 				// Use Gmail search query for pre-filtering
 				ctx := context.Background()
@@ -1486,30 +1331,20 @@ func (u *emailUsecase) FuzzySearch(userID, query string, limit, offset int) ([]*
 
 		totalProcessed += len(batchEmails)
 
-		// Pre-filter with quick contains check (for local storage and IMAP)
-		var preFilteredEmails []*emaildomain.Email
-		if user.Provider == "imap" || (user.Provider != "imap" && accessToken == "") {
-			preFilteredEmails = make([]*emaildomain.Email, 0, len(batchEmails)/2)
-			for _, email := range batchEmails {
-				if fuzzy.QuickFilter(query, email.Subject, email.From, email.FromName) {
-					preFilteredEmails = append(preFilteredEmails, email)
-				}
-			}
-			batchEmails = preFilteredEmails
-		}
-
-		// Process batch: fuzzy match and score
+		// Process batch: match the rest of the query and score the free text
 		for _, email := range batchEmails {
-			if fuzzy.FuzzyMatchEmail(query, email.Subject, email.From, email.FromName, email.Preview) {
-				score := fuzzy.CalculateRelevanceScore(query, email.Subject, email.From, email.FromName)
-
-				if score > 0 {
-					matchedEmails = append(matchedEmails, scoredEmail{
-						email: email,
-						score: score,
-					})
-				}
+			if !residual.Match(searchMessage(email, columns)) {
+				continue
 			}
+			// Matches on the body only (or on operators only) score 0 and come last, newest first
+			var score float64
+			if text != "" {
+				score = fuzzy.CalculateRelevanceScore(text, email.Subject, email.From, email.FromName)
+			}
+			matchedEmails = append(matchedEmails, scoredEmail{
+				email: email,
+				score: score,
+			})
 		}
 
 		// Check if we have enough high-quality results
@@ -1577,11 +1412,11 @@ func (u *emailUsecase) FuzzySearch(userID, query string, limit, offset int) ([]*
 	return result, total, nil
 }
 
-// SyncAllEmailsForUser syncs all emails for a user to vector DB (async, non-blocking)
+// SyncAllEmailsForUser syncs all emails for a user to vector DB and the full-text search index (async, non-blocking)
 // This is typically called after login/registration to index all existing emails
 func (u *emailUsecase) SyncAllEmailsForUser(userID string) {
-	if u.vectorSearchService == nil {
-		log.Printf("Vector search service not available, skipping email sync for user %s", userID)
+	if u.vectorSearchService == nil && u.searchIndexRepo == nil {
+		log.Printf("Vector search service and search index not available, skipping email sync for user %s", userID)
 		return
 	}
 
@@ -1603,8 +1438,10 @@ func (u *emailUsecase) SyncAllEmailsForUser(userID string) {
 			return
 		}
 
-		// Check if emails have already been synced
-		if user.EmailsSynced {
+		// Check if emails have already been synced (users synced before the search index existed are synced again)
+		vectorSynced := user.EmailsSynced || u.vectorSearchService == nil
+		indexSynced := u.searchIndexRepo == nil || u.isSearchIndexed(userID)
+		if vectorSynced && indexSynced {
 			log.Printf("Emails already synced for user %s, skipping", userID)
 			return
 		}
@@ -1624,7 +1461,7 @@ func (u *emailUsecase) SyncAllEmailsForUser(userID string) {
 		}
 
 		// Get all mailboxes for the user
-		mailboxes, err := u.GetAllMailboxes(userID)
+		mailboxes, err := u.providerMailboxes(userID)
 		if err != nil {
 			// Check if error is about insufficient scopes
 			errStr := err.Error()
@@ -1646,12 +1483,15 @@ func (u *emailUsecase) SyncAllEmailsForUser(userID string) {
 		// Common mailboxes to sync (prioritize these)
 		priorityMailboxes := []string{"INBOX", "SENT", "DRAFT"}
 		syncedMailboxes := make(map[string]bool)
+		complete := true // Every mailbox was read to the end
 
 		// First, sync priority mailboxes
 		for _, mailboxID := range priorityMailboxes {
 			for _, mb := range mailboxes {
 				if mb.ID == mailboxID {
-					u.syncMailboxEmails(userID, mailboxID)
+					if syncErr := u.syncMailboxEmails(userID, mailboxID); syncErr != nil {
+						complete = false
+					}
 					syncedMailboxes[mailboxID] = true
 					break
 				}
@@ -1661,24 +1501,38 @@ func (u *emailUsecase) SyncAllEmailsForUser(userID string) {
 		// Then sync other mailboxes
 		for _, mb := range mailboxes {
 			if !syncedMailboxes[mb.ID] {
-				u.syncMailboxEmails(userID, mb.ID)
+				if syncErr := u.syncMailboxEmails(userID, mb.ID); syncErr != nil {
+					complete = false
+				}
 			}
 		}
 
 		// Mark user as synced after successful completion
-		user.EmailsSynced = true
-		if updateErr := u.userRepo.Update(user); updateErr != nil {
-			log.Printf("Failed to mark user %s as synced: %v", userID, updateErr)
-		} else {
-			log.Printf("Marked user %s as emails synced", userID)
+		if u.vectorSearchService != nil {
+			user.EmailsSynced = true
+			if updateErr := u.userRepo.Update(user); updateErr != nil {
+				log.Printf("Failed to mark user %s as synced: %v", userID, updateErr)
+			} else {
+				log.Printf("Marked user %s as emails synced", userID)
+			}
+		}
+		u.pruneSuggestions(userID)
+		// FuzzySearch switches to the search index from now on, once it holds every email
+		if u.searchIndexRepo != nil && !complete {
+			log.Printf("Search index of user %s is incomplete: a mailbox sync stopped early", userID)
+		} else if u.searchIndexRepo != nil {
+			if markErr := u.searchIndexRepo.MarkUserIndexed(userID); markErr != nil {
+				log.Printf("Failed to mark search index of user %s as complete: %v", userID, markErr)
+			}
 		}
 
 		log.Printf("Completed full email sync for user %s", userID)
 	}()
 }
 
-// syncMailboxEmails syncs all emails from a specific mailbox
-func (u *emailUsecase) syncMailboxEmails(userID, mailboxID string) {
+// syncMailboxEmails syncs all emails from a specific mailbox. It returns an error when a batch
+// could not be fetched, so the mailbox was not synced to the end.
+func (u *emailUsecase) syncMailboxEmails(userID, mailboxID string) error {
 	const batchSize = 100 // Fetch 100 emails at a time
 	offset := 0
 
@@ -1692,23 +1546,22 @@ func (u *emailUsecase) syncMailboxEmails(userID, mailboxID string) {
 				strings.Contains(errStr, "ACCESS_TOKEN_SCOPE_INSUFFICIENT") ||
 				strings.Contains(errStr, "Insufficient Permission") {
 				log.Printf("Stopping email sync for mailbox %s (user %s): access token doesn't have Gmail scopes", mailboxID, userID)
-				break
+				return err
 			}
 			log.Printf("Failed to fetch emails from mailbox %s for user %s (offset %d): %v", mailboxID, userID, offset, err)
-			break
+			return err
 		}
 
 		if len(emails) == 0 {
 			// No more emails
-			break
+			return nil
 		}
 
 		// Sync each email to vector DB
 		// Note: GetEmailsByMailbox already calls syncEmailToVectorDB, but we want to ensure
 		// all emails are synced even if they were fetched before
-		for _, email := range emails {
-			u.SyncEmailToVectorDB(userID, email)
-		}
+		u.syncEmails(userID, emails)
+		u.DetectFollowUpReplies(userID, emails)
 
 		log.Printf("Synced %d emails from mailbox %s for user %s (offset %d/%d)", len(emails), mailboxID, userID, offset, total)
 
@@ -1716,7 +1569,7 @@ func (u *emailUsecase) syncMailboxEmails(userID, mailboxID string) {
 
 		// Stop if we've fetched all emails
 		if offset >= total || len(emails) < batchSize {
-			break
+			return nil
 		}
 	}
 }
@@ -1837,6 +1690,9 @@ func (u *emailUsecase) GetKanbanColumns(userID string) ([]*emaildomain.KanbanCol
 // CreateKanbanColumn creates a new Kanban column
 func (u *emailUsecase) CreateKanbanColumn(userID string, column *emaildomain.KanbanColumn) error {
 	column.UserID = userID
+	if err := u.checkColumnSavedSearch(userID, column.SavedSearchID); err != nil {
+		return err
+	}
 	return u.kanbanColumnRepo.CreateColumn(column)
 }
 
@@ -1851,12 +1707,19 @@ func (u *emailUsecase) UpdateKanbanColumn(userID string, column *emaildomain.Kan
 		return fmt.Errorf("column not found: %s", column.ColumnID)
 	}
 
+	if err := u.checkColumnSavedSearch(userID, column.SavedSearchID); err != nil {
+		return err
+	}
+
 	// Preserve the primary key ID and check for changes
 	labelChanged := existing.GmailLabelID != column.GmailLabelID
 	
 	existing.Name = column.Name
 	existing.GmailLabelID = column.GmailLabelID
 	existing.RemoveLabelIDs = column.RemoveLabelIDs
+	existing.Description = column.Description
+	existing.Examples = column.Examples
+	existing.SavedSearchID = column.SavedSearchID
 	if column.Order > 0 {
 		existing.Order = column.Order
 	}
@@ -1889,27 +1752,37 @@ func (u *emailUsecase) UpdateKanbanColumn(userID string, column *emaildomain.Kan
 			}
 
 			// Map these emails to the column
-			count := 0
+			mapped := make([]*emaildomain.Email, 0, len(emails))
 			for _, email := range emails {
 				// Update mapping
 				if err := u.emailKanbanColumnRepo.SetEmailColumn(userID, email.ID, column.ColumnID); err == nil {
-					count++
-					// Also update local cache/status if needed
-					u.kanbanStatusMu.Lock()
-					u.kanbanStatus[email.ID] = column.ColumnID
-					u.kanbanStatusMu.Unlock()
-					
-					// Sync to vector DB as well since we fetched fresh data
-					u.SyncEmailToVectorDB(userID, email)
+					mapped = append(mapped, email)
 				}
 			}
-			log.Printf("[UpdateKanbanColumn] Automatically synced %d emails to column %s (label: %s)", count, column.ColumnID, column.GmailLabelID)
+			// Sync to vector DB as well since we fetched fresh data
+			u.syncEmails(userID, mapped)
+			log.Printf("[UpdateKanbanColumn] Automatically synced %d emails to column %s (label: %s)", len(mapped), column.ColumnID, column.GmailLabelID)
 		}()
 	}
 
 	return nil
 }
 
+// checkColumnSavedSearch checks that the saved search a Kanban column lists its emails from exists
+func (u *emailUsecase) checkColumnSavedSearch(userID, savedSearchID string) error {
+	if savedSearchID == "" {
+		return nil
+	}
+	saved, err := u.savedSearchRepo.GetByID(userID, savedSearchID)
+	if err != nil {
+		return err
+	}
+	if saved == nil {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
 // DeleteKanbanColumn deletes a Kanban column
 func (u *emailUsecase) DeleteKanbanColumn(userID, columnID string) error {
 	return u.kanbanColumnRepo.DeleteColumn(userID, columnID)
@@ -0,0 +1,377 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	authdomain "ga03-backend/internal/auth/domain"
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/utils/crypto"
+	"log"
+	"strings"
+	"time"
+)
+
+const maxFollowUpDays = 365
+
+// ScheduleFollowUp schedules a "remind me if no reply in N days" reminder for an existing sent email
+func (u *emailUsecase) ScheduleFollowUp(userID, emailID string, days int, columnID string) (*emaildomain.FollowUpReminder, error) {
+	columnID, err := u.resolveFollowUpColumn(userID, days, columnID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := u.followUpRepo.GetPendingByEmailID(userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("a follow-up reminder is already scheduled for this email")
+	}
+
+	email, err := u.GetEmailByID(userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+	if email == nil {
+		return nil, fmt.Errorf("email not found")
+	}
+
+	threadID := email.ThreadID
+	if threadID == "" {
+		// IMAP: the Message-ID is the conversation key
+		threadID = email.MessageID
+	}
+	if threadID == "" {
+		return nil, fmt.Errorf("email has no thread or Message-ID, cannot track replies")
+	}
+
+	sentAt := email.ReceivedAt
+	if sentAt.IsZero() {
+		sentAt = time.Now()
+	}
+
+	reminder := &emaildomain.FollowUpReminder{
+		UserID:         userID,
+		EmailID:        email.ID,
+		ThreadID:       threadID,
+		MessageID:      email.MessageID,
+		Subject:        email.Subject,
+		To:             strings.Join(email.To, ", "),
+		TargetColumnID: columnID,
+		SentAt:         sentAt,
+		RemindAt:       time.Now().AddDate(0, 0, days),
+	}
+	if err := u.followUpRepo.Create(reminder); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[FollowUp] Scheduled reminder %s for email %s (user %s) at %s", reminder.ID, emailID, userID, reminder.RemindAt.Format(time.RFC3339))
+	return reminder, nil
+}
+
+// ScheduleFollowUpForSentEmail schedules a follow-up reminder right after sending an email
+func (u *emailUsecase) ScheduleFollowUpForSentEmail(userID string, sent *emaildomain.SentEmail, to, subject string, days int, columnID string) (*emaildomain.FollowUpReminder, error) {
+	if sent == nil || (sent.ThreadID == "" && sent.MessageID == "") {
+		return nil, fmt.Errorf("follow-up reminders are not supported for this account")
+	}
+
+	columnID, err := u.resolveFollowUpColumn(userID, days, columnID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	reminder := &emaildomain.FollowUpReminder{
+		UserID:         userID,
+		EmailID:        sent.ID,
+		ThreadID:       sent.ThreadID,
+		MessageID:      sent.MessageID,
+		Subject:        subject,
+		To:             to,
+		TargetColumnID: columnID,
+		SentAt:         now,
+		RemindAt:       now.AddDate(0, 0, days),
+	}
+	if err := u.followUpRepo.Create(reminder); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[FollowUp] Scheduled reminder %s for sent message %s (user %s) at %s", reminder.ID, sent.MessageID, userID, reminder.RemindAt.Format(time.RFC3339))
+	return reminder, nil
+}
+
+// GetFollowUpReminders lists follow-up reminders of a user (status empty = all)
+func (u *emailUsecase) GetFollowUpReminders(userID string, status emaildomain.FollowUpStatus) ([]*emaildomain.FollowUpReminder, error) {
+	return u.followUpRepo.ListByUserID(userID, status)
+}
+
+// CancelFollowUp cancels a pending follow-up reminder
+func (u *emailUsecase) CancelFollowUp(userID, reminderID string) error {
+	reminder, err := u.followUpRepo.GetByID(userID, reminderID)
+	if err != nil {
+		return err
+	}
+	if reminder == nil {
+		return fmt.Errorf("follow-up reminder not found")
+	}
+	if reminder.Status != emaildomain.FollowUpStatusPending {
+		return fmt.Errorf("follow-up reminder is already %s", reminder.Status)
+	}
+	cancelled, err := u.followUpRepo.UpdateStatus(reminder.ID, emaildomain.FollowUpStatusCancelled, time.Now())
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return fmt.Errorf("follow-up reminder is no longer pending")
+	}
+	return nil
+}
+
+// DetectFollowUpReply marks pending follow-ups as replied when the given email answers them.
+// Called from the Pub/Sub notification pipeline for every incoming email.
+func (u *emailUsecase) DetectFollowUpReply(userID string, email *emaildomain.Email) {
+	if email == nil {
+		return
+	}
+	u.DetectFollowUpReplies(userID, []*emaildomain.Email{email})
+}
+
+// DetectFollowUpReplies marks pending follow-ups as replied when one of the emails answers them.
+// The reminders of a whole page of emails are looked up with a single query.
+func (u *emailUsecase) DetectFollowUpReplies(userID string, emails []*emaildomain.Email) {
+	if u.followUpRepo == nil || len(emails) == 0 {
+		return
+	}
+
+	var threadIDs, inReplyTos []string
+	for _, email := range emails {
+		if email == nil {
+			continue
+		}
+		if email.ThreadID != "" {
+			threadIDs = append(threadIDs, email.ThreadID)
+		}
+		if email.InReplyTo != "" {
+			inReplyTos = append(inReplyTos, email.InReplyTo)
+		}
+	}
+	if len(threadIDs) == 0 && len(inReplyTos) == 0 {
+		return
+	}
+
+	reminders, err := u.followUpRepo.FindPendingForReplies(userID, threadIDs, inReplyTos)
+	if err != nil {
+		log.Printf("[FollowUp] Error finding reminders for %d emails: %v", len(emails), err)
+		return
+	}
+	if len(reminders) == 0 {
+		return
+	}
+
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return
+	}
+
+	for _, reminder := range reminders {
+		var reply *emaildomain.Email
+		for _, email := range emails {
+			if email == nil {
+				continue
+			}
+			sameThread := (reminder.ThreadID != "" && email.ThreadID == reminder.ThreadID) ||
+				(reminder.MessageID != "" && email.InReplyTo == reminder.MessageID)
+			if sameThread && isFollowUpReply(user, reminder, email) {
+				reply = email
+				break
+			}
+		}
+		if reply == nil {
+			continue
+		}
+
+		updated, err := u.followUpRepo.UpdateStatus(reminder.ID, emaildomain.FollowUpStatusReplied, reply.ReceivedAt)
+		if err != nil {
+			log.Printf("[FollowUp] Error marking reminder %s as replied: %v", reminder.ID, err)
+			continue
+		}
+		if !updated {
+			continue // Cancelled or triggered meanwhile
+		}
+		log.Printf("[FollowUp] Reply detected for reminder %s (email %s)", reminder.ID, reply.ID)
+
+		if u.eventService != nil {
+			u.eventService.SendToUser(userID, "follow_up_update", map[string]string{
+				"reminder_id": reminder.ID,
+				"email_id":    reminder.EmailID,
+				"status":      string(emaildomain.FollowUpStatusReplied),
+			})
+		}
+	}
+}
+
+// ResurfaceFollowUp handles a due reminder: if a reply arrived it is closed, otherwise the email
+// is moved into the reminder's Kanban column. Returns true if the email was resurfaced.
+func (u *emailUsecase) ResurfaceFollowUp(reminder *emaildomain.FollowUpReminder) (bool, error) {
+	user, err := u.userRepo.FindByID(reminder.UserID)
+	if err != nil {
+		return false, err
+	}
+	if user == nil {
+		return false, fmt.Errorf("user not found")
+	}
+
+	// Final check against the provider in case the pipeline missed the reply
+	replied, err := u.hasFollowUpReply(user, reminder)
+	if err != nil {
+		log.Printf("[FollowUp] Could not check replies for reminder %s: %v", reminder.ID, err)
+	}
+	if replied {
+		_, err := u.followUpRepo.UpdateStatus(reminder.ID, emaildomain.FollowUpStatusReplied, time.Now())
+		return false, err
+	}
+
+	emailID := reminder.EmailID
+	if emailID == "" && user.Provider == "imap" {
+		// SMTP sends don't return an ID, look the message up in the Sent folder
+		emailID = u.findSentEmailID(user, reminder.MessageID)
+		if emailID != "" {
+			u.followUpRepo.UpdateEmailID(reminder.ID, emailID)
+			reminder.EmailID = emailID
+		}
+	}
+
+	targetColumn := reminder.TargetColumnID
+	if targetColumn == "" {
+		targetColumn = u.config.FollowUpDefaultColumn
+	}
+
+	// Trigger before moving: a reminder cancelled while claimed must not resurface
+	triggered, err := u.followUpRepo.UpdateStatus(reminder.ID, emaildomain.FollowUpStatusTriggered, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if !triggered {
+		log.Printf("[FollowUp] Reminder %s is no longer pending, not resurfacing", reminder.ID)
+		return false, nil
+	}
+
+	if emailID != "" {
+		var moveErr error
+		if user.Provider == "google" {
+			// Sync Gmail labels the same way a Kanban drag & drop does
+			moveErr = u.moveEmail(reminder.UserID, emailID, targetColumn, "")
+		} else {
+			moveErr = u.emailKanbanColumnRepo.SetEmailColumn(reminder.UserID, emailID, targetColumn)
+		}
+		if moveErr != nil {
+			// The reminder is triggered: still notify the user rather than lose it
+			log.Printf("[FollowUp] Failed to move email %s of reminder %s: %v", emailID, reminder.ID, moveErr)
+		}
+	} else {
+		log.Printf("[FollowUp] Email for reminder %s not found in Sent folder, notifying only", reminder.ID)
+	}
+
+	// Notify user via SSE to refresh UI
+	if u.eventService != nil {
+		u.eventService.SendToUser(reminder.UserID, "email_update", map[string]string{
+			"email_id":    emailID,
+			"action":      "follow_up",
+			"column":      targetColumn,
+			"reminder_id": reminder.ID,
+		})
+	}
+
+	return true, nil
+}
+
+// resolveFollowUpColumn validates the number of days and the target column (defaulting from config)
+func (u *emailUsecase) resolveFollowUpColumn(userID string, days int, columnID string) (string, error) {
+	if days < 1 || days > maxFollowUpDays {
+		return "", fmt.Errorf("follow-up days must be between 1 and %d", maxFollowUpDays)
+	}
+
+	if columnID == "" {
+		columnID = u.config.FollowUpDefaultColumn
+	}
+
+	// GetKanbanColumns also makes sure default columns exist
+	columns, err := u.GetKanbanColumns(userID)
+	if err != nil {
+		return "", err
+	}
+	for _, col := range columns {
+		if col.ColumnID == columnID {
+			return columnID, nil
+		}
+	}
+	return "", fmt.Errorf("kanban column %s not found", columnID)
+}
+
+// hasFollowUpReply asks the provider whether the conversation received a reply
+func (u *emailUsecase) hasFollowUpReply(user *authdomain.User, reminder *emaildomain.FollowUpReminder) (bool, error) {
+	ctx := context.Background()
+
+	if user.Provider == "imap" {
+		if reminder.MessageID == "" {
+			return false, nil
+		}
+		decryptedPass, err := crypto.Decrypt(user.ImapPassword, u.config.EncryptionKey)
+		if err != nil {
+			return false, fmt.Errorf("failed to decrypt password: %w", err)
+		}
+		ids, err := u.imapProvider.SearchByHeader(ctx, user.ImapServer, user.ImapPort, user.Email, decryptedPass, "INBOX", "In-Reply-To", reminder.MessageID)
+		if err != nil {
+			return false, err
+		}
+		return len(ids) > 0, nil
+	}
+
+	if user.AccessToken == "" || reminder.ThreadID == "" {
+		return false, nil
+	}
+
+	emails, err := u.mailProvider.GetThreadEmails(ctx, user.AccessToken, user.RefreshToken, reminder.ThreadID, u.makeTokenUpdateCallback(user.ID))
+	if err != nil {
+		return false, err
+	}
+	for _, email := range emails {
+		if isFollowUpReply(user, reminder, email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// findSentEmailID resolves the IMAP ID of a sent message by its Message-ID
+func (u *emailUsecase) findSentEmailID(user *authdomain.User, messageID string) string {
+	if messageID == "" {
+		return ""
+	}
+	decryptedPass, err := crypto.Decrypt(user.ImapPassword, u.config.EncryptionKey)
+	if err != nil {
+		return ""
+	}
+	ids, err := u.imapProvider.SearchByHeader(context.Background(), user.ImapServer, user.ImapPort, user.Email, decryptedPass, "SENT", "Message-ID", messageID)
+	if err != nil || len(ids) == 0 {
+		return ""
+	}
+	return ids[0]
+}
+
+// isFollowUpReply reports whether email is a reply from someone else, sent after the tracked message
+func isFollowUpReply(user *authdomain.User, reminder *emaildomain.FollowUpReminder, email *emaildomain.Email) bool {
+	if email.ID != "" && email.ID == reminder.EmailID {
+		return false // The tracked message itself
+	}
+	if email.MessageID != "" && email.MessageID == reminder.MessageID {
+		return false
+	}
+	if user.Email != "" && strings.Contains(strings.ToLower(email.From), strings.ToLower(user.Email)) {
+		return false // Our own follow-up in the same thread
+	}
+	if !email.ReceivedAt.IsZero() && email.ReceivedAt.Before(reminder.SentAt) {
+		return false // Older message in the thread
+	}
+	return true
+}
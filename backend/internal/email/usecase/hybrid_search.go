@@ -0,0 +1,470 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	authdomain "ga03-backend/internal/auth/domain"
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/fuzzy"
+	"ga03-backend/pkg/search"
+	"ga03-backend/pkg/utils/crypto"
+
+	goimap "github.com/emersion/go-imap"
+)
+
+const (
+	// hybridIndexCandidates is the number of full-text index matches (any query word, best ts_rank
+	// first) ranked by BM25 once the user's mailbox is indexed
+	hybridIndexCandidates = 1000
+	// hybridRecentCandidates is the number of recent inbox emails ranked by BM25 before the mailbox is
+	// indexed (accent-folded, so "canh" finds "cảnh báo" even where the provider search is accent-sensitive)
+	hybridRecentCandidates = 200
+	// hybridTermCandidates is the number of emails returned by the provider's own search for the query terms
+	hybridTermCandidates = 100
+	// hybridSemanticCandidates is the number of nearest vectors fused with the keyword results
+	hybridSemanticCandidates = 100
+	// hybridRankingTTL keeps a fused ranking so that the pages of a query come from the same results
+	hybridRankingTTL = 2 * time.Minute
+)
+
+// BM25 field weights: a term in the subject or sender says more than a term in the body
+const (
+	subjectWeight = 3
+	senderWeight  = 2
+	bodyWeight    = 1
+)
+
+// hybridRanking is the fused ranking of a query, kept for pagination
+type hybridRanking struct {
+	fused     []search.Fused
+	keyword   map[string]emaildomain.KeywordScore
+	semantic  map[string]emaildomain.SemanticScore
+	emails    map[string]*emaildomain.Email // Keyword candidates (already fetched)
+	sources   []string
+	total     int  // Matching emails, including keyword matches beyond the candidates
+	estimated bool // total is an estimate
+	createdAt time.Time
+}
+
+// keywordCandidates are the emails ranked by BM25 and the number of keyword matches they were taken from
+type keywordCandidates struct {
+	emails    []*emaildomain.Email
+	docs      []search.Document
+	total     int
+	estimated bool
+}
+
+// hybridRankingCache holds recent rankings by user and normalized query
+type hybridRankingCache struct {
+	mu       sync.Mutex
+	rankings map[string]*hybridRanking
+}
+
+var hybridRankings = &hybridRankingCache{rankings: make(map[string]*hybridRanking)}
+
+func (c *hybridRankingCache) get(key string) *hybridRanking {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ranking, ok := c.rankings[key]
+	if !ok || time.Since(ranking.createdAt) > hybridRankingTTL {
+		return nil
+	}
+	return ranking
+}
+
+func (c *hybridRankingCache) put(key string, ranking *hybridRanking) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, r := range c.rankings {
+		if time.Since(r.createdAt) > hybridRankingTTL {
+			delete(c.rankings, k)
+		}
+	}
+	c.rankings[key] = ranking
+}
+
+// invalidate drops the rankings of a user, after a change that may move emails in or out of results
+func (c *hybridRankingCache) invalidate(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prefix := userID + "\x00"
+	for k := range c.rankings {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.rankings, k)
+		}
+	}
+}
+
+// HybridSearch ranks emails with a keyword (BM25) query and a vector query, fused with reciprocal rank fusion.
+// The free text of the query is ranked; its operators (from:, is:unread, in:todo...) filter both rankings.
+// The first page of a query computes the ranking; following pages (within hybridRankingTTL) reuse it,
+// so pages neither overlap nor skip emails and the total does not change between pages.
+func (u *emailUsecase) HybridSearch(userID, query string, limit, offset int) (*emaildomain.HybridSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if len(fuzzy.Tokenize(query)) == 0 {
+		return &emaildomain.HybridSearchResult{Hits: []*emaildomain.HybridSearchHit{}, Limit: limit, Offset: offset, Sources: []string{}}, nil
+	}
+
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	parsed, err := parseSearchQuery(user, query)
+	if err != nil {
+		return nil, err
+	}
+	text, filter := parsed.Split()
+
+	cacheKey := userID + "\x00" + strings.Join(fuzzy.Tokenize(text), " ") + "\x00" + filter.String()
+	ranking := hybridRankings.get(cacheKey)
+	if ranking == nil || offset == 0 {
+		// A new search (first page) always sees the latest emails
+		ranking, err = u.rankHybrid(user, text, filter)
+		if err != nil {
+			return nil, err
+		}
+		hybridRankings.put(cacheKey, ranking)
+	}
+
+	result := &emaildomain.HybridSearchResult{
+		Hits:           []*emaildomain.HybridSearchHit{},
+		Total:          ranking.total,
+		TotalEstimated: ranking.estimated,
+		Ranked:         len(ranking.fused),
+		Limit:          limit,
+		Offset:         offset,
+		Sources:        ranking.sources,
+	}
+	if offset >= len(ranking.fused) {
+		return result, nil
+	}
+	end := offset + limit
+	if end > len(ranking.fused) {
+		end = len(ranking.fused)
+	}
+	page := ranking.fused[offset:end]
+
+	// Semantic-only hits are fetched from the provider
+	emails := make(map[string]*emaildomain.Email, len(page))
+	var missing []string
+	for _, fused := range page {
+		if email, ok := ranking.emails[fused.ID]; ok {
+			emails[fused.ID] = email
+		} else {
+			missing = append(missing, fused.ID)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := u.fetchEmailsByIDs(user, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, email := range fetched {
+			emails[email.ID] = email
+		}
+	}
+
+	for _, fused := range page {
+		email, ok := emails[fused.ID]
+		if !ok {
+			continue // Deleted since it was indexed
+		}
+		hit := &emaildomain.HybridSearchHit{Email: email, Score: fused.Score}
+		if score, ok := ranking.keyword[fused.ID]; ok {
+			hit.Keyword = &score
+		}
+		if score, ok := ranking.semantic[fused.ID]; ok {
+			hit.Semantic = &score
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	return result, nil
+}
+
+// rankHybrid runs the keyword and vector queries of text concurrently and fuses their rankings.
+// Only emails matching filter are ranked: vector hits are kept when a keyword candidate (which was
+// checked against the filter) has the same ID. Without text, the filtered candidates come newest first.
+func (u *emailUsecase) rankHybrid(user *authdomain.User, text string, filter *search.Query) (*hybridRanking, error) {
+	semantic := u.vectorSearchService != nil && text != ""
+	var wg sync.WaitGroup
+	var keywordRanked []search.Scored
+	var candidates *keywordCandidates
+	var keywordErr error
+	var semanticIDs []string
+	var distances []float64
+	var semanticErr error
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if u.isSearchIndexed(user.ID) {
+			candidates, keywordErr = u.indexKeywordCandidates(user.ID, text, filter)
+		} else {
+			candidates, keywordErr = u.fetchKeywordCandidates(user, text, filter)
+		}
+		if keywordErr != nil {
+			return
+		}
+		if text == "" {
+			emails := candidates.emails
+			sort.SliceStable(emails, func(i, j int) bool { return emails[i].ReceivedAt.After(emails[j].ReceivedAt) })
+			for _, email := range emails {
+				keywordRanked = append(keywordRanked, search.Scored{ID: email.ID})
+			}
+			return
+		}
+		keywordRanked = search.BM25{}.Rank(text, candidates.docs)
+	}()
+
+	if semantic {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			semanticIDs, distances, semanticErr = u.vectorSearchService.SemanticSearch(ctx, "emails", user.ID, text, hybridSemanticCandidates)
+		}()
+	}
+	wg.Wait()
+
+	// With a filter, vector hits cannot be checked without the keyword candidates
+	if keywordErr != nil && (!semantic || semanticErr != nil || !filter.IsEmpty()) {
+		return nil, fmt.Errorf("hybrid search failed: %w", keywordErr)
+	}
+
+	ranking := &hybridRanking{
+		keyword:   make(map[string]emaildomain.KeywordScore),
+		semantic:  make(map[string]emaildomain.SemanticScore),
+		emails:    make(map[string]*emaildomain.Email),
+		sources:   []string{},
+		createdAt: time.Now(),
+	}
+	var rankings []search.Ranking
+
+	if keywordErr != nil {
+		log.Printf("[HybridSearch] Keyword query failed, using semantic results only: %v", keywordErr)
+	} else {
+		ranking.sources = append(ranking.sources, emaildomain.SearchSourceKeyword)
+		for _, email := range candidates.emails {
+			ranking.emails[email.ID] = email
+		}
+		ranking.total, ranking.estimated = candidates.total, candidates.estimated
+		ids := make([]string, len(keywordRanked))
+		for i, scored := range keywordRanked {
+			ids[i] = scored.ID
+			ranking.keyword[scored.ID] = emaildomain.KeywordScore{Rank: i + 1, Score: scored.Score}
+		}
+		rankings = append(rankings, search.Ranking{Source: emaildomain.SearchSourceKeyword, IDs: ids})
+	}
+
+	if semantic {
+		if semanticErr != nil {
+			log.Printf("[HybridSearch] Semantic query failed, using keyword results only: %v", semanticErr)
+		} else {
+			ranking.sources = append(ranking.sources, emaildomain.SearchSourceSemantic)
+			threshold := defaultDistanceThreshold
+			if t, ok := u.vectorSearchService.(distanceThresholder); ok {
+				threshold = t.DistanceThreshold()
+			}
+			// Far vectors only count when the keyword query also found the email
+			ids := make([]string, 0, len(semanticIDs))
+			for i, id := range semanticIDs {
+				if i >= len(distances) {
+					break
+				}
+				if _, keywordHit := ranking.keyword[id]; distances[i] > threshold && !keywordHit {
+					continue
+				}
+				if _, candidate := ranking.emails[id]; !filter.IsEmpty() && !candidate {
+					continue // Not known to match the filter
+				}
+				ids = append(ids, id)
+				ranking.semantic[id] = emaildomain.SemanticScore{Rank: len(ids), Distance: distances[i]}
+			}
+			rankings = append(rankings, search.Ranking{Source: emaildomain.SearchSourceSemantic, IDs: ids})
+		}
+	}
+
+	ranking.fused = search.FuseRRF(search.DefaultRRFK, rankings...)
+	// Vector hits outside the keyword candidates add to the keyword matches (when the candidates were
+	// capped, some of them may be keyword matches already counted: the total stays an estimate)
+	for id := range ranking.semantic {
+		if _, candidate := ranking.emails[id]; !candidate {
+			ranking.total++
+		}
+	}
+	log.Printf("[HybridSearch] user %s: %d keyword, %d semantic, %d fused results", user.ID, len(ranking.keyword), len(ranking.semantic), len(ranking.fused))
+	return ranking, nil
+}
+
+// keywordDocument returns the BM25 document of an email
+func keywordDocument(email *emaildomain.Email) search.Document {
+	body := cleanHTMLForEmbedding(email.Body)
+	if body == "" {
+		body = email.Preview
+	}
+	return search.NewDocument(email.ID,
+		search.Field{Text: email.Subject, Weight: subjectWeight},
+		search.Field{Text: email.FromName + " " + email.From, Weight: senderWeight},
+		search.Field{Text: body, Weight: bodyWeight},
+	)
+}
+
+// indexKeywordCandidates returns the emails ranked by BM25 from the full-text index: the indexed emails
+// matching the filter and any word of the text (by prefix, accent-insensitive), best ts_rank first.
+// Trash and spam are left out unless the filter uses in:, as in FuzzySearch.
+func (u *emailUsecase) indexKeywordCandidates(userID, text string, filter *search.Query) (*keywordCandidates, error) {
+	where, args, residual := filter.SQL(searchIndexColumns)
+	where = scopeSearchMailboxes(filter, where, args)
+	rank := search.AnyPrefixTSQuery(text)
+	if rank != "" {
+		args["hybrid_terms"] = rank
+		where = andWhere(where, "document @@ to_tsquery('simple', @hybrid_terms)")
+	}
+
+	docs, total, err := u.searchIndexRepo.Search(userID, where, args, rank, hybridIndexCandidates, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// The rest of the filter (in:<column>) is matched here: the count of the index is then an estimate
+	columns := u.searchColumns(userID, residual)
+	matched := make([]*emaildomain.EmailSearchDocument, 0, len(docs))
+	for _, doc := range docs {
+		if residual.Match(searchDocumentMessage(doc, columns)) {
+			matched = append(matched, doc)
+		}
+	}
+
+	candidates := &keywordCandidates{
+		emails:    searchDocumentsToEmails(matched),
+		docs:      make([]search.Document, 0, len(matched)),
+		total:     int(total),
+		estimated: int(total) > len(docs) || !residual.IsEmpty(),
+	}
+	if !candidates.estimated {
+		candidates.total = len(matched)
+	}
+	for _, doc := range matched {
+		candidates.docs = append(candidates.docs, search.NewDocument(doc.EmailID,
+			search.Field{Text: doc.Subject, Weight: subjectWeight},
+			search.Field{Text: doc.FromName + " " + doc.FromAddress, Weight: senderWeight},
+			search.Field{Text: doc.BodyFolded, Weight: bodyWeight},
+		))
+	}
+	return candidates, nil
+}
+
+// fetchKeywordCandidates returns the emails ranked by BM25 before the mailbox is indexed: the provider's
+// search for the text terms (whole inbox, accent-sensitive) plus the most recent inbox emails (matched
+// accent-insensitively). The filter is sent to the provider's search where possible; the rest of it is
+// matched here. The total is an estimate when a provider query returned as many emails as asked for.
+func (u *emailUsecase) fetchKeywordCandidates(user *authdomain.User, text string, filter *search.Query) (*keywordCandidates, error) {
+	ctx := context.Background()
+	var batches [][]*emaildomain.Email
+	capped := false
+	residual := filter
+
+	if user.Provider == "imap" {
+		decryptedPass, err := crypto.Decrypt(user.ImapPassword, u.config.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt password: %w", err)
+		}
+		var criteria *goimap.SearchCriteria
+		criteria, residual = filter.IMAP()
+		emails, total, err := u.imapProvider.SearchEmails(ctx, user.ImapServer, user.ImapPort, user.Email, decryptedPass, "INBOX", criteria, hybridRecentCandidates, 0)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, emails)
+		capped = total > len(emails)
+	} else {
+		accessToken, refreshToken, err := u.getUserTokens(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if accessToken == "" || u.mailProvider == nil {
+			// Fallback to local storage
+			emails, total, err := u.emailRepo.GetEmailsByMailbox("INBOX", hybridRecentCandidates, 0)
+			if err != nil {
+				return nil, err
+			}
+			batches = append(batches, emails)
+			capped = total > len(emails)
+		} else {
+			var gmailQuery string
+			gmailQuery, residual = filter.Gmail()
+			onRefresh := u.makeTokenUpdateCallback(user.ID)
+			if terms := gmailTermsQuery(text); terms != "" {
+				matching, total, err := u.mailProvider.GetEmails(ctx, accessToken, refreshToken, "INBOX", hybridTermCandidates, 0, strings.TrimSpace(gmailQuery+" "+terms), onRefresh)
+				if err != nil {
+					return nil, err
+				}
+				batches = append(batches, matching)
+				capped = total > len(matching)
+			}
+			recent, total, err := u.mailProvider.GetEmails(ctx, accessToken, refreshToken, "INBOX", hybridRecentCandidates, 0, gmailQuery, onRefresh)
+			if err != nil {
+				return nil, err
+			}
+			batches = append(batches, recent)
+			// Recent emails are only candidates: they cap the total when there is no text to match
+			capped = capped || (text == "" && total > len(recent))
+		}
+	}
+
+	columns := u.searchColumns(user.ID, residual)
+	seen := make(map[string]bool)
+	candidates := make([]*emaildomain.Email, 0, hybridRecentCandidates+hybridTermCandidates)
+	for _, batch := range batches {
+		for _, email := range batch {
+			if email == nil || seen[email.ID] {
+				continue
+			}
+			seen[email.ID] = true
+			if !residual.Match(searchMessage(email, columns)) {
+				continue
+			}
+			candidates = append(candidates, email)
+		}
+	}
+
+	result := &keywordCandidates{
+		emails:    candidates,
+		docs:      make([]search.Document, 0, len(candidates)),
+		total:     len(candidates),
+		estimated: capped,
+	}
+	for _, email := range candidates {
+		result.docs = append(result.docs, keywordDocument(email))
+	}
+	return result, nil
+}
+
+// gmailTermsQuery returns a Gmail query matching any of the query words, e.g. "{invoice march}"
+func gmailTermsQuery(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		word = strings.Trim(word, `"{}()`)
+		if word != "" && !strings.ContainsAny(word, ":") {
+			terms = append(terms, word)
+		}
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	return "{" + strings.Join(terms, " ") + "}"
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	emaildomain "ga03-backend/internal/email/domain"
 	"ga03-backend/pkg/ai"
+	"ga03-backend/pkg/jobqueue"
 	"mime/multipart"
 	"time"
 )
@@ -19,7 +20,7 @@ type EmailUsecase interface {
 	MarkEmailAsRead(userID, id string) error
 	MarkEmailAsUnread(userID, id string) error
 	ToggleStar(userID, id string) error
-	SendEmail(userID, to, cc, bcc, subject, body string, files []*multipart.FileHeader) error
+	SendEmail(userID, to, cc, bcc, subject, body string, files []*multipart.FileHeader) (*emaildomain.SentEmail, error)
 	TrashEmail(userID, id string) error
 	ArchiveEmail(userID, id string) error
 	PermanentDeleteEmail(userID, id string) error
@@ -28,20 +29,60 @@ type EmailUsecase interface {
 	MoveEmailToMailbox(userID, emailID, mailboxID, sourceColumnID string) error
 	SnoozeEmail(userID, emailID string, snoozeUntil time.Time) error
 	UnsnoozeEmail(userID, emailID string) (targetColumn string, err error)
+	HandleSnoozeExpired(userID, emailID, targetColumn string) // Called by the snooze scheduler after a wake-up
 	FuzzySearch(userID, query string, limit, offset int) ([]*emaildomain.Email, int, error)
 	SemanticSearch(userID, query string, limit, offset int) ([]*emaildomain.Email, int, error)
-	GetSearchSuggestions(userID, query string, limit int) ([]string, error)
+	HybridSearch(userID, query string, limit, offset int) (*emaildomain.HybridSearchResult, error) // Keyword (BM25) + vector, fused with RRF
+	GetSearchSuggestions(userID, query string, limit int) (*emaildomain.SearchSuggestions, error)  // Limit per kind
+	RecordSearchQuery(userID, query string)                                                        // Remember a searched query for suggestions
+	GetRelatedEmails(userID, emailID string, limit int) ([]*emaildomain.RelatedEmail, error)       // "More like this" from the stored vector
+	ListSavedSearches(userID string) ([]*emaildomain.SavedSearch, error)
+	CreateSavedSearch(userID string, search *emaildomain.SavedSearch) error
+	UpdateSavedSearch(userID string, search *emaildomain.SavedSearch) (*emaildomain.SavedSearch, error)
+	DeleteSavedSearch(userID, id string) error
+	NotifySavedSearches(userID string, email *emaildomain.Email) []*emaildomain.SavedSearch // Alerts of saved searches a new email matches
 	StoreEmailEmbedding(ctx context.Context, userID, emailID, subject, body string) error
 	UpsertEmailEmbedding(ctx context.Context, userID, emailID, subject, body string) error
 	SyncEmailToVectorDB(userID string, email *emaildomain.Email) // Sync a single email to vector DB
 	SyncAllEmailsForUser(userID string)                          // Sync all emails for a user to vector DB (async, non-blocking)
+	QueueStaleEmbeddings() (int, error)                          // Queue re-embedding of vectors from a previous embedding model
+	// Follow-up reminders for sent emails
+	ScheduleFollowUp(userID, emailID string, days int, columnID string) (*emaildomain.FollowUpReminder, error)
+	ScheduleFollowUpForSentEmail(userID string, sent *emaildomain.SentEmail, to, subject string, days int, columnID string) (*emaildomain.FollowUpReminder, error)
+	GetFollowUpReminders(userID string, status emaildomain.FollowUpStatus) ([]*emaildomain.FollowUpReminder, error)
+	CancelFollowUp(userID, reminderID string) error
+	DetectFollowUpReply(userID string, email *emaildomain.Email) // Mark pending follow-ups as replied if email answers them
+	DetectFollowUpReplies(userID string, emails []*emaildomain.Email)
+	ResurfaceFollowUp(reminder *emaildomain.FollowUpReminder) (bool, error)
 	// Kanban Column Management
 	GetKanbanColumns(userID string) ([]*emaildomain.KanbanColumn, error)
 	CreateKanbanColumn(userID string, column *emaildomain.KanbanColumn) error
 	UpdateKanbanColumn(userID string, column *emaildomain.KanbanColumn) error
 	DeleteKanbanColumn(userID, columnID string) error
 	UpdateKanbanColumnOrders(userID string, orders map[string]int) error
+
+	// Kanban board export / import
+	ExportKanbanBoard(userID string) (*emaildomain.KanbanBoardExport, error)
+	ExportKanbanCards(userID string) ([]*emaildomain.KanbanCard, error)
+	ImportKanbanBoard(userID string, board *emaildomain.KanbanBoardExport, mode emaildomain.KanbanImportMode) (*emaildomain.KanbanImportResult, error)
+	ImportKanbanCards(userID string, assignments []emaildomain.KanbanAssignmentExport, mode emaildomain.KanbanImportMode) (*emaildomain.KanbanImportResult, error)
+	GetKanbanStats(userID string, from, to time.Time, loc *time.Location, doneColumn string) (*emaildomain.KanbanStats, error)
+
+	// AI auto-triage into Kanban columns
+	TriageNewEmails(userID string, emails []*emaildomain.Email) // Queue new inbox emails for classification (async)
+	TriageEmail(userID, emailID string) (*emaildomain.TriageSuggestion, error)
+	GetTriageSettings(userID string) (*emaildomain.TriageSettings, error)
+	UpdateTriageSettings(userID string, enabled bool, autoMoveThreshold, suggestThreshold float64) (*emaildomain.TriageSettings, error)
+	ListTriageSuggestions(userID string, status emaildomain.TriageSuggestionStatus, limit int) ([]*emaildomain.TriageSuggestion, error)
+	AcceptTriageSuggestion(userID, suggestionID, columnID string) (*emaildomain.TriageSuggestion, error)
+	RejectTriageSuggestion(userID, suggestionID string) error
+
+	// AI reply drafting
+	DraftReply(ctx context.Context, userID, emailID string, opts emaildomain.ReplyDraftOptions) (*emaildomain.ReplyDraft, error)
+	StreamDraftReply(userID, emailID string, opts emaildomain.ReplyDraftOptions) (*emaildomain.ReplyDraft, error)
+
 	SetAIService(svc ai.SummarizerService)
 	SetVectorSearchService(svc VectorSearchService)
 	SetEventService(svc EventService)
+	SetJobQueue(queue *jobqueue.Queue)
 }
@@ -0,0 +1,435 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	emaildomain "ga03-backend/internal/email/domain"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInvalidKanbanImport is returned when an import fails validation. Nothing is applied in that case.
+var ErrInvalidKanbanImport = errors.New("invalid kanban import")
+
+// defaultKanbanColumnIDs are the columns GetKanbanColumns always recreates, so replace imports never delete them
+var defaultKanbanColumnIDs = []string{"inbox", "todo", "done", "snoozed"}
+
+// maxImportProblems caps how many validation problems are reported back
+const maxImportProblems = 20
+
+// cardLookupConcurrency bounds parallel provider calls when building the CSV export or syncing imported labels
+const cardLookupConcurrency = 5
+
+// ExportKanbanBoard returns columns (with order and label mapping) and every card assignment of the user
+func (u *emailUsecase) ExportKanbanBoard(userID string) (*emaildomain.KanbanBoardExport, error) {
+	columns, err := u.GetKanbanColumns(userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(columns, func(i, j int) bool { return columns[i].Order < columns[j].Order })
+
+	mappings, err := u.emailKanbanColumnRepo.GetMappingsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &emaildomain.KanbanBoardExport{
+		Version:     emaildomain.KanbanBoardExportVersion,
+		ExportedAt:  time.Now(),
+		Columns:     make([]emaildomain.KanbanColumnExport, 0, len(columns)),
+		Assignments: make([]emaildomain.KanbanAssignmentExport, 0, len(mappings)),
+	}
+	for _, col := range columns {
+		removeLabels := []string(col.RemoveLabelIDs)
+		if removeLabels == nil {
+			removeLabels = []string{}
+		}
+		export.Columns = append(export.Columns, emaildomain.KanbanColumnExport{
+			ColumnID:       col.ColumnID,
+			Name:           col.Name,
+			Order:          col.Order,
+			GmailLabelID:   col.GmailLabelID,
+			RemoveLabelIDs: removeLabels,
+			Description:    col.Description,
+			Examples:       []string(col.Examples),
+			SavedSearchID:  col.SavedSearchID,
+		})
+	}
+
+	seen := make(map[string]bool, len(mappings))
+	for _, m := range mappings {
+		if seen[m.EmailID] {
+			continue // Legacy duplicate rows
+		}
+		seen[m.EmailID] = true
+
+		assignment := emaildomain.KanbanAssignmentExport{
+			EmailID:  m.EmailID,
+			ColumnID: m.ColumnID,
+		}
+		if m.ColumnID == "snoozed" {
+			assignment.PreviousColumnID = m.PreviousColumnID
+			assignment.SnoozedUntil = m.SnoozedUntil
+		}
+		export.Assignments = append(export.Assignments, assignment)
+	}
+
+	return export, nil
+}
+
+// ExportKanbanCards returns one card per assigned email, with subject and sender looked up from the provider.
+// Emails that can no longer be fetched are still exported with empty subject/from.
+func (u *emailUsecase) ExportKanbanCards(userID string) ([]*emaildomain.KanbanCard, error) {
+	board, err := u.ExportKanbanBoard(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]*emaildomain.KanbanCard, len(board.Assignments))
+	sem := make(chan struct{}, cardLookupConcurrency)
+	var wg sync.WaitGroup
+
+	for i, a := range board.Assignments {
+		cards[i] = &emaildomain.KanbanCard{
+			EmailID:      a.EmailID,
+			ColumnID:     a.ColumnID,
+			SnoozedUntil: a.SnoozedUntil,
+		}
+
+		wg.Add(1)
+		go func(card *emaildomain.KanbanCard) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			email, err := u.GetEmailByID(userID, card.EmailID)
+			if err != nil || email == nil {
+				log.Printf("[KanbanExport] Could not fetch email %s: %v", card.EmailID, err)
+				return
+			}
+			card.Subject = email.Subject
+			card.From = email.From
+		}(cards[i])
+	}
+	wg.Wait()
+
+	return cards, nil
+}
+
+// ImportKanbanBoard applies a JSON board export (columns and assignments).
+// The whole file is validated first; on any problem ErrInvalidKanbanImport is returned and nothing changes.
+func (u *emailUsecase) ImportKanbanBoard(userID string, board *emaildomain.KanbanBoardExport, mode emaildomain.KanbanImportMode) (*emaildomain.KanbanImportResult, error) {
+	if err := validateKanbanImportMode(mode); err != nil {
+		return nil, err
+	}
+	if board == nil {
+		return nil, fmt.Errorf("%w: empty board", ErrInvalidKanbanImport)
+	}
+	if board.Version > emaildomain.KanbanBoardExportVersion {
+		return nil, fmt.Errorf("%w: unsupported export version %d", ErrInvalidKanbanImport, board.Version)
+	}
+
+	var problems []string
+	columns, columnProblems := validateImportColumns(board.Columns)
+	problems = append(problems, columnProblems...)
+	for _, col := range columns {
+		// Saved searches are the user's own: a board exported from another account cannot reference them
+		err := u.checkColumnSavedSearch(userID, col.SavedSearchID)
+		if errors.Is(err, ErrSavedSearchNotFound) {
+			problems = append(problems, fmt.Sprintf("column %q: saved search %s not found", col.ColumnID, col.SavedSearchID))
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// Columns that assignments may point to once the import is applied
+	knownColumns := make(map[string]bool)
+	for _, col := range columns {
+		knownColumns[col.ColumnID] = true
+	}
+	for _, id := range defaultKanbanColumnIDs {
+		knownColumns[id] = true
+	}
+	existing, err := u.GetKanbanColumns(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mode == emaildomain.KanbanImportMerge {
+		for _, col := range existing {
+			knownColumns[col.ColumnID] = true
+		}
+	}
+
+	mappings, assignmentProblems := validateImportAssignments(board.Assignments, knownColumns)
+	problems = append(problems, assignmentProblems...)
+	if err := importProblemsError(problems); err != nil {
+		return nil, err
+	}
+
+	result := &emaildomain.KanbanImportResult{Mode: mode}
+
+	var deleteExcept []string
+	if mode == emaildomain.KanbanImportReplace {
+		deleteExcept = append(deleteExcept, defaultKanbanColumnIDs...)
+		for _, col := range columns {
+			deleteExcept = append(deleteExcept, col.ColumnID)
+		}
+	}
+	// Columns and assignments are written together so a failed import leaves the board untouched
+	imported, err := u.kanbanColumnRepo.ImportBoard(userID, columns, deleteExcept, mappings, mode == emaildomain.KanbanImportReplace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import board: %w", err)
+	}
+	result.ColumnsCreated, result.ColumnsUpdated, result.ColumnsDeleted = imported.ColumnsCreated, imported.ColumnsUpdated, imported.ColumnsDeleted
+	result.AssignmentsImported = len(mappings)
+	result.AssignmentsRemoved = imported.MappingsRemoved
+
+	current, err := u.GetKanbanColumns(userID)
+	if err != nil {
+		log.Printf("[KanbanImport] Could not reload columns of user %s: %v", userID, err)
+		current = columns
+	}
+	u.finishKanbanImport(userID, mappings, imported.PreviousColumns, existing, current)
+
+	log.Printf("[KanbanImport] User %s imported board (%s): %d columns created, %d updated, %d deleted, %d cards",
+		userID, mode, result.ColumnsCreated, result.ColumnsUpdated, result.ColumnsDeleted, result.AssignmentsImported)
+	return result, nil
+}
+
+// ImportKanbanCards applies card assignments only (CSV import). Columns must already exist.
+func (u *emailUsecase) ImportKanbanCards(userID string, assignments []emaildomain.KanbanAssignmentExport, mode emaildomain.KanbanImportMode) (*emaildomain.KanbanImportResult, error) {
+	if err := validateKanbanImportMode(mode); err != nil {
+		return nil, err
+	}
+
+	existing, err := u.GetKanbanColumns(userID)
+	if err != nil {
+		return nil, err
+	}
+	knownColumns := make(map[string]bool, len(existing))
+	for _, col := range existing {
+		knownColumns[col.ColumnID] = true
+	}
+
+	mappings, problems := validateImportAssignments(assignments, knownColumns)
+	if err := importProblemsError(problems); err != nil {
+		return nil, err
+	}
+
+	removed, previous, err := u.emailKanbanColumnRepo.ImportMappings(userID, mappings, mode == emaildomain.KanbanImportReplace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import card assignments: %w", err)
+	}
+	result := &emaildomain.KanbanImportResult{
+		Mode:                mode,
+		AssignmentsImported: len(mappings),
+		AssignmentsRemoved:  removed,
+	}
+	u.finishKanbanImport(userID, mappings, previous, existing, existing)
+
+	log.Printf("[KanbanImport] User %s imported %d cards (%s)", userID, result.AssignmentsImported, mode)
+	return result, nil
+}
+
+// finishKanbanImport syncs the Gmail labels of imported assignments and tells the client to reload the board.
+// before and after are the user's columns around the import, used to resolve source and target labels.
+func (u *emailUsecase) finishKanbanImport(userID string, mappings []*emaildomain.EmailKanbanColumn, previous map[string]string, before, after []*emaildomain.KanbanColumn) {
+	u.syncImportedLabels(userID, mappings, previous, before, after)
+
+	if u.eventService != nil {
+		u.eventService.SendToUser(userID, "email_update", map[string]string{
+			"action": "kanban_import",
+		})
+	}
+}
+
+// syncImportedLabels applies the Gmail labels of the columns imported emails moved to, as a manual
+// move does. Emails moving between columns without any label configuration are left alone. The
+// import is already committed at this point, so failures are logged rather than returned.
+func (u *emailUsecase) syncImportedLabels(userID string, mappings []*emaildomain.EmailKanbanColumn, previous map[string]string, before, after []*emaildomain.KanbanColumn) {
+	if u.mailProvider == nil || len(mappings) == 0 {
+		return
+	}
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil || user == nil || user.Provider != "google" {
+		return
+	}
+	accessToken, refreshToken, err := u.getUserTokens(userID)
+	if err != nil || accessToken == "" {
+		return
+	}
+
+	sources := make(map[string]*emaildomain.KanbanColumn, len(before))
+	for _, col := range before {
+		sources[col.ColumnID] = col
+	}
+	targets := make(map[string]*emaildomain.KanbanColumn, len(after))
+	for _, col := range after {
+		targets[col.ColumnID] = col
+	}
+
+	var wg sync.WaitGroup
+	var failed int32
+	sem := make(chan struct{}, cardLookupConcurrency)
+	for _, m := range mappings {
+		from := previous[m.EmailID]
+		if from == m.ColumnID {
+			continue
+		}
+		source, target := sources[from], targets[m.ColumnID]
+		if !hasColumnLabels(source) && !hasColumnLabels(target) {
+			continue
+		}
+		addLabelIDs, removeLabelIDs := columnLabelChanges(source, target)
+
+		wg.Add(1)
+		go func(emailID string, addLabelIDs, removeLabelIDs []string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := u.mailProvider.ModifyMessageLabels(context.Background(), accessToken, refreshToken, emailID, addLabelIDs, removeLabelIDs, u.makeTokenUpdateCallback(userID))
+			if err != nil {
+				atomic.AddInt32(&failed, 1)
+				log.Printf("[KanbanImport] Could not apply labels to email %s: %v", emailID, err)
+			}
+		}(m.EmailID, addLabelIDs, removeLabelIDs)
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		log.Printf("[KanbanImport] User %s: labels of %d imported cards could not be synced", userID, failed)
+	}
+}
+
+// hasColumnLabels reports whether moving an email into or out of the column changes Gmail labels
+func hasColumnLabels(column *emaildomain.KanbanColumn) bool {
+	return column != nil && (column.GmailLabelID != "" || len(column.RemoveLabelIDs) > 0)
+}
+
+func validateKanbanImportMode(mode emaildomain.KanbanImportMode) error {
+	if mode != emaildomain.KanbanImportMerge && mode != emaildomain.KanbanImportReplace {
+		return fmt.Errorf("%w: mode must be %q or %q", ErrInvalidKanbanImport, emaildomain.KanbanImportMerge, emaildomain.KanbanImportReplace)
+	}
+	return nil
+}
+
+// validateImportColumns checks the KanbanColumn constraints (required column_id and name,
+// column_id unique per user, non-negative order) and converts the export rows to columns
+func validateImportColumns(rows []emaildomain.KanbanColumnExport) ([]*emaildomain.KanbanColumn, []string) {
+	var problems []string
+	if len(rows) == 0 {
+		return nil, []string{"columns: at least one column is required"}
+	}
+
+	columns := make([]*emaildomain.KanbanColumn, 0, len(rows))
+	seen := make(map[string]bool, len(rows))
+	for i, row := range rows {
+		columnID := strings.TrimSpace(row.ColumnID)
+		name := strings.TrimSpace(row.Name)
+
+		if columnID == "" {
+			problems = append(problems, fmt.Sprintf("columns[%d]: column_id is required", i))
+			continue
+		}
+		if seen[columnID] {
+			problems = append(problems, fmt.Sprintf("columns[%d]: duplicate column_id %q", i, columnID))
+			continue
+		}
+		seen[columnID] = true
+		if name == "" {
+			problems = append(problems, fmt.Sprintf("columns[%d]: name is required", i))
+		}
+		if row.Order < 0 {
+			problems = append(problems, fmt.Sprintf("columns[%d]: order must not be negative", i))
+		}
+
+		removeLabels := emaildomain.StringArray{}
+		for _, label := range row.RemoveLabelIDs {
+			if label = strings.TrimSpace(label); label != "" {
+				removeLabels = append(removeLabels, label)
+			}
+		}
+
+		columns = append(columns, &emaildomain.KanbanColumn{
+			ColumnID:       columnID,
+			Name:           name,
+			Order:          row.Order,
+			GmailLabelID:   strings.TrimSpace(row.GmailLabelID),
+			RemoveLabelIDs: removeLabels,
+			Description:    strings.TrimSpace(row.Description),
+			Examples:       emaildomain.StringArray(row.Examples),
+			SavedSearchID:  strings.TrimSpace(row.SavedSearchID),
+		})
+	}
+	return columns, problems
+}
+
+// validateImportAssignments checks that every card references a known column exactly once
+// and that snoozed cards carry their expiration
+func validateImportAssignments(rows []emaildomain.KanbanAssignmentExport, knownColumns map[string]bool) ([]*emaildomain.EmailKanbanColumn, []string) {
+	var problems []string
+	mappings := make([]*emaildomain.EmailKanbanColumn, 0, len(rows))
+	seen := make(map[string]bool, len(rows))
+
+	for i, row := range rows {
+		emailID := strings.TrimSpace(row.EmailID)
+		columnID := strings.TrimSpace(row.ColumnID)
+
+		if emailID == "" {
+			problems = append(problems, fmt.Sprintf("assignments[%d]: email_id is required", i))
+			continue
+		}
+		if seen[emailID] {
+			problems = append(problems, fmt.Sprintf("assignments[%d]: email %s appears more than once", i, emailID))
+			continue
+		}
+		seen[emailID] = true
+
+		if columnID == "" {
+			problems = append(problems, fmt.Sprintf("assignments[%d]: column_id is required", i))
+			continue
+		}
+		if !knownColumns[columnID] {
+			problems = append(problems, fmt.Sprintf("assignments[%d]: unknown column %q", i, columnID))
+			continue
+		}
+
+		mapping := &emaildomain.EmailKanbanColumn{
+			EmailID:  emailID,
+			ColumnID: columnID,
+		}
+		if columnID == "snoozed" {
+			if row.SnoozedUntil == nil {
+				problems = append(problems, fmt.Sprintf("assignments[%d]: snoozed_until is required for snoozed cards", i))
+				continue
+			}
+			prevColumn := strings.TrimSpace(row.PreviousColumnID)
+			if prevColumn != "" && !knownColumns[prevColumn] {
+				problems = append(problems, fmt.Sprintf("assignments[%d]: unknown previous column %q", i, prevColumn))
+				continue
+			}
+			until := *row.SnoozedUntil
+			mapping.PreviousColumnID = prevColumn
+			mapping.SnoozedUntil = &until
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, problems
+}
+
+func importProblemsError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	if len(problems) > maxImportProblems {
+		more := len(problems) - maxImportProblems
+		problems = append(problems[:maxImportProblems:maxImportProblems], fmt.Sprintf("... and %d more", more))
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidKanbanImport, strings.Join(problems, "; "))
+}
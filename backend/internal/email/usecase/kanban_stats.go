@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"fmt"
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/internal/email/repository"
+	"sort"
+	"time"
+)
+
+// GetKanbanStats computes board analytics: cards per column with their age distribution,
+// time-to-done and daily throughput into doneColumn over [from, to). All aggregation happens in SQL.
+func (u *emailUsecase) GetKanbanStats(userID string, from, to time.Time, loc *time.Location, doneColumn string) (*emaildomain.KanbanStats, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	if doneColumn == "" {
+		doneColumn = "done"
+	}
+
+	columns, err := u.GetKanbanColumns(userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(columns, func(i, j int) bool { return columns[i].Order < columns[j].Order })
+
+	now := time.Now()
+	cardStats, err := u.emailKanbanColumnRepo.GetColumnCardStats(userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count cards: %w", err)
+	}
+	bucketCounts, err := u.emailKanbanColumnRepo.GetCardAgeBuckets(userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute card ages: %w", err)
+	}
+	cycle, err := u.emailKanbanColumnRepo.GetTimeToColumnStats(userID, doneColumn, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute time to done: %w", err)
+	}
+	daily, err := u.emailKanbanColumnRepo.GetDailyArrivals(userID, doneColumn, from, to, loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute throughput: %w", err)
+	}
+
+	stats := &emaildomain.KanbanStats{
+		From:       from,
+		To:         to,
+		Timezone:   loc.String(),
+		DoneColumn: doneColumn,
+		TimeToDone: emaildomain.KanbanCycleTime{
+			SampleSize:  cycle.SampleSize,
+			MedianHours: secondsToHours(cycle.MedianSeconds),
+			P90Hours:    secondsToHours(cycle.P90Seconds),
+			AvgHours:    secondsToHours(cycle.AvgSeconds),
+		},
+		Throughput: make([]emaildomain.KanbanDailyThroughput, 0, len(daily)),
+	}
+
+	// Index per-column rows; mappings may point to columns that were deleted since
+	byColumn := make(map[string]*emaildomain.KanbanColumnStats)
+	var ordered []*emaildomain.KanbanColumnStats
+	addColumn := func(columnID, name string) *emaildomain.KanbanColumnStats {
+		if col, ok := byColumn[columnID]; ok {
+			return col
+		}
+		col := &emaildomain.KanbanColumnStats{ColumnID: columnID, Name: name}
+		byColumn[columnID] = col
+		ordered = append(ordered, col)
+		return col
+	}
+	for _, col := range columns {
+		addColumn(col.ColumnID, col.Name)
+	}
+	for _, row := range cardStats {
+		col := addColumn(row.ColumnID, row.ColumnID)
+		col.Count = row.Count
+		col.AvgAgeHours = row.AvgAgeSeconds / 3600
+		col.OldestAgeHours = row.MaxAgeSeconds / 3600
+		stats.TotalCards += row.Count
+	}
+
+	// Fill every bucket (including empty ones) in ascending order, per column and overall
+	perColumnBuckets := make(map[string]map[string]int64)
+	totalBuckets := make(map[string]int64)
+	for _, row := range bucketCounts {
+		if perColumnBuckets[row.ColumnID] == nil {
+			perColumnBuckets[row.ColumnID] = make(map[string]int64)
+		}
+		perColumnBuckets[row.ColumnID][row.Bucket] += row.Count
+		totalBuckets[row.Bucket] += row.Count
+	}
+	for _, col := range ordered {
+		col.AgeDistribution = buildAgeBuckets(perColumnBuckets[col.ColumnID])
+		stats.Columns = append(stats.Columns, *col)
+	}
+	stats.AgeDistribution = buildAgeBuckets(totalBuckets)
+
+	for _, day := range daily {
+		stats.Throughput = append(stats.Throughput, emaildomain.KanbanDailyThroughput{
+			Date:  day.Day.Format("2006-01-02"),
+			Count: day.Count,
+		})
+	}
+
+	return stats, nil
+}
+
+func buildAgeBuckets(counts map[string]int64) []emaildomain.KanbanAgeBucket {
+	buckets := make([]emaildomain.KanbanAgeBucket, len(repository.CardAgeBuckets))
+	for i, b := range repository.CardAgeBuckets {
+		buckets[i] = emaildomain.KanbanAgeBucket{Bucket: b.Label, Count: counts[b.Label]}
+	}
+	return buckets
+}
+
+func secondsToHours(seconds *float64) *float64 {
+	if seconds == nil {
+		return nil
+	}
+	hours := *seconds / 3600
+	return &hours
+}
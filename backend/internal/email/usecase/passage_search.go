@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/embedding"
+	"ga03-backend/pkg/textextract"
+)
+
+const (
+	// emailChunkRunes is the size of an embedded email passage; shorter emails are only embedded whole
+	emailChunkRunes = 1500
+	// maxEmailChunks bounds the passages embedded per email (long threads repeat their history)
+	maxEmailChunks = 50
+	// passageSearchCandidates is the number of nearest passages of each kind merged with the email results
+	passageSearchCandidates = 100
+	// passageSnippetRunes is the length of the passage returned in search results
+	passageSnippetRunes = 300
+	// passageAgreementBonus lowers the distance of an email by this fraction of the threshold
+	// for each other passage that matches, up to maxAgreeingPassages
+	passageAgreementBonus = 0.02
+	maxAgreeingPassages   = 3
+)
+
+// semanticMatch is an email of a semantic search: the distances of its vector and matching passages,
+// their aggregate, and the passage that matched best, if any
+type semanticMatch struct {
+	emailID   string
+	distance  float64
+	distances []float64
+	highlight *emaildomain.SearchHighlight
+}
+
+// embedEmail embeds an email as one vector and, when it is long, as passages
+func (u *emailUsecase) embedEmail(ctx context.Context, userID, emailID, subject, body string) error {
+	embedCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	err := u.UpsertEmailEmbedding(embedCtx, userID, emailID, subject, body)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if utf8.RuneCountInString(body) <= emailChunkRunes {
+		return nil
+	}
+	passages := textextract.ChunkEmail(body, emailChunkRunes)
+	if len(passages) > maxEmailChunks {
+		passages = passages[:maxEmailChunks]
+	}
+	chunks := make([]embedding.Chunk, len(passages))
+	for i, passage := range passages {
+		chunks[i] = embedding.Chunk{EmailID: emailID, Subject: subject, Index: i, Text: passage}
+	}
+
+	embedCtx, cancel = context.WithTimeout(ctx, 2*time.Minute)
+	err = u.vectorSearchService.UpsertEmailChunks(embedCtx, userID, emailID, chunks)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to embed passages: %w", err)
+	}
+	return nil
+}
+
+// searchPassages returns the email body and attachment passages closer than threshold.
+// Failures are logged: email results are still returned.
+func (u *emailUsecase) searchPassages(ctx context.Context, userID, query string, threshold float64) []embedding.ChunkMatch {
+	var passages []embedding.ChunkMatch
+	bodies, err := u.vectorSearchService.SearchEmailChunks(ctx, userID, query, passageSearchCandidates)
+	if err != nil {
+		log.Printf("[SemanticSearch] Email passage search failed: %v", err)
+	}
+	attachments, err := u.vectorSearchService.SearchAttachmentChunks(ctx, userID, query, passageSearchCandidates)
+	if err != nil {
+		log.Printf("[SemanticSearch] Attachment search failed: %v", err)
+	}
+	for _, match := range append(bodies, attachments...) {
+		if match.Distance <= threshold {
+			passages = append(passages, match)
+		}
+	}
+	return passages
+}
+
+// passageHighlight returns the highlight of a passage (email body or attachment)
+func passageHighlight(passage embedding.ChunkMatch) *emaildomain.SearchHighlight {
+	highlight := &emaildomain.SearchHighlight{
+		Source:   emaildomain.HighlightSourceBody,
+		Snippet:  truncateSummary(passage.Text, passageSnippetRunes),
+		Distance: passage.Distance,
+	}
+	if passage.AttachmentID != "" {
+		highlight.Source = emaildomain.HighlightSourceAttachment
+		highlight.AttachmentID = passage.AttachmentID
+		highlight.Filename = passage.Filename
+		highlight.MimeType = passage.MimeType
+	}
+	return highlight
+}
+
+// aggregateDistances scores an email from the distances of its vector and passages: the best one,
+// lowered a little for each other passage that matches (an email relevant in several places ranks first).
+// An email without any distance (backend without distance info) gets the threshold.
+func aggregateDistances(distances []float64, threshold float64) float64 {
+	if len(distances) == 0 {
+		return threshold
+	}
+	sorted := append([]float64(nil), distances...)
+	sort.Float64s(sorted)
+	agreeing := len(sorted) - 1
+	if agreeing > maxAgreeingPassages {
+		agreeing = maxAgreeingPassages
+	}
+	distance := sorted[0] - float64(agreeing)*passageAgreementBonus*threshold
+	if distance < 0 {
+		distance = 0
+	}
+	return distance
+}
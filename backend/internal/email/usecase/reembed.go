@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"ga03-backend/pkg/jobqueue"
+	"ga03-backend/pkg/textextract"
+)
+
+// VectorReembedQueue is the job queue of emails whose vector comes from a previous embedding model
+const VectorReembedQueue = "vector_reembed"
+
+// vectorReembedWorkerCount is the number of re-embedding workers per instance (kept low: background work)
+const vectorReembedWorkerCount = 2
+
+// maxReembedPerUser bounds the emails queued per user and call; the rest is queued on the next call
+const maxReembedPerUser = 5000
+
+// EmailReembedJob re-embeds one email with the current model (stored as the job payload)
+type EmailReembedJob struct {
+	UserID  string `json:"user_id"`
+	EmailID string `json:"email_id"`
+	Model   string `json:"model"` // Target model; jobs queued for another model are dropped
+}
+
+// QueueStaleEmbeddings queues the re-embedding of every user's vectors produced by another model
+// than the current one. Search only compares vectors of the current model, so emails are missing
+// from semantic results until re-embedded.
+func (u *emailUsecase) QueueStaleEmbeddings() (int, error) {
+	if u.vectorSearchService == nil || u.jobQueue == nil {
+		return 0, nil
+	}
+	model, _ := u.vectorSearchService.EmbeddingModel()
+
+	userIDs, err := u.emailSyncHistoryRepo.ListUsersWithStaleEmbeddings(model)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list users to re-embed: %w", err)
+	}
+
+	total := 0
+	for _, userID := range userIDs {
+		queued, err := u.queueUserReembedding(userID, model)
+		total += queued
+		if err != nil {
+			return total, err
+		}
+	}
+	if total > 0 {
+		log.Printf("[VectorReembed] Queued %d emails of %d users for re-embedding with %s", total, len(userIDs), model)
+	}
+	return total, nil
+}
+
+func (u *emailUsecase) queueUserReembedding(userID, model string) (int, error) {
+	emailIDs, err := u.emailSyncHistoryRepo.ListStaleEmbeddings(userID, model, maxReembedPerUser)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list emails to re-embed: %w", err)
+	}
+
+	queued := 0
+	for _, emailID := range emailIDs {
+		err := u.jobQueue.Enqueue(VectorReembedQueue, EmailReembedJob{UserID: userID, EmailID: emailID, Model: model}, jobqueue.EnqueueOptions{
+			UserID:   userID,
+			Priority: jobqueue.PriorityLow,
+			DedupKey: userID + "/" + emailID + "/" + model,
+		})
+		if err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// handleVectorReembedJob fetches an email again and replaces its vector. Failures are retried by the queue.
+func (u *emailUsecase) handleVectorReembedJob(ctx context.Context, queued *jobqueue.Job) error {
+	if u.vectorSearchService == nil {
+		return nil
+	}
+
+	var job EmailReembedJob
+	if err := json.Unmarshal([]byte(queued.Payload), &job); err != nil {
+		return jobqueue.Permanent(fmt.Errorf("invalid vector reembed job: %w", err))
+	}
+
+	model, _ := u.vectorSearchService.EmbeddingModel()
+	if job.Model != model {
+		return nil // The model changed again since the job was queued
+	}
+	synced, err := u.emailSyncHistoryRepo.IsEmailSynced(job.UserID, job.EmailID, model)
+	if err != nil {
+		return fmt.Errorf("failed to check sync status: %w", err)
+	}
+	if synced {
+		return nil // Re-synced meanwhile (e.g. by the vector sync queue)
+	}
+
+	// The vector stores keep no text: fetch the email from its provider
+	email, err := u.GetEmailByID(job.UserID, job.EmailID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch email %s: %w", job.EmailID, err)
+	}
+	if email == nil {
+		// Email deleted: forget it so that it is not queued again
+		if err := u.emailSyncHistoryRepo.DeleteSyncHistory(job.UserID, job.EmailID); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if err := u.embedEmail(ctx, job.UserID, job.EmailID, email.Subject, textextract.EmailText(email.Body)); err != nil {
+		return fmt.Errorf("failed to re-embed email %s: %w", job.EmailID, err)
+	}
+
+	_, dims := u.vectorSearchService.EmbeddingModel() // Known once an embedding was made
+	if err := u.emailSyncHistoryRepo.MarkEmailAsSynced(job.UserID, job.EmailID, model, dims); err != nil {
+		return fmt.Errorf("failed to mark email %s as re-embedded: %w", job.EmailID, err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/fuzzy"
+)
+
+var (
+	// ErrRelatedEmailsUnavailable is returned when no vector store is configured
+	ErrRelatedEmailsUnavailable = errors.New("related emails are not available")
+	// ErrRelatedEmailNotFound is returned when the source email does not exist for the user
+	ErrRelatedEmailNotFound = errors.New("email not found")
+)
+
+// relatedCandidateFactor over-fetches neighbours: messages of the same thread are dropped after fetching
+const relatedCandidateFactor = 3
+
+// GetRelatedEmails returns the user's emails closest to an email ("more like this"), nearest first.
+// The stored vector of the email is the query (nothing is re-embedded); messages of its thread are excluded.
+// An email not indexed yet has no related emails.
+func (u *emailUsecase) GetRelatedEmails(userID, emailID string, limit int) ([]*emaildomain.RelatedEmail, error) {
+	if u.vectorSearchService == nil {
+		return nil, ErrRelatedEmailsUnavailable
+	}
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	source, err := u.GetEmailByID(userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, ErrRelatedEmailNotFound
+	}
+
+	emailIDs, distances, err := u.vectorSearchService.RelatedEmails(context.Background(), userID, emailID, limit*relatedCandidateFactor)
+	if err != nil {
+		return nil, fmt.Errorf("related email search failed: %w", err)
+	}
+
+	distanceThreshold := defaultDistanceThreshold
+	if t, ok := u.vectorSearchService.(distanceThresholder); ok {
+		distanceThreshold = t.DistanceThreshold()
+	}
+	distanceByID := make(map[string]float64, len(emailIDs))
+	candidateIDs := make([]string, 0, len(emailIDs))
+	for i, id := range emailIDs {
+		if i < len(distances) && distances[i] > distanceThreshold {
+			continue
+		}
+		if i < len(distances) {
+			distanceByID[id] = distances[i]
+		}
+		candidateIDs = append(candidateIDs, id)
+	}
+	if len(candidateIDs) == 0 {
+		return []*emaildomain.RelatedEmail{}, nil
+	}
+
+	emails, err := u.fetchEmailsByIDs(user, candidateIDs)
+	if err != nil {
+		return nil, err
+	}
+	related := make([]*emaildomain.RelatedEmail, 0, limit)
+	for _, email := range emails {
+		if sameThread(source, email) {
+			continue
+		}
+		related = append(related, &emaildomain.RelatedEmail{Email: email, Distance: distanceByID[email.ID]})
+		if len(related) == limit {
+			break
+		}
+	}
+	return related, nil
+}
+
+// sameThread reports whether two emails belong to the same conversation: same provider thread (Gmail),
+// one answers the other, or (IMAP has no thread IDs) the same base subject between the same people
+func sameThread(a, b *emaildomain.Email) bool {
+	if a.ID == b.ID {
+		return true
+	}
+	if a.ThreadID != "" && a.ThreadID == b.ThreadID {
+		return true
+	}
+	if (a.MessageID != "" && a.MessageID == b.InReplyTo) || (b.MessageID != "" && b.MessageID == a.InReplyTo) {
+		return true
+	}
+	if a.ThreadID != "" && b.ThreadID != "" {
+		return false // Both threaded by the provider, in different threads
+	}
+	return threadKey(a) != "" && threadKey(a) == threadKey(b) && participates(a.From, b) && participates(b.From, a)
+}
+
+// threadKey returns the folded subject of an email without its reply and forward prefixes
+func threadKey(email *emaildomain.Email) string {
+	return fuzzy.Fold(baseSubject(email.Subject))
+}
+
+// participates reports whether an address is the sender or a recipient of an email
+func participates(address string, email *emaildomain.Email) bool {
+	address = mailboxAddress(address)
+	if address == "" {
+		return false
+	}
+	if mailboxAddress(email.From) == address {
+		return true
+	}
+	for _, recipient := range append(append([]string{}, email.To...), email.Cc...) {
+		if mailboxAddress(recipient) == address {
+			return true
+		}
+	}
+	return false
+}
+
+// mailboxAddress returns the lowercase address of "Name <user@example.com>" or "user@example.com"
+func mailboxAddress(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return strings.ToLower(parsed.Address)
+	}
+	return strings.ToLower(strings.TrimSpace(address))
+}
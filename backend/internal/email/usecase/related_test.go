@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"testing"
+
+	emaildomain "ga03-backend/internal/email/domain"
+)
+
+func TestSameThread(t *testing.T) {
+	source := &emaildomain.Email{
+		ID:        "1",
+		MessageID: "<m1@example.com>",
+		Subject:   "Báo cáo quý 1",
+		From:      "Alice <alice@example.com>",
+		To:        []string{"bob@example.com"},
+	}
+
+	tests := []struct {
+		name  string
+		a     *emaildomain.Email
+		other *emaildomain.Email
+		want  bool
+	}{
+		{"same email", source, &emaildomain.Email{ID: "1"}, true},
+		{
+			name:  "same provider thread",
+			a:     &emaildomain.Email{ID: "1", ThreadID: "t1", Subject: "Budget"},
+			other: &emaildomain.Email{ID: "2", ThreadID: "t1", Subject: "Other"},
+			want:  true,
+		},
+		{
+			name:  "different provider threads with the same subject and people",
+			a:     &emaildomain.Email{ID: "1", ThreadID: "t1", Subject: "Budget", From: "alice@example.com", To: []string{"bob@example.com"}},
+			other: &emaildomain.Email{ID: "2", ThreadID: "t2", Subject: "Re: Budget", From: "bob@example.com", To: []string{"alice@example.com"}},
+			want:  false,
+		},
+		{"answer", source, &emaildomain.Email{ID: "2", InReplyTo: "<m1@example.com>", Subject: "Unrelated"}, true},
+		{
+			name:  "answered",
+			a:     &emaildomain.Email{ID: "2", InReplyTo: "<m9@example.com>"},
+			other: &emaildomain.Email{ID: "9", MessageID: "<m9@example.com>"},
+			want:  true,
+		},
+		{
+			name:  "reply by subject between the same people",
+			a:     source,
+			other: &emaildomain.Email{ID: "2", Subject: "RE: Trả lời: bao cao quy 1", From: "Bob <BOB@example.com>", To: []string{"Alice <alice@example.com>"}},
+			want:  true,
+		},
+		{
+			name:  "one side threaded by the provider",
+			a:     source,
+			other: &emaildomain.Email{ID: "2", ThreadID: "t1", Subject: "Re: Báo cáo quý 1", From: "bob@example.com", Cc: []string{"alice@example.com"}},
+			want:  true,
+		},
+		{
+			name:  "same subject from someone else",
+			a:     source,
+			other: &emaildomain.Email{ID: "2", Subject: "Re: Báo cáo quý 1", From: "carol@example.com", To: []string{"alice@example.com"}},
+			want:  false,
+		},
+		{
+			name:  "same people, different subject",
+			a:     source,
+			other: &emaildomain.Email{ID: "2", Subject: "Báo cáo quý 2", From: "bob@example.com", To: []string{"alice@example.com"}},
+			want:  false,
+		},
+		{
+			name:  "empty subjects",
+			a:     &emaildomain.Email{ID: "1", From: "alice@example.com", To: []string{"bob@example.com"}},
+			other: &emaildomain.Email{ID: "2", Subject: "Re:", From: "bob@example.com", To: []string{"alice@example.com"}},
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameThread(tt.a, tt.other); got != tt.want {
+				t.Errorf("sameThread() = %v, want %v", got, tt.want)
+			}
+			if got := sameThread(tt.other, tt.a); got != tt.want {
+				t.Errorf("sameThread() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,565 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	authdomain "ga03-backend/internal/auth/domain"
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/jobqueue"
+	"ga03-backend/pkg/textextract"
+)
+
+var (
+	// ErrSavedSearchNotFound is returned for a saved search that does not exist for the user
+	ErrSavedSearchNotFound = errors.New("saved search not found")
+	// ErrInvalidSavedSearch is returned for a saved search without name or query, or with an unknown mode
+	ErrInvalidSavedSearch = errors.New("invalid saved search")
+)
+
+// SavedSearchAlertQueue is the job queue of new emails waiting to be matched against saved searches with alerts
+const SavedSearchAlertQueue = "saved_search_alert"
+
+const (
+	savedSearchAlertWorkerCount = 2
+	// savedSearchCountTTL keeps the unread count of a saved search, so listing mailboxes does not run
+	// every saved search each time
+	savedSearchCountTTL = 2 * time.Minute
+)
+
+// SavedSearchAlertJob is a new email claimed by saved searches with alerts (stored as the job payload;
+// the worker loads the email)
+type SavedSearchAlertJob struct {
+	UserID         string   `json:"user_id"`
+	EmailID        string   `json:"email_id"`
+	SavedSearchIDs []string `json:"saved_search_ids"`
+}
+
+// savedSearchCount is a cached unread count
+type savedSearchCount struct {
+	count     int
+	countedAt time.Time
+}
+
+// savedSearchCountCache holds recent unread counts by user, saved search and version of the search
+type savedSearchCountCache struct {
+	mu     sync.Mutex
+	counts map[string]savedSearchCount
+}
+
+var savedSearchCounts = &savedSearchCountCache{counts: make(map[string]savedSearchCount)}
+
+func (c *savedSearchCountCache) get(key string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.counts[key]
+	if !ok || time.Since(cached.countedAt) > savedSearchCountTTL {
+		return 0, false
+	}
+	return cached.count, true
+}
+
+func (c *savedSearchCountCache) put(key string, count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, cached := range c.counts {
+		if time.Since(cached.countedAt) > savedSearchCountTTL {
+			delete(c.counts, k)
+		}
+	}
+	c.counts[key] = savedSearchCount{count: count, countedAt: time.Now()}
+}
+
+// invalidate drops the counts of a user, after a change of read state or mailbox
+func (c *savedSearchCountCache) invalidate(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prefix := userID + "\x00"
+	for k := range c.counts {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.counts, k)
+		}
+	}
+}
+
+// savedSearchCountKey identifies the count of a saved search; editing the search changes the key
+func savedSearchCountKey(saved *emaildomain.SavedSearch) string {
+	return saved.UserID + "\x00" + saved.ID + "\x00" + saved.UpdatedAt.Format(time.RFC3339Nano)
+}
+
+// ListSavedSearches returns the saved searches of a user
+func (u *emailUsecase) ListSavedSearches(userID string) ([]*emaildomain.SavedSearch, error) {
+	return u.savedSearchRepo.ListByUser(userID)
+}
+
+// CreateSavedSearch validates and stores a saved search (fuzzy when no mode is given)
+func (u *emailUsecase) CreateSavedSearch(userID string, search *emaildomain.SavedSearch) error {
+	search.ID = ""
+	search.UserID = userID
+	if err := u.validateSavedSearch(search); err != nil {
+		return err
+	}
+	return u.savedSearchRepo.Create(search)
+}
+
+// UpdateSavedSearch replaces the name, query, mode and alert flag of a saved search
+func (u *emailUsecase) UpdateSavedSearch(userID string, search *emaildomain.SavedSearch) (*emaildomain.SavedSearch, error) {
+	existing, err := u.savedSearchRepo.GetByID(userID, search.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrSavedSearchNotFound
+	}
+
+	if search.Alert && !existing.Alert {
+		// Alerts start with the emails received from now on
+		now := time.Now()
+		existing.AlertsSince = &now
+	}
+	existing.Name = search.Name
+	existing.Query = search.Query
+	existing.Mode = search.Mode
+	existing.Alert = search.Alert
+	if err := u.validateSavedSearch(existing); err != nil {
+		return nil, err
+	}
+	if err := u.savedSearchRepo.Update(existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// DeleteSavedSearch removes a saved search; Kanban columns it was the source of list their own emails again
+func (u *emailUsecase) DeleteSavedSearch(userID, id string) error {
+	existing, err := u.savedSearchRepo.GetByID(userID, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrSavedSearchNotFound
+	}
+	return u.savedSearchRepo.Delete(userID, id)
+}
+
+// validateSavedSearch normalizes a saved search and checks that its query can be run
+func (u *emailUsecase) validateSavedSearch(search *emaildomain.SavedSearch) error {
+	search.Name = strings.TrimSpace(search.Name)
+	search.Query = strings.TrimSpace(search.Query)
+	if search.Mode == "" {
+		search.Mode = emaildomain.SavedSearchModeFuzzy
+	}
+	switch {
+	case search.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidSavedSearch)
+	case search.Query == "":
+		return fmt.Errorf("%w: query is required", ErrInvalidSavedSearch)
+	case !search.Mode.IsValid():
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidSavedSearch, search.Mode)
+	}
+	if search.Mode == emaildomain.SavedSearchModeSemantic {
+		return nil // Semantic queries are plain text
+	}
+
+	user, err := u.userRepo.FindByID(search.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	if _, err := parseSearchQuery(user, search.Query); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSavedSearch, err)
+	}
+	return nil
+}
+
+// getSavedSearchEmails returns a page of the emails of a saved search (virtual mailbox or smart column)
+func (u *emailUsecase) getSavedSearchEmails(userID, id string, limit, offset int) ([]*emaildomain.Email, int, error) {
+	saved, err := u.savedSearchRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if saved == nil {
+		return nil, 0, ErrSavedSearchNotFound
+	}
+	return u.runSavedSearch(saved, saved.Query, limit, offset)
+}
+
+// getSavedSearchMailboxEmails lists the virtual mailbox of a saved search, narrowed by the search bar query
+func (u *emailUsecase) getSavedSearchMailboxEmails(userID, id string, limit, offset int, query string) ([]*emaildomain.Email, int, error) {
+	saved, err := u.savedSearchRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if saved == nil {
+		return nil, 0, ErrSavedSearchNotFound
+	}
+	combined := saved.Query
+	if query = strings.TrimSpace(query); query != "" {
+		combined = "(" + saved.Query + ") " + query
+	}
+	emails, total, err := u.runSavedSearch(saved, combined, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, email := range emails {
+		email.MailboxID = saved.MailboxID()
+	}
+	return emails, total, nil
+}
+
+// runSavedSearch runs query (the query of the saved search, possibly narrowed) in the mode of the saved search.
+// Semantic searches are not expanded with AI synonyms: the folder and its count stay stable and cheap.
+func (u *emailUsecase) runSavedSearch(saved *emaildomain.SavedSearch, query string, limit, offset int) ([]*emaildomain.Email, int, error) {
+	switch saved.Mode {
+	case emaildomain.SavedSearchModeSemantic:
+		if u.vectorSearchService == nil {
+			return []*emaildomain.Email{}, 0, nil
+		}
+		user, err := u.userRepo.FindByID(saved.UserID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if user == nil {
+			return nil, 0, fmt.Errorf("user not found")
+		}
+		matches, err := u.rankSemantic(saved.UserID, query)
+		if err != nil {
+			return nil, 0, err
+		}
+		emails, err := u.fetchSemanticPage(user, matches, limit, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		return emails, len(matches), nil
+
+	case emaildomain.SavedSearchModeHybrid:
+		result, err := u.HybridSearch(saved.UserID, query, limit, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		emails := make([]*emaildomain.Email, 0, len(result.Hits))
+		for _, hit := range result.Hits {
+			emails = append(emails, hit.Email)
+		}
+		return emails, result.Total, nil
+
+	default:
+		return u.FuzzySearch(saved.UserID, query, limit, offset)
+	}
+}
+
+// savedSearchUnreadCount counts the unread emails of a saved search (cached for savedSearchCountTTL)
+func (u *emailUsecase) savedSearchUnreadCount(saved *emaildomain.SavedSearch) (int, error) {
+	key := savedSearchCountKey(saved)
+	if count, ok := savedSearchCounts.get(key); ok {
+		return count, nil
+	}
+	count, err := u.countSavedSearchUnread(saved)
+	if err != nil {
+		return 0, err
+	}
+	savedSearchCounts.put(key, count)
+	return count, nil
+}
+
+// countSavedSearchUnread runs a saved search to count its unread emails (from the search index for
+// keyword searches of indexed users)
+func (u *emailUsecase) countSavedSearchUnread(saved *emaildomain.SavedSearch) (int, error) {
+	if saved.Mode != emaildomain.SavedSearchModeSemantic {
+		// Keyword and hybrid searches filter with the is:unread operator
+		_, total, err := u.runSavedSearch(saved, "("+saved.Query+") is:unread", 1, 0)
+		return total, err
+	}
+
+	// Vector matches carry no read state: it is read from the search index
+	if u.vectorSearchService == nil || u.searchIndexRepo == nil {
+		return 0, nil
+	}
+	matches, err := u.rankSemantic(saved.UserID, saved.Query)
+	if err != nil {
+		return 0, err
+	}
+	emailIDs := make([]string, len(matches))
+	for i, match := range matches {
+		emailIDs[i] = match.emailID
+	}
+	count, err := u.searchIndexRepo.CountUnread(saved.UserID, emailIDs)
+	return int(count), err
+}
+
+// savedSearchMailboxes returns the virtual mailboxes of the user's saved searches with their unread counts
+func (u *emailUsecase) savedSearchMailboxes(userID string) []*emaildomain.Mailbox {
+	if u.savedSearchRepo == nil {
+		return nil
+	}
+	searches, err := u.savedSearchRepo.ListByUser(userID)
+	if err != nil {
+		log.Printf("[SavedSearch] Failed to list saved searches of user %s: %v", userID, err)
+		return nil
+	}
+
+	mailboxes := make([]*emaildomain.Mailbox, len(searches))
+	var wg sync.WaitGroup
+	for i, saved := range searches {
+		mailboxes[i] = &emaildomain.Mailbox{
+			ID:          saved.MailboxID(),
+			Name:        saved.Name,
+			Type:        emaildomain.MailboxTypeSavedSearch,
+			SavedSearch: saved,
+		}
+		wg.Add(1)
+		go func(mailbox *emaildomain.Mailbox, saved *emaildomain.SavedSearch) {
+			defer wg.Done()
+			count, err := u.savedSearchUnreadCount(saved)
+			if err != nil {
+				log.Printf("[SavedSearch] Failed to count unread emails of %q: %v", saved.Name, err)
+				return
+			}
+			mailbox.Count = count
+		}(mailboxes[i], saved)
+	}
+	wg.Wait()
+	return mailboxes
+}
+
+// NotifySavedSearches matches a new email against the saved searches with alerts, sends a
+// "saved_search_match" event for each match and returns the matching searches (the caller sends the
+// push notification). Each email is checked once per saved search, whichever path sees it first.
+func (u *emailUsecase) NotifySavedSearches(userID string, email *emaildomain.Email) []*emaildomain.SavedSearch {
+	if email == nil {
+		return nil
+	}
+	alerting := u.alertingSavedSearches(userID)
+	return u.matchSavedSearches(userID, u.claimSavedSearchAlerts(userID, alerting, email), email)
+}
+
+// AlertSavedSearches queues newly listed or synced emails for matching against the saved searches with
+// alerts (non-blocking). Emails are claimed oldest first; emails already checked are skipped.
+func (u *emailUsecase) AlertSavedSearches(userID string, emails []*emaildomain.Email) {
+	if u.jobQueue == nil || len(emails) == 0 {
+		return
+	}
+	alerting := u.alertingSavedSearches(userID)
+	if len(alerting) == 0 {
+		return
+	}
+
+	sorted := make([]*emaildomain.Email, 0, len(emails))
+	for _, email := range emails {
+		if email != nil {
+			sorted = append(sorted, email)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ReceivedAt.Before(sorted[j].ReceivedAt) })
+
+	for _, email := range sorted {
+		claimed := u.claimSavedSearchAlerts(userID, alerting, email)
+		if len(claimed) == 0 {
+			continue
+		}
+		ids := make([]string, len(claimed))
+		for i, saved := range claimed {
+			ids[i] = saved.ID
+		}
+		err := u.jobQueue.Enqueue(SavedSearchAlertQueue, SavedSearchAlertJob{UserID: userID, EmailID: email.ID, SavedSearchIDs: ids}, jobqueue.EnqueueOptions{
+			UserID:   userID,
+			Priority: jobqueue.PriorityNormal,
+			DedupKey: userID + "/" + email.ID,
+		})
+		if err != nil {
+			log.Printf("[SavedSearch] Failed to queue email %s: %v", email.ID, err)
+		}
+	}
+}
+
+// handleSavedSearchAlertJob matches a queued email against the saved searches that claimed it
+func (u *emailUsecase) handleSavedSearchAlertJob(ctx context.Context, queued *jobqueue.Job) error {
+	var job SavedSearchAlertJob
+	if err := json.Unmarshal([]byte(queued.Payload), &job); err != nil {
+		return jobqueue.Permanent(fmt.Errorf("invalid saved search alert job: %w", err))
+	}
+
+	email, err := u.loadEmail(job.UserID, job.EmailID)
+	if errors.Is(err, emaildomain.ErrEmailNotFound) || (err == nil && email == nil) {
+		return jobqueue.Permanent(fmt.Errorf("email %s not found", job.EmailID))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load email %s: %w", job.EmailID, err)
+	}
+
+	var searches []*emaildomain.SavedSearch
+	for _, id := range job.SavedSearchIDs {
+		saved, err := u.savedSearchRepo.GetByID(job.UserID, id)
+		if err != nil {
+			return fmt.Errorf("failed to load saved search %s: %w", id, err)
+		}
+		if saved != nil && saved.Alert {
+			searches = append(searches, saved)
+		}
+	}
+	u.matchSavedSearches(job.UserID, searches, email)
+	return nil
+}
+
+// alertingSavedSearches returns the saved searches of a user with alerts on
+func (u *emailUsecase) alertingSavedSearches(userID string) []*emaildomain.SavedSearch {
+	if u.savedSearchRepo == nil {
+		return nil
+	}
+	searches, err := u.savedSearchRepo.ListByUser(userID)
+	if err != nil {
+		log.Printf("[SavedSearch] Failed to list saved searches of user %s: %v", userID, err)
+		return nil
+	}
+	var alerting []*emaildomain.SavedSearch
+	for _, saved := range searches {
+		if saved.Alert {
+			alerting = append(alerting, saved)
+		}
+	}
+	return alerting
+}
+
+// claimSavedSearchAlerts returns the saved searches that had not checked an email yet, recording the
+// check (emails received before a search was created or got alerts are not claimed)
+func (u *emailUsecase) claimSavedSearchAlerts(userID string, alerting []*emaildomain.SavedSearch, email *emaildomain.Email) []*emaildomain.SavedSearch {
+	if email.ReceivedAt.IsZero() || email.ID == "" {
+		return nil
+	}
+	var claimed []*emaildomain.SavedSearch
+	for _, saved := range alerting {
+		since := saved.CreatedAt
+		if saved.AlertsSince != nil {
+			since = *saved.AlertsSince
+		}
+		if email.ReceivedAt.Before(since) {
+			continue
+		}
+		ok, err := u.savedSearchRepo.ClaimAlert(userID, saved.ID, email.ID)
+		if err != nil {
+			log.Printf("[SavedSearch] Failed to claim email %s for %q: %v", email.ID, saved.Name, err)
+			continue
+		}
+		if ok {
+			claimed = append(claimed, saved)
+		}
+	}
+	return claimed
+}
+
+// matchSavedSearches sends a "saved_search_match" event for every saved search an email matches, and
+// returns those saved searches
+func (u *emailUsecase) matchSavedSearches(userID string, searches []*emaildomain.SavedSearch, email *emaildomain.Email) []*emaildomain.SavedSearch {
+	if len(searches) == 0 {
+		return nil
+	}
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return nil
+	}
+
+	var matched []*emaildomain.SavedSearch
+	embedded := false
+	for _, saved := range searches {
+		keyword, filtered := u.savedSearchKeywordMatch(user, saved, email)
+		ok := keyword
+		if !ok && filtered && saved.Mode != emaildomain.SavedSearchModeFuzzy {
+			// The vector of a new email may not be stored yet: embed it now, once
+			if !embedded {
+				embedded = u.embedNewEmail(userID, email)
+			}
+			ok = embedded && u.savedSearchSemanticMatch(user, saved, email.ID)
+		}
+		if !ok {
+			continue
+		}
+		matched = append(matched, saved)
+		if u.eventService != nil {
+			u.eventService.SendToUser(userID, "saved_search_match", &emaildomain.SavedSearchMatch{
+				SavedSearch: saved,
+				Email: &emaildomain.Email{
+					ID:         email.ID,
+					ThreadID:   email.ThreadID,
+					MailboxID:  email.MailboxID,
+					From:       email.From,
+					FromName:   email.FromName,
+					Subject:    email.Subject,
+					Preview:    email.Preview,
+					ReceivedAt: email.ReceivedAt,
+				},
+			})
+		}
+	}
+	return matched
+}
+
+// savedSearchKeywordMatch matches an email against the query of a saved search: keyword reports a match
+// of the whole query, filtered a match of its operators alone (what a vector match still has to satisfy)
+func (u *emailUsecase) savedSearchKeywordMatch(user *authdomain.User, saved *emaildomain.SavedSearch, email *emaildomain.Email) (keyword, filtered bool) {
+	if saved.Mode == emaildomain.SavedSearchModeSemantic {
+		return false, true
+	}
+	parsed, err := parseSearchQuery(user, saved.Query)
+	if err != nil {
+		return false, false
+	}
+	message := searchMessage(email, u.searchColumns(user.ID, parsed))
+	_, filter := parsed.Split()
+	filtered = filter.IsEmpty() || filter.Match(message)
+	return filtered && parsed.Match(message), filtered
+}
+
+// savedSearchSemanticMatch reports whether an email is among the vector matches of a saved search
+func (u *emailUsecase) savedSearchSemanticMatch(user *authdomain.User, saved *emaildomain.SavedSearch, emailID string) bool {
+	query := saved.Query
+	if saved.Mode == emaildomain.SavedSearchModeHybrid {
+		parsed, err := parseSearchQuery(user, saved.Query)
+		if err != nil {
+			return false
+		}
+		query, _ = parsed.Split()
+		if strings.TrimSpace(query) == "" {
+			return false // Operators only: the keyword match decided
+		}
+	}
+	matches, err := u.rankSemantic(user.ID, query)
+	if err != nil {
+		log.Printf("[SavedSearch] Semantic match of %q failed: %v", saved.Name, err)
+		return false
+	}
+	for _, match := range matches {
+		if match.emailID == emailID {
+			return true
+		}
+	}
+	return false
+}
+
+// embedNewEmail stores the vector of a new email unless it is already synced
+func (u *emailUsecase) embedNewEmail(userID string, email *emaildomain.Email) bool {
+	if u.vectorSearchService == nil {
+		return false
+	}
+	model, _ := u.vectorSearchService.EmbeddingModel()
+	if synced, err := u.emailSyncHistoryRepo.IsEmailSynced(userID, email.ID, model); err == nil && synced {
+		return true
+	}
+	if err := u.embedEmail(context.Background(), userID, email.ID, email.Subject, textextract.EmailText(email.Body)); err != nil {
+		log.Printf("[SavedSearch] Failed to embed new email %s: %v", email.ID, err)
+		return false
+	}
+	_, dims := u.vectorSearchService.EmbeddingModel()
+	if err := u.emailSyncHistoryRepo.MarkEmailAsSynced(userID, email.ID, model, dims); err != nil {
+		log.Printf("[SavedSearch] Failed to mark email %s as synced: %v", email.ID, err)
+	}
+	return true
+}
@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/fuzzy"
+	"ga03-backend/pkg/search"
+)
+
+// maxIndexCandidates is the number of index matches checked here when part of the query
+// cannot be compiled to SQL (e.g. in:<column>)
+const maxIndexCandidates = 2000
+
+// searchIndexColumns maps the search operators to the columns of the full-text index.
+// The *_folded columns are stored folded, so they compare with the query words as they are.
+var searchIndexColumns = search.SQLColumns{
+	Document:      "document",
+	Subject:       "subject_folded",
+	From:          "sender_folded",
+	To:            "recipients_folded",
+	HasAttachment: "has_attachment",
+	IsRead:        "is_read",
+	IsStarred:     "is_starred",
+	ReceivedAt:    "received_at",
+	Size:          "size",
+	Fold:          "%s",
+}
+
+// searchIndexBatchSize bounds the documents upserted per statement (19 bind parameters each)
+const searchIndexBatchSize = 500
+
+// searchExcludedMailboxes are left out of searches unless the query names a place with in:
+var searchExcludedMailboxes = []string{"TRASH", "SPAM"}
+
+// indexEmailsForSearch adds emails to the full-text search index (subject, sender, recipients and body
+// folded with fuzzy.Fold, so that "canh" finds "cảnh báo"), one statement per batch
+func (u *emailUsecase) indexEmailsForSearch(userID string, emails []*emaildomain.Email) {
+	if u.searchIndexRepo == nil {
+		return
+	}
+	docs := make([]*emaildomain.EmailSearchDocument, 0, len(emails))
+	seen := make(map[string]int, len(emails))
+	for _, email := range emails {
+		if email == nil || email.ID == "" {
+			continue
+		}
+		// An upsert statement cannot touch the same row twice
+		if i, ok := seen[email.ID]; ok {
+			docs[i] = searchDocument(userID, email)
+			continue
+		}
+		seen[email.ID] = len(docs)
+		docs = append(docs, searchDocument(userID, email))
+	}
+	for start := 0; start < len(docs); start += searchIndexBatchSize {
+		end := start + searchIndexBatchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		if err := u.searchIndexRepo.Upsert(docs[start:end]); err != nil {
+			log.Printf("[SearchIndex] Failed to index %d emails of user %s: %v", end-start, userID, err)
+		}
+	}
+}
+
+// refreshSearchIndex re-reads an email from the provider after it was trashed, archived or moved, so
+// that its indexed mailbox follows. An email that is gone under this ID (IMAP moves change the UID) is
+// removed; it is indexed again under its new ID when listed.
+func (u *emailUsecase) refreshSearchIndex(userID, emailID string) {
+	if u.searchIndexRepo == nil {
+		return
+	}
+	email, err := u.loadEmail(userID, emailID)
+	if errors.Is(err, emaildomain.ErrEmailNotFound) || (err == nil && email == nil) {
+		u.updateSearchIndex(userID, emailID, func() error { return u.searchIndexRepo.Delete(userID, emailID) })
+		return
+	}
+	if err != nil {
+		log.Printf("[SearchIndex] Failed to reload email %s: %v", emailID, err)
+		return
+	}
+	u.indexEmailsForSearch(userID, []*emaildomain.Email{email})
+}
+
+// searchDocument returns the search index document of an email
+func searchDocument(userID string, email *emaildomain.Email) *emaildomain.EmailSearchDocument {
+	body := cleanHTMLForEmbedding(email.Body)
+	if body == "" {
+		body = email.Preview
+	}
+	recipients := strings.Join(append(append([]string{}, email.To...), email.Cc...), ", ")
+	size := int64(len(email.Body))
+	for _, attachment := range email.Attachments {
+		size += attachment.Size
+	}
+	return &emaildomain.EmailSearchDocument{
+		UserID:           userID,
+		EmailID:          email.ID,
+		MailboxID:        email.MailboxID,
+		ThreadID:         email.ThreadID,
+		Subject:          email.Subject,
+		FromAddress:      email.From,
+		FromName:         email.FromName,
+		Recipients:       recipients,
+		Preview:          email.Preview,
+		SubjectFolded:    fuzzy.Fold(email.Subject),
+		SenderFolded:     fuzzy.Fold(email.FromName + " " + email.From),
+		RecipientsFolded: fuzzy.Fold(recipients),
+		BodyFolded:       fuzzy.Fold(body),
+		HasAttachment:    len(email.Attachments) > 0,
+		IsRead:           email.IsRead,
+		IsStarred:        email.IsStarred,
+		Size:             size,
+		ReceivedAt:       email.ReceivedAt,
+	}
+}
+
+// isSearchIndexed reports whether the whole mailbox of a user is in the full-text index
+func (u *emailUsecase) isSearchIndexed(userID string) bool {
+	if u.searchIndexRepo == nil {
+		return false
+	}
+	indexed, err := u.searchIndexRepo.IsUserIndexed(userID)
+	if err != nil {
+		log.Printf("[SearchIndex] Failed to check index status of user %s: %v", userID, err)
+		return false
+	}
+	return indexed
+}
+
+// searchLocalIndex runs a parsed query against the full-text index: free text matches word prefixes
+// of the folded subject, sender, recipients and body, ranked with ts_rank (subject first), newest first on ties
+func (u *emailUsecase) searchLocalIndex(userID string, query *search.Query, limit, offset int) ([]*emaildomain.Email, int, error) {
+	text, _ := query.Split()
+	rank := search.PrefixTSQuery(text)
+	where, args, residual := query.SQL(searchIndexColumns)
+	where = scopeSearchMailboxes(query, where, args)
+
+	if residual.IsEmpty() {
+		docs, total, err := u.searchIndexRepo.Search(userID, where, args, rank, limit, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		return searchDocumentsToEmails(docs), int(total), nil
+	}
+
+	// The rest of the query (in:<column>) is matched here on the best candidates
+	docs, _, err := u.searchIndexRepo.Search(userID, where, args, rank, maxIndexCandidates, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	columns := u.searchColumns(userID, residual)
+	matched := make([]*emaildomain.EmailSearchDocument, 0, len(docs))
+	for _, doc := range docs {
+		if residual.Match(searchDocumentMessage(doc, columns)) {
+			matched = append(matched, doc)
+		}
+	}
+
+	total := len(matched)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= total {
+		return []*emaildomain.Email{}, total, nil
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return searchDocumentsToEmails(matched[offset:end]), total, nil
+}
+
+// scopeSearchMailboxes adds the exclusion of trash and spam to an index condition: they are only
+// searched on request (in:trash, in:spam)
+func scopeSearchMailboxes(query *search.Query, where string, args map[string]interface{}) string {
+	if query.HasField(search.FieldIn) {
+		return where
+	}
+	args["search_excluded_mailboxes"] = searchExcludedMailboxes
+	return andWhere(where, "mailbox_id NOT IN @search_excluded_mailboxes")
+}
+
+// andWhere joins two SQL conditions ("" when both are empty)
+func andWhere(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return "(" + a + ") AND (" + b + ")"
+}
+
+// searchDocumentMessage returns what a search query is matched against for an indexed email
+func searchDocumentMessage(doc *emaildomain.EmailSearchDocument, columns func(emailID string) []string) *search.Message {
+	m := &search.Message{
+		Subject:       doc.Subject,
+		From:          doc.FromAddress,
+		FromName:      doc.FromName,
+		To:            splitRecipients(doc.Recipients),
+		Body:          doc.BodyFolded,
+		HasAttachment: doc.HasAttachment,
+		IsRead:        doc.IsRead,
+		IsStarred:     doc.IsStarred,
+		ReceivedAt:    doc.ReceivedAt,
+		Size:          doc.Size,
+	}
+	if columns != nil {
+		// in: also names the mailbox (in:trash, in:spam)
+		m.Columns = append(columns(doc.EmailID), doc.MailboxID)
+	}
+	return m
+}
+
+// searchDocumentsToEmails returns indexed emails as list items (preview without body)
+func searchDocumentsToEmails(docs []*emaildomain.EmailSearchDocument) []*emaildomain.Email {
+	emails := make([]*emaildomain.Email, 0, len(docs))
+	for _, doc := range docs {
+		emails = append(emails, &emaildomain.Email{
+			ID:         doc.EmailID,
+			ThreadID:   doc.ThreadID,
+			UserID:     doc.UserID,
+			MailboxID:  doc.MailboxID,
+			From:       doc.FromAddress,
+			FromName:   doc.FromName,
+			To:         splitRecipients(doc.Recipients),
+			Subject:    doc.Subject,
+			Preview:    doc.Preview,
+			IsRead:     doc.IsRead,
+			IsStarred:  doc.IsStarred,
+			ReceivedAt: doc.ReceivedAt,
+		})
+	}
+	return emails
+}
+
+func splitRecipients(recipients string) []string {
+	if recipients == "" {
+		return []string{}
+	}
+	return strings.Split(recipients, ", ")
+}
+
+// updateSearchIndex applies a change (read, star, delete) to the index once the provider accepted it.
+// Cached hybrid rankings and saved search counts of the user are dropped, since their filters may no longer hold.
+func (u *emailUsecase) updateSearchIndex(userID, emailID string, update func() error) {
+	defer invalidateSearchCaches(userID)
+	if u.searchIndexRepo == nil {
+		return
+	}
+	if err := update(); err != nil {
+		log.Printf("[SearchIndex] Failed to update email %s: %v", emailID, err)
+	}
+}
+
+// invalidateSearchCaches drops the cached hybrid rankings and saved search counts of a user, after a
+// change that may move emails in or out of results
+func invalidateSearchCaches(userID string) {
+	hybridRankings.invalidate(userID)
+	savedSearchCounts.invalidate(userID)
+}
@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	authdomain "ga03-backend/internal/auth/domain"
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/search"
+)
+
+// ErrInvalidSearchQuery is returned when a search operator has an invalid value (e.g. before:yesterday)
+var ErrInvalidSearchQuery = errors.New("invalid search query")
+
+// parseSearchQuery parses a Gmail-style query; dates are days in the user's time zone
+func parseSearchQuery(user *authdomain.User, query string) (*search.Query, error) {
+	loc := time.UTC
+	if user.TimeZone != "" {
+		if l, err := time.LoadLocation(user.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	parsed, err := search.ParseQuery(query, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSearchQuery, err)
+	}
+	return parsed, nil
+}
+
+// searchColumns returns the Kanban column (ID and name) of the user's emails, for in:<column>.
+// Emails without a mapping are in the inbox column. Nil when the query does not use in:.
+func (u *emailUsecase) searchColumns(userID string, query *search.Query) func(emailID string) []string {
+	if !query.HasField(search.FieldIn) {
+		return nil
+	}
+	emailToCol, err := u.emailKanbanColumnRepo.GetEmailColumnMap(userID)
+	if err != nil {
+		log.Printf("[Search] Failed to get email-column map: %v", err)
+		emailToCol = map[string]string{}
+	}
+	names := make(map[string]string)
+	if columns, err := u.kanbanColumnRepo.GetColumnsByUserID(userID); err == nil {
+		for _, column := range columns {
+			names[column.ColumnID] = column.Name
+		}
+	}
+	return func(emailID string) []string {
+		columnID, ok := emailToCol[emailID]
+		if !ok {
+			columnID = "inbox"
+		}
+		return []string{columnID, names[columnID]}
+	}
+}
+
+// searchMessage returns what a search query is matched against for an email
+func searchMessage(email *emaildomain.Email, columns func(emailID string) []string) *search.Message {
+	body := cleanHTMLForEmbedding(email.Body)
+	if body == "" {
+		body = email.Preview
+	}
+	size := int64(len(email.Body))
+	for _, attachment := range email.Attachments {
+		size += attachment.Size
+	}
+	m := &search.Message{
+		Subject:       email.Subject,
+		From:          email.From,
+		FromName:      email.FromName,
+		To:            append(append([]string{}, email.To...), email.Cc...),
+		Body:          body,
+		HasAttachment: len(email.Attachments) > 0,
+		IsRead:        email.IsRead,
+		IsStarred:     email.IsStarred,
+		ReceivedAt:    email.ReceivedAt,
+		Size:          size,
+	}
+	if columns != nil {
+		m.Columns = columns(email.ID)
+	}
+	return m
+}
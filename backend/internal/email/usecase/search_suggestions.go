@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/fuzzy"
+)
+
+const (
+	// maxSuggestionsPerKind is the number of suggestions of each kind returned for a prefix
+	maxSuggestionsPerKind = 10
+	// keptSuggestionsPerKind is the number of suggestions of each kind kept per user after a full sync
+	keptSuggestionsPerKind = 2000
+	// Longer names and subjects are not useful as suggestions
+	maxSuggestionNameLength    = 100
+	maxSuggestionSubjectLength = 200
+)
+
+// reSubjectPrefix matches the reply and forward prefixes of a subject ("Re: Fwd: ...", "Trả lời: ...")
+var reSubjectPrefix = regexp.MustCompile(`(?i)^\s*(re|fwd?|tr|aw|sv|trả lời|chuyển tiếp)\s*(\[\d+\])?\s*:\s*`)
+
+// GetSearchSuggestions returns the user's past queries, contacts and subjects whose words start with the
+// words of the query (accent folded: "ngu" suggests "Nguyễn"), each kind ranked by frequency and recency.
+// This is used for auto-complete, NOT semantic search.
+func (u *emailUsecase) GetSearchSuggestions(userID, query string, limit int) (*emaildomain.SearchSuggestions, error) {
+	suggestions := &emaildomain.SearchSuggestions{
+		Queries:  []*emaildomain.SearchSuggestion{},
+		Contacts: []*emaildomain.SearchSuggestion{},
+		Subjects: []*emaildomain.SearchSuggestion{},
+	}
+	words := fuzzy.Tokenize(query)
+	if len(words) == 0 || u.suggestionRepo == nil {
+		return suggestions, nil
+	}
+
+	if limit <= 0 {
+		limit = 5
+	}
+	if limit > maxSuggestionsPerKind {
+		limit = maxSuggestionsPerKind
+	}
+
+	for kind, target := range map[string]*[]*emaildomain.SearchSuggestion{
+		emaildomain.SuggestionKindQuery:   &suggestions.Queries,
+		emaildomain.SuggestionKindContact: &suggestions.Contacts,
+		emaildomain.SuggestionKindSubject: &suggestions.Subjects,
+	} {
+		found, err := u.suggestionRepo.Search(userID, kind, words, limit)
+		if err != nil {
+			return nil, err
+		}
+		*target = found
+	}
+	return suggestions, nil
+}
+
+// RecordSearchQuery adds a query the user searched for to their suggestions
+func (u *emailUsecase) RecordSearchQuery(userID, query string) {
+	query = strings.Join(strings.Fields(query), " ")
+	if u.suggestionRepo == nil || query == "" || len(query) > maxSuggestionSubjectLength {
+		return
+	}
+	err := u.suggestionRepo.Record([]*emaildomain.SearchSuggestion{{
+		UserID:     userID,
+		Kind:       emaildomain.SuggestionKindQuery,
+		Key:        strings.ToLower(query),
+		Value:      query,
+		Folded:     fuzzy.Fold(query),
+		LastUsedAt: time.Now(),
+	}})
+	if err != nil {
+		log.Printf("[Suggestions] Failed to record query of user %s: %v", userID, err)
+	}
+}
+
+// recordEmailSuggestions adds the sender and the subject of an email to the user's suggestions
+// (an email fetched again is not counted twice)
+func (u *emailUsecase) recordEmailSuggestions(userID string, email *emaildomain.Email) {
+	if u.suggestionRepo == nil || email.ID == "" {
+		return
+	}
+	usedAt := email.ReceivedAt
+	if usedAt.IsZero() {
+		usedAt = time.Now()
+	}
+
+	var suggestions []*emaildomain.SearchSuggestion
+	if address := strings.ToLower(strings.TrimSpace(email.From)); address != "" {
+		name := strings.TrimSpace(email.FromName)
+		if name == "" || len(name) > maxSuggestionNameLength {
+			name = address
+		}
+		suggestions = append(suggestions, &emaildomain.SearchSuggestion{
+			UserID:     userID,
+			Kind:       emaildomain.SuggestionKindContact,
+			Key:        address,
+			Value:      name,
+			Detail:     address,
+			Folded:     fuzzy.Fold(name + " " + address),
+			LastUsedAt: usedAt,
+		})
+	}
+	if subject := baseSubject(email.Subject); subject != "" && len(subject) <= maxSuggestionSubjectLength {
+		if folded := fuzzy.Fold(subject); folded != "" {
+			suggestions = append(suggestions, &emaildomain.SearchSuggestion{
+				UserID:     userID,
+				Kind:       emaildomain.SuggestionKindSubject,
+				Key:        folded,
+				Value:      subject,
+				Folded:     folded,
+				LastUsedAt: usedAt,
+			})
+		}
+	}
+
+	if err := u.suggestionRepo.RecordEmail(userID, email.ID, suggestions); err != nil {
+		log.Printf("[Suggestions] Failed to record suggestions of email %s: %v", email.ID, err)
+	}
+}
+
+// baseSubject returns a subject without its reply and forward prefixes, so that a thread is one suggestion
+func baseSubject(subject string) string {
+	subject = strings.TrimSpace(subject)
+	for {
+		stripped := reSubjectPrefix.ReplaceAllString(subject, "")
+		if stripped == subject {
+			return strings.Join(strings.Fields(subject), " ")
+		}
+		subject = stripped
+	}
+}
+
+// pruneSuggestions drops the lowest ranked suggestions of a user (a full sync records every sender and subject)
+func (u *emailUsecase) pruneSuggestions(userID string) {
+	if u.suggestionRepo == nil {
+		return
+	}
+	if err := u.suggestionRepo.Prune(userID, keptSuggestionsPerKind); err != nil {
+		log.Printf("[Suggestions] Failed to prune suggestions of user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,30 @@
+package usecase
+
+import "testing"
+
+func TestBaseSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Weekly report", "Weekly report"},
+		{"Re: Weekly report", "Weekly report"},
+		{"RE: Fwd: re: Weekly report", "Weekly report"},
+		{"Fw: Budget", "Budget"},
+		{"Re[2]: Budget", "Budget"},
+		{"AW: SV: Angebot", "Angebot"},
+		{"Trả lời: Chuyển tiếp: Báo cáo tuần", "Báo cáo tuần"},
+		{"TR : Réunion", "Réunion"},
+		{"  Re:   Weekly    report  ", "Weekly report"},
+		{"Regarding: the plan", "Regarding: the plan"},
+		{"Fwd:", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			if got := baseSubject(tt.subject); got != tt.want {
+				t.Errorf("baseSubject(%q) = %q, want %q", tt.subject, got, tt.want)
+			}
+		})
+	}
+}
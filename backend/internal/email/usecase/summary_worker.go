@@ -2,21 +2,59 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	emaildomain "ga03-backend/internal/email/domain"
 	"ga03-backend/internal/email/repository"
+	"ga03-backend/pkg/ai"
+	"ga03-backend/pkg/jobqueue"
 	"ga03-backend/pkg/sse"
 )
 
-// SummaryJob represents a job to generate AI summary for an email
+const (
+	maxSummaryInputRunes = 5000 // Email text sent to the model
+	maxSummaryRunes      = 200  // Stored/displayed summary length
+)
+
+// SummaryQueue is the job queue processed by the summary workers
+const SummaryQueue = "summary"
+
+// ErrSummaryUnavailable is returned when no AI service (or email source, for refreshes) is configured
+var ErrSummaryUnavailable = errors.New("AI summaries are not available")
+
+var errSummaryEmailNotFound = errors.New("email not found")
+
+// SummaryJob represents a job to generate AI summary for an email (stored as the job payload)
 type SummaryJob struct {
-	UserID  string
-	EmailID string
-	Subject string
-	Body    string
+	UserID   string `json:"user_id"`
+	EmailID  string `json:"email_id"`
+	Subject  string `json:"subject,omitempty"`
+	Body     string `json:"body,omitempty"`
+	Language string `json:"language,omitempty"` // User's language for the summary prompt (empty = default)
+	Force    bool   `json:"force,omitempty"`    // Regenerate even if a summary is cached
+	Refresh  bool   `json:"refresh,omitempty"`  // Bulk refresh of a stale summary: the email is loaded by the worker
+}
+
+// SummaryEmailSource loads the emails of stale summaries being refreshed
+type SummaryEmailSource interface {
+	GetEmailByID(userID, id string) (*emaildomain.Email, error)
+}
+
+// summaryGenerationSource is implemented by AI services that report their current models and
+// prompt versions (the fallback chain); summaries generated otherwise are stale
+type summaryGenerationSource interface {
+	ProviderModels() map[string]string
+	PromptRegistry() *ai.PromptRegistry
 }
 
 // SummaryWorkerService handles background AI summary generation
@@ -26,29 +64,35 @@ type SummaryWorkerService struct {
 		SummarizeEmail(ctx context.Context, emailText string) (string, error)
 	}
 	sseManager  *sse.Manager
-	jobQueue    chan SummaryJob
-	workerWg    sync.WaitGroup
-	workerCount int
-	started     bool
+	emailSource SummaryEmailSource
+	queue       *jobqueue.Queue
 	mu          sync.Mutex
+
+	// Stale summary refresh
+	refreshStop   chan struct{}
+	refreshWg     sync.WaitGroup
+	refreshActive bool
 }
 
-// NewSummaryWorkerService creates a new summary worker service
+// NewSummaryWorkerService creates a new summary worker service and registers its workers
+// on the job queue (they run once the queue is started)
 func NewSummaryWorkerService(
 	summaryRepo repository.EmailSummaryRepository,
 	sseManager *sse.Manager,
+	queue *jobqueue.Queue,
 	workerCount int,
 ) *SummaryWorkerService {
 	if workerCount <= 0 {
 		workerCount = 3 // Default to 3 workers
 	}
 
-	return &SummaryWorkerService{
+	s := &SummaryWorkerService{
 		summaryRepo: summaryRepo,
 		sseManager:  sseManager,
-		jobQueue:    make(chan SummaryJob, 500), // Buffered channel
-		workerCount: workerCount,
+		queue:       queue,
 	}
+	queue.Register(SummaryQueue, workerCount, s.handleJob)
+	return s
 }
 
 // SetGeminiService sets the Gemini service for AI summarization
@@ -58,89 +102,169 @@ func (s *SummaryWorkerService) SetGeminiService(svc interface {
 	s.geminiService = svc
 }
 
-// Start starts the summary workers
-func (s *SummaryWorkerService) Start() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if s.started {
-		return
-	}
-
-	for i := 0; i < s.workerCount; i++ {
-		s.workerWg.Add(1)
-		go s.worker(i)
-	}
-	s.started = true
-	log.Printf("[SummaryWorker] Started %d workers", s.workerCount)
+// SetEmailSource sets where stale summaries being refreshed get their email content
+func (s *SummaryWorkerService) SetEmailSource(source SummaryEmailSource) {
+	s.emailSource = source
 }
 
-// Stop stops all workers gracefully
+// Stop stops the stale summary refresh; queued jobs stay in the database
 func (s *SummaryWorkerService) Stop() {
-	close(s.jobQueue)
-	s.workerWg.Wait()
-	log.Println("[SummaryWorker] All workers stopped")
+	s.mu.Lock()
+	if s.refreshActive {
+		close(s.refreshStop)
+		s.refreshActive = false
+	}
+	s.mu.Unlock()
+	s.refreshWg.Wait()
 }
 
-// worker processes summary jobs from the queue
-func (s *SummaryWorkerService) worker(id int) {
-	defer s.workerWg.Done()
-
-	for job := range s.jobQueue {
-		s.processJob(job)
+// handleJob processes a summary job from the queue. AI errors are retried by the queue;
+// jobs that cannot succeed are dead-lettered right away.
+func (s *SummaryWorkerService) handleJob(ctx context.Context, queued *jobqueue.Job) error {
+	var job SummaryJob
+	if err := json.Unmarshal([]byte(queued.Payload), &job); err != nil {
+		return jobqueue.Permanent(fmt.Errorf("invalid summary job: %w", err))
 	}
-
-	log.Printf("[SummaryWorker] Worker %d stopped", id)
+	_, err := s.summarize(job)
+	if errors.Is(err, ErrSummaryUnavailable) || errors.Is(err, errSummaryEmailNotFound) {
+		return jobqueue.Permanent(err)
+	}
+	var quotaErr *ai.QuotaError
+	if errors.As(err, &quotaErr) {
+		// Over quota with no local provider to fall back to: wait for the quota to reset
+		return jobqueue.RetryAfter(err, time.Until(quotaErr.ResetAt))
+	}
+	return err
 }
 
-// processJob processes a single summary job
-func (s *SummaryWorkerService) processJob(job SummaryJob) {
+// summarize returns the cached summary of a job, generating it if missing, forced or if the
+// email content changed since it was generated
+func (s *SummaryWorkerService) summarize(job SummaryJob) (*emaildomain.EmailSummary, error) {
 	if s.geminiService == nil {
-		return
+		return nil, ErrSummaryUnavailable
 	}
 
-	// Check if summary already exists (cache hit)
-	existing, err := s.summaryRepo.GetSummary(job.UserID, job.EmailID)
-	if err != nil {
-		log.Printf("[SummaryWorker] Error checking cache: %v", err)
-		return
-	}
-	if existing != nil {
-		// Already have summary, send via SSE
-		s.sendSummaryUpdate(job.UserID, job.EmailID, existing.Summary)
-		return
+	if job.Refresh {
+		// Bulk refresh jobs only carry IDs: load the email now, in the worker
+		if s.emailSource == nil {
+			return nil, ErrSummaryUnavailable
+		}
+		email, err := s.emailSource.GetEmailByID(job.UserID, job.EmailID)
+		if errors.Is(err, emaildomain.ErrEmailNotFound) || (err == nil && email == nil) {
+			// The email is gone: drop its summary instead of retrying it on every refresh
+			if err := s.summaryRepo.DeleteSummary(job.UserID, job.EmailID); err != nil {
+				log.Printf("[SummaryWorker] Failed to delete summary of missing email %s: %v", job.EmailID, err)
+			}
+			return nil, errSummaryEmailNotFound
+		}
+		if err != nil {
+			// Expired token, provider error or timeout: keep the summary, the queue retries the job
+			return nil, fmt.Errorf("failed to load email %s: %w", job.EmailID, err)
+		}
+		job.Subject, job.Body = email.Subject, email.Body
 	}
 
 	// Generate summary using Gemini AI
-	ctx := context.Background()
 	emailText := fmt.Sprintf("Subject: %s\n\nBody: %s", job.Subject, job.Body)
 
 	// Truncate to avoid token limits
-	if len(emailText) > 5000 {
-		emailText = emailText[:5000]
+	emailText = truncateRunes(emailText, maxSummaryInputRunes)
+	contentHash := summaryContentHash(emailText)
+
+	if !job.Force {
+		// Check if summary already exists (cache hit); summaries from before content hashing are kept
+		existing, err := s.summaryRepo.GetSummary(job.UserID, job.EmailID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check cache: %w", err)
+		}
+		if existing != nil && (existing.ContentHash == "" || existing.ContentHash == contentHash) {
+			// Already have summary, send via SSE
+			s.sendSummaryUpdate(job.UserID, job.EmailID, existing.Summary)
+			return existing, nil
+		}
 	}
 
-	summary, err := s.geminiService.SummarizeEmail(ctx, emailText)
+	summaryText, trace, err := s.generateSummary(job, emailText)
 	if err != nil {
-		log.Printf("[SummaryWorker] AI error for email %s: %v", job.EmailID, err)
-		return
+		return nil, fmt.Errorf("AI error: %w", err)
 	}
 
-	// Truncate summary to 2-3 sentences if too long
-	if len(summary) > 200 {
-		summary = summary[:200] + "..."
+	// Keep the summary short (2-3 sentences), cutting at a sentence boundary when possible
+	summary := &emaildomain.EmailSummary{
+		UserID:      job.UserID,
+		EmailID:     job.EmailID,
+		Summary:     truncateSummary(summaryText, maxSummaryRunes),
+		ContentHash: contentHash,
+	}
+	summary.Provider, summary.Model = trace.Provider()
+	if usage, ok := trace.Usage(ai.PromptSummary); ok {
+		summary.Language = usage.Language
+		summary.PromptVersion = usage.Version
 	}
 
 	// Save to database (cache)
-	if err := s.summaryRepo.SaveSummary(job.UserID, job.EmailID, summary); err != nil {
-		log.Printf("[SummaryWorker] Save error: %v", err)
-		return
+	if err := s.summaryRepo.SaveSummary(summary); err != nil {
+		return nil, fmt.Errorf("failed to save summary: %w", err)
+	}
+
+	// Send the final (truncated) summary; clients replace the streamed text with it
+	s.sendSummaryUpdate(job.UserID, job.EmailID, summary.Summary)
+
+	log.Printf("[SummaryWorker] Generated summary for %s (%s/%s, prompt %s v%d)", job.EmailID, summary.Provider, summary.Model, summary.Language, summary.PromptVersion)
+	return summary, nil
+}
+
+// Regenerate summarizes an email again, replacing its cached summary. Deltas are streamed
+// over SSE like queued jobs; the new summary is returned once generated.
+func (s *SummaryWorkerService) Regenerate(job SummaryJob) (*emaildomain.EmailSummary, error) {
+	job.Force = true
+	return s.summarize(job)
+}
+
+// summaryContentHash identifies the email text a summary was generated from
+func summaryContentHash(emailText string) string {
+	sum := sha256.Sum256([]byte(emailText))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateSummary streams the summary as summary_delta events when the AI service supports it.
+// Generation is cut short once the text is past maxSummaryRunes, since the rest would be truncated anyway.
+// The returned trace tells which provider, model and prompt version produced the summary.
+func (s *SummaryWorkerService) generateSummary(job SummaryJob, emailText string) (string, *ai.PromptTrace, error) {
+	baseCtx, trace := ai.WithPromptTrace(ai.WithPromptContext(context.Background(), ai.PromptContext{Language: job.Language, UserID: job.UserID}))
+	streamer, ok := s.geminiService.(ai.StreamingSummarizer)
+	if !ok || s.sseManager == nil {
+		summary, err := s.geminiService.SummarizeEmail(baseCtx, emailText)
+		return summary, trace, err
 	}
 
-	// Send real-time update via SSE
-	s.sendSummaryUpdate(job.UserID, job.EmailID, summary)
+	ctx, cancel := context.WithCancel(baseCtx)
+	defer cancel()
 
-	log.Printf("[SummaryWorker] Generated summary for %s", job.EmailID)
+	var streamed strings.Builder
+	streamedRunes := 0
+	seq := 0
+	summary, err := streamer.SummarizeEmailStream(ctx, emailText, func(delta string) {
+		if streamedRunes > maxSummaryRunes {
+			return
+		}
+		streamed.WriteString(delta)
+		streamedRunes += utf8.RuneCountInString(delta)
+		seq++
+		s.sseManager.SendToUser(job.UserID, "summary_delta", map[string]interface{}{
+			"email_id": job.EmailID,
+			"seq":      seq,
+			"delta":    delta,
+		})
+		if streamedRunes > maxSummaryRunes {
+			cancel()
+		}
+	})
+	if err != nil && streamedRunes > maxSummaryRunes {
+		// Canceled on purpose: what was streamed is enough
+		return streamed.String(), trace, nil
+	}
+	return summary, trace, err
 }
 
 // sendSummaryUpdate sends summary update to frontend via SSE
@@ -155,14 +279,62 @@ func (s *SummaryWorkerService) sendSummaryUpdate(userID, emailID, summary string
 	})
 }
 
-// QueueJob adds a single job to the queue (non-blocking)
-func (s *SummaryWorkerService) QueueJob(job SummaryJob) bool {
-	select {
-	case s.jobQueue <- job:
-		return true
-	default:
-		return false // Queue full
+// truncateRunes cuts s to at most max runes without splitting a multi-byte character
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+// truncateSummary cuts a summary to at most max runes (plus an ellipsis).
+// It prefers the last sentence or line end in the second half of the limit,
+// then the last word boundary, and only cuts mid-word as a last resort.
+func truncateSummary(summary string, max int) string {
+	summary = strings.TrimSpace(summary)
+	runes := []rune(summary)
+	if len(runes) <= max {
+		return summary
+	}
+
+	cut := runes[:max]
+	for i := len(cut) - 1; i >= max/2; i-- {
+		switch cut[i] {
+		case '.', '!', '?', '…', '\n':
+			return strings.TrimSpace(string(cut[:i+1]))
+		}
+	}
+	for i := len(cut) - 1; i >= max/2; i-- {
+		if unicode.IsSpace(cut[i]) {
+			return strings.TrimRightFunc(string(cut[:i]), func(r rune) bool {
+				return unicode.IsSpace(r) || unicode.IsPunct(r)
+			}) + "…"
+		}
+	}
+	return string(cut) + "…"
+}
+
+// QueueJob adds a job to the persistent queue. A job already queued for the same email is kept,
+// with its priority raised if this one is higher (an email on screen overtakes a backfill).
+func (s *SummaryWorkerService) QueueJob(job SummaryJob, priority int) bool {
+	// Only the text sent to the model is stored
+	job.Body = truncateRunes(job.Body, maxSummaryInputRunes)
+
+	err := s.queue.Enqueue(SummaryQueue, job, jobqueue.EnqueueOptions{
+		UserID:   job.UserID,
+		Priority: priority,
+		DedupKey: summaryDedupKey(job.UserID, job.EmailID),
+	})
+	if err != nil {
+		log.Printf("[SummaryWorker] Failed to queue email %s: %v", job.EmailID, err)
+		return false
 	}
+	return true
+}
+
+// summaryDedupKey is the dedup key of the summary job of an email (ListStaleSummaries builds it in SQL too)
+func summaryDedupKey(userID, emailID string) string {
+	return userID + "/" + emailID
 }
 
 // QueueEmailsForSummary queues multiple emails for summary generation
@@ -194,7 +366,7 @@ func (s *SummaryWorkerService) QueueEmailsForSummary(userID string, emails []*em
 				Subject: email.Subject,
 				Body:    email.Body,
 			}
-			if s.QueueJob(job) {
+			if s.QueueJob(job, jobqueue.PriorityNormal) {
 				queuedCount++
 			}
 		}
@@ -203,6 +375,81 @@ func (s *SummaryWorkerService) QueueEmailsForSummary(userID string, emails []*em
 	return cachedSummaries, queuedCount, nil
 }
 
+// RefreshStaleSummaries queues up to limit summaries that were not generated by the current
+// models and prompt versions (empty userID = every user). Refreshed summaries keep the prompt
+// language they were generated in. Summaries whose refresh was dead-lettered are skipped until
+// the dead job is retried or purged. Returns the number of jobs queued.
+func (s *SummaryWorkerService) RefreshStaleSummaries(userID string, limit int) (int, error) {
+	source, ok := s.geminiService.(summaryGenerationSource)
+	if !ok || s.emailSource == nil {
+		return 0, ErrSummaryUnavailable
+	}
+
+	current := emaildomain.SummaryGeneration{
+		Models:         source.ProviderModels(),
+		PromptVersions: source.PromptRegistry().ServingVersions(ai.PromptSummary),
+	}
+	stale, err := s.summaryRepo.ListStaleSummaries(userID, current, SummaryQueue, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list stale summaries: %w", err)
+	}
+
+	queued := 0
+	for _, summary := range stale {
+		job := SummaryJob{
+			UserID:   summary.UserID,
+			EmailID:  summary.EmailID,
+			Language: summary.Language,
+			Force:    true,
+			Refresh:  true,
+		}
+		if s.QueueJob(job, jobqueue.PriorityLow) {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// StartStaleRefresh refreshes stale summaries in the background, batch per interval,
+// so a prompt or model change does not flood the AI providers
+func (s *SummaryWorkerService) StartStaleRefresh(interval time.Duration, batch int) {
+	if interval <= 0 || batch <= 0 {
+		log.Println("[SummaryWorker] Stale summary refresh disabled")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshActive {
+		return
+	}
+	s.refreshActive = true
+	s.refreshStop = make(chan struct{}) // Closed by Stop
+	stop := s.refreshStop
+
+	s.refreshWg.Add(1)
+	go func() {
+		defer s.refreshWg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				queued, err := s.RefreshStaleSummaries("", batch)
+				if err != nil {
+					log.Printf("[SummaryWorker] Stale refresh failed: %v", err)
+				} else if queued > 0 {
+					log.Printf("[SummaryWorker] Queued %d stale summaries for refresh", queued)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	log.Printf("[SummaryWorker] Stale summary refresh started (every %v, %d per batch)", interval, batch)
+}
+
 // GetCachedSummaries returns cached summaries for given email IDs
 func (s *SummaryWorkerService) GetCachedSummaries(userID string, emailIDs []string) (map[string]string, error) {
 	return s.summaryRepo.GetSummaries(userID, emailIDs)
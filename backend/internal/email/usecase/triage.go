@@ -0,0 +1,406 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/ai"
+	"ga03-backend/pkg/jobqueue"
+)
+
+var (
+	// ErrTriageUnavailable is returned when no configured AI provider can classify emails
+	ErrTriageUnavailable = errors.New("AI auto-triage is not available")
+	// ErrInvalidTriageSettings is returned for out-of-range thresholds
+	ErrInvalidTriageSettings = errors.New("invalid triage settings")
+	// ErrTriageSuggestionNotFound is returned when a suggestion does not exist or is not the user's
+	ErrTriageSuggestionNotFound = errors.New("triage suggestion not found")
+)
+
+// TriageQueue is the job queue of new inbox emails waiting for AI auto-triage
+const TriageQueue = "triage"
+
+const (
+	triageWorkerCount          = 2
+	triageTimeout              = 30 * time.Second
+	maxTriageEmailRunes        = 1500 // Email body given to the classifier
+	maxTriageSnippetRunes      = 200  // Body snippet stored with a few-shot example
+	triageExamplesPerColumn    = 3    // Few-shot examples per column in the prompt
+	triageExamplesKeptByColumn = 10   // Examples kept per column in the database
+)
+
+// TriageJob is an email waiting to be classified (stored as the job payload; the worker loads the email)
+type TriageJob struct {
+	UserID  string `json:"user_id"`
+	EmailID string `json:"email_id"`
+}
+
+// handleTriageJob classifies a queued email. Failures are retried by the queue; over quota, the
+// job waits for the quota to reset.
+func (u *emailUsecase) handleTriageJob(ctx context.Context, queued *jobqueue.Job) error {
+	var job TriageJob
+	if err := json.Unmarshal([]byte(queued.Payload), &job); err != nil {
+		return jobqueue.Permanent(fmt.Errorf("invalid triage job: %w", err))
+	}
+
+	email, err := u.loadEmail(job.UserID, job.EmailID)
+	if errors.Is(err, emaildomain.ErrEmailNotFound) || (err == nil && email == nil) {
+		return jobqueue.Permanent(fmt.Errorf("email %s not found", job.EmailID))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load email %s: %w", job.EmailID, err)
+	}
+
+	if _, err := u.triageEmail(job.UserID, email, false); err != nil {
+		if errors.Is(err, ErrTriageUnavailable) {
+			return jobqueue.Permanent(err)
+		}
+		var quotaErr *ai.QuotaError
+		if errors.As(err, &quotaErr) {
+			// Over quota with no local provider to fall back to: wait for the quota to reset
+			return jobqueue.RetryAfter(err, time.Until(quotaErr.ResetAt))
+		}
+		return fmt.Errorf("failed to triage email %s: %w", job.EmailID, err)
+	}
+	return nil
+}
+
+// TriageNewEmails queues newly received inbox emails for classification (non-blocking).
+// Only emails received after auto-triage was enabled are considered, so listing an old
+// mailbox does not classify its whole history. An email already queued is not queued twice.
+func (u *emailUsecase) TriageNewEmails(userID string, emails []*emaildomain.Email) {
+	if u.triageRepo == nil || u.jobQueue == nil || len(emails) == 0 {
+		return
+	}
+	if _, ok := u.aiService.(ai.EmailClassifier); !ok {
+		return
+	}
+
+	settings, err := u.triageRepo.GetSettings(userID)
+	if err != nil || settings == nil || !settings.Enabled || settings.EnabledAt == nil {
+		return
+	}
+
+	for _, email := range emails {
+		if email == nil || email.ReceivedAt.Before(*settings.EnabledAt) {
+			continue
+		}
+		err := u.jobQueue.Enqueue(TriageQueue, TriageJob{UserID: userID, EmailID: email.ID}, jobqueue.EnqueueOptions{
+			UserID:   userID,
+			Priority: jobqueue.PriorityNormal,
+			DedupKey: userID + "/" + email.ID,
+		})
+		if err != nil {
+			log.Printf("[Triage] Failed to queue email %s: %v", email.ID, err)
+		}
+	}
+}
+
+// TriageEmail classifies one email on demand, even if it was classified before or
+// auto-triage is disabled. Thresholds from the user's settings still decide whether it is moved.
+func (u *emailUsecase) TriageEmail(userID, emailID string) (*emaildomain.TriageSuggestion, error) {
+	email, err := u.GetEmailByID(userID, emailID)
+	if err != nil || email == nil {
+		return nil, fmt.Errorf("email not found")
+	}
+	return u.triageEmail(userID, email, true)
+}
+
+// triageEmail classifies an email and applies the result: auto-move above the auto-move
+// threshold, pending suggestion above the suggestion threshold, recorded otherwise
+func (u *emailUsecase) triageEmail(userID string, email *emaildomain.Email, force bool) (*emaildomain.TriageSuggestion, error) {
+	classifier, ok := u.aiService.(ai.EmailClassifier)
+	if !ok || u.triageRepo == nil {
+		return nil, ErrTriageUnavailable
+	}
+
+	settings, err := u.GetTriageSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !force {
+		if !settings.Enabled {
+			return nil, nil
+		}
+		existing, err := u.triageRepo.GetSuggestion(userID, email.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return existing, nil
+		}
+		// The user (or a rule) already filed it
+		currentColumn, err := u.emailKanbanColumnRepo.GetEmailColumn(userID, email.ID)
+		if err != nil {
+			return nil, err
+		}
+		if currentColumn != "" && currentColumn != "inbox" {
+			return nil, nil
+		}
+	}
+
+	req, err := u.buildClassifyRequest(userID, email)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(u.withUserPrompt(context.Background(), userID), triageTimeout)
+	defer cancel()
+	result, err := classifier.ClassifyEmail(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify email: %w", err)
+	}
+
+	suggestion := &emaildomain.TriageSuggestion{
+		UserID:     userID,
+		EmailID:    email.ID,
+		ColumnID:   result.ColumnID,
+		Confidence: result.Confidence,
+		Reason:     result.Reason,
+		Subject:    email.Subject,
+		From:       email.From,
+	}
+	if existing, _ := u.triageRepo.GetSuggestion(userID, email.ID); existing != nil {
+		suggestion.ID = existing.ID
+		suggestion.CreatedAt = existing.CreatedAt
+	}
+
+	currentColumn, _ := u.emailKanbanColumnRepo.GetEmailColumn(userID, email.ID)
+	if currentColumn == "" {
+		currentColumn = "inbox"
+	}
+
+	switch {
+	case result.ColumnID == currentColumn:
+		suggestion.Status = emaildomain.TriageStatusKept
+	case result.Confidence >= settings.AutoMoveThreshold:
+		if err := u.moveEmail(userID, email.ID, result.ColumnID, currentColumn); err != nil {
+			return nil, fmt.Errorf("failed to move email: %w", err)
+		}
+		suggestion.Status = emaildomain.TriageStatusAutoMoved
+	case result.Confidence >= settings.SuggestThreshold:
+		suggestion.Status = emaildomain.TriageStatusPending
+	default:
+		suggestion.Status = emaildomain.TriageStatusLowConfidence
+	}
+
+	if err := u.triageRepo.SaveSuggestion(suggestion); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Triage] Email %s -> %s (confidence %.2f, %s)", email.ID, result.ColumnID, result.Confidence, suggestion.Status)
+
+	if u.eventService != nil {
+		switch suggestion.Status {
+		case emaildomain.TriageStatusAutoMoved:
+			u.eventService.SendToUser(userID, "email_update", map[string]interface{}{
+				"action":     "triage_moved",
+				"email_id":   email.ID,
+				"column_id":  suggestion.ColumnID,
+				"confidence": suggestion.Confidence,
+			})
+		case emaildomain.TriageStatusPending:
+			u.eventService.SendToUser(userID, "triage_suggestion", suggestion)
+		}
+	}
+	return suggestion, nil
+}
+
+// buildClassifyRequest gathers the user's columns, few-shot examples and the email
+func (u *emailUsecase) buildClassifyRequest(userID string, email *emaildomain.Email) (ai.ClassifyRequest, error) {
+	var req ai.ClassifyRequest
+
+	columns, err := u.GetKanbanColumns(userID)
+	if err != nil {
+		return req, err
+	}
+	for _, col := range columns {
+		if col.ColumnID == "snoozed" {
+			continue // Snoozing needs a date, it is never a triage target
+		}
+		req.Columns = append(req.Columns, ai.ClassifyColumn{
+			ID:          col.ColumnID,
+			Name:        col.Name,
+			Description: col.Description,
+			Examples:    []string(col.Examples),
+		})
+	}
+
+	examples, err := u.triageRepo.GetExamples(userID, triageExamplesPerColumn)
+	if err != nil {
+		return req, err
+	}
+	for _, ex := range examples {
+		if ex.EmailID == email.ID {
+			continue
+		}
+		req.Examples = append(req.Examples, ai.ClassifyExample{
+			ColumnID: ex.ColumnID,
+			Subject:  ex.Subject,
+			From:     ex.From,
+			Snippet:  ex.Snippet,
+		})
+	}
+
+	req.Email = toDraftMessage(email, maxTriageEmailRunes)
+	return req, nil
+}
+
+// learnFromManualMove resolves the email's suggestion and remembers the move as a few-shot example
+func (u *emailUsecase) learnFromManualMove(userID, emailID, columnID string) {
+	if u.triageRepo == nil || columnID == "snoozed" {
+		return
+	}
+
+	if suggestion, err := u.triageRepo.GetSuggestion(userID, emailID); err == nil && suggestion != nil {
+		var status emaildomain.TriageSuggestionStatus
+		switch suggestion.Status {
+		case emaildomain.TriageStatusPending:
+			status = emaildomain.TriageStatusRejected
+			if suggestion.ColumnID == columnID {
+				status = emaildomain.TriageStatusAccepted
+			}
+		case emaildomain.TriageStatusAutoMoved:
+			if suggestion.ColumnID != columnID {
+				status = emaildomain.TriageStatusOverridden
+			}
+		}
+		if status != "" {
+			if err := u.triageRepo.UpdateSuggestionStatus(suggestion.ID, status); err != nil {
+				log.Printf("[Triage] Failed to resolve suggestion %s: %v", suggestion.ID, err)
+			}
+		}
+	}
+
+	// Examples cost a provider call to fetch the email, only collect them when triage is on
+	settings, err := u.triageRepo.GetSettings(userID)
+	if err != nil || settings == nil || !settings.Enabled {
+		return
+	}
+
+	go func() {
+		email, err := u.GetEmailByID(userID, emailID)
+		if err != nil || email == nil {
+			return
+		}
+		snippet := toDraftMessage(email, maxTriageSnippetRunes).Body
+		example := &emaildomain.TriageExample{
+			UserID:   userID,
+			ColumnID: columnID,
+			EmailID:  emailID,
+			Subject:  email.Subject,
+			From:     email.From,
+			Snippet:  snippet,
+		}
+		if err := u.triageRepo.AddExample(example, triageExamplesKeptByColumn); err != nil {
+			log.Printf("[Triage] Failed to store example for email %s: %v", emailID, err)
+		}
+	}()
+}
+
+// GetTriageSettings returns the user's triage settings, with defaults if never saved
+func (u *emailUsecase) GetTriageSettings(userID string) (*emaildomain.TriageSettings, error) {
+	if u.triageRepo == nil {
+		return nil, ErrTriageUnavailable
+	}
+	settings, err := u.triageRepo.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &emaildomain.TriageSettings{
+			UserID:            userID,
+			AutoMoveThreshold: emaildomain.DefaultTriageAutoMoveThreshold,
+			SuggestThreshold:  emaildomain.DefaultTriageSuggestThreshold,
+		}
+	}
+	return settings, nil
+}
+
+// UpdateTriageSettings enables/disables auto-triage and sets its thresholds
+func (u *emailUsecase) UpdateTriageSettings(userID string, enabled bool, autoMoveThreshold, suggestThreshold float64) (*emaildomain.TriageSettings, error) {
+	if suggestThreshold <= 0 || autoMoveThreshold > 1 || suggestThreshold > autoMoveThreshold {
+		return nil, fmt.Errorf("%w: thresholds must satisfy 0 < suggest_threshold <= auto_move_threshold <= 1", ErrInvalidTriageSettings)
+	}
+
+	settings, err := u.GetTriageSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled && !settings.Enabled {
+		now := time.Now()
+		settings.EnabledAt = &now
+	}
+	settings.Enabled = enabled
+	settings.AutoMoveThreshold = autoMoveThreshold
+	settings.SuggestThreshold = suggestThreshold
+
+	if err := u.triageRepo.SaveSettings(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// ListTriageSuggestions lists the user's suggestions (pending ones by default in the API)
+func (u *emailUsecase) ListTriageSuggestions(userID string, status emaildomain.TriageSuggestionStatus, limit int) ([]*emaildomain.TriageSuggestion, error) {
+	if u.triageRepo == nil {
+		return nil, ErrTriageUnavailable
+	}
+	return u.triageRepo.ListSuggestions(userID, status, limit)
+}
+
+// AcceptTriageSuggestion moves the email to the suggested column (or to columnID if given,
+// which counts as a correction). Either way the move becomes a few-shot example.
+func (u *emailUsecase) AcceptTriageSuggestion(userID, suggestionID, columnID string) (*emaildomain.TriageSuggestion, error) {
+	suggestion, err := u.getTriageSuggestion(userID, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	target := suggestion.ColumnID
+	if columnID != "" {
+		target = columnID
+	}
+	if err := u.MoveEmailToMailbox(userID, suggestion.EmailID, target, ""); err != nil {
+		return nil, err
+	}
+
+	status := emaildomain.TriageStatusAccepted
+	if target != suggestion.ColumnID {
+		status = emaildomain.TriageStatusRejected
+	}
+	if err := u.triageRepo.UpdateSuggestionStatus(suggestion.ID, status); err != nil {
+		return nil, err
+	}
+	return u.triageRepo.GetSuggestionByID(userID, suggestionID)
+}
+
+// RejectTriageSuggestion dismisses a suggestion without moving the email
+func (u *emailUsecase) RejectTriageSuggestion(userID, suggestionID string) error {
+	suggestion, err := u.getTriageSuggestion(userID, suggestionID)
+	if err != nil {
+		return err
+	}
+	return u.triageRepo.UpdateSuggestionStatus(suggestion.ID, emaildomain.TriageStatusRejected)
+}
+
+func (u *emailUsecase) getTriageSuggestion(userID, suggestionID string) (*emaildomain.TriageSuggestion, error) {
+	if u.triageRepo == nil {
+		return nil, ErrTriageUnavailable
+	}
+	suggestion, err := u.triageRepo.GetSuggestionByID(userID, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+	if suggestion == nil {
+		return nil, ErrTriageSuggestionNotFound
+	}
+	return suggestion, nil
+}
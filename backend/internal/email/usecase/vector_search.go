@@ -3,10 +3,15 @@ package usecase
 import (
 	"context"
 	"fmt"
+	authdomain "ga03-backend/internal/auth/domain"
 	emaildomain "ga03-backend/internal/email/domain"
+	"ga03-backend/pkg/ai"
+	"ga03-backend/pkg/embedding"
+	"ga03-backend/pkg/jobqueue"
 	"ga03-backend/pkg/utils/crypto"
 	"log"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -55,6 +60,27 @@ type VectorSearchService interface {
 	SemanticSearch(ctx context.Context, collectionName, userID, query string, limit int) ([]string, []float64, error)
 	AddEmailEmbedding(ctx context.Context, collectionName, emailID, userID, subject, body string) error
 	UpsertEmailEmbedding(ctx context.Context, collectionName, emailID, userID, subject, body string) error
+	// EmbeddingModel returns the model ("<provider>:<model>") and dimension of the vectors written now
+	EmbeddingModel() (model string, dimensions int)
+	// UpsertAttachmentChunks embeds the passages of an attachment, replacing the ones stored before
+	UpsertAttachmentChunks(ctx context.Context, userID, emailID, attachmentID string, chunks []embedding.Chunk) error
+	// SearchAttachmentChunks returns the user's attachment passages closest to the query
+	SearchAttachmentChunks(ctx context.Context, userID, query string, limit int) ([]embedding.ChunkMatch, error)
+	// RelatedEmails returns the user's emails closest to the stored vector of emailID (itself excluded),
+	// without embedding anything; nothing while the email has no vector of the current model
+	RelatedEmails(ctx context.Context, userID, emailID string, limit int) ([]string, []float64, error)
+	// UpsertEmailChunks embeds the passages of a long email body, replacing the ones stored before
+	UpsertEmailChunks(ctx context.Context, userID, emailID string, chunks []embedding.Chunk) error
+	// SearchEmailChunks returns the user's email body passages closest to the query
+	SearchEmailChunks(ctx context.Context, userID, query string, limit int) ([]embedding.ChunkMatch, error)
+}
+
+// defaultDistanceThreshold filters out irrelevant results (Chroma squared L2 distance)
+const defaultDistanceThreshold = 1.2
+
+// distanceThresholder is implemented by vector backends whose distances are not squared L2 (e.g. pgvector cosine)
+type distanceThresholder interface {
+	DistanceThreshold() float64
 }
 
 // SemanticSearch performs semantic search using vector embeddings
@@ -82,7 +108,8 @@ func (u *emailUsecase) SemanticSearch(userID, query string, limit, offset int) (
 	// Generate synonyms to expand query (Conceptual Search)
 	expandedQuery := query
 	if u.aiService != nil {
-		synonyms, err := u.aiService.GenerateSynonyms(context.Background(), query)
+		ctx := ai.WithPromptContext(context.Background(), ai.PromptContext{Language: user.Language, UserID: user.ID})
+		synonyms, err := u.aiService.GenerateSynonyms(ctx, query)
 		if err == nil && len(synonyms) > 0 {
 			// Combine original query with top 10 concepts for better context
 			// e.g. "tiền" -> "tiền invoice salary payment transaction billing..."
@@ -97,50 +124,116 @@ func (u *emailUsecase) SemanticSearch(userID, query string, limit, offset int) (
 		}
 	}
 
-	// Perform semantic search
+	matches, err := u.rankSemantic(userID, expandedQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+	emails, err := u.fetchSemanticPage(user, matches, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return emails, len(matches), nil
+}
+
+// fetchSemanticPage fetches one page of semantic matches, with their highlights
+func (u *emailUsecase) fetchSemanticPage(user *authdomain.User, matches []semanticMatch, limit, offset int) ([]*emaildomain.Email, error) {
+	if offset >= len(matches) {
+		return []*emaildomain.Email{}, nil
+	}
+
+	// Apply offset and limit to the matches
+	endIdx := offset + limit
+	if endIdx > len(matches) {
+		endIdx = len(matches)
+	}
+	page := matches[offset:endIdx]
+	targetIDs := make([]string, len(page))
+	highlights := make(map[string]*emaildomain.SearchHighlight)
+	for i, match := range page {
+		targetIDs[i] = match.emailID
+		if match.highlight != nil {
+			highlights[match.emailID] = match.highlight
+		}
+	}
+
+	finalEmails, err := u.fetchEmailsByIDs(user, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, email := range finalEmails {
+		email.Highlight = highlights[email.ID]
+	}
+	return finalEmails, nil
+}
+
+// rankSemantic returns the user's emails matching a query (email vectors and passages closer than the
+// threshold of the backend), most similar first
+func (u *emailUsecase) rankSemantic(userID, query string) ([]semanticMatch, error) {
 	ctx := context.Background()
 	emailIDs, distances, err := u.vectorSearchService.SemanticSearch(
 		ctx,
 		"emails", // collection name
 		userID,
-		expandedQuery,
+		query,
 		300, // Fetch fixed top 300 (Chroma quota limit) to ensure stable total count
 	)
 	if err != nil {
-		return nil, 0, fmt.Errorf("semantic search failed: %w", err)
+		return nil, fmt.Errorf("semantic search failed: %w", err)
 	}
 
 	// Filter results by distance threshold
-	// Lower distance = more similar. Threshold of 1.2 filters out irrelevant results
-	const distanceThreshold = 1.2
-	var filteredIDs []string
+	// Lower distance = more similar. Threshold of 1.2 (squared L2) filters out irrelevant results
+	distanceThreshold := defaultDistanceThreshold
+	if t, ok := u.vectorSearchService.(distanceThresholder); ok {
+		distanceThreshold = t.DistanceThreshold()
+	}
+	byEmail := make(map[string]*semanticMatch, len(emailIDs))
 	for i, id := range emailIDs {
-		if i < len(distances) {
-			fmt.Printf("[SemanticSearch] Email %s - distance: %.4f\n", id, distances[i])
-			if distances[i] <= distanceThreshold {
-				filteredIDs = append(filteredIDs, id)
-			}
-		} else {
-			// If no distance info, include the result
-			filteredIDs = append(filteredIDs, id)
+		if i >= len(distances) {
+			// No distance info: include the result, ranked as the farthest relevant match (see aggregateDistances)
+			byEmail[id] = &semanticMatch{emailID: id}
+			continue
+		}
+		if distances[i] > distanceThreshold {
+			continue
 		}
+		byEmail[id] = &semanticMatch{emailID: id, distances: []float64{distances[i]}}
 	}
-	emailIDs = filteredIDs
-	fmt.Printf("[SemanticSearch] After filtering: %d results (threshold: %.2f)\n", len(emailIDs), distanceThreshold)
 
-	if len(emailIDs) == 0 {
-		return []*emaildomain.Email{}, 0, nil
+	// Long emails and attachments also match through their passages (the email vector only covers
+	// the beginning of the text); the closest passage is returned as the highlight
+	for _, passage := range u.searchPassages(ctx, userID, query, distanceThreshold) {
+		match, ok := byEmail[passage.EmailID]
+		if !ok {
+			match = &semanticMatch{emailID: passage.EmailID}
+			byEmail[passage.EmailID] = match
+		}
+		match.distances = append(match.distances, passage.Distance)
+		if match.highlight == nil || passage.Distance < match.highlight.Distance {
+			match.highlight = passageHighlight(passage)
+		}
 	}
 
-	// Apply offset and limit to email IDs
-	endIdx := offset + limit
-	if endIdx > len(emailIDs) {
-		endIdx = len(emailIDs)
+	matches := make([]semanticMatch, 0, len(byEmail))
+	for _, match := range byEmail {
+		match.distance = aggregateDistances(match.distances, distanceThreshold)
+		matches = append(matches, *match)
 	}
-	if offset >= len(emailIDs) {
-		return []*emaildomain.Email{}, len(emailIDs), nil
-	}
-	targetIDs := emailIDs[offset:endIdx]
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].emailID < matches[j].emailID
+	})
+
+	return matches, nil
+}
+
+// fetchEmailsByIDs fetches emails from the user's provider in parallel, keeping the order of ids.
+// Emails that cannot be fetched are skipped.
+func (u *emailUsecase) fetchEmailsByIDs(user *authdomain.User, targetIDs []string) ([]*emaildomain.Email, error) {
+	userID := user.ID
+	ctx := context.Background()
 
 	// Fetch email details in parallel for better performance
 	type emailResult struct {
@@ -157,7 +250,7 @@ func (u *emailUsecase) SemanticSearch(userID, query string, limit, offset int) (
 		var decryptErr error
 		decryptedPass, decryptErr = crypto.Decrypt(user.ImapPassword, u.config.EncryptionKey)
 		if decryptErr != nil {
-			return nil, 0, fmt.Errorf("failed to decrypt password: %w", decryptErr)
+			return nil, fmt.Errorf("failed to decrypt password: %w", decryptErr)
 		}
 	} else {
 		accessToken, refreshToken, _ = u.getUserTokens(userID)
@@ -203,7 +296,7 @@ func (u *emailUsecase) SemanticSearch(userID, query string, limit, offset int) (
 		}
 	}
 
-	return finalEmails, len(emailIDs), nil
+	return finalEmails, nil
 }
 
 // StoreEmailEmbedding stores embedding for an email
@@ -224,146 +317,57 @@ func (u *emailUsecase) UpsertEmailEmbedding(ctx context.Context, userID, emailID
 	return u.vectorSearchService.UpsertEmailEmbedding(ctx, "emails", emailID, userID, subject, body)
 }
 
-// GetSearchSuggestions returns suggestions based on sender names and full subjects from inbox
-// This is used for auto-complete, NOT semantic search
-// OPTIMIZED: Uses in-memory cache instead of Gmail API calls for fast response
-// Returns full subject or sender name if it contains ANY word from the query
-func (u *emailUsecase) GetSearchSuggestions(userID, query string, limit int) ([]string, error) {
-	query = strings.TrimSpace(query)
-	if len(query) < 1 {
-		return []string{}, nil
-	}
-
-	if limit <= 0 {
-		limit = 5
-	}
-	if limit > 10 {
-		limit = 10 // Cap at 10 suggestions
-	}
-
-	// OPTIMIZED: Use in-memory cache instead of Gmail API
-	u.suggestionCacheMu.RLock()
-	userCache, exists := u.suggestionCache[userID]
-	u.suggestionCacheMu.RUnlock()
-
-	if !exists || len(userCache) == 0 {
-		// Cache empty - return empty suggestions
-		// Cache will be populated when user loads emails
-		return []string{}, nil
-	}
-
-	// Split query into words (remove empty strings from multiple spaces)
-	queryWords := strings.Fields(query)
-	if len(queryWords) == 0 {
-		return []string{}, nil
-	}
-
-	// Convert all query words to lowercase for matching
-	queryWordsLower := make([]string, len(queryWords))
-	for i, word := range queryWords {
-		queryWordsLower[i] = strings.ToLower(word)
-	}
-
-	// Helper function to check if text contains ANY of the query words
-	containsAnyWord := func(text string, words []string) bool {
-		textLower := strings.ToLower(text)
-		for _, word := range words {
-			if strings.Contains(textLower, word) {
-				return true
-			}
-		}
-		return false
-	}
-
-	// Search through cache for matching suggestions
-	suggestions := make([]string, 0, limit)
-	u.suggestionCacheMu.RLock()
-	for suggestion := range userCache {
-		if containsAnyWord(suggestion, queryWordsLower) {
-			suggestions = append(suggestions, suggestion)
-			if len(suggestions) >= limit {
-				break
-			}
-		}
-	}
-	u.suggestionCacheMu.RUnlock()
-
-	return suggestions, nil
-}
-
 // SyncEmailToVectorDB syncs a single email to vector database asynchronously
 // This is called after fetching emails to ensure they are indexed for semantic search
 // Uses job worker pattern to process sync jobs with controlled concurrency
-// ALSO populates the suggestion cache for fast auto-suggest
+// ALSO records the search suggestions of the email and populates the full-text search index
 func (u *emailUsecase) SyncEmailToVectorDB(userID string, email *emaildomain.Email) {
 	if email == nil {
 		return
 	}
+	u.syncEmails(userID, []*emaildomain.Email{email})
+}
 
-	// Always populate suggestion cache (even if vector search is disabled)
-	u.addToSuggestionCache(userID, email.FromName, email.Subject)
+// syncEmails indexes a page of fetched emails for full-text search in one statement, then records
+// their suggestions and queues their vector sync
+func (u *emailUsecase) syncEmails(userID string, emails []*emaildomain.Email) {
+	u.indexEmailsForSearch(userID, emails)
+	for _, email := range emails {
+		if email != nil {
+			u.queueEmailSync(userID, email)
+		}
+	}
+}
 
-	if u.vectorSearchService == nil {
+// queueEmailSync records the search suggestions of an email and queues its vector and attachment sync
+func (u *emailUsecase) queueEmailSync(userID string, email *emaildomain.Email) {
+	// Always record suggestions (even if vector search is disabled)
+	u.recordEmailSuggestions(userID, email)
+
+	if u.vectorSearchService == nil || u.jobQueue == nil {
 		return
 	}
 
+	// Attachments are downloaded and embedded by their own (low priority) jobs
+	u.queueAttachmentIndexing(userID, email)
+
 	// Skip if email doesn't have subject or body
 	if email.Subject == "" && email.Body == "" {
 		log.Printf("[VectorSync] Email %s has no subject or body, skipping", email.ID)
 		return
 	}
 
-	// Clean HTML from body for better embedding quality
-	cleanedBody := cleanHTMLForEmbedding(email.Body)
-
-	// Enqueue job (non-blocking, skip if queue is full)
+	// Enqueue job (persistent; an email already queued is not queued twice). The worker loads the email.
 	job := EmailSyncJob{
 		UserID:  userID,
 		EmailID: email.ID,
-		Subject: email.Subject,
-		Body:    cleanedBody,
-	}
-
-	select {
-	case u.syncJobQueue <- job:
-		// Job enqueued successfully
-	default:
-		// Queue is full, skip this sync to avoid blocking
-		// Emails will be synced when user fetches them again or queue has space
-	}
-}
-
-// addToSuggestionCache adds FromName and Subject to the suggestion cache
-// This is called when emails are fetched/synced to build up the cache
-func (u *emailUsecase) addToSuggestionCache(userID, fromName, subject string) {
-	u.suggestionCacheMu.Lock()
-	defer u.suggestionCacheMu.Unlock()
-
-	if u.suggestionCache[userID] == nil {
-		u.suggestionCache[userID] = make(map[string]struct{})
-	}
-
-	// Add FromName if not empty and not too long
-	if fromName != "" && len(fromName) <= 100 {
-		u.suggestionCache[userID][fromName] = struct{}{}
-	}
-
-	// Add Subject if not empty and not too long
-	if subject != "" && len(subject) <= 200 {
-		u.suggestionCache[userID][subject] = struct{}{}
 	}
-
-	// Limit cache size per user to prevent memory issues
-	const maxCacheSize = 1000
-	if len(u.suggestionCache[userID]) > maxCacheSize {
-		// Remove oldest entries (since map iteration is random, this is approximate)
-		count := 0
-		for key := range u.suggestionCache[userID] {
-			if count >= maxCacheSize/2 {
-				break
-			}
-			delete(u.suggestionCache[userID], key)
-			count++
-		}
+	err := u.jobQueue.Enqueue(VectorSyncQueue, job, jobqueue.EnqueueOptions{
+		UserID:   userID,
+		Priority: jobqueue.PriorityNormal,
+		DedupKey: userID + "/" + email.ID,
+	})
+	if err != nil {
+		log.Printf("[VectorSync] Failed to queue email %s: %v", email.ID, err)
 	}
 }
@@ -7,11 +7,13 @@ import (
 	"log"
 	"time"
 
+	authdomain "ga03-backend/internal/auth/domain"
 	authrepo "ga03-backend/internal/auth/repository"
 	"ga03-backend/pkg/fcm"
 	"ga03-backend/pkg/gmail"
 	"ga03-backend/pkg/sse"
 
+	emaildomain "ga03-backend/internal/email/domain"
 	"ga03-backend/internal/email/usecase"
 
 
@@ -152,112 +154,151 @@ func (s *Service) handleMessage(ctx context.Context, msg *pubsub.Message) {
 		"timestamp": time.Now(),
 	})
 
-	// Notify user via FCM (Push Notification)
-	if s.fcmClient != nil && s.fcmRepo != nil {
-		go func() {
-			log.Printf("[FCM] Attempting to send push notification to user: %s", user.ID)
-			
-			tokens, err := s.fcmRepo.GetTokensByUserID(user.ID)
-			if err != nil {
-				log.Printf("[FCM] Error getting FCM tokens for user %s: %v", user.ID, err)
-				return
-			}
-			
-			log.Printf("[FCM] Found %d tokens for user %s", len(tokens), user.ID)
-			
-			if len(tokens) > 0 {
-				var tokenStrings []string
-				for _, t := range tokens {
-					tokenStrings = append(tokenStrings, t.Token)
-				}
-				
-				// Try to get latest email details for better notification
-				title := "Email mới"
-				body := "Bạn có email mới trong hộp thư đến"
-				messageID := ""
-				
-				// Fetch latest email from inbox to get subject and sender
-				if s.gmailService != nil && user.AccessToken != "" {
-					// Token refresh callback
-					onTokenRefresh := func(newToken *oauth2.Token) error {
-						user.AccessToken = newToken.AccessToken
-						user.RefreshToken = newToken.RefreshToken
-						return s.userRepo.Update(user)
-					}
-					
-					emails, _, err := s.gmailService.GetEmails(
-						context.Background(),
-						user.AccessToken,
-						user.RefreshToken,
-						"INBOX",
-						1, // Only need latest email
-						0,
-						"", // No additional query
-						onTokenRefresh,
-					)
-					
-					if err == nil && len(emails) > 0 {
-						latestEmail := emails[0]
-						messageID = latestEmail.ID
-						// Use sender name and subject for notification
-						senderName := latestEmail.FromName
-						if senderName == "" {
-							senderName = latestEmail.From
-						}
-						// Truncate subject if too long
-						subject := latestEmail.Subject
-						if len(subject) > 100 {
-							subject = subject[:97] + "..."
-						}
-						
-						title = fmt.Sprintf("Email từ %s", senderName)
-						body = subject
-						if body == "" {
-							body = "(Không có tiêu đề)"
-						}
-						log.Printf("[FCM] Got email details - From: %s, Subject: %s, ID: %s", senderName, subject, messageID)
-
-						// SYNC TO CHROME DB: Ensure this new email is indexed for semantic search
-						if s.emailUsecase != nil {
-							go s.emailUsecase.SyncEmailToVectorDB(user.ID, latestEmail)
-							log.Printf("[PubSub] Triggered async vector sync for email %s", messageID)
-						}
-					} else {
-						log.Printf("[FCM] Could not fetch email details (using generic message): %v", err)
-					}
-				}
-				
-				failedTokens, err := s.fcmClient.SendToDevices(context.Background(), tokenStrings, fcm.NotificationData{
-					Title: title,
-					Body:  body,
-					Data: map[string]string{
-						"type":         "email_update",
-						"email":        notification.EmailAddress,
-						"historyId":    fmt.Sprintf("%d", notification.HistoryID),
-						"messageId":    messageID,
-						"click_action": s.buildEmailClickAction(messageID),
-					},
-				})
-				
-				if err != nil {
-					log.Printf("[FCM] Error sending notifications: %v", err)
-				} else {
-					log.Printf("[FCM] Successfully sent to %d devices", len(tokens)-len(failedTokens))
-				}
-				
-				// Cleanup failed tokens
-				if len(failedTokens) > 0 {
-					log.Printf("[FCM] Cleaning up %d failed tokens", len(failedTokens))
-					for _, token := range failedTokens {
-						s.fcmRepo.DeleteToken(token)
-					}
-				}
-			} else {
-				log.Printf("[FCM] No tokens found for user %s, skipping push notification", user.ID)
-			}
-		}()
-	} else {
+	// Process the new email and send the push notification in the background: the email is indexed,
+	// triaged and matched against saved searches even when the user has no device
+	go s.handleNewEmail(user, notification)
+}
+
+// handleNewEmail fetches the latest inbox email of a notification, runs the processing of new emails
+// (vector sync, follow-up replies, auto-triage, saved search alerts) and sends the push notification,
+// which names the first saved search the email matches
+func (s *Service) handleNewEmail(user *authdomain.User, notification GmailNotification) {
+	latestEmail := s.fetchLatestEmail(user)
+
+	var matches []*emaildomain.SavedSearch
+	if latestEmail != nil && s.emailUsecase != nil {
+		// SYNC TO CHROME DB: Ensure this new email is indexed for semantic search
+		go s.emailUsecase.SyncEmailToVectorDB(user.ID, latestEmail)
+		log.Printf("[PubSub] Triggered async vector sync for email %s", latestEmail.ID)
+
+		// Close follow-up reminders this email replies to
+		go s.emailUsecase.DetectFollowUpReply(user.ID, latestEmail)
+
+		// AI auto-triage into Kanban columns (no-op unless enabled by the user)
+		s.emailUsecase.TriageNewEmails(user.ID, []*emaildomain.Email{latestEmail})
+
+		// Saved searches with alerts: SSE "saved_search_match" events, and the push names the search
+		matches = s.emailUsecase.NotifySavedSearches(user.ID, latestEmail)
+	}
+
+	if s.fcmClient == nil || s.fcmRepo == nil {
 		log.Printf("[FCM] FCM client or repo not available (client=%v, repo=%v)", s.fcmClient != nil, s.fcmRepo != nil)
+		return
+	}
+	s.sendPushNotification(user, notification, latestEmail, matches)
+}
+
+// fetchLatestEmail returns the latest inbox email of a Gmail user (nil when it cannot be fetched)
+func (s *Service) fetchLatestEmail(user *authdomain.User) *emaildomain.Email {
+	if s.gmailService == nil || user.AccessToken == "" {
+		return nil
+	}
+
+	// Token refresh callback
+	onTokenRefresh := func(newToken *oauth2.Token) error {
+		user.AccessToken = newToken.AccessToken
+		user.RefreshToken = newToken.RefreshToken
+		return s.userRepo.Update(user)
+	}
+
+	emails, _, err := s.gmailService.GetEmails(
+		context.Background(),
+		user.AccessToken,
+		user.RefreshToken,
+		"INBOX",
+		1, // Only need latest email
+		0,
+		"", // No additional query
+		onTokenRefresh,
+	)
+	if err != nil || len(emails) == 0 {
+		log.Printf("[FCM] Could not fetch email details (using generic message): %v", err)
+		return nil
+	}
+	return emails[0]
+}
+
+// sendPushNotification notifies the devices of a user via FCM (Push Notification)
+func (s *Service) sendPushNotification(user *authdomain.User, notification GmailNotification, latestEmail *emaildomain.Email, matches []*emaildomain.SavedSearch) {
+	log.Printf("[FCM] Attempting to send push notification to user: %s", user.ID)
+
+	tokens, err := s.fcmRepo.GetTokensByUserID(user.ID)
+	if err != nil {
+		log.Printf("[FCM] Error getting FCM tokens for user %s: %v", user.ID, err)
+		return
+	}
+
+	log.Printf("[FCM] Found %d tokens for user %s", len(tokens), user.ID)
+
+	if len(tokens) == 0 {
+		log.Printf("[FCM] No tokens found for user %s, skipping push notification", user.ID)
+		return
+	}
+
+	var tokenStrings []string
+	for _, t := range tokens {
+		tokenStrings = append(tokenStrings, t.Token)
+	}
+
+	// Use the latest email details for a better notification
+	title := "Email mới"
+	body := "Bạn có email mới trong hộp thư đến"
+	messageID := ""
+	savedSearchID := ""
+
+	if latestEmail != nil {
+		messageID = latestEmail.ID
+		// Use sender name and subject for notification
+		senderName := latestEmail.FromName
+		if senderName == "" {
+			senderName = latestEmail.From
+		}
+		// Truncate subject if too long
+		subject := latestEmail.Subject
+		if len(subject) > 100 {
+			subject = subject[:97] + "..."
+		}
+
+		title = fmt.Sprintf("Email từ %s", senderName)
+		body = subject
+		if body == "" {
+			body = "(Không có tiêu đề)"
+		}
+		if len(matches) > 0 {
+			savedSearchID = matches[0].ID
+			title = fmt.Sprintf("%s: email từ %s", matches[0].Name, senderName)
+		}
+		log.Printf("[FCM] Got email details - From: %s, Subject: %s, ID: %s", senderName, subject, messageID)
+	}
+
+	notificationData := fcm.NotificationData{
+		Title: title,
+		Body:  body,
+		Data: map[string]string{
+			"type":         "email_update",
+			"email":        notification.EmailAddress,
+			"historyId":    fmt.Sprintf("%d", notification.HistoryID),
+			"messageId":    messageID,
+			"click_action": s.buildEmailClickAction(messageID),
+		},
+	}
+	if savedSearchID != "" {
+		notificationData.Data["saved_search_id"] = savedSearchID
+	}
+
+	failedTokens, err := s.fcmClient.SendToDevices(context.Background(), tokenStrings, notificationData)
+	if err != nil {
+		log.Printf("[FCM] Error sending notifications: %v", err)
+	} else {
+		log.Printf("[FCM] Successfully sent to %d devices", len(tokens)-len(failedTokens))
+	}
+
+	// Cleanup failed tokens
+	if len(failedTokens) > 0 {
+		log.Printf("[FCM] Cleaning up %d failed tokens", len(failedTokens))
+		for _, token := range failedTokens {
+			s.fcmRepo.DeleteToken(token)
+		}
 	}
 }
 
@@ -0,0 +1,171 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	authdomain "ga03-backend/internal/auth/domain"
+	"ga03-backend/internal/prompt/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultExperimentDays    = 14
+	maxExperimentDays        = 365
+	defaultExperimentSamples = 5
+	maxExperimentSamples     = 50
+)
+
+// PromptHandler handles the admin API of the AI prompt templates
+type PromptHandler struct {
+	promptUsecase usecase.PromptUsecase
+}
+
+// NewPromptHandler creates a new PromptHandler (its routes are guarded by the admin middleware of the router)
+func NewPromptHandler(promptUsecase usecase.PromptUsecase) *PromptHandler {
+	return &PromptHandler{
+		promptUsecase: promptUsecase,
+	}
+}
+
+// CreatePromptRequest represents the request body for creating a prompt version
+type CreatePromptRequest struct {
+	Language    string `json:"language" binding:"required"`
+	Body        string `json:"body" binding:"required"`
+	Description string `json:"description"`
+	Activate    bool   `json:"activate"` // Serve it to all traffic right away
+}
+
+// ActivatePromptRequest sets the traffic share of each version ("0" is the built-in template).
+// Example: {"language": "vi", "weights": {"0": 50, "3": 50}}
+type ActivatePromptRequest struct {
+	Language string         `json:"language" binding:"required"`
+	Weights  map[string]int `json:"weights"`
+}
+
+// PreviewPromptRequest represents the request body for rendering a template with sample data
+type PreviewPromptRequest struct {
+	Name string `json:"name" binding:"required"`
+	Body string `json:"body" binding:"required"`
+}
+
+// promptErrorStatus maps prompt usecase errors to HTTP status codes
+func promptErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidPrompt):
+		return http.StatusBadRequest
+	case errors.Is(err, usecase.ErrPromptVersionNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListPrompts returns built-in and stored versions with their traffic share
+// GET /api/settings/ai/prompts?name=summary&language=vi
+func (h *PromptHandler) ListPrompts(c *gin.Context) {
+	prompts, err := h.promptUsecase.ListPrompts(c.Query("name"), c.Query("language"))
+	if err != nil {
+		c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"prompts": prompts})
+}
+
+// CreatePrompt stores a new version of a prompt
+// POST /api/settings/ai/prompts/:name
+func (h *PromptHandler) CreatePrompt(c *gin.Context) {
+	var req CreatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	createdBy := ""
+	if user, exists := c.Get("user"); exists {
+		if userData, ok := user.(*authdomain.User); ok {
+			createdBy = userData.Email
+		}
+	}
+
+	prompt, err := h.promptUsecase.CreatePromptVersion(c.Param("name"), req.Language, req.Body, req.Description, createdBy, req.Activate)
+	if err != nil {
+		c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"prompt": prompt})
+}
+
+// ActivatePrompt sets which versions serve a prompt; several versions run an A/B test
+// PUT /api/settings/ai/prompts/:name/active
+func (h *PromptHandler) ActivatePrompt(c *gin.Context) {
+	var req ActivatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	weights := make(map[int]int, len(req.Weights))
+	for key, weight := range req.Weights {
+		version, err := strconv.Atoi(key)
+		if err != nil || version < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version: " + key})
+			return
+		}
+		weights[version] = weight
+	}
+
+	activations, err := h.promptUsecase.ActivatePrompt(c.Param("name"), req.Language, weights)
+	if err != nil {
+		c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"active": activations})
+}
+
+// PreviewPrompt renders a template with sample data, to check it before saving
+// POST /api/settings/ai/prompts/preview
+func (h *PromptHandler) PreviewPrompt(c *gin.Context) {
+	var req PreviewPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := h.promptUsecase.PreviewPrompt(req.Name, req.Body)
+	if err != nil {
+		c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"prompt": output})
+}
+
+// GetExperiment compares the versions of a prompt on their stored runs
+// GET /api/settings/ai/prompts/:name/experiment?language=vi&days=14&samples=5
+func (h *PromptHandler) GetExperiment(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultExperimentDays)))
+	if err != nil || days <= 0 {
+		days = defaultExperimentDays
+	}
+	if days > maxExperimentDays {
+		days = maxExperimentDays
+	}
+	samples, err := strconv.Atoi(c.DefaultQuery("samples", strconv.Itoa(defaultExperimentSamples)))
+	if err != nil || samples < 0 {
+		samples = defaultExperimentSamples
+	}
+	if samples > maxExperimentSamples {
+		samples = maxExperimentSamples
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	report, err := h.promptUsecase.GetExperimentReport(c.Param("name"), c.Query("language"), since, samples)
+	if err != nil {
+		c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"experiment": report})
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "time"
+
+// PromptTemplate is a version of an AI prompt written through the admin API (text/template syntax).
+// Built-in templates are not stored: they are version 0 of every prompt and language.
+type PromptTemplate struct {
+	ID          string    `json:"id,omitempty" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex:idx_prompt_template_version;not null"`
+	Language    string    `json:"language" gorm:"uniqueIndex:idx_prompt_template_version;not null"`
+	Version     int       `json:"version" gorm:"uniqueIndex:idx_prompt_template_version;not null"`
+	Body        string    `json:"body" gorm:"type:text;not null"`
+	Description string    `json:"description,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"` // Email of the admin who wrote it
+	CreatedAt   time.Time `json:"created_at"`
+
+	Builtin bool `json:"builtin" gorm:"-"`
+	Weight  int  `json:"weight" gorm:"-"` // Current traffic share (0 = inactive)
+}
+
+// PromptActivation is the traffic share of a version serving a prompt. Several activations of
+// the same prompt and language form an A/B test; version 0 is the built-in template.
+// A prompt without activations uses its built-in template.
+type PromptActivation struct {
+	Name      string    `json:"name" gorm:"primaryKey"`
+	Language  string    `json:"language" gorm:"primaryKey"`
+	Version   int       `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Weight    int       `json:"weight" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PromptRun is the stored outcome of an AI operation rendered with an A/B-tested version
+type PromptRun struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"index:idx_prompt_run_version;not null"`
+	Language  string    `json:"language" gorm:"index:idx_prompt_run_version;not null"`
+	Version   int       `json:"version" gorm:"index:idx_prompt_run_version"`
+	UserID    string    `json:"user_id" gorm:"index"`
+	Output    string    `json:"output,omitempty" gorm:"type:text"`
+	Error     string    `json:"error,omitempty" gorm:"type:text"`
+	LatencyMs int64     `json:"latency_ms"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// PromptVersionStats aggregates the stored runs of one version
+type PromptVersionStats struct {
+	Version         int          `json:"version"`
+	Weight          int          `json:"weight"` // Current traffic share (0 = no longer active)
+	Runs            int64        `json:"runs"`
+	Errors          int64        `json:"errors"`
+	ErrorRate       float64      `json:"error_rate"`
+	AvgLatencyMs    float64      `json:"avg_latency_ms"`
+	AvgOutputLength float64      `json:"avg_output_length"` // Characters, successful runs only
+	Samples         []*PromptRun `json:"samples,omitempty"` // Most recent runs, to compare outputs side by side
+}
+
+// PromptExperimentReport compares the versions of a prompt on their stored runs
+type PromptExperimentReport struct {
+	Name     string                `json:"name"`
+	Language string                `json:"language"`
+	Since    time.Time             `json:"since"`
+	Versions []*PromptVersionStats `json:"versions"`
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"time"
+
+	promptdomain "ga03-backend/internal/prompt/domain"
+)
+
+// PromptRepository stores prompt versions, their activations and A/B test runs
+type PromptRepository interface {
+	// ListTemplates lists stored versions, newest first (empty name/language = all)
+	ListTemplates(name, language string) ([]*promptdomain.PromptTemplate, error)
+	// GetTemplate returns one version, or nil if it does not exist
+	GetTemplate(name, language string, version int) (*promptdomain.PromptTemplate, error)
+	// CreateTemplate stores a template as the next version of its prompt and language
+	CreateTemplate(template *promptdomain.PromptTemplate) error
+
+	// ListActivations returns the active versions of every prompt
+	ListActivations() ([]*promptdomain.PromptActivation, error)
+	// SetActivations replaces the active versions of a prompt in a language (empty = back to built-in)
+	SetActivations(name, language string, activations []*promptdomain.PromptActivation) error
+
+	// SaveRun stores the outcome of an A/B-tested operation
+	SaveRun(run *promptdomain.PromptRun) error
+	// GetRunStats aggregates runs per version since a date
+	GetRunStats(name, language string, since time.Time) ([]*promptdomain.PromptVersionStats, error)
+	// GetRecentRuns returns the latest runs of a version
+	GetRecentRuns(name, language string, version int, since time.Time, limit int) ([]*promptdomain.PromptRun, error)
+	// DeleteRunsBefore removes runs older than a date
+	DeleteRunsBefore(before time.Time) error
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	promptdomain "ga03-backend/internal/prompt/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type promptRepository struct {
+	db *gorm.DB
+}
+
+// NewPromptRepository creates a new prompt repository
+func NewPromptRepository(db *gorm.DB) PromptRepository {
+	return &promptRepository{db: db}
+}
+
+func (r *promptRepository) ListTemplates(name, language string) ([]*promptdomain.PromptTemplate, error) {
+	var templates []*promptdomain.PromptTemplate
+	query := r.db.Model(&promptdomain.PromptTemplate{})
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+	if err := query.Order("name, language, version DESC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *promptRepository) GetTemplate(name, language string, version int) (*promptdomain.PromptTemplate, error) {
+	var template promptdomain.PromptTemplate
+	err := r.db.Where("name = ? AND language = ? AND version = ?", name, language, version).First(&template).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// CreateTemplate numbers the version inside a transaction; the unique index rejects a concurrent duplicate
+func (r *promptRepository) CreateTemplate(template *promptdomain.PromptTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		err := tx.Model(&promptdomain.PromptTemplate{}).
+			Where("name = ? AND language = ?", template.Name, template.Language).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+
+		if template.ID == "" {
+			template.ID = uuid.New().String()
+		}
+		template.Version = latest + 1
+		return tx.Create(template).Error
+	})
+}
+
+func (r *promptRepository) ListActivations() ([]*promptdomain.PromptActivation, error) {
+	var activations []*promptdomain.PromptActivation
+	if err := r.db.Order("name, language, version").Find(&activations).Error; err != nil {
+		return nil, err
+	}
+	return activations, nil
+}
+
+func (r *promptRepository) SetActivations(name, language string, activations []*promptdomain.PromptActivation) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("name = ? AND language = ?", name, language).Delete(&promptdomain.PromptActivation{}).Error; err != nil {
+			return err
+		}
+		if len(activations) == 0 {
+			return nil
+		}
+		return tx.Create(&activations).Error
+	})
+}
+
+func (r *promptRepository) SaveRun(run *promptdomain.PromptRun) error {
+	if run.ID == "" {
+		run.ID = uuid.New().String()
+	}
+	return r.db.Create(run).Error
+}
+
+func (r *promptRepository) GetRunStats(name, language string, since time.Time) ([]*promptdomain.PromptVersionStats, error) {
+	var rows []*promptdomain.PromptVersionStats
+	err := r.db.Raw(`
+		SELECT version,
+		       COUNT(*) AS runs,
+		       COUNT(*) FILTER (WHERE error <> '') AS errors,
+		       COALESCE(AVG(latency_ms), 0) AS avg_latency_ms,
+		       COALESCE(AVG(CHAR_LENGTH(output)) FILTER (WHERE error = ''), 0) AS avg_output_length
+		FROM prompt_runs
+		WHERE name = @name AND language = @language AND created_at >= @since
+		GROUP BY version
+		ORDER BY version`,
+		map[string]interface{}{"name": name, "language": language, "since": since},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *promptRepository) GetRecentRuns(name, language string, version int, since time.Time, limit int) ([]*promptdomain.PromptRun, error) {
+	var runs []*promptdomain.PromptRun
+	err := r.db.Where("name = ? AND language = ? AND version = ? AND created_at >= ?", name, language, version, since).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (r *promptRepository) DeleteRunsBefore(before time.Time) error {
+	return r.db.Where("created_at < ?", before).Delete(&promptdomain.PromptRun{}).Error
+}
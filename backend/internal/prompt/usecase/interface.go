@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"errors"
+	"time"
+
+	promptdomain "ga03-backend/internal/prompt/domain"
+	"ga03-backend/pkg/ai"
+)
+
+var (
+	// ErrInvalidPrompt is returned when a prompt name, language, template or weight is invalid
+	ErrInvalidPrompt = errors.New("invalid prompt")
+	// ErrPromptVersionNotFound is returned when activating a version that does not exist
+	ErrPromptVersionNotFound = errors.New("prompt version not found")
+)
+
+// PromptUsecase manages the versions of the AI prompts and their A/B tests.
+// It implements ai.PromptRunRecorder to store the outcome of A/B-tested operations.
+type PromptUsecase interface {
+	// ListPrompts lists built-in (version 0) and stored versions with their current weight
+	ListPrompts(name, language string) ([]*promptdomain.PromptTemplate, error)
+	// CreatePromptVersion validates and stores a new version; if activate is set it replaces the active versions
+	CreatePromptVersion(name, language, body, description, createdBy string, activate bool) (*promptdomain.PromptTemplate, error)
+	// ActivatePrompt sets the traffic share of each version (version => weight); two or more versions form an A/B test.
+	// An empty map makes the prompt use its built-in template again.
+	ActivatePrompt(name, language string, weights map[int]int) ([]*promptdomain.PromptActivation, error)
+	// PreviewPrompt renders a template body with sample data
+	PreviewPrompt(name, body string) (string, error)
+	// GetExperimentReport compares the versions of a prompt on the runs stored since a date
+	GetExperimentReport(name, language string, since time.Time, samples int) (*promptdomain.PromptExperimentReport, error)
+
+	// Reload loads the active versions from the database into the registry
+	Reload() error
+	// Start reloads the registry periodically, so changes made by other instances are picked up
+	Start(interval time.Duration)
+	// Stop stops the periodic reload
+	Stop()
+
+	RecordPromptRun(run ai.PromptRun)
+}
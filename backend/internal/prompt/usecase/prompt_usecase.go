@@ -0,0 +1,383 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	promptdomain "ga03-backend/internal/prompt/domain"
+	"ga03-backend/internal/prompt/repository"
+	"ga03-backend/pkg/ai"
+)
+
+const (
+	// maxPromptLanguageLength bounds language tags ("vi", "en", "pt-br"...)
+	maxPromptLanguageLength = 16
+	// maxPromptRunOutput bounds the output stored per run (runes)
+	maxPromptRunOutput = 8000
+	// promptRunRetention is how long A/B test runs are kept
+	promptRunRetention = 90 * 24 * time.Hour
+	// defaultPromptReloadInterval is used when the configured interval is not positive
+	defaultPromptReloadInterval = time.Minute
+)
+
+type promptUsecase struct {
+	repo     repository.PromptRepository
+	registry *ai.PromptRegistry
+	stopChan chan struct{}
+}
+
+// NewPromptUsecase creates a prompt usecase that keeps the registry in sync with the database
+func NewPromptUsecase(repo repository.PromptRepository, registry *ai.PromptRegistry) PromptUsecase {
+	return &promptUsecase{
+		repo:     repo,
+		registry: registry,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (u *promptUsecase) ListPrompts(name, language string) ([]*promptdomain.PromptTemplate, error) {
+	if name != "" && !ai.IsPromptName(ai.PromptName(name)) {
+		return nil, fmt.Errorf("%w: unknown prompt %q", ErrInvalidPrompt, name)
+	}
+	language = normalizeLanguage(language)
+
+	weights, err := u.activeWeights()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*promptdomain.PromptTemplate
+	for _, builtin := range u.registry.Builtins() {
+		if (name != "" && string(builtin.Name) != name) || (language != "" && builtin.Language != language) {
+			continue
+		}
+		result = append(result, &promptdomain.PromptTemplate{
+			Name:     string(builtin.Name),
+			Language: builtin.Language,
+			Version:  ai.BuiltinPromptVersion,
+			Body:     builtin.Body,
+			Builtin:  true,
+			Weight:   weights.builtinWeight(string(builtin.Name), builtin.Language),
+		})
+	}
+
+	stored, err := u.repo.ListTemplates(name, language)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range stored {
+		t.Weight = weights[activationKey{t.Name, t.Language, t.Version}]
+		result = append(result, t)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		if result[i].Language != result[j].Language {
+			return result[i].Language < result[j].Language
+		}
+		return result[i].Version > result[j].Version
+	})
+	return result, nil
+}
+
+func (u *promptUsecase) CreatePromptVersion(name, language, body, description, createdBy string, activate bool) (*promptdomain.PromptTemplate, error) {
+	language, err := validatePrompt(name, language)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidPrompt)
+	}
+	if _, err := ai.PreviewPrompt(ai.PromptName(name), body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrompt, err)
+	}
+
+	template := &promptdomain.PromptTemplate{
+		Name:        name,
+		Language:    language,
+		Body:        body,
+		Description: strings.TrimSpace(description),
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+	}
+	if err := u.repo.CreateTemplate(template); err != nil {
+		return nil, err
+	}
+
+	if activate {
+		if _, err := u.ActivatePrompt(name, language, map[int]int{template.Version: 100}); err != nil {
+			return nil, err
+		}
+		template.Weight = 100
+	}
+	return template, nil
+}
+
+func (u *promptUsecase) ActivatePrompt(name, language string, weights map[int]int) ([]*promptdomain.PromptActivation, error) {
+	language, err := validatePrompt(name, language)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	activations := make([]*promptdomain.PromptActivation, 0, len(weights))
+	for version, weight := range weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("%w: weight of version %d must not be negative", ErrInvalidPrompt, version)
+		}
+		if weight == 0 {
+			continue
+		}
+
+		if version == ai.BuiltinPromptVersion {
+			if _, ok := u.registry.Builtin(ai.PromptName(name), language); !ok {
+				return nil, fmt.Errorf("%w: no built-in %s prompt for language %q", ErrPromptVersionNotFound, name, language)
+			}
+		} else {
+			template, err := u.repo.GetTemplate(name, language, version)
+			if err != nil {
+				return nil, err
+			}
+			if template == nil {
+				return nil, fmt.Errorf("%w: %s/%s v%d", ErrPromptVersionNotFound, name, language, version)
+			}
+		}
+
+		activations = append(activations, &promptdomain.PromptActivation{
+			Name:      name,
+			Language:  language,
+			Version:   version,
+			Weight:    weight,
+			UpdatedAt: now,
+		})
+	}
+	sort.Slice(activations, func(i, j int) bool { return activations[i].Version < activations[j].Version })
+
+	if err := u.repo.SetActivations(name, language, activations); err != nil {
+		return nil, err
+	}
+	if err := u.Reload(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Prompts] Activated %s/%s: %v", name, language, weights)
+	return activations, nil
+}
+
+func (u *promptUsecase) PreviewPrompt(name, body string) (string, error) {
+	if !ai.IsPromptName(ai.PromptName(name)) {
+		return "", fmt.Errorf("%w: unknown prompt %q", ErrInvalidPrompt, name)
+	}
+	output, err := ai.PreviewPrompt(ai.PromptName(name), body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPrompt, err)
+	}
+	return output, nil
+}
+
+func (u *promptUsecase) GetExperimentReport(name, language string, since time.Time, samples int) (*promptdomain.PromptExperimentReport, error) {
+	language, err := validatePrompt(name, language)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := u.repo.GetRunStats(name, language, since)
+	if err != nil {
+		return nil, err
+	}
+	weights, err := u.activeWeights()
+	if err != nil {
+		return nil, err
+	}
+
+	// Active versions without runs yet are listed too, so the report shows the whole experiment
+	byVersion := make(map[int]*promptdomain.PromptVersionStats, len(stats))
+	for _, s := range stats {
+		byVersion[s.Version] = s
+	}
+	for key, weight := range weights {
+		if key.name != name || key.language != language || weight == 0 {
+			continue
+		}
+		if _, ok := byVersion[key.version]; !ok {
+			s := &promptdomain.PromptVersionStats{Version: key.version}
+			byVersion[key.version] = s
+			stats = append(stats, s)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Version < stats[j].Version })
+
+	for _, s := range stats {
+		s.Weight = weights[activationKey{name, language, s.Version}]
+		if s.Runs > 0 {
+			s.ErrorRate = float64(s.Errors) / float64(s.Runs)
+		}
+		if samples > 0 && s.Runs > 0 {
+			runs, err := u.repo.GetRecentRuns(name, language, s.Version, since, samples)
+			if err != nil {
+				return nil, err
+			}
+			s.Samples = runs
+		}
+	}
+
+	return &promptdomain.PromptExperimentReport{
+		Name:     name,
+		Language: language,
+		Since:    since,
+		Versions: stats,
+	}, nil
+}
+
+func (u *promptUsecase) Reload() error {
+	activations, err := u.repo.ListActivations()
+	if err != nil {
+		return err
+	}
+
+	templates := make([]ai.PromptTemplate, 0, len(activations))
+	for _, a := range activations {
+		t := ai.PromptTemplate{
+			Name:     ai.PromptName(a.Name),
+			Language: a.Language,
+			Version:  a.Version,
+			Weight:   a.Weight,
+		}
+		if a.Version != ai.BuiltinPromptVersion {
+			stored, err := u.repo.GetTemplate(a.Name, a.Language, a.Version)
+			if err != nil {
+				return err
+			}
+			if stored == nil {
+				log.Printf("[Prompts] Active version %s/%s v%d no longer exists, skipping", a.Name, a.Language, a.Version)
+				continue
+			}
+			t.Body = stored.Body
+		}
+		templates = append(templates, t)
+	}
+	return u.registry.SetActive(templates)
+}
+
+func (u *promptUsecase) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultPromptReloadInterval
+	}
+	if err := u.Reload(); err != nil {
+		log.Printf("[Prompts] Failed to load active prompt versions: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		lastPrune := time.Time{}
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := u.Reload(); err != nil {
+					log.Printf("[Prompts] Failed to reload active prompt versions: %v", err)
+				}
+				if time.Since(lastPrune) >= 24*time.Hour {
+					if err := u.repo.DeleteRunsBefore(time.Now().Add(-promptRunRetention)); err != nil {
+						log.Printf("[Prompts] Failed to prune prompt runs: %v", err)
+					}
+					lastPrune = time.Now()
+				}
+			case <-u.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (u *promptUsecase) Stop() {
+	close(u.stopChan)
+}
+
+// RecordPromptRun stores a run in the background so AI calls are not slowed down by the database
+func (u *promptUsecase) RecordPromptRun(run ai.PromptRun) {
+	record := &promptdomain.PromptRun{
+		Name:      string(run.Name),
+		Language:  run.Language,
+		Version:   run.Version,
+		UserID:    run.UserID,
+		Output:    truncateRunes(run.Output, maxPromptRunOutput),
+		Error:     run.Error,
+		LatencyMs: run.Latency.Milliseconds(),
+		CreatedAt: time.Now(),
+	}
+	go func() {
+		if err := u.repo.SaveRun(record); err != nil {
+			log.Printf("[Prompts] Failed to save %s run: %v", record.Name, err)
+		}
+	}()
+}
+
+type activationKey struct {
+	name     string
+	language string
+	version  int
+}
+
+type activationWeights map[activationKey]int
+
+// builtinWeight is the weight of the built-in version: 100 when the prompt has no activations
+func (w activationWeights) builtinWeight(name, language string) int {
+	for key, weight := range w {
+		if key.name == name && key.language == language && weight > 0 {
+			return w[activationKey{name, language, ai.BuiltinPromptVersion}]
+		}
+	}
+	return 100
+}
+
+func (u *promptUsecase) activeWeights() (activationWeights, error) {
+	activations, err := u.repo.ListActivations()
+	if err != nil {
+		return nil, err
+	}
+	weights := make(activationWeights, len(activations))
+	for _, a := range activations {
+		weights[activationKey{a.Name, a.Language, a.Version}] = a.Weight
+	}
+	return weights, nil
+}
+
+// validatePrompt checks the prompt name and returns the normalized language
+func validatePrompt(name, language string) (string, error) {
+	if !ai.IsPromptName(ai.PromptName(name)) {
+		return "", fmt.Errorf("%w: unknown prompt %q", ErrInvalidPrompt, name)
+	}
+	language = normalizeLanguage(language)
+	if language == "" {
+		return "", fmt.Errorf("%w: language is required", ErrInvalidPrompt)
+	}
+	if len(language) > maxPromptLanguageLength {
+		return "", fmt.Errorf("%w: invalid language %q", ErrInvalidPrompt, language)
+	}
+	for _, r := range language {
+		if (r < 'a' || r > 'z') && r != '-' {
+			return "", fmt.Errorf("%w: invalid language %q", ErrInvalidPrompt, language)
+		}
+	}
+	return language, nil
+}
+
+// normalizeLanguage matches the registry's normalization ("pt_BR" => "pt-br")
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -1,10 +1,14 @@
 package delivery
 
 import (
+	"errors"
+	authdomain "ga03-backend/internal/auth/domain"
 	"ga03-backend/internal/task/domain"
 	"ga03-backend/internal/task/usecase"
+	"ga03-backend/pkg/ai"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -162,9 +166,27 @@ func (h *TaskHandler) ExtractTasksFromEmail(c *gin.Context) {
 	userID := c.GetString("userID")
 	emailID := c.Param("emailId")
 
-	tasks, err := h.taskUsecase.ExtractTasksFromEmail(c.Request.Context(), userID, emailID)
+	// Extraction prompt in the user's language, relative dates in their time zone
+	ctx := c.Request.Context()
+	var loc *time.Location
+	if user, exists := c.Get("user"); exists {
+		if userData, ok := user.(*authdomain.User); ok {
+			ctx = ai.WithPromptContext(ctx, ai.PromptContext{Language: userData.Language, UserID: userData.ID})
+			if userData.TimeZone != "" {
+				if l, err := time.LoadLocation(userData.TimeZone); err == nil {
+					loc = l
+				}
+			}
+		}
+	}
+
+	tasks, err := h.taskUsecase.ExtractTasksFromEmail(ctx, userID, emailID, loc)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, ai.ErrQuotaExceeded) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -32,6 +32,12 @@ type Task struct {
 	Status      TaskStatus `json:"status" gorm:"default:pending"`
 	ReminderAt  *time.Time `json:"reminder_at,omitempty"`           // When to send FCM reminder
 	ReminderSent bool      `json:"reminder_sent" gorm:"default:false"` // Track if reminder was sent
+	// AI extraction details (empty for tasks created manually)
+	Assignee    string     `json:"assignee,omitempty"`
+	Confidence  float64    `json:"confidence,omitempty"`                // 0-1, how sure the model is that this is a task
+	SourceText  string     `json:"source_text,omitempty" gorm:"type:text"` // Sentence of the email the task came from
+	SourceStart int        `json:"source_start"`                         // Rune offsets of SourceText in the email body, -1 if not found
+	SourceEnd   int        `json:"source_end"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
@@ -42,4 +48,9 @@ type TaskExtraction struct {
 	Description string     `json:"description,omitempty"`
 	DueDate     *time.Time `json:"due_date,omitempty"`
 	Priority    Priority   `json:"priority"`
+	Assignee    string     `json:"assignee,omitempty"`
+	Confidence  float64    `json:"confidence"`
+	SourceText  string     `json:"source_text,omitempty"`
+	SourceStart int        `json:"source_start"`
+	SourceEnd   int        `json:"source_end"`
 }
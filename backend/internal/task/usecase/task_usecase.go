@@ -139,7 +139,7 @@ func (u *taskUsecase) DeleteTask(userID, taskID string) error {
 	return u.taskRepo.Delete(task.ID)
 }
 
-func (u *taskUsecase) ExtractTasksFromEmail(ctx context.Context, userID, emailID string) ([]*domain.Task, error) {
+func (u *taskUsecase) ExtractTasksFromEmail(ctx context.Context, userID, emailID string, loc *time.Location) ([]*domain.Task, error) {
 	if u.geminiService == nil {
 		return nil, errors.New("AI service not configured")
 	}
@@ -148,16 +148,19 @@ func (u *taskUsecase) ExtractTasksFromEmail(ctx context.Context, userID, emailID
 	}
 
 	// Get email content
-	subject, body, err := u.emailFetcher.GetEmailByID(userID, emailID)
+	subject, body, receivedAt, err := u.emailFetcher.GetEmailByID(userID, emailID)
 	if err != nil {
 		return nil, err
 	}
 
-	emailText := "Subject: " + subject + "\n\n" + body
-
 	// Extract tasks using AI
 	log.Printf("[TaskUsecase] Extracting tasks from email %s for user %s", emailID, userID)
-	extractions, err := u.geminiService.ExtractTasksFromEmail(ctx, emailText)
+	extractions, err := u.geminiService.ExtractTasksFromEmail(ctx, ai.TaskExtractionRequest{
+		Subject:    subject,
+		Body:       body,
+		ReceivedAt: receivedAt,
+		Location:   loc,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -174,8 +177,13 @@ func (u *taskUsecase) ExtractTasksFromEmail(ctx context.Context, userID, emailID
 			Title:       extraction.Title,
 			Description: extraction.Description,
 			DueDate:     extraction.DueDate,
-			Priority:    domain.Priority(extraction.Priority),
+			Priority:    parsePriority(extraction.Priority),
 			Status:      domain.TaskStatusPending,
+			Assignee:    extraction.Assignee,
+			Confidence:  extraction.Confidence,
+			SourceText:  extraction.Source.Text,
+			SourceStart: extraction.Source.Start,
+			SourceEnd:   extraction.Source.End,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"ga03-backend/internal/task/domain"
 	"ga03-backend/pkg/ai"
+	"time"
 )
 
 // TaskUsecase defines the interface for task business logic
@@ -23,8 +24,9 @@ type TaskUsecase interface {
 	// DeleteTask deletes a task
 	DeleteTask(userID, taskID string) error
 	
-	// ExtractTasksFromEmail uses AI to extract tasks from an email
-	ExtractTasksFromEmail(ctx context.Context, userID, emailID string) ([]*domain.Task, error)
+	// ExtractTasksFromEmail uses AI to extract tasks from an email.
+	// Relative dates resolve against the email's reception in loc (UTC when nil).
+	ExtractTasksFromEmail(ctx context.Context, userID, emailID string, loc *time.Location) ([]*domain.Task, error)
 	
 	// SetGeminiService sets the AI service for task extraction
 	SetGeminiService(svc ai.SummarizerService)
@@ -45,6 +47,6 @@ type TaskUpdateRequest struct {
 
 // EmailFetcher defines the interface for fetching email content
 type EmailFetcher interface {
-	GetEmailByID(userID, id string) (subject, body string, err error)
+	GetEmailByID(userID, id string) (subject, body string, receivedAt time.Time, err error)
 }
 
@@ -0,0 +1,61 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+
+	authdomain "ga03-backend/internal/auth/domain"
+	"ga03-backend/internal/usage/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultUsageDays = 7
+	maxUsageDays     = 90
+)
+
+// UsageHandler handles the AI usage API
+type UsageHandler struct {
+	usageUsecase usecase.UsageUsecase
+}
+
+// NewUsageHandler creates a new UsageHandler
+func NewUsageHandler(usageUsecase usecase.UsageUsecase) *UsageHandler {
+	return &UsageHandler{usageUsecase: usageUsecase}
+}
+
+// GetUsage returns the AI usage of the current user per day, provider and operation, and their daily quota
+// GET /api/ai/usage?days=7
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	userData, ok := user.(*authdomain.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user data"})
+		return
+	}
+
+	days := defaultUsageDays
+	if d := c.Query("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive number"})
+			return
+		}
+		days = parsed
+	}
+	if days > maxUsageDays {
+		days = maxUsageDays
+	}
+
+	report, err := h.usageUsecase.GetUsage(userData.ID, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,77 @@
+package domain
+
+import "time"
+
+// AIUsage aggregates the AI usage of a user for one day (UTC), provider and operation.
+// Characters are counted exactly; tokens are estimated from them.
+type AIUsage struct {
+	UserID       string    `json:"-" gorm:"primaryKey"`
+	Day          time.Time `json:"day" gorm:"primaryKey;type:date"`
+	Provider     string    `json:"provider" gorm:"primaryKey"`
+	Operation    string    `json:"operation" gorm:"primaryKey"`
+	Model        string    `json:"model,omitempty"` // Last model used
+	Calls        int64     `json:"calls" gorm:"not null;default:0"`
+	Failures     int64     `json:"failures" gorm:"not null;default:0"`
+	InputChars   int64     `json:"input_chars" gorm:"not null;default:0"`
+	OutputChars  int64     `json:"output_chars" gorm:"not null;default:0"`
+	InputTokens  int64     `json:"input_tokens" gorm:"not null;default:0"`
+	OutputTokens int64     `json:"output_tokens" gorm:"not null;default:0"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AIUsage) TableName() string {
+	return "ai_usage"
+}
+
+// UsageTotals sums usage rows
+type UsageTotals struct {
+	Calls        int64 `json:"calls"`
+	Failures     int64 `json:"failures"`
+	InputChars   int64 `json:"input_chars"`
+	OutputChars  int64 `json:"output_chars"`
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+// Add adds a usage row to the totals
+func (t *UsageTotals) Add(u *AIUsage) {
+	t.Calls += u.Calls
+	t.Failures += u.Failures
+	t.InputChars += u.InputChars
+	t.OutputChars += u.OutputChars
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+}
+
+// Tokens returns the input and output tokens
+func (t *UsageTotals) Tokens() int64 {
+	return t.InputTokens + t.OutputTokens
+}
+
+// DailyUsage is the usage of one day, with its breakdown per provider and operation
+type DailyUsage struct {
+	Day string `json:"day"` // YYYY-MM-DD (UTC)
+	UsageTotals
+	Breakdown []*AIUsage `json:"breakdown"`
+}
+
+// QuotaStatus is the state of a user's daily quotas. Providers in ExemptProviders are not counted
+// and stay available once a quota is reached (0 = unlimited).
+type QuotaStatus struct {
+	TokensUsed      int64     `json:"tokens_used"`
+	TokensLimit     int64     `json:"tokens_limit"`
+	RequestsUsed    int64     `json:"requests_used"`
+	RequestsLimit   int64     `json:"requests_limit"`
+	Exceeded        bool      `json:"exceeded"`
+	ResetAt         time.Time `json:"reset_at"`
+	ExemptProviders []string  `json:"exempt_providers"`
+}
+
+// UsageReport is the AI usage of a user over the last days, and their quota today
+type UsageReport struct {
+	Since  time.Time     `json:"since"`
+	Totals UsageTotals   `json:"totals"`
+	Days   []*DailyUsage `json:"days"`
+	Quota  QuotaStatus   `json:"quota"`
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"time"
+
+	usagedomain "ga03-backend/internal/usage/domain"
+)
+
+// UsageRepository stores the daily AI usage aggregates of each user
+type UsageRepository interface {
+	// AddUsage adds the counters of a row to the stored row of the same user, day, provider and operation
+	AddUsage(usage *usagedomain.AIUsage) error
+	// GetDayTotals sums a user's usage of a day, except for the given providers
+	GetDayTotals(userID string, day time.Time, excludeProviders []string) (*usagedomain.UsageTotals, error)
+	// ListUsage returns a user's usage rows since a day, oldest first
+	ListUsage(userID string, since time.Time) ([]*usagedomain.AIUsage, error)
+}
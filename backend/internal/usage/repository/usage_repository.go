@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	usagedomain "ga03-backend/internal/usage/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type usageRepository struct {
+	db *gorm.DB
+}
+
+// NewUsageRepository creates a new AI usage repository
+func NewUsageRepository(db *gorm.DB) UsageRepository {
+	return &usageRepository{db: db}
+}
+
+func (r *usageRepository) AddUsage(usage *usagedomain.AIUsage) error {
+	increment := func(column string) clause.Assignment {
+		return clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value:  gorm.Expr("ai_usage." + column + " + EXCLUDED." + column),
+		}
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "day"}, {Name: "provider"}, {Name: "operation"}},
+		DoUpdates: clause.Set{
+			increment("calls"),
+			increment("failures"),
+			increment("input_chars"),
+			increment("output_chars"),
+			increment("input_tokens"),
+			increment("output_tokens"),
+			{Column: clause.Column{Name: "model"}, Value: gorm.Expr("EXCLUDED.model")},
+			{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("EXCLUDED.updated_at")},
+		},
+	}).Create(usage).Error
+}
+
+func (r *usageRepository) GetDayTotals(userID string, day time.Time, excludeProviders []string) (*usagedomain.UsageTotals, error) {
+	query := r.db.Model(&usagedomain.AIUsage{}).
+		Select(`COALESCE(SUM(calls), 0) AS calls, COALESCE(SUM(failures), 0) AS failures,
+			COALESCE(SUM(input_chars), 0) AS input_chars, COALESCE(SUM(output_chars), 0) AS output_chars,
+			COALESCE(SUM(input_tokens), 0) AS input_tokens, COALESCE(SUM(output_tokens), 0) AS output_tokens`).
+		Where("user_id = ? AND day = ?", userID, day)
+	if len(excludeProviders) > 0 {
+		query = query.Where("provider NOT IN ?", excludeProviders)
+	}
+
+	var totals usagedomain.UsageTotals
+	if err := query.Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+func (r *usageRepository) ListUsage(userID string, since time.Time) ([]*usagedomain.AIUsage, error) {
+	var rows []*usagedomain.AIUsage
+	err := r.db.Where("user_id = ? AND day >= ?", userID, since).
+		Order("day, provider, operation").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	usagedomain "ga03-backend/internal/usage/domain"
+	"ga03-backend/pkg/ai"
+)
+
+// UsageUsecase accounts the AI usage of each user and enforces their daily quotas.
+// It implements ai.UsageTracker.
+type UsageUsecase interface {
+	// GetUsage returns the usage of the last days (today included) and today's quota
+	GetUsage(userID string, days int) (*usagedomain.UsageReport, error)
+
+	CheckQuota(userID, provider string) error
+	RecordUsage(record ai.UsageRecord)
+}
+
+// QuotaConfig sets the daily quotas of every user (0 = unlimited).
+// Exempt providers (local ones) are neither counted nor blocked: users over quota fall back to them.
+type QuotaConfig struct {
+	DailyTokens     int64
+	DailyRequests   int64
+	ExemptProviders []string
+}
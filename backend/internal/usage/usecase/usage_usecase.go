@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	usagedomain "ga03-backend/internal/usage/domain"
+	"ga03-backend/internal/usage/repository"
+	"ga03-backend/pkg/ai"
+)
+
+// counterTTL is how long a counter is trusted before the day totals are read again, so that
+// the calls made through other instances count against the quota too
+const counterTTL = 30 * time.Second
+
+// dayCounter is a user's usage of quota-counted providers today, kept in memory so quota
+// checks do not hit the database on every AI call
+type dayCounter struct {
+	day      time.Time
+	loadedAt time.Time // When the database totals were read
+	requests int64
+	tokens   int64
+	// Usage recorded by this instance and not stored yet, so missing from the database totals
+	unsavedRequests int64
+	unsavedTokens   int64
+}
+
+type usageUsecase struct {
+	repo   repository.UsageRepository
+	cfg    QuotaConfig
+	exempt map[string]bool
+
+	mu         sync.Mutex
+	counters   map[string]*dayCounter // By user
+	evictedDay time.Time              // Day the counters of previous days were last dropped
+}
+
+// NewUsageUsecase creates a usage usecase enforcing the given quotas
+func NewUsageUsecase(repo repository.UsageRepository, cfg QuotaConfig) UsageUsecase {
+	exempt := make(map[string]bool, len(cfg.ExemptProviders))
+	providers := []string{}
+	for _, p := range cfg.ExemptProviders {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" && !exempt[p] {
+			exempt[p] = true
+			providers = append(providers, p)
+		}
+	}
+	cfg.ExemptProviders = providers
+
+	return &usageUsecase{
+		repo:     repo,
+		cfg:      cfg,
+		exempt:   exempt,
+		counters: make(map[string]*dayCounter),
+	}
+}
+
+// today returns the current usage day (UTC)
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// counter returns the user's counter of today, reading the day totals from the database when
+// the counter is older than counterTTL (the last counter is kept if they cannot be read)
+func (u *usageUsecase) counter(userID string) (*dayCounter, error) {
+	day := today()
+	now := time.Now()
+
+	u.mu.Lock()
+	c, ok := u.counters[userID]
+	u.mu.Unlock()
+	current := ok && c.day.Equal(day)
+	if current && now.Sub(c.loadedAt) < counterTTL {
+		return c, nil
+	}
+
+	totals, err := u.repo.GetDayTotals(userID, day, u.cfg.ExemptProviders)
+	if err != nil {
+		if current {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.evictCounters(day)
+	c, ok = u.counters[userID]
+	if ok && c.day.Equal(day) && !c.loadedAt.Before(now) {
+		return c, nil // Loaded concurrently
+	}
+	fresh := &dayCounter{day: day, loadedAt: now, requests: totals.Calls, tokens: totals.Tokens()}
+	if ok && c.day.Equal(day) {
+		fresh.unsavedRequests, fresh.unsavedTokens = c.unsavedRequests, c.unsavedTokens
+		fresh.requests += c.unsavedRequests
+		fresh.tokens += c.unsavedTokens
+	}
+	u.counters[userID] = fresh
+	return fresh, nil
+}
+
+// evictCounters drops the counters of previous days, once a day (caller holds u.mu)
+func (u *usageUsecase) evictCounters(day time.Time) {
+	if u.evictedDay.Equal(day) {
+		return
+	}
+	for userID, c := range u.counters {
+		if c.day.Before(day) {
+			delete(u.counters, userID)
+		}
+	}
+	u.evictedDay = day
+}
+
+// quotaError returns the quota the counter exceeds, if any (caller holds u.mu)
+func (u *usageUsecase) quotaError(c *dayCounter) error {
+	resetAt := c.day.Add(24 * time.Hour)
+	if u.cfg.DailyTokens > 0 && c.tokens >= u.cfg.DailyTokens {
+		return &ai.QuotaError{Limit: "tokens", Used: c.tokens, Quota: u.cfg.DailyTokens, ResetAt: resetAt}
+	}
+	if u.cfg.DailyRequests > 0 && c.requests >= u.cfg.DailyRequests {
+		return &ai.QuotaError{Limit: "requests", Used: c.requests, Quota: u.cfg.DailyRequests, ResetAt: resetAt}
+	}
+	return nil
+}
+
+func (u *usageUsecase) CheckQuota(userID, provider string) error {
+	if u.exempt[provider] || (u.cfg.DailyTokens <= 0 && u.cfg.DailyRequests <= 0) {
+		return nil
+	}
+	c, err := u.counter(userID)
+	if err != nil {
+		// Do not block AI features because usage could not be read
+		log.Printf("[AIUsage] Failed to load usage of user %s: %v", userID, err)
+		return nil
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.quotaError(c)
+}
+
+// RecordUsage counts the usage against today's quota right away and stores it in the background
+func (u *usageUsecase) RecordUsage(record ai.UsageRecord) {
+	day := today()
+	requests := int64(record.Calls)
+	tokens := int64(record.InputTokens + record.OutputTokens)
+	counted := false
+	if !u.exempt[record.Provider] {
+		u.mu.Lock()
+		// The counter was loaded by CheckQuota before the provider was called
+		if c, ok := u.counters[record.UserID]; ok && c.day.Equal(day) {
+			c.requests += requests
+			c.tokens += tokens
+			c.unsavedRequests += requests
+			c.unsavedTokens += tokens
+			counted = true
+		}
+		u.mu.Unlock()
+	}
+
+	row := &usagedomain.AIUsage{
+		UserID:       record.UserID,
+		Day:          day,
+		Provider:     record.Provider,
+		Operation:    string(record.Operation),
+		Model:        record.Model,
+		Calls:        int64(record.Calls),
+		Failures:     int64(record.Failures),
+		InputChars:   int64(record.InputChars),
+		OutputChars:  int64(record.OutputChars),
+		InputTokens:  int64(record.InputTokens),
+		OutputTokens: int64(record.OutputTokens),
+		UpdatedAt:    time.Now(),
+	}
+	go func() {
+		if err := u.repo.AddUsage(row); err != nil {
+			// Still counted by this instance: it will never be in the database totals
+			log.Printf("[AIUsage] Failed to save %s usage of user %s: %v", row.Provider, row.UserID, err)
+			return
+		}
+		if counted {
+			u.mu.Lock()
+			if c, ok := u.counters[row.UserID]; ok && c.day.Equal(day) {
+				c.unsavedRequests -= requests
+				c.unsavedTokens -= tokens
+			}
+			u.mu.Unlock()
+		}
+	}()
+}
+
+func (u *usageUsecase) GetUsage(userID string, days int) (*usagedomain.UsageReport, error) {
+	day := today()
+	since := day.AddDate(0, 0, -(days - 1))
+
+	rows, err := u.repo.ListUsage(userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &usagedomain.UsageReport{Since: since, Days: []*usagedomain.DailyUsage{}}
+	byDay := make(map[string]*usagedomain.DailyUsage)
+	for _, row := range rows {
+		key := row.Day.UTC().Format("2006-01-02")
+		d, ok := byDay[key]
+		if !ok {
+			d = &usagedomain.DailyUsage{Day: key}
+			byDay[key] = d
+			report.Days = append(report.Days, d)
+		}
+		d.Add(row)
+		d.Breakdown = append(d.Breakdown, row)
+		report.Totals.Add(row)
+	}
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Day < report.Days[j].Day })
+
+	report.Quota = usagedomain.QuotaStatus{
+		TokensLimit:     u.cfg.DailyTokens,
+		RequestsLimit:   u.cfg.DailyRequests,
+		ResetAt:         day.Add(24 * time.Hour),
+		ExemptProviders: u.cfg.ExemptProviders,
+	}
+	c, err := u.counter(userID)
+	if err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	report.Quota.TokensUsed, report.Quota.RequestsUsed = c.tokens, c.requests
+	report.Quota.Exceeded = u.quotaError(c) != nil
+	u.mu.Unlock()
+	return report, nil
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	api "ga03-backend/cmd/api"
 	authdomain "ga03-backend/internal/auth/domain"
@@ -12,17 +17,25 @@ import (
 	authUsecase "ga03-backend/internal/auth/usecase"
 	emaildomain "ga03-backend/internal/email/domain"
 	emailRepo "ga03-backend/internal/email/repository"
+	emailScheduler "ga03-backend/internal/email/scheduler"
 	emailUsecase "ga03-backend/internal/email/usecase"
 	"ga03-backend/internal/notification"
+	promptdomain "ga03-backend/internal/prompt/domain"
+	promptRepo "ga03-backend/internal/prompt/repository"
 	taskdomain "ga03-backend/internal/task/domain"
 	taskRepo "ga03-backend/internal/task/repository"
 	taskScheduler "ga03-backend/internal/task/scheduler"
 	taskUsecase "ga03-backend/internal/task/usecase"
+	usagedomain "ga03-backend/internal/usage/domain"
+	usageRepo "ga03-backend/internal/usage/repository"
 	"ga03-backend/pkg/config"
 	"ga03-backend/pkg/database"
+	"ga03-backend/pkg/embedding"
 	"ga03-backend/pkg/fcm"
 	"ga03-backend/pkg/gmail"
 	"ga03-backend/pkg/imap"
+	"ga03-backend/pkg/jobqueue"
+	"ga03-backend/pkg/pgvector"
 	"ga03-backend/pkg/sse"
 )
 
@@ -37,9 +50,15 @@ func main() {
 	}
 
 	// Auto-migrate database schemas (including Task)
-	if err := db.AutoMigrate(&authdomain.User{}, &authdomain.RefreshToken{}, &authdomain.FCMToken{}, &emaildomain.EmailSyncHistory{}, &emaildomain.KanbanColumn{}, &emaildomain.EmailKanbanColumn{}, &emaildomain.EmailSummary{}, &taskdomain.Task{}); err != nil {
+	if err := db.AutoMigrate(&authdomain.User{}, &authdomain.RefreshToken{}, &authdomain.FCMToken{}, &emaildomain.EmailSyncHistory{}, &emaildomain.AttachmentSyncHistory{}, &emaildomain.KanbanColumn{}, &emaildomain.EmailKanbanColumn{}, &emaildomain.KanbanColumnTransition{}, &emaildomain.EmailSummary{}, &emaildomain.FollowUpReminder{}, &emaildomain.TriageSettings{}, &emaildomain.TriageSuggestion{}, &emaildomain.TriageExample{}, &emaildomain.SearchSuggestion{}, &emaildomain.SearchSuggestionSource{}, &emaildomain.SavedSearch{}, &emaildomain.SavedSearchAlert{}, &promptdomain.PromptTemplate{}, &promptdomain.PromptActivation{}, &promptdomain.PromptRun{}, &usagedomain.AIUsage{}, &taskdomain.Task{}); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	if err := jobqueue.Migrate(db); err != nil {
+		log.Fatal("Failed to migrate job queue:", err)
+	}
+	if err := emailRepo.MigrateEmailSearchIndex(db); err != nil {
+		log.Fatal("Failed to migrate search index:", err)
+	}
 
 	// Initialize repositories (dependency injection)
 	userRepo := authRepo.NewUserRepository(db)
@@ -49,7 +68,43 @@ func main() {
 	kanbanColumnRepo := emailRepo.NewKanbanColumnRepository(db)
 	emailKanbanColumnRepo := emailRepo.NewEmailKanbanColumnRepository(db)
 	emailSummaryRepo := emailRepo.NewEmailSummaryRepository(db)
+	followUpRepo := emailRepo.NewFollowUpReminderRepository(db)
+	triageRepo := emailRepo.NewTriageRepository(db)
+	searchIndexRepo := emailRepo.NewEmailSearchIndexRepository(db)
+	suggestionRepo := emailRepo.NewSearchSuggestionRepository(db)
+	savedSearchRepo := emailRepo.NewSavedSearchRepository(db)
 	taskRepository := taskRepo.NewGormTaskRepository(db)
+	promptRepository := promptRepo.NewPromptRepository(db)
+	usageRepository := usageRepo.NewUsageRepository(db)
+
+	// Initialize persistent job queue (summaries, vector sync); workers start after handlers register
+	jobQueue := jobqueue.New(db, jobqueue.Config{
+		PollInterval:      cfg.JobQueuePollInterval,
+		MaxAttempts:       cfg.JobMaxAttempts,
+		BaseBackoff:       cfg.JobRetryBaseDelay,
+		MaxBackoff:        cfg.JobRetryMaxDelay,
+		StuckTimeout:      cfg.JobStuckTimeout,
+		JobTimeout:        cfg.JobTimeout,
+		DeadRetention:     cfg.JobDeadRetention,
+		MaxRunningPerUser: cfg.JobMaxRunningPerUser,
+	})
+
+	// Initialize the embedding model shared by the vector search backends
+	embedder, err := embedding.New(cfg)
+	if err != nil {
+		log.Printf("[WARN] Failed to initialize embedding model (semantic search disabled): %v", err)
+		embedder = nil
+	}
+
+	// Initialize pgvector store when selected as vector search backend (creates the extension, table and HNSW index)
+	var pgVectorStore *pgvector.Client
+	if cfg.VectorBackend == "pgvector" && embedder != nil {
+		pgVectorStore, err = pgvector.NewClient(db, embedder, cfg)
+		if err != nil {
+			log.Printf("[WARN] Failed to initialize pgvector store (semantic search disabled): %v", err)
+			pgVectorStore = nil
+		}
+	}
 
 	// Initialize SSE Manager
 	sseManager := sse.NewManager()
@@ -77,7 +132,7 @@ func main() {
 
 	// Initialize use cases (dependency injection)
 	authUsecaseInstance := authUsecase.NewAuthUsecase(userRepo, fcmTokenRepo, cfg)
-	emailUsecaseInstance := emailUsecase.NewEmailUsecase(emailRepository, emailSyncHistoryRepo, kanbanColumnRepo, emailKanbanColumnRepo, userRepo, gmailService, imapService, cfg, cfg.GooglePubSubTopic)
+	emailUsecaseInstance := emailUsecase.NewEmailUsecase(emailRepository, emailSyncHistoryRepo, kanbanColumnRepo, emailKanbanColumnRepo, followUpRepo, triageRepo, searchIndexRepo, suggestionRepo, savedSearchRepo, userRepo, gmailService, imapService, cfg, cfg.GooglePubSubTopic)
 	taskUsecaseInstance := taskUsecase.NewTaskUsecase(taskRepository)
 
 	// Set up email sync callback for auth usecase
@@ -87,6 +142,9 @@ func main() {
 	// Set EventService (SSE) for email usecase to enable real-time updates
 	emailUsecaseInstance.SetEventService(sseManager)
 
+	// Vector sync jobs run on the persistent job queue
+	emailUsecaseInstance.SetJobQueue(jobQueue)
+
 	// Initialize Notification Service (Pub/Sub)
 	// Only start if project ID is configured
 	if cfg.GoogleProjectID != "" {
@@ -118,8 +176,17 @@ func main() {
 	reminderScheduler.Start()
 	log.Println("[TaskScheduler] Task reminder scheduler started")
 
+	// Initialize Follow-up Reminder Scheduler (resurfaces sent emails without reply)
+	followUpScheduler := emailScheduler.NewFollowUpReminderScheduler(followUpRepo, emailUsecaseInstance, fcmTokenRepo, fcmClient)
+	followUpScheduler.Start()
+
+	// Initialize Snooze Scheduler (DB-driven, safe to run on several instances)
+	snoozeScheduler := emailScheduler.NewSnoozeScheduler(emailKanbanColumnRepo, emailUsecaseInstance, cfg.SnoozeCheckInterval)
+	snoozeScheduler.Start()
+
 	// Initialize HTTP handler with Task handler
-	handler := api.NewHandler(authUsecaseInstance, emailUsecaseInstance, taskUsecaseInstance, sseManager, cfg, emailSummaryRepo, taskRepository)
+	handler := api.NewHandler(authUsecaseInstance, emailUsecaseInstance, taskUsecaseInstance, sseManager, cfg, emailSummaryRepo, taskRepository, promptRepository, usageRepository, jobQueue, embedder, pgVectorStore)
+	jobQueue.Start()
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -127,9 +194,34 @@ func main() {
 		port = "8080"
 	}
 
+	handler.SetSnoozeScheduler(snoozeScheduler)
+
 	log.Printf("Server starting on port %s", port)
-	if err := handler.Start(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	go func() {
+		if err := handler.Start(":" + port); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shut down
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := handler.Shutdown(ctx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
 	}
-}
 
+	// Stop schedulers (snooze scheduler waits for the batch in progress)
+	snoozeScheduler.Stop()
+	followUpScheduler.Stop()
+	reminderScheduler.Stop()
+
+	// Stop job queue workers (unfinished jobs stay in the database)
+	jobQueue.Stop()
+
+	log.Println("Server exited")
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a provider circuit breaker
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Calls flow normally
+	BreakerOpen     BreakerState = "open"      // Provider is skipped until the cooldown elapses
+	BreakerHalfOpen BreakerState = "half_open" // A limited number of probe calls decide whether to close again
+)
+
+// CircuitBreaker stops calling a provider after consecutive failures and probes it again after a cooldown
+type CircuitBreaker struct {
+	mu sync.Mutex
+
+	failureThreshold int
+	cooldown         time.Duration
+	halfOpenProbes   int
+
+	state               BreakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	probesInFlight      int
+}
+
+// NewCircuitBreaker creates a closed breaker
+func NewCircuitBreaker(failureThreshold int, cooldown time.Duration, halfOpenProbes int) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	if halfOpenProbes < 1 {
+		halfOpenProbes = 1
+	}
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		halfOpenProbes:   halfOpenProbes,
+		state:            BreakerClosed,
+	}
+}
+
+// Allow reports whether a call may go through. In half-open state each allowed call is a probe
+// and must be followed by Success, Failure or Release.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probesInFlight = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probesInFlight >= b.halfOpenProbes {
+			return false
+		}
+		b.probesInFlight++
+		return true
+	default:
+		return true
+	}
+}
+
+// Success records a successful call; a successful probe closes the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.consecutiveFailures = 0
+	b.probesInFlight = 0
+}
+
+// Failure records a failed call; a failed probe or too many failures open the breaker
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures++
+	if b.state == BreakerHalfOpen || b.consecutiveFailures >= b.failureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+		b.probesInFlight = 0
+	}
+}
+
+// Release gives back a probe slot without counting a result (e.g. the caller canceled)
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.probesInFlight > 0 {
+		b.probesInFlight--
+	}
+}
+
+// Snapshot returns the current state, consecutive failures and when the breaker last opened
+func (b *CircuitBreaker) Snapshot() (BreakerState, int, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state
+	if state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		state = BreakerHalfOpen // Next call will probe
+	}
+	return state, b.consecutiveFailures, b.openedAt
+}
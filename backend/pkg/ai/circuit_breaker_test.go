@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	// Steps: "allow" (want reports Allow), "success", "failure", "release", "cooldown" (the cooldown elapses)
+	type step struct {
+		action string
+		want   bool
+		state  BreakerState
+	}
+	tests := []struct {
+		name      string
+		threshold int
+		probes    int
+		steps     []step
+	}{
+		{
+			name:      "opens after consecutive failures",
+			threshold: 2,
+			probes:    1,
+			steps: []step{
+				{"allow", true, BreakerClosed},
+				{"failure", false, BreakerClosed},
+				{"failure", false, BreakerOpen},
+				{"allow", false, BreakerOpen},
+			},
+		},
+		{
+			name:      "success resets the failure count",
+			threshold: 2,
+			probes:    1,
+			steps: []step{
+				{"failure", false, BreakerClosed},
+				{"success", false, BreakerClosed},
+				{"failure", false, BreakerClosed},
+			},
+		},
+		{
+			name:      "successful probe closes",
+			threshold: 1,
+			probes:    1,
+			steps: []step{
+				{"failure", false, BreakerOpen},
+				{"cooldown", false, BreakerHalfOpen},
+				{"allow", true, BreakerHalfOpen},
+				{"allow", false, BreakerHalfOpen},
+				{"success", false, BreakerClosed},
+				{"allow", true, BreakerClosed},
+			},
+		},
+		{
+			name:      "failed probe reopens",
+			threshold: 3,
+			probes:    1,
+			steps: []step{
+				{"failure", false, BreakerClosed},
+				{"failure", false, BreakerClosed},
+				{"failure", false, BreakerOpen},
+				{"cooldown", false, BreakerHalfOpen},
+				{"allow", true, BreakerHalfOpen},
+				{"failure", false, BreakerOpen},
+				{"allow", false, BreakerOpen},
+			},
+		},
+		{
+			name:      "released probe frees its slot",
+			threshold: 1,
+			probes:    1,
+			steps: []step{
+				{"failure", false, BreakerOpen},
+				{"cooldown", false, BreakerHalfOpen},
+				{"allow", true, BreakerHalfOpen},
+				{"allow", false, BreakerHalfOpen},
+				{"release", false, BreakerHalfOpen},
+				{"allow", true, BreakerHalfOpen},
+			},
+		},
+		{
+			name:      "several concurrent probes",
+			threshold: 1,
+			probes:    2,
+			steps: []step{
+				{"failure", false, BreakerOpen},
+				{"cooldown", false, BreakerHalfOpen},
+				{"allow", true, BreakerHalfOpen},
+				{"allow", true, BreakerHalfOpen},
+				{"allow", false, BreakerHalfOpen},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker(tt.threshold, time.Minute, tt.probes)
+			for i, s := range tt.steps {
+				switch s.action {
+				case "allow":
+					if got := b.Allow(); got != s.want {
+						t.Fatalf("step %d: Allow() = %v, want %v", i, got, s.want)
+					}
+				case "success":
+					b.Success()
+				case "failure":
+					b.Failure()
+				case "release":
+					b.Release()
+				case "cooldown":
+					b.mu.Lock()
+					b.openedAt = b.openedAt.Add(-b.cooldown)
+					b.mu.Unlock()
+				}
+				if state, _, _ := b.Snapshot(); state != s.state {
+					t.Fatalf("step %d (%s): state = %s, want %s", i, s.action, state, s.state)
+				}
+			}
+		})
+	}
+}
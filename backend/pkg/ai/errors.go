@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"ga03-backend/pkg/gemini"
+)
+
+// ErrorKind classifies provider failures so the chain can decide whether to retry or move on
+type ErrorKind string
+
+const (
+	ErrorKindConnection ErrorKind = "connection" // Network failure or timeout
+	ErrorKindQuota      ErrorKind = "quota"      // 429 / rate limited
+	ErrorKindServer     ErrorKind = "server"     // 5xx
+	ErrorKindClient     ErrorKind = "client"     // Other 4xx (bad request, invalid key, unknown model)
+	ErrorKindResponse   ErrorKind = "response"   // Model answered but the output is empty or unparseable
+	ErrorKindCanceled   ErrorKind = "canceled"   // Caller canceled the request, not the provider's fault
+	ErrorKindUnknown    ErrorKind = "unknown"
+)
+
+// ProviderError is a classified error returned by the HTTP providers
+type ProviderError struct {
+	Kind       ErrorKind
+	StatusCode int // HTTP status when the provider answered
+	Err        error
+}
+
+func (e *ProviderError) Error() string { return e.Err.Error() }
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// connectionError wraps a failed HTTP round trip
+func connectionError(err error) error {
+	return &ProviderError{Kind: ErrorKindConnection, Err: err}
+}
+
+// httpStatusError builds a ProviderError from a non-200 response
+func httpStatusError(provider string, statusCode int, body []byte) error {
+	return &ProviderError{
+		Kind:       kindForStatus(statusCode),
+		StatusCode: statusCode,
+		Err:        fmt.Errorf("%s API error (%d): %s", provider, statusCode, string(body)),
+	}
+}
+
+// responseError marks output the model produced but we could not use
+func responseError(format string, args ...interface{}) error {
+	return &ProviderError{Kind: ErrorKindResponse, Err: fmt.Errorf(format, args...)}
+}
+
+func kindForStatus(statusCode int) ErrorKind {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorKindQuota
+	case statusCode >= 500:
+		return ErrorKindServer
+	case statusCode == http.StatusRequestTimeout:
+		return ErrorKindConnection
+	case statusCode >= 400:
+		return ErrorKindClient
+	default:
+		return ErrorKindUnknown
+	}
+}
+
+// classifyError maps an error returned by a provider to an ErrorKind
+func classifyError(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorKindCanceled
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.Kind
+	}
+	var geminiErr *gemini.APIError
+	if errors.As(err, &geminiErr) {
+		return kindForStatus(geminiErr.StatusCode)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorKindConnection
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorKindConnection
+	}
+	return ErrorKindUnknown
+}
+
+// isRetryable reports whether retrying the same provider may succeed
+func isRetryable(kind ErrorKind) bool {
+	return kind == ErrorKindConnection || kind == ErrorKindServer
+}
+
+// partialStreamError marks a streaming failure that happened after text was already
+// delivered to the caller: retrying or falling back would repeat output the user has seen.
+type partialStreamError struct {
+	err error
+}
+
+func (e *partialStreamError) Error() string { return "stream interrupted: " + e.err.Error() }
+
+func (e *partialStreamError) Unwrap() error { return e.err }
+
+func isPartialStream(err error) bool {
+	var partial *partialStreamError
+	return errors.As(err, &partial)
+}
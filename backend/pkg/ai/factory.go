@@ -3,20 +3,27 @@ package ai
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"ga03-backend/pkg/gemini"
 )
 
 // Config holds AI provider configuration
 type Config struct {
-	Provider   ProviderType // "gemini" or "ollama"
-	
+	Provider ProviderType // "gemini" or "ollama"
+
 	// Gemini config
 	GeminiAPIKey string
-	
+
 	// Ollama config
 	OllamaBaseURL string // e.g., "http://localhost:11434"
 	OllamaModel   string // e.g., "llama3", "mistral"
+
+	// OpenAI-compatible config (vLLM, llama.cpp server, LM Studio, LocalAI)
+	OpenAI OpenAICompatibleConfig
+
+	// Provider orders, circuit breakers and retries (zero values = defaults)
+	Chain ChainConfig
 }
 
 // GeminiAdapter wraps gemini.GeminiService to implement SummarizerService
@@ -24,104 +31,192 @@ type GeminiAdapter struct {
 	service *gemini.GeminiService
 }
 
+// ModelName returns the Gemini model answering requests
+func (g *GeminiAdapter) ModelName() string {
+	return gemini.Model
+}
+
 func (g *GeminiAdapter) SummarizeEmail(ctx context.Context, emailText string) (string, error) {
-	return g.service.SummarizeEmail(ctx, emailText)
+	prompt, err := buildSummaryPrompt(ctx, emailText)
+	if err != nil {
+		return "", err
+	}
+	summary, err := g.service.GenerateContent(ctx, prompt, gemini.GenerateOptions{Temperature: 0.3})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
 }
 
-func (g *GeminiAdapter) ExtractTasksFromEmail(ctx context.Context, emailText string) ([]TaskExtraction, error) {
-	geminiTasks, err := g.service.ExtractTasksFromEmail(ctx, emailText)
+// ExtractTasksFromEmail constrains the output with Gemini's responseSchema
+func (g *GeminiAdapter) ExtractTasksFromEmail(ctx context.Context, req TaskExtractionRequest) ([]TaskExtraction, error) {
+	prompt, err := buildTaskExtractionPrompt(ctx, req, true)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert gemini.TaskExtraction to ai.TaskExtraction
-	tasks := make([]TaskExtraction, len(geminiTasks))
-	for i, gt := range geminiTasks {
-		tasks[i] = TaskExtraction{
-			Title:       gt.Title,
-			Description: gt.Description,
-			DueDate:     gt.DueDate,
-			Priority:    gt.Priority,
-		}
+	// Lower temperature for more deterministic output
+	text, err := g.service.GenerateContent(ctx, prompt, gemini.GenerateOptions{
+		Temperature:      0.2,
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   geminiSchema(taskExtractionSchema),
+	})
+	if err != nil {
+		return nil, err
 	}
-	return tasks, nil
+	return parseTaskExtractionJSON(text, req)
 }
 
 func (g *GeminiAdapter) GenerateSynonyms(ctx context.Context, word string) ([]string, error) {
-	return g.service.GenerateSynonyms(ctx, word)
+	prompt, err := buildSynonymsPrompt(ctx, word, false)
+	if err != nil {
+		return nil, err
+	}
+	text, err := g.service.GenerateContent(ctx, prompt, gemini.GenerateOptions{Temperature: 0.2})
+	if err != nil {
+		return nil, err
+	}
+	return parseSynonymsJSON(text)
+}
+
+// SummarizeEmailStream implements StreamingSummarizer
+func (g *GeminiAdapter) SummarizeEmailStream(ctx context.Context, emailText string, onDelta func(string)) (string, error) {
+	prompt, err := buildSummaryPrompt(ctx, emailText)
+	if err != nil {
+		return "", err
+	}
+	summary, err := g.service.GenerateContentStream(ctx, prompt, 0.3, onDelta)
+	if err != nil {
+		return summary, err
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// ClassifyEmail implements EmailClassifier using Gemini's JSON output mode
+func (g *GeminiAdapter) ClassifyEmail(ctx context.Context, req ClassifyRequest) (*Classification, error) {
+	prompt, err := buildClassifyPrompt(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	text, err := g.service.GenerateContent(ctx, prompt, gemini.GenerateOptions{
+		Temperature:      0.1,
+		ResponseMIMEType: "application/json",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseClassificationJSON(text, req.Columns)
+}
+
+// DraftReply implements ReplyDrafter using Gemini's streaming endpoint
+func (g *GeminiAdapter) DraftReply(ctx context.Context, req DraftReplyRequest, onDelta func(string)) (string, error) {
+	prompt, err := buildDraftReplyPrompt(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	draft, err := g.service.GenerateContentStream(ctx, prompt, 0.6, onDelta)
+	if err != nil {
+		return draft, err
+	}
+	return strings.TrimSpace(draft), nil
 }
 
 // NewSummarizerService creates a SummarizerService based on the config
-// This is the factory function - switch AI provider by changing config.Provider
+// This is the factory function - switch AI provider by changing config.Provider.
+// Every mode returns a *FallbackService, so breakers, retries and metrics apply even to a single provider.
 func NewSummarizerService(cfg Config) (SummarizerService, error) {
+	chain := NewFallbackService(cfg.Chain)
+
 	switch cfg.Provider {
 	case ProviderGemini:
 		if cfg.GeminiAPIKey == "" {
 			return nil, fmt.Errorf("GEMINI_API_KEY is required for Gemini provider")
 		}
-		return &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)}, nil
-		
+		chain.AddProvider(ProviderNameGemini, &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)})
+
 	case ProviderOllama:
-		return NewOllamaService(cfg.OllamaBaseURL, cfg.OllamaModel), nil
-		
-	case ProviderAuto:
-		var geminiSvc SummarizerService
-		if cfg.GeminiAPIKey != "" {
-			geminiSvc = &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)}
+		chain.AddProvider(ProviderNameOllama, NewOllamaService(cfg.OllamaBaseURL, cfg.OllamaModel))
+
+	case ProviderOpenAI:
+		if cfg.OpenAI.BaseURL == "" {
+			return nil, fmt.Errorf("OPENAI_BASE_URL is required for OpenAI-compatible provider")
 		}
-		ollamaSvc := NewOllamaService(cfg.OllamaBaseURL, cfg.OllamaModel)
-		return NewFallbackService(geminiSvc, ollamaSvc), nil
-		
+		chain.AddProvider(ProviderNameOpenAI, NewOpenAICompatibleService(cfg.OpenAI))
+
 	default:
-		// Default to Auto/Fallback mode
-		var geminiSvc SummarizerService
+		// Auto/Fallback mode: every available provider, routed by cfg.Chain.Orders
 		if cfg.GeminiAPIKey != "" {
-			geminiSvc = &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)}
+			chain.AddProvider(ProviderNameGemini, &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)})
+		}
+		chain.AddProvider(ProviderNameOllama, NewOllamaService(cfg.OllamaBaseURL, cfg.OllamaModel))
+		if cfg.OpenAI.BaseURL != "" {
+			chain.AddProvider(ProviderNameOpenAI, NewOpenAICompatibleService(cfg.OpenAI))
 		}
-		ollamaSvc := NewOllamaService(cfg.OllamaBaseURL, cfg.OllamaModel)
-		return NewFallbackService(geminiSvc, ollamaSvc), nil
 	}
+
+	return chain, nil
 }
 
 // DynamicConfig holds AI provider configuration with dynamic getters for runtime updates
 type DynamicConfig struct {
-	Provider   ProviderType
+	Provider     ProviderType
 	GeminiAPIKey string
 	// Dynamic getters for Ollama config - allows runtime updates
 	GetOllamaBaseURL func() string
 	GetOllamaModel   func() string
+	// Dynamic getters for the OpenAI-compatible provider (empty base URL = disabled in auto mode)
+	GetOpenAIBaseURL func() string
+	GetOpenAIModel   func() string
+	GetOpenAIAPIKey  func() string
+	OpenAIJSONMode   bool
+	// Provider orders, circuit breakers and retries (zero values = defaults)
+	Chain ChainConfig
 }
 
 // NewSummarizerServiceWithDynamicConfig creates a SummarizerService with dynamic config getters
 // Use this when you need runtime-updateable Ollama settings
-func NewSummarizerServiceWithDynamicConfig(cfg DynamicConfig) (SummarizerService, error) {
+func NewSummarizerServiceWithDynamicConfig(cfg DynamicConfig) (*FallbackService, error) {
+	chain := NewFallbackService(cfg.Chain)
+
 	switch cfg.Provider {
 	case ProviderGemini:
 		if cfg.GeminiAPIKey == "" {
 			return nil, fmt.Errorf("GEMINI_API_KEY is required for Gemini provider")
 		}
-		return &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)}, nil
-		
+		chain.AddProvider(ProviderNameGemini, &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)})
+
 	case ProviderOllama:
-		return NewOllamaServiceWithGetters(cfg.GetOllamaBaseURL, cfg.GetOllamaModel), nil
-		
-	case ProviderAuto:
-		var geminiSvc SummarizerService
-		if cfg.GeminiAPIKey != "" {
-			geminiSvc = &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)}
+		chain.AddProvider(ProviderNameOllama, NewOllamaServiceWithGetters(cfg.GetOllamaBaseURL, cfg.GetOllamaModel))
+
+	case ProviderOpenAI:
+		if cfg.GetOpenAIBaseURL == nil || cfg.GetOpenAIBaseURL() == "" {
+			return nil, fmt.Errorf("OPENAI_BASE_URL is required for OpenAI-compatible provider")
 		}
-		ollamaSvc := NewOllamaServiceWithGetters(cfg.GetOllamaBaseURL, cfg.GetOllamaModel)
-		return NewFallbackService(geminiSvc, ollamaSvc), nil
-		
+		chain.AddProvider(ProviderNameOpenAI, newDynamicOpenAIService(cfg))
+
 	default:
-		// Default to Auto/Fallback mode
-		var geminiSvc SummarizerService
+		// Auto/Fallback mode: every available provider, routed by cfg.Chain.Orders
 		if cfg.GeminiAPIKey != "" {
-			geminiSvc = &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)}
+			chain.AddProvider(ProviderNameGemini, &GeminiAdapter{service: gemini.NewGeminiService(cfg.GeminiAPIKey)})
+		}
+		chain.AddProvider(ProviderNameOllama, NewOllamaServiceWithGetters(cfg.GetOllamaBaseURL, cfg.GetOllamaModel))
+		if cfg.GetOpenAIBaseURL != nil {
+			// Skipped at call time while no base URL is configured
+			chain.AddProvider(ProviderNameOpenAI, newDynamicOpenAIService(cfg))
 		}
-		ollamaSvc := NewOllamaServiceWithGetters(cfg.GetOllamaBaseURL, cfg.GetOllamaModel)
-		return NewFallbackService(geminiSvc, ollamaSvc), nil
 	}
-}
 
+	return chain, nil
+}
 
+// newDynamicOpenAIService builds the OpenAI-compatible provider from DynamicConfig getters
+func newDynamicOpenAIService(cfg DynamicConfig) *OpenAICompatibleService {
+	getModel := cfg.GetOpenAIModel
+	if getModel == nil {
+		getModel = func() string { return "" }
+	}
+	getAPIKey := cfg.GetOpenAIAPIKey
+	if getAPIKey == nil {
+		getAPIKey = func() string { return "" }
+	}
+	return NewOpenAICompatibleServiceWithGetters(cfg.GetOpenAIBaseURL, getModel, getAPIKey, cfg.OpenAIJSONMode)
+}
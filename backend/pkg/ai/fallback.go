@@ -2,197 +2,682 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"net"
-	"strings"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
-// FallbackService implements smart AI provider routing with fallback
-// - Summarization: Ollama first (local, free), fallback to Gemini
-// - Task extraction: Gemini first (better quality), fallback to Ollama
+// Operation identifies an AI operation that has its own provider order
+type Operation string
+
+const (
+	OperationSummarize    Operation = "summarize"
+	OperationExtractTasks Operation = "extract_tasks"
+	OperationSynonyms     Operation = "synonyms"
+	OperationDraftReply   Operation = "draft_reply"
+	OperationClassify     Operation = "classify"
+)
+
+// Operations lists all operations routed by FallbackService
+var Operations = []Operation{OperationSummarize, OperationExtractTasks, OperationSynonyms, OperationDraftReply, OperationClassify}
+
+// Provider names used in chain orders
+const (
+	ProviderNameGemini = "gemini"
+	ProviderNameOllama = "ollama"
+	ProviderNameOpenAI = "openai"
+)
+
+// ChainConfig configures provider orders, circuit breakers and retries
+type ChainConfig struct {
+	// Orders is the provider order per operation. Unknown providers are ignored at construction.
+	Orders map[Operation][]string
+
+	FailureThreshold int           // Consecutive failures before a breaker opens
+	Cooldown         time.Duration // How long a breaker stays open before half-open probes
+	HalfOpenProbes   int           // Concurrent probe calls allowed in half-open state
+
+	MaxAttempts int           // Attempts per provider for retryable errors (connection, 5xx)
+	BaseBackoff time.Duration // First retry delay, doubled each attempt, with jitter
+	MaxBackoff  time.Duration
+}
+
+// DefaultChainConfig returns the historical routing:
+// - Summarization: Ollama first (local, free), then OpenAI-compatible, fallback to Gemini
+// - Task extraction / synonyms: Gemini first (better quality), then OpenAI-compatible, fallback to Ollama
+// - Reply drafting: same as task extraction, since the text is sent on the user's behalf
+// - Classification (Kanban triage): Ollama first like summaries, since it runs for every new email
+func DefaultChainConfig() ChainConfig {
+	return ChainConfig{
+		Orders: map[Operation][]string{
+			OperationSummarize:    {ProviderNameOllama, ProviderNameOpenAI, ProviderNameGemini},
+			OperationExtractTasks: {ProviderNameGemini, ProviderNameOpenAI, ProviderNameOllama},
+			OperationSynonyms:     {ProviderNameGemini, ProviderNameOpenAI, ProviderNameOllama},
+			OperationDraftReply:   {ProviderNameGemini, ProviderNameOpenAI, ProviderNameOllama},
+			OperationClassify:     {ProviderNameOllama, ProviderNameOpenAI, ProviderNameGemini},
+		},
+		FailureThreshold: 3,
+		Cooldown:         30 * time.Second,
+		HalfOpenProbes:   1,
+		MaxAttempts:      2,
+		BaseBackoff:      200 * time.Millisecond,
+		MaxBackoff:       2 * time.Second,
+	}
+}
+
+// maxLastErrorLen caps the error message (in runes) kept in ProviderStats (API errors include the response body)
+const maxLastErrorLen = 300
+
+// modelNamer is implemented by providers that report the model answering their requests
+type modelNamer interface {
+	ModelName() string
+}
+
+// providerModel returns the model of a provider, or "" if it does not report one
+func providerModel(svc SummarizerService) string {
+	if namer, ok := svc.(modelNamer); ok {
+		return namer.ModelName()
+	}
+	return ""
+}
+
+// availabilityChecker is implemented by providers that can be disabled at runtime
+type availabilityChecker interface {
+	Configured() bool
+}
+
+// supportsOperation reports whether a provider implements the optional interface an operation needs
+func supportsOperation(svc SummarizerService, op Operation) bool {
+	switch op {
+	case OperationDraftReply:
+		_, ok := svc.(ReplyDrafter)
+		return ok
+	case OperationClassify:
+		_, ok := svc.(EmailClassifier)
+		return ok
+	}
+	return true
+}
+
+// FallbackService routes each operation through an ordered chain of providers.
+// Every provider has a circuit breaker and latency/error counters; retryable errors
+// (connection, 5xx) are retried with jittered exponential backoff before moving on.
 type FallbackService struct {
-	gemini SummarizerService
-	ollama *OllamaService
+	cfg ChainConfig
+
+	mu        sync.RWMutex
+	providers map[string]*chainProvider
+	names     []string // Registration order
+	orders    map[Operation][]string
+	prompts   *PromptRegistry   // nil = built-in templates
+	recorder  PromptRunRecorder // Receives the runs of A/B-tested prompt versions
+	usage     UsageTracker      // Accounts usage per user and enforces quotas (nil = unlimited)
+}
+
+type chainProvider struct {
+	name    string
+	svc     SummarizerService
+	breaker *CircuitBreaker
+
+	statsMu sync.Mutex
+	stats   ProviderStats
+}
+
+// ProviderStats are the counters of one provider (calls = attempts, including retries)
+type ProviderStats struct {
+	Name                string                        `json:"name"`
+	State               BreakerState                  `json:"state"`
+	ConsecutiveFailures int                           `json:"consecutive_failures"`
+	OpenedAt            *time.Time                    `json:"opened_at,omitempty"`
+	Calls               int64                         `json:"calls"`
+	Successes           int64                         `json:"successes"`
+	Failures            int64                         `json:"failures"`
+	Retries             int64                         `json:"retries"`
+	SkippedOpen         int64                         `json:"skipped_open"` // Calls skipped because the breaker was open
+	ErrorsByKind        map[ErrorKind]int64           `json:"errors_by_kind"`
+	AvgLatencyMs        float64                       `json:"avg_latency_ms"`
+	MaxLatencyMs        int64                         `json:"max_latency_ms"`
+	LastLatencyMs       int64                         `json:"last_latency_ms"`
+	LastError           string                        `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time                    `json:"last_error_at,omitempty"`
+	Operations          map[Operation]*OperationStats `json:"operations"`
+	totalLatencyMs      int64
+}
+
+// OperationStats are the counters of one provider for one operation
+type OperationStats struct {
+	Calls        int64   `json:"calls"`
+	Failures     int64   `json:"failures"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	totalMs      int64
 }
 
-// NewFallbackService creates a new fallback service with both providers
-func NewFallbackService(gemini SummarizerService, ollama *OllamaService) *FallbackService {
+// NewFallbackService creates an empty chain; register providers with AddProvider
+func NewFallbackService(cfg ChainConfig) *FallbackService {
+	defaults := DefaultChainConfig()
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaults.FailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaults.Cooldown
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = defaults.HalfOpenProbes
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = defaults.BaseBackoff
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = defaults.MaxBackoff
+	}
+	if cfg.Orders == nil {
+		cfg.Orders = defaults.Orders
+	}
+
 	return &FallbackService{
-		gemini: gemini,
-		ollama: ollama,
+		cfg:       cfg,
+		providers: make(map[string]*chainProvider),
+		orders:    make(map[Operation][]string),
 	}
 }
 
-// isConnectionError checks if the error is a network/connection error
-func isConnectionError(err error) bool {
-	if err == nil {
-		return false
-	}
-	
-	// Check for network errors
-	if _, ok := err.(net.Error); ok {
-		return true
+// AddProvider registers a provider and recomputes the configured orders
+func (f *FallbackService) AddProvider(name string, svc SummarizerService) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.providers[name]; !exists {
+		f.names = append(f.names, name)
 	}
-	
-	// Check for common connection error messages
-	errStr := err.Error()
-	connectionIndicators := []string{
-		"connection refused",
-		"no such host",
-		"network is unreachable",
-		"connection reset",
-		"timeout",
-		"dial tcp",
-		"EOF",
+	f.providers[name] = &chainProvider{
+		name:    name,
+		svc:     svc,
+		breaker: NewCircuitBreaker(f.cfg.FailureThreshold, f.cfg.Cooldown, f.cfg.HalfOpenProbes),
+		stats: ProviderStats{
+			Name:         name,
+			ErrorsByKind: make(map[ErrorKind]int64),
+			Operations:   make(map[Operation]*OperationStats),
+		},
 	}
-	
-	for _, indicator := range connectionIndicators {
-		if strings.Contains(strings.ToLower(errStr), strings.ToLower(indicator)) {
-			return true
+
+	// Configured orders keep only registered providers; an empty result means "all, in registration order"
+	for _, op := range Operations {
+		var order []string
+		for _, n := range f.cfg.Orders[op] {
+			if _, ok := f.providers[n]; ok {
+				order = append(order, n)
+			}
+		}
+		if len(order) == 0 {
+			order = append([]string(nil), f.names...)
 		}
+		f.orders[op] = order
 	}
-	
-	return false
 }
 
-// isQuotaError checks if the error indicates API quota exhaustion (429)
-func isQuotaError(err error) bool {
-	if err == nil {
-		return false
+// Providers returns the registered provider names
+func (f *FallbackService) Providers() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]string(nil), f.names...)
+}
+
+// Orders returns a copy of the current provider order per operation
+func (f *FallbackService) Orders() map[Operation][]string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	orders := make(map[Operation][]string, len(f.orders))
+	for op, order := range f.orders {
+		orders[op] = append([]string(nil), order...)
 	}
-	
-	errStr := err.Error()
-	quotaIndicators := []string{
-		"429",
-		"quota",
-		"rate limit",
-		"too many requests",
-		"resource exhausted",
-		"RESOURCE_EXHAUSTED",
+	return orders
+}
+
+// SetOrder changes the provider order of an operation at runtime
+func (f *FallbackService) SetOrder(op Operation, names []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.orders[op]; !ok {
+		return fmt.Errorf("unknown operation: %s", op)
 	}
-	
-	for _, indicator := range quotaIndicators {
-		if strings.Contains(strings.ToLower(errStr), strings.ToLower(indicator)) {
-			return true
+	if len(names) == 0 {
+		return fmt.Errorf("order for %s must contain at least one provider", op)
+	}
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		if _, ok := f.providers[n]; !ok {
+			return fmt.Errorf("unknown provider: %s", n)
+		}
+		if seen[n] {
+			return fmt.Errorf("provider %s listed twice for %s", n, op)
 		}
+		seen[n] = true
 	}
-	
-	return false
+
+	f.orders[op] = append([]string(nil), names...)
+	log.Printf("[AI] Provider order for %s set to %v", op, names)
+	return nil
 }
 
-// SummarizeEmail tries Ollama first (free, local), falls back to Gemini on connection error
-func (f *FallbackService) SummarizeEmail(ctx context.Context, emailText string) (string, error) {
-	// Try Ollama first for summarization (local, free)
-	if f.ollama != nil {
-		log.Println("[AI] Trying Ollama for summarization...")
-		result, err := f.ollama.SummarizeEmail(ctx, emailText)
-		if err == nil {
-			log.Println("[AI] Ollama summarization successful")
-			return result, nil
-		}
-		
-		// If connection error, try Gemini
-		if isConnectionError(err) {
-			log.Printf("[AI] Ollama connection failed: %v, falling back to Gemini", err)
-		} else {
-			// Other errors, still try Gemini but log the error
-			log.Printf("[AI] Ollama error: %v, falling back to Gemini", err)
-		}
-	}
-	
-	// Fallback to Gemini
-	if f.gemini != nil {
-		log.Println("[AI] Using Gemini for summarization...")
-		result, err := f.gemini.SummarizeEmail(ctx, emailText)
-		if err == nil {
-			log.Println("[AI] Gemini summarization successful")
-			return result, nil
+// SetPromptRegistry sets the templates every provider renders its prompts from
+func (f *FallbackService) SetPromptRegistry(prompts *PromptRegistry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prompts = prompts
+}
+
+// PromptRegistry returns the registry used by the chain (the built-in templates if none was set)
+func (f *FallbackService) PromptRegistry() *PromptRegistry {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.prompts == nil {
+		return builtinPrompts
+	}
+	return f.prompts
+}
+
+// SetPromptRecorder sets where the runs of A/B-tested prompt versions are stored
+func (f *FallbackService) SetPromptRecorder(recorder PromptRunRecorder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recorder = recorder
+}
+
+// SetUsageTracker sets where the usage of each user is accounted and their quotas enforced.
+// Users over quota only get the providers the tracker lets through (e.g. local Ollama).
+func (f *FallbackService) SetUsageTracker(tracker UsageTracker) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.usage = tracker
+}
+
+// observe attaches the chain's prompt registry to ctx, traces the versions rendered by the
+// providers and meters their usage. done reports the operation's outcome: the runs of versions
+// picked by an A/B test and the usage of every provider called.
+func (f *FallbackService) observe(ctx context.Context) (context.Context, func(output interface{}, err error)) {
+	f.mu.RLock()
+	prompts, recorder, tracker := f.prompts, f.recorder, f.usage
+	f.mu.RUnlock()
+
+	if prompts != nil {
+		ctx = withPromptRegistry(ctx, prompts)
+	}
+	ctx, trace := WithPromptTrace(ctx)
+	ctx, meter := withUsageMeter(ctx)
+	pc := promptContextFrom(ctx)
+	start := time.Now()
+
+	return ctx, func(output interface{}, err error) {
+		text, ok := output.(string)
+		if !ok && output != nil {
+			if encoded, jsonErr := json.Marshal(output); jsonErr == nil {
+				text = string(encoded)
+			}
+		}
+
+		if tracker != nil && pc.UserID != "" {
+			if provider, _ := trace.Provider(); provider != "" {
+				meter.addOutput(provider, utf8.RuneCountInString(text))
+			}
+			for _, record := range meter.snapshot(pc.UserID) {
+				tracker.RecordUsage(record)
+			}
 		}
-		
-		// If Gemini also fails with quota error, try Ollama again (might have been temp issue)
-		if isQuotaError(err) && f.ollama != nil {
-			log.Printf("[AI] Gemini quota exhausted: %v, retrying Ollama", err)
-			return f.ollama.SummarizeEmail(ctx, emailText)
+
+		if recorder == nil {
+			return
+		}
+		for _, usage := range trace.Usages() {
+			if !usage.Experiment {
+				continue
+			}
+			run := PromptRun{PromptUsage: usage, UserID: pc.UserID, Latency: time.Since(start)}
+			if err != nil {
+				run.Error = err.Error()
+			} else {
+				run.Output = text
+			}
+			recorder.RecordPromptRun(run)
 		}
-		
-		return "", fmt.Errorf("gemini summarization failed: %w", err)
 	}
-	
-	return "", fmt.Errorf("no AI provider available for summarization")
 }
 
-// ExtractTasksFromEmail tries Gemini first (better quality), falls back to Ollama on quota error
-func (f *FallbackService) ExtractTasksFromEmail(ctx context.Context, emailText string) ([]TaskExtraction, error) {
-	// Try Gemini first for task extraction (better quality)
-	if f.gemini != nil {
-		log.Println("[AI] Trying Gemini for task extraction...")
-		result, err := f.gemini.ExtractTasksFromEmail(ctx, emailText)
-		if err == nil {
-			log.Println("[AI] Gemini task extraction successful")
-			return result, nil
-		}
-		
-		// If quota error, try Ollama
-		if isQuotaError(err) {
-			log.Printf("[AI] Gemini quota exhausted: %v, falling back to Ollama", err)
-		} else {
-			// Other errors, still try Ollama but log
-			log.Printf("[AI] Gemini error: %v, falling back to Ollama", err)
-		}
-	}
-	
-	// Fallback to Ollama
-	if f.ollama != nil {
-		log.Println("[AI] Using Ollama for task extraction...")
-		result, err := f.ollama.ExtractTasksFromEmail(ctx, emailText)
-		if err == nil {
-			log.Println("[AI] Ollama task extraction successful")
-			return result, nil
+// ProviderModels returns the model currently configured for each provider that reports one
+func (f *FallbackService) ProviderModels() map[string]string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	models := make(map[string]string, len(f.providers))
+	for name, p := range f.providers {
+		if model := providerModel(p.svc); model != "" {
+			models[name] = model
+		}
+	}
+	return models
+}
+
+// Stats returns a snapshot of every provider's breaker state and counters
+func (f *FallbackService) Stats() []ProviderStats {
+	f.mu.RLock()
+	providers := make([]*chainProvider, 0, len(f.names))
+	for _, n := range f.names {
+		providers = append(providers, f.providers[n])
+	}
+	f.mu.RUnlock()
+
+	result := make([]ProviderStats, 0, len(providers))
+	for _, p := range providers {
+		state, failures, openedAt := p.breaker.Snapshot()
+
+		p.statsMu.Lock()
+		s := p.stats
+		s.ErrorsByKind = make(map[ErrorKind]int64, len(p.stats.ErrorsByKind))
+		for k, v := range p.stats.ErrorsByKind {
+			s.ErrorsByKind[k] = v
 		}
-		
-		// If Ollama also fails with connection error, try Gemini again
-		if isConnectionError(err) && f.gemini != nil {
-			log.Printf("[AI] Ollama connection failed: %v, retrying Gemini", err)
-			return f.gemini.ExtractTasksFromEmail(ctx, emailText)
+		s.Operations = make(map[Operation]*OperationStats, len(p.stats.Operations))
+		for op, os := range p.stats.Operations {
+			copied := *os
+			s.Operations[op] = &copied
+		}
+		p.statsMu.Unlock()
+
+		s.State = state
+		s.ConsecutiveFailures = failures
+		if !openedAt.IsZero() {
+			s.OpenedAt = &openedAt
 		}
-		
-		return nil, fmt.Errorf("ollama task extraction failed: %w", err)
+		result = append(result, s)
 	}
-	
-	return nil, fmt.Errorf("no AI provider available for task extraction")
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// SummarizeEmail runs the summarize chain
+func (f *FallbackService) SummarizeEmail(ctx context.Context, emailText string) (string, error) {
+	ctx, done := f.observe(ctx)
+	var summary string
+	err := f.run(ctx, OperationSummarize, func(ctx context.Context, svc SummarizerService) error {
+		result, err := svc.SummarizeEmail(ctx, emailText)
+		summary = result
+		return err
+	})
+	done(summary, err)
+	return summary, err
+}
+
+// ExtractTasksFromEmail runs the task extraction chain
+func (f *FallbackService) ExtractTasksFromEmail(ctx context.Context, req TaskExtractionRequest) ([]TaskExtraction, error) {
+	ctx, done := f.observe(ctx)
+	var tasks []TaskExtraction
+	err := f.run(ctx, OperationExtractTasks, func(ctx context.Context, svc SummarizerService) error {
+		result, err := svc.ExtractTasksFromEmail(ctx, req)
+		tasks = result
+		return err
+	})
+	done(tasks, err)
+	return tasks, err
 }
 
-// GenerateSynonyms tries Gemini first (better quality), falls back to Ollama
+// GenerateSynonyms runs the synonyms chain
 func (f *FallbackService) GenerateSynonyms(ctx context.Context, word string) ([]string, error) {
-	// Try Gemini first for synonyms (better understanding of concepts)
-	if f.gemini != nil {
-		result, err := f.gemini.GenerateSynonyms(ctx, word)
+	ctx, done := f.observe(ctx)
+	var synonyms []string
+	err := f.run(ctx, OperationSynonyms, func(ctx context.Context, svc SummarizerService) error {
+		result, err := svc.GenerateSynonyms(ctx, word)
+		synonyms = result
+		return err
+	})
+	done(synonyms, err)
+	return synonyms, err
+}
+
+// ClassifyEmail runs the classification chain
+func (f *FallbackService) ClassifyEmail(ctx context.Context, req ClassifyRequest) (*Classification, error) {
+	ctx, done := f.observe(ctx)
+	var classification *Classification
+	err := f.run(ctx, OperationClassify, func(ctx context.Context, svc SummarizerService) error {
+		result, err := svc.(EmailClassifier).ClassifyEmail(ctx, req)
+		classification = result
+		return err
+	})
+	done(classification, err)
+	return classification, err
+}
+
+// SummarizeEmailStream runs the summarize chain, streaming text through onDelta.
+// Providers without streaming support answer in one piece, delivered as a single delta.
+func (f *FallbackService) SummarizeEmailStream(ctx context.Context, emailText string, onDelta func(string)) (string, error) {
+	ctx, done := f.observe(ctx)
+	var summary string
+	err := f.run(ctx, OperationSummarize, func(ctx context.Context, svc SummarizerService) error {
+		result, err := callStreaming(onDelta, func(onDelta func(string)) (string, error) {
+			if streamer, ok := svc.(StreamingSummarizer); ok {
+				return streamer.SummarizeEmailStream(ctx, emailText, onDelta)
+			}
+			result, err := svc.SummarizeEmail(ctx, emailText)
+			if err == nil && result != "" {
+				onDelta(result)
+			}
+			return result, err
+		})
+		summary = result
+		return err
+	})
+	done(summary, err)
+	return summary, err
+}
+
+// DraftReply runs the reply drafting chain, streaming text through onDelta
+func (f *FallbackService) DraftReply(ctx context.Context, req DraftReplyRequest, onDelta func(string)) (string, error) {
+	ctx, done := f.observe(ctx)
+	var draft string
+	err := f.run(ctx, OperationDraftReply, func(ctx context.Context, svc SummarizerService) error {
+		result, err := callStreaming(onDelta, func(onDelta func(string)) (string, error) {
+			return svc.(ReplyDrafter).DraftReply(ctx, req, onDelta)
+		})
+		draft = result
+		return err
+	})
+	done(draft, err)
+	return draft, err
+}
+
+// callStreaming runs one streaming provider call. Once text has reached onDelta, a failure is
+// wrapped in partialStreamError so the chain neither retries nor falls back: the caller would
+// otherwise see two different outputs mixed together.
+func callStreaming(onDelta func(string), call func(onDelta func(string)) (string, error)) (string, error) {
+	streamed := false
+	result, err := call(func(delta string) {
+		streamed = true
+		if onDelta != nil {
+			onDelta(delta)
+		}
+	})
+	if err != nil && streamed {
+		return result, &partialStreamError{err: err}
+	}
+	return result, err
+}
+
+// run tries each provider of the operation's chain until one succeeds
+func (f *FallbackService) run(ctx context.Context, op Operation, call func(context.Context, SummarizerService) error) error {
+	f.mu.RLock()
+	order := f.orders[op]
+	providers := make([]*chainProvider, 0, len(order))
+	for _, n := range order {
+		providers = append(providers, f.providers[n])
+	}
+	tracker := f.usage
+	f.mu.RUnlock()
+
+	userID := promptContextFrom(ctx).UserID
+	var errs []error
+	var quotaErr error
+	for _, p := range providers {
+		if checker, ok := p.svc.(availabilityChecker); ok && !checker.Configured() {
+			continue
+		}
+		if !supportsOperation(p.svc, op) {
+			continue
+		}
+		if tracker != nil && userID != "" {
+			// Over quota: only the providers the tracker exempts (local ones) are tried
+			if err := tracker.CheckQuota(userID, p.name); err != nil {
+				log.Printf("[AI] %s skipped for %s: %v", p.name, op, err)
+				quotaErr = err
+				continue
+			}
+		}
+		if !p.breaker.Allow() {
+			p.recordSkipped()
+			log.Printf("[AI] %s circuit open, skipping for %s", p.name, op)
+			continue
+		}
+
+		log.Printf("[AI] Trying %s for %s...", p.name, op)
+		err := f.callWithRetry(ctx, op, p, call)
+		if err == nil || isPartialStream(err) {
+			// This provider's output is the one the caller gets (streams are never mixed)
+			if trace := promptTraceFrom(ctx); trace != nil {
+				trace.setProvider(p.name, providerModel(p.svc))
+			}
+		}
 		if err == nil {
-			return result, nil
+			p.breaker.Success()
+			log.Printf("[AI] %s %s successful", p.name, op)
+			return nil
+		}
+
+		if classifyError(err) == ErrorKindCanceled {
+			p.breaker.Release()
+			return err
 		}
-		
-		if isQuotaError(err) {
-			log.Printf("[AI] Gemini quota exhausted for synonyms: %v, falling back to Ollama", err)
-		} else {
-			log.Printf("[AI] Gemini error for synonyms: %v, falling back to Ollama", err)
+		p.breaker.Failure()
+		if isPartialStream(err) {
+			log.Printf("[AI] %s %s failed mid-stream: %v", p.name, op, err)
+			return fmt.Errorf("%s: %w", p.name, err)
+		}
+		log.Printf("[AI] %s %s failed: %v, trying next provider", p.name, op, err)
+		errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+
+		if ctx.Err() != nil {
+			break
 		}
 	}
-	
-	// Fallback to Ollama
-	if f.ollama != nil {
-		result, err := f.ollama.GenerateSynonyms(ctx, word)
-		if err == nil {
-			return result, nil
+
+	if quotaErr != nil {
+		if len(errs) == 0 {
+			return quotaErr
 		}
-		
-		if isConnectionError(err) && f.gemini != nil {
-			log.Printf("[AI] Ollama connection failed for synonyms: %v, retrying Gemini", err)
-			return f.gemini.GenerateSynonyms(ctx, word)
+		errs = append(errs, quotaErr)
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no AI provider available for %s", op)
+	}
+	return fmt.Errorf("all AI providers failed for %s: %w", op, errors.Join(errs...))
+}
+
+// callWithRetry calls one provider, retrying retryable errors with jittered exponential backoff
+func (f *FallbackService) callWithRetry(ctx context.Context, op Operation, p *chainProvider, call func(context.Context, SummarizerService) error) error {
+	var err error
+	for attempt := 1; attempt <= f.cfg.MaxAttempts; attempt++ {
+		meter := usageMeterFrom(ctx)
+		if meter != nil {
+			meter.begin(op, p.name, providerModel(p.svc))
+		}
+		start := time.Now()
+		err = call(ctx, p.svc)
+		if meter != nil {
+			meter.end(err)
+		}
+		kind := classifyError(err)
+		p.recordCall(op, time.Since(start), err, kind, attempt > 1)
+
+		if err == nil || !isRetryable(kind) || isPartialStream(err) || attempt == f.cfg.MaxAttempts {
+			return err
+		}
+
+		delay := f.backoff(attempt)
+		log.Printf("[AI] %s %s attempt %d failed (%s), retrying in %s", p.name, op, attempt, kind, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-		
-		return nil, fmt.Errorf("ollama synonyms generation failed: %w", err)
 	}
-	
-	return nil, fmt.Errorf("no AI provider available for synonyms generation")
+	return err
+}
+
+// backoff returns BaseBackoff * 2^(attempt-1) capped at MaxBackoff, with equal jitter
+func (f *FallbackService) backoff(attempt int) time.Duration {
+	d := f.cfg.BaseBackoff << (attempt - 1)
+	if d > f.cfg.MaxBackoff || d <= 0 {
+		d = f.cfg.MaxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (p *chainProvider) recordCall(op Operation, latency time.Duration, err error, kind ErrorKind, retry bool) {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	ms := latency.Milliseconds()
+	s := &p.stats
+	s.Calls++
+	if retry {
+		s.Retries++
+	}
+	s.totalLatencyMs += ms
+	s.AvgLatencyMs = float64(s.totalLatencyMs) / float64(s.Calls)
+	s.LastLatencyMs = ms
+	if ms > s.MaxLatencyMs {
+		s.MaxLatencyMs = ms
+	}
+
+	opStats, ok := s.Operations[op]
+	if !ok {
+		opStats = &OperationStats{}
+		s.Operations[op] = opStats
+	}
+	opStats.Calls++
+	opStats.totalMs += ms
+	opStats.AvgLatencyMs = float64(opStats.totalMs) / float64(opStats.Calls)
+
+	if err == nil {
+		s.Successes++
+		return
+	}
+	s.Failures++
+	opStats.Failures++
+	s.ErrorsByKind[kind]++
+	now := time.Now()
+	s.LastError = truncateRunes(err.Error(), maxLastErrorLen)
+	s.LastErrorAt = &now
+}
+
+// truncateRunes cuts s to at most max runes without splitting a multi-byte character
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max]) + "..."
+}
+
+func (p *chainProvider) recordSkipped() {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	p.stats.SkippedOpen++
 }
@@ -0,0 +1,251 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider answers with its errors in turn (nil once they run out) and counts its calls
+type fakeProvider struct {
+	name  string
+	errs  []error
+	calls int
+}
+
+func (p *fakeProvider) next() error {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return p.errs[p.calls-1]
+	}
+	return nil
+}
+
+func (p *fakeProvider) SummarizeEmail(ctx context.Context, emailText string) (string, error) {
+	if err := p.next(); err != nil {
+		return "", err
+	}
+	return "summary from " + p.name, nil
+}
+
+func (p *fakeProvider) ExtractTasksFromEmail(ctx context.Context, req TaskExtractionRequest) ([]TaskExtraction, error) {
+	return nil, p.next()
+}
+
+func (p *fakeProvider) GenerateSynonyms(ctx context.Context, word string) ([]string, error) {
+	return nil, p.next()
+}
+
+// fakeStreamer streams its deltas, then fails with err
+type fakeStreamer struct {
+	fakeProvider
+	deltas []string
+	err    error
+}
+
+func (p *fakeStreamer) SummarizeEmailStream(ctx context.Context, emailText string, onDelta func(string)) (string, error) {
+	p.calls++
+	for _, delta := range p.deltas {
+		onDelta(delta)
+	}
+	return strings.Join(p.deltas, ""), p.err
+}
+
+func providerError(kind ErrorKind) error {
+	return &ProviderError{Kind: kind, Err: errors.New(string(kind) + " error")}
+}
+
+func testChain(providers ...SummarizerService) *FallbackService {
+	f := NewFallbackService(ChainConfig{
+		Orders:           map[Operation][]string{OperationSummarize: {"a", "b"}},
+		FailureThreshold: 2,
+		MaxAttempts:      2,
+		BaseBackoff:      time.Millisecond,
+		MaxBackoff:       2 * time.Millisecond,
+	})
+	for i, p := range providers {
+		f.AddProvider(string(rune('a'+i)), p)
+	}
+	return f
+}
+
+func TestFallbackRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		errsA     []error
+		errsB     []error
+		openA     bool // Breaker of a opened before the call
+		want      string
+		wantErr   string
+		wantCalls [2]int
+	}{
+		{name: "first provider answers", want: "summary from a", wantCalls: [2]int{1, 0}},
+		{
+			name:      "server error is retried on the same provider",
+			errsA:     []error{providerError(ErrorKindServer)},
+			want:      "summary from a",
+			wantCalls: [2]int{2, 0},
+		},
+		{
+			name:      "connection errors use up the attempts, then fall back",
+			errsA:     []error{providerError(ErrorKindConnection), providerError(ErrorKindConnection)},
+			want:      "summary from b",
+			wantCalls: [2]int{2, 1},
+		},
+		{
+			name:      "client error falls back without retry",
+			errsA:     []error{providerError(ErrorKindClient)},
+			want:      "summary from b",
+			wantCalls: [2]int{1, 1},
+		},
+		{
+			name:      "quota error falls back without retry",
+			errsA:     []error{providerError(ErrorKindQuota)},
+			want:      "summary from b",
+			wantCalls: [2]int{1, 1},
+		},
+		{
+			name:      "open breaker is skipped",
+			openA:     true,
+			want:      "summary from b",
+			wantCalls: [2]int{0, 1},
+		},
+		{
+			name:      "every provider fails",
+			errsA:     []error{providerError(ErrorKindClient)},
+			errsB:     []error{providerError(ErrorKindResponse)},
+			wantErr:   "all AI providers failed for summarize",
+			wantCalls: [2]int{1, 1},
+		},
+		{
+			name:      "cancellation stops the chain",
+			errsA:     []error{context.Canceled},
+			wantErr:   "context canceled",
+			wantCalls: [2]int{1, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &fakeProvider{name: "a", errs: tt.errsA}
+			b := &fakeProvider{name: "b", errs: tt.errsB}
+			f := testChain(a, b)
+			if tt.openA {
+				for i := 0; i < 2; i++ {
+					f.providers["a"].breaker.Failure()
+				}
+			}
+
+			got, err := f.SummarizeEmail(context.Background(), "text")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("SummarizeEmail() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil || got != tt.want {
+				t.Errorf("SummarizeEmail() = %q, %v; want %q", got, err, tt.want)
+			}
+			if calls := [2]int{a.calls, b.calls}; calls != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if tt.openA {
+				if skipped := f.Stats()[0].SkippedOpen; skipped != 1 {
+					t.Errorf("SkippedOpen of a = %d, want 1", skipped)
+				}
+			}
+		})
+	}
+}
+
+func TestFallbackBreakerOpensAcrossCalls(t *testing.T) {
+	a := &fakeProvider{name: "a", errs: []error{providerError(ErrorKindClient), providerError(ErrorKindClient)}}
+	b := &fakeProvider{name: "b"}
+	f := testChain(a, b)
+	for i := 0; i < 3; i++ {
+		if _, err := f.SummarizeEmail(context.Background(), "text"); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if a.calls != 2 || b.calls != 3 {
+		t.Errorf("calls = %d, %d; want a skipped once its breaker opened (2, 3)", a.calls, b.calls)
+	}
+	if state := f.Stats()[0].State; state != BreakerOpen {
+		t.Errorf("state of a = %s, want open", state)
+	}
+}
+
+func TestFallbackStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		deltas     []string
+		err        error
+		wantDeltas []string
+		wantErr    bool
+		wantCalls  [2]int
+	}{
+		{
+			name:       "failure after text stops the chain",
+			deltas:     []string{"Hel"},
+			err:        providerError(ErrorKindServer),
+			wantDeltas: []string{"Hel"},
+			wantErr:    true,
+			wantCalls:  [2]int{1, 0},
+		},
+		{
+			name:       "failure before any text falls back",
+			err:        providerError(ErrorKindClient),
+			wantDeltas: []string{"summary from b"},
+			wantCalls:  [2]int{1, 1},
+		},
+		{
+			name:       "complete stream",
+			deltas:     []string{"Hel", "lo"},
+			wantDeltas: []string{"Hel", "lo"},
+			wantCalls:  [2]int{1, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &fakeStreamer{fakeProvider: fakeProvider{name: "a"}, deltas: tt.deltas, err: tt.err}
+			b := &fakeProvider{name: "b"}
+			f := testChain(a, b)
+
+			var deltas []string
+			_, err := f.SummarizeEmailStream(context.Background(), "text", func(delta string) { deltas = append(deltas, delta) })
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SummarizeEmailStream() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !isPartialStream(err) {
+				t.Errorf("error = %v, want a partial stream error", err)
+			}
+			if !reflect.DeepEqual(deltas, tt.wantDeltas) {
+				t.Errorf("deltas = %q, want %q", deltas, tt.wantDeltas)
+			}
+			if calls := [2]int{a.calls, b.calls}; calls != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFallbackBackoff(t *testing.T) {
+	f := NewFallbackService(ChainConfig{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+		{70, time.Second}, // Shift overflow
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := f.backoff(tt.attempt); d < tt.max/2 || d > tt.max {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", tt.attempt, d, tt.max/2, tt.max)
+			}
+		}
+	}
+}
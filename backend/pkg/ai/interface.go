@@ -5,28 +5,113 @@ import (
 	"time"
 )
 
+// TaskExtractionRequest is the email to extract tasks from
+type TaskExtractionRequest struct {
+	Subject    string
+	Body       string         // Plain text
+	ReceivedAt time.Time      // Anchors relative dates ("tomorrow", "on Friday"); zero = now
+	Location   *time.Location // User's time zone, for dates without an offset; nil = UTC
+}
+
+// SourceSpan is the sentence of the email a task was extracted from.
+// Start and End are rune offsets in the body; both are -1 when the sentence is not in the body
+// (it comes from the subject, or the model did not quote the email exactly).
+type SourceSpan struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
 // TaskExtraction represents an extracted task from email (shared type)
 type TaskExtraction struct {
 	Title       string     `json:"title"`
 	Description string     `json:"description,omitempty"`
 	DueDate     *time.Time `json:"due_date,omitempty"`
 	Priority    string     `json:"priority"`
+	Assignee    string     `json:"assignee,omitempty"` // Who should do it, as named in the email ("" = the recipient)
+	Confidence  float64    `json:"confidence"`         // In [0, 1]
+	Source      SourceSpan `json:"source"`
 }
 
 // SummarizerService is the interface for AI summarization and task extraction
 // Implement this interface to add new AI providers (Gemini, Ollama, OpenAI, etc.)
 type SummarizerService interface {
 	SummarizeEmail(ctx context.Context, emailText string) (string, error)
-	ExtractTasksFromEmail(ctx context.Context, emailText string) ([]TaskExtraction, error)
+	ExtractTasksFromEmail(ctx context.Context, req TaskExtractionRequest) ([]TaskExtraction, error)
 	GenerateSynonyms(ctx context.Context, word string) ([]string, error)
 }
 
+// StreamingSummarizer is implemented by providers that can stream a summary as it is generated.
+// onDelta (optional) receives text chunks; the full summary is returned at the end.
+type StreamingSummarizer interface {
+	SummarizeEmailStream(ctx context.Context, emailText string, onDelta func(string)) (string, error)
+}
+
 // ProviderType represents the AI provider type
 type ProviderType string
 
 const (
 	ProviderGemini ProviderType = "gemini"
 	ProviderOllama ProviderType = "ollama"
+	ProviderOpenAI ProviderType = "openai" // Any OpenAI-compatible chat completions server
 	ProviderAuto   ProviderType = "auto"
 )
 
+// DraftMessage is one email given to the model as context (reply drafting, classification)
+type DraftMessage struct {
+	From    string    `json:"from"`
+	To      []string  `json:"to,omitempty"`
+	Subject string    `json:"subject"`
+	Date    time.Time `json:"date"`
+	Body    string    `json:"body"` // Plain text
+}
+
+// DraftReplyRequest describes the reply to write
+type DraftReplyRequest struct {
+	Email       DraftMessage   // The email being replied to
+	Thread      []DraftMessage // Earlier messages of the thread, oldest first (may be empty)
+	Tone        string         // e.g. "formal", "friendly", "concise" (free text)
+	Instruction string         // What the reply should say, e.g. "accept the meeting but ask to move it to 3pm"
+	SenderName  string         // Used to sign the reply
+}
+
+// ReplyDrafter is implemented by providers that can write reply drafts.
+// onDelta (optional) receives text chunks as they are generated; the full draft is returned at the end.
+type ReplyDrafter interface {
+	DraftReply(ctx context.Context, req DraftReplyRequest, onDelta func(string)) (string, error)
+}
+
+// ClassifyColumn is a Kanban column the classifier can choose
+type ClassifyColumn struct {
+	ID          string
+	Name        string
+	Description string
+	Examples    []string // Example subjects/phrases configured by the user
+}
+
+// ClassifyExample is an email the user filed by hand (few-shot example)
+type ClassifyExample struct {
+	ColumnID string
+	Subject  string
+	From     string
+	Snippet  string
+}
+
+// ClassifyRequest asks which column an email belongs to
+type ClassifyRequest struct {
+	Email    DraftMessage
+	Columns  []ClassifyColumn
+	Examples []ClassifyExample
+}
+
+// Classification is the predicted column with a confidence in [0, 1]
+type Classification struct {
+	ColumnID   string  `json:"column_id"`
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// EmailClassifier is implemented by providers that can sort emails into Kanban columns
+type EmailClassifier interface {
+	ClassifyEmail(ctx context.Context, req ClassifyRequest) (*Classification, error)
+}
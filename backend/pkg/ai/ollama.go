@@ -1,15 +1,14 @@
 package ai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
 	"strings"
-	"time"
 )
 
 // OllamaService implements SummarizerService using Ollama local LLM
@@ -41,29 +40,19 @@ func NewOllamaServiceWithGetters(getBaseURL, getModel func() string) *OllamaServ
 	}
 }
 
+// ModelName returns the model currently configured
+func (o *OllamaService) ModelName() string {
+	return o.getModel()
+}
+
 // SummarizeEmail implements SummarizerService
 func (o *OllamaService) SummarizeEmail(ctx context.Context, emailText string) (string, error) {
 	url := o.getBaseURL() + "/api/generate"
 
-	// Enhanced Vietnamese prompt with professional prompting techniques
-	// (Same as Gemini for consistency across providers)
-	prompt := fmt.Sprintf(`Bạn là trợ lý email thông minh. Phân tích email sau và tạo tóm tắt HỮU ÍCH giúp user quyết định nhanh.
-
-HƯỚNG DẪN:
-- Dòng 1: Tóm tắt ý chính trong 1 câu ngắn gọn
-- Dòng 2 (nếu có): "📌 Cần làm: [action item]" hoặc "📅 Deadline: [thời gian]" hoặc "💡 Lưu ý: [điểm quan trọng]"
-- Nếu email quảng cáo/spam: chỉ ghi "Quảng cáo từ [tên công ty]"
-- Ngôn ngữ: Tiếng Việt, tối đa 2 dòng
-- QUAN TRỌNG: Viết đầy đủ, KHÔNG được cắt ngắn với "..." hoặc bỏ lửng câu
-
-VÍ DỤ OUTPUT TỐT:
-"Cuộc họp team vào thứ 5 lúc 14h về tiến độ dự án ABC.
-📌 Cần làm: Chuẩn bị báo cáo tiến độ trước thứ 4."
-
-EMAIL:
-%s
-
-TÓM TẮT:`, emailText)
+	prompt, err := buildSummaryPrompt(ctx, emailText)
+	if err != nil {
+		return "", err
+	}
 
 	payload := map[string]interface{}{
 		"model":  o.getModel(),
@@ -89,7 +78,7 @@ TÓM TẮT:`, emailText)
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("ollama request failed: %w", err)
+		return "", connectionError(fmt.Errorf("ollama request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -99,7 +88,7 @@ TÓM TẮT:`, emailText)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ollama API error (%d): %s", resp.StatusCode, string(respBody))
+		return "", httpStatusError("ollama", resp.StatusCode, respBody)
 	}
 
 	var result struct {
@@ -107,46 +96,30 @@ TÓM TẮT:`, emailText)
 		Done     bool   `json:"done"`
 	}
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return "", responseError("failed to parse response: %w", err)
 	}
 
 	return result.Response, nil
 }
 
-// ExtractTasksFromEmail implements SummarizerService for task extraction
-func (o *OllamaService) ExtractTasksFromEmail(ctx context.Context, emailText string) ([]TaskExtraction, error) {
+// ExtractTasksFromEmail implements SummarizerService for task extraction.
+// The output is constrained by the extraction JSON schema (Ollama structured outputs).
+func (o *OllamaService) ExtractTasksFromEmail(ctx context.Context, req TaskExtractionRequest) ([]TaskExtraction, error) {
 	url := o.getBaseURL() + "/api/generate"
 
-	currentDate := time.Now().Format("2006-01-02")
-
-	prompt := fmt.Sprintf(`Bạn là trợ lý AI chuyên phân tích email để trích xuất các TASK/VIỆC CẦN LÀM.
-
-NGÀY HÔM NAY: %s
-
-HƯỚNG DẪN:
-1. Đọc email và tìm TẤT CẢ các việc cần làm, deadline, cuộc họp, reminder
-2. Trả về danh sách tasks dưới dạng JSON array
-3. Mỗi task cần có: title (bắt buộc), description, due_date (ISO 8601 format nếu có), priority (high/medium/low)
-4. Nếu email không có task nào, trả về mảng rỗng []
-5. Priority: 
-   - high: deadline gấp (trong 24h), urgent, important
-   - medium: deadline vài ngày, cần làm sớm
-   - low: không gấp, FYI
-
-CHỈ trả về JSON array, KHÔNG có text khác.
-
-EMAIL:
-%s
-
-JSON OUTPUT:`, currentDate, emailText)
+	prompt, err := buildTaskExtractionPrompt(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
 
 	payload := map[string]interface{}{
 		"model":  o.getModel(),
 		"prompt": prompt,
 		"stream": false,
+		"format": taskExtractionSchema,
 		"options": map[string]interface{}{
 			"temperature": 0.2,
-			"num_predict": 500,
+			"num_predict": 800,
 		},
 	}
 
@@ -155,16 +128,16 @@ JSON OUTPUT:`, currentDate, emailText)
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("ollama request failed: %w", err)
+		return nil, connectionError(fmt.Errorf("ollama request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -174,110 +147,91 @@ JSON OUTPUT:`, currentDate, emailText)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ollama API error (%d): %s", resp.StatusCode, string(respBody))
+		return nil, httpStatusError("ollama", resp.StatusCode, respBody)
 	}
 
 	var result struct {
 		Response string `json:"response"`
 	}
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, responseError("failed to parse response: %w", err)
 	}
 
-	// Extract JSON from response
-	responseText := strings.TrimSpace(result.Response)
-	jsonStart := strings.Index(responseText, "[")
-	jsonEnd := strings.LastIndex(responseText, "]")
-	if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
-		responseText = responseText[jsonStart : jsonEnd+1]
-	}
+	return parseTaskExtractionJSON(result.Response, req)
+}
 
-	var rawTasks []struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		DueDate     string `json:"due_date"`
-		Priority    string `json:"priority"`
-	}
+// GenerateSynonyms generates synonyms for a query using Ollama
+func (o *OllamaService) GenerateSynonyms(ctx context.Context, word string) ([]string, error) {
+	url := o.getBaseURL() + "/api/generate"
 
-	if err := json.Unmarshal([]byte(responseText), &rawTasks); err != nil {
-		return nil, fmt.Errorf("failed to parse task JSON: %v", err)
+	prompt, err := buildSynonymsPrompt(ctx, word, false)
+	if err != nil {
+		return nil, err
 	}
 
-	var tasks []TaskExtraction
-	for _, rt := range rawTasks {
-		if rt.Title == "" {
-			continue
-		}
-
-		task := TaskExtraction{
-			Title:       rt.Title,
-			Description: rt.Description,
-			Priority:    rt.Priority,
-		}
+	payload := map[string]interface{}{
+		"model":  o.getModel(),
+		"prompt": prompt,
+		"stream": false,
+		"options": map[string]interface{}{
+			"temperature": 0.2, // Lower temperature for more consistent results
+		},
+	}
 
-		if task.Priority == "" {
-			task.Priority = "medium"
-		}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
 
-		if rt.DueDate != "" {
-			formats := []string{time.RFC3339, "2006-01-02T15:04:05Z", "2006-01-02T15:04:05", "2006-01-02"}
-			for _, format := range formats {
-				if t, err := time.Parse(format, rt.DueDate); err == nil {
-					task.DueDate = &t
-					break
-				}
-			}
-			if task.DueDate == nil {
-				task.DueDate = parseRelativeDate(rt.DueDate)
-			}
-		}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
 
-		tasks = append(tasks, task)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, connectionError(fmt.Errorf("ollama request failed: %w", err))
 	}
+	defer resp.Body.Close()
 
-	return tasks, nil
-}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
 
-// parseRelativeDate attempts to parse relative date expressions
-func parseRelativeDate(dateStr string) *time.Time {
-	now := time.Now()
-	dateStr = strings.ToLower(dateStr)
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpStatusError("ollama", resp.StatusCode, respBody)
+	}
 
-	if matched, _ := regexp.MatchString(`(tomorrow|ngày mai)`, dateStr); matched {
-		t := now.AddDate(0, 0, 1)
-		return &t
+	var result struct {
+		Response string `json:"response"`
 	}
-	if matched, _ := regexp.MatchString(`(next week|tuần sau|tuần tới)`, dateStr); matched {
-		t := now.AddDate(0, 0, 7)
-		return &t
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, responseError("failed to parse response: %w", err)
 	}
 
-	return nil
+	return parseSynonymsJSON(result.Response)
 }
 
-// GenerateSynonyms generates synonyms for a query using Ollama
-func (o *OllamaService) GenerateSynonyms(ctx context.Context, word string) ([]string, error) {
+// ClassifyEmail implements EmailClassifier using Ollama's JSON output format
+func (o *OllamaService) ClassifyEmail(ctx context.Context, req ClassifyRequest) (*Classification, error) {
 	url := o.getBaseURL() + "/api/generate"
 
-	prompt := fmt.Sprintf(`Tìm các "RELATED CONCEPTS" (khái niệm liên quan), "SPECIFIC EXAMPLES" (ví dụ cụ thể), và "DOMAIN TERMS" (thuật ngữ chuyên ngành) cho từ khóa sau trong ngữ cảnh EMAIL CÔNG VIỆC: "%s"
-	
-	Mục tiêu: Mở rộng tìm kiếm sang các từ khóa mà không nhất thiết phải đồng nghĩa hoàn toàn, nhưng có liên quan mật thiết về mặt ngữ nghĩa/ngữ cảnh.
-	
-	Ví dụ:
-	- Input "money" -> Output: ["invoice", "salary", "payment", "transaction", "billing", "cost", "chuyển khoản", "lương", "hóa đơn", "chi phí"]
-	
-	Yêu cầu:
-	1. Trả về kết quả dưới dạng JSON Array các string.
-	2. Bao gồm cả tiếng Anh và tiếng Việt nếu phù hợp.
-	3. CHỈ trả về JSON Array, không thêm text khác.
-	4. Tối đa 15 từ quan trọng nhất.`, word)
+	prompt, err := buildClassifyPrompt(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
 	payload := map[string]interface{}{
 		"model":  o.getModel(),
 		"prompt": prompt,
 		"stream": false,
+		"format": "json",
 		"options": map[string]interface{}{
-			"temperature": 0.2, // Lower temperature for more consistent results
+			"temperature": 0.1,
+			"num_predict": 200,
 		},
 	}
 
@@ -286,16 +240,16 @@ func (o *OllamaService) GenerateSynonyms(ctx context.Context, word string) ([]st
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("ollama request failed: %w", err)
+		return nil, connectionError(fmt.Errorf("ollama request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -305,51 +259,118 @@ func (o *OllamaService) GenerateSynonyms(ctx context.Context, word string) ([]st
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ollama API error (%d): %s", resp.StatusCode, string(respBody))
+		return nil, httpStatusError("ollama", resp.StatusCode, respBody)
 	}
 
 	var result struct {
 		Response string `json:"response"`
 	}
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Extract JSON from response
-	responseText := strings.TrimSpace(result.Response)
-	// Clean up markdown code blocks if present
-	if strings.HasPrefix(responseText, "```json") {
-		responseText = strings.TrimPrefix(responseText, "```json")
-		responseText = strings.TrimSuffix(responseText, "```")
-	} else if strings.HasPrefix(responseText, "```") {
-		responseText = strings.TrimPrefix(responseText, "```")
-		responseText = strings.TrimSuffix(responseText, "```")
-	}
-	responseText = strings.TrimSpace(responseText)
-
-	jsonStart := strings.Index(responseText, "[")
-	jsonEnd := strings.LastIndex(responseText, "]")
-	if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
-		responseText = responseText[jsonStart : jsonEnd+1]
-	}
-
-	var synonyms []string
-	if err := json.Unmarshal([]byte(responseText), &synonyms); err != nil {
-		// If JSON parse fails, try fallback similar to Gemini implementation
-		lines := strings.Split(responseText, "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			line = strings.TrimPrefix(line, "- ")
-			line = strings.TrimPrefix(line, "* ")
-			if line != "" {
-				synonyms = append(synonyms, line)
+		return nil, responseError("failed to parse response: %w", err)
+	}
+
+	return parseClassificationJSON(result.Response, req.Columns)
+}
+
+// SummarizeEmailStream implements StreamingSummarizer
+func (o *OllamaService) SummarizeEmailStream(ctx context.Context, emailText string, onDelta func(string)) (string, error) {
+	prompt, err := buildSummaryPrompt(ctx, emailText)
+	if err != nil {
+		return "", err
+	}
+	summary, err := o.generateStream(ctx, prompt, map[string]interface{}{
+		"temperature": 0.3,
+		"num_predict": 100,
+	}, onDelta)
+	if err != nil {
+		return summary, err
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// DraftReply implements ReplyDrafter
+func (o *OllamaService) DraftReply(ctx context.Context, req DraftReplyRequest, onDelta func(string)) (string, error) {
+	prompt, err := buildDraftReplyPrompt(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	draft, err := o.generateStream(ctx, prompt, map[string]interface{}{
+		"temperature": 0.6,
+		"num_predict": 800,
+	}, onDelta)
+	if err != nil {
+		return draft, err
+	}
+	return strings.TrimSpace(draft), nil
+}
+
+// generateStream calls /api/generate with stream=true and forwards each token to onDelta
+func (o *OllamaService) generateStream(ctx context.Context, prompt string, options map[string]interface{}, onDelta func(string)) (string, error) {
+	url := o.getBaseURL() + "/api/generate"
+
+	payload := map[string]interface{}{
+		"model":   o.getModel(),
+		"prompt":  prompt,
+		"stream":  true,
+		"options": options,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", connectionError(fmt.Errorf("ollama request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", httpStatusError("ollama", resp.StatusCode, respBody)
+	}
+
+	// Streaming responses are NDJSON: one {"response": "...", "done": false} object per line
+	var text strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk struct {
+			Response string `json:"response"`
+			Done     bool   `json:"done"`
+			Error    string `json:"error"`
+		}
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return text.String(), responseError("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return text.String(), responseError("ollama stream error: %s", chunk.Error)
+		}
+		if chunk.Response != "" {
+			text.WriteString(chunk.Response)
+			if onDelta != nil {
+				onDelta(chunk.Response)
 			}
 		}
-		if len(synonyms) == 0 {
-			return nil, fmt.Errorf("failed to parse synonyms: %v", err)
+		if chunk.Done {
+			return text.String(), nil
 		}
 	}
-
-	return synonyms, nil
+	if err := scanner.Err(); err != nil {
+		return text.String(), connectionError(fmt.Errorf("ollama stream interrupted: %w", err))
+	}
+	return text.String(), responseError("ollama stream ended before done")
 }
-
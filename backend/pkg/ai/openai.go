@@ -0,0 +1,319 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAICompatibleService implements SummarizerService against any server exposing the
+// OpenAI chat completions API (vLLM, llama.cpp server, LM Studio, LocalAI, OpenAI itself...)
+type OpenAICompatibleService struct {
+	getBaseURL func() string // e.g. "http://localhost:8000/v1"
+	getModel   func() string
+	getAPIKey  func() string // Optional, sent as Bearer token
+	jsonMode   bool          // Send response_format json_object for structured operations
+}
+
+// OpenAICompatibleConfig holds the static configuration of an OpenAI-compatible provider
+type OpenAICompatibleConfig struct {
+	BaseURL  string
+	Model    string
+	APIKey   string
+	JSONMode bool
+}
+
+// NewOpenAICompatibleService creates a new OpenAI-compatible service
+func NewOpenAICompatibleService(cfg OpenAICompatibleConfig) *OpenAICompatibleService {
+	baseURL := cfg.BaseURL
+	model := cfg.Model
+	apiKey := cfg.APIKey
+	return &OpenAICompatibleService{
+		getBaseURL: func() string { return baseURL },
+		getModel:   func() string { return model },
+		getAPIKey:  func() string { return apiKey },
+		jsonMode:   cfg.JSONMode,
+	}
+}
+
+// NewOpenAICompatibleServiceWithGetters creates a new OpenAI-compatible service with dynamic getters
+func NewOpenAICompatibleServiceWithGetters(getBaseURL, getModel, getAPIKey func() string, jsonMode bool) *OpenAICompatibleService {
+	return &OpenAICompatibleService{
+		getBaseURL: getBaseURL,
+		getModel:   getModel,
+		getAPIKey:  getAPIKey,
+		jsonMode:   jsonMode,
+	}
+}
+
+// ModelName returns the model currently configured
+func (o *OpenAICompatibleService) ModelName() string {
+	return o.getModel()
+}
+
+type openAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string              `json:"model"`
+	Messages       []openAIChatMessage `json:"messages"`
+	Temperature    float64             `json:"temperature"`
+	MaxTokens      int                 `json:"max_tokens,omitempty"`
+	ResponseFormat *openAIFormat       `json:"response_format,omitempty"`
+	Stream         bool                `json:"stream"`
+}
+
+type openAIFormat struct {
+	Type string `json:"type"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// Configured reports whether a base URL is set (it can be set later through the settings API)
+func (o *OpenAICompatibleService) Configured() bool {
+	return strings.TrimSpace(o.getBaseURL()) != ""
+}
+
+// SummarizeEmail implements SummarizerService
+func (o *OpenAICompatibleService) SummarizeEmail(ctx context.Context, emailText string) (string, error) {
+	prompt, err := buildSummaryPrompt(ctx, emailText)
+	if err != nil {
+		return "", err
+	}
+	content, err := o.chat(ctx, prompt, 0.3, 200, false)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// ExtractTasksFromEmail implements SummarizerService for task extraction
+func (o *OpenAICompatibleService) ExtractTasksFromEmail(ctx context.Context, req TaskExtractionRequest) ([]TaskExtraction, error) {
+	// JSON mode requires a top-level object
+	prompt, err := buildTaskExtractionPrompt(ctx, req, o.jsonMode)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := o.chat(ctx, prompt, 0.2, 800, o.jsonMode)
+	if err != nil {
+		return nil, err
+	}
+	return parseTaskExtractionJSON(content, req)
+}
+
+// GenerateSynonyms generates synonyms for a query
+func (o *OpenAICompatibleService) GenerateSynonyms(ctx context.Context, word string) ([]string, error) {
+	prompt, err := buildSynonymsPrompt(ctx, word, o.jsonMode)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := o.chat(ctx, prompt, 0.2, 300, o.jsonMode)
+	if err != nil {
+		return nil, err
+	}
+	return parseSynonymsJSON(content)
+}
+
+// chat sends a single-turn chat completion and returns the assistant message
+func (o *OpenAICompatibleService) chat(ctx context.Context, prompt string, temperature float64, maxTokens int, jsonMode bool) (string, error) {
+	baseURL := strings.TrimRight(o.getBaseURL(), "/")
+	if baseURL == "" {
+		return "", fmt.Errorf("openai-compatible base URL is not configured")
+	}
+	url := baseURL + "/chat/completions"
+
+	payload := openAIChatRequest{
+		Model:       o.getModel(),
+		Messages:    []openAIChatMessage{{Role: "user", Content: prompt}},
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+	}
+	if jsonMode {
+		payload.ResponseFormat = &openAIFormat{Type: "json_object"}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey := o.getAPIKey(); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", connectionError(fmt.Errorf("openai-compatible request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", httpStatusError("openai-compatible", resp.StatusCode, respBody)
+	}
+
+	var result openAIChatResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", responseError("failed to parse response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", responseError("openai-compatible API returned no choices")
+	}
+
+	return result.Choices[0].Message.Content, nil
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// ClassifyEmail implements EmailClassifier
+func (o *OpenAICompatibleService) ClassifyEmail(ctx context.Context, req ClassifyRequest) (*Classification, error) {
+	prompt, err := buildClassifyPrompt(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	content, err := o.chat(ctx, prompt, 0.1, 200, o.jsonMode)
+	if err != nil {
+		return nil, err
+	}
+	return parseClassificationJSON(content, req.Columns)
+}
+
+// SummarizeEmailStream implements StreamingSummarizer
+func (o *OpenAICompatibleService) SummarizeEmailStream(ctx context.Context, emailText string, onDelta func(string)) (string, error) {
+	prompt, err := buildSummaryPrompt(ctx, emailText)
+	if err != nil {
+		return "", err
+	}
+	content, err := o.chatStream(ctx, prompt, 0.3, 200, onDelta)
+	if err != nil {
+		return content, err
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// DraftReply implements ReplyDrafter using a streamed chat completion
+func (o *OpenAICompatibleService) DraftReply(ctx context.Context, req DraftReplyRequest, onDelta func(string)) (string, error) {
+	prompt, err := buildDraftReplyPrompt(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	content, err := o.chatStream(ctx, prompt, 0.6, 800, onDelta)
+	if err != nil {
+		return content, err
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// chatStream sends a single-turn chat completion with stream=true and forwards content deltas
+func (o *OpenAICompatibleService) chatStream(ctx context.Context, prompt string, temperature float64, maxTokens int, onDelta func(string)) (string, error) {
+	baseURL := strings.TrimRight(o.getBaseURL(), "/")
+	if baseURL == "" {
+		return "", fmt.Errorf("openai-compatible base URL is not configured")
+	}
+	url := baseURL + "/chat/completions"
+
+	payload := openAIChatRequest{
+		Model:       o.getModel(),
+		Messages:    []openAIChatMessage{{Role: "user", Content: prompt}},
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+		Stream:      true,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if apiKey := o.getAPIKey(); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", connectionError(fmt.Errorf("openai-compatible request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", httpStatusError("openai-compatible", resp.StatusCode, respBody)
+	}
+
+	// Server-sent events: "data: {chunk}" lines, terminated by "data: [DONE]"
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return content.String(), nil
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return content.String(), responseError("failed to parse stream chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if delta := chunk.Choices[0].Delta.Content; delta != "" {
+			content.WriteString(delta)
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return content.String(), connectionError(fmt.Errorf("openai-compatible stream interrupted: %w", err))
+	}
+	// Some servers close the stream without [DONE]
+	if content.Len() == 0 {
+		return "", responseError("openai-compatible stream returned no content")
+	}
+	return content.String(), nil
+}
@@ -0,0 +1,501 @@
+package ai
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PromptName identifies a prompt template shared by every provider
+type PromptName string
+
+const (
+	PromptSummary        PromptName = "summary"
+	PromptTaskExtraction PromptName = "task_extraction"
+	PromptSynonyms       PromptName = "synonyms"
+	PromptDraftReply     PromptName = "draft_reply"
+	PromptClassify       PromptName = "classify"
+)
+
+// PromptNames lists every prompt rendered by the providers
+var PromptNames = []PromptName{PromptSummary, PromptTaskExtraction, PromptSynonyms, PromptDraftReply, PromptClassify}
+
+const (
+	// DefaultPromptLanguage is used when the user has no language or no template exists in it
+	DefaultPromptLanguage = "vi"
+	// BuiltinPromptVersion is the version of the templates shipped with the binary (or read from the prompt directory)
+	BuiltinPromptVersion = 0
+)
+
+// Built-in templates, one file per language and prompt: prompts/<language>/<name>.tmpl
+//
+//go:embed prompts/*/*.tmpl
+var builtinPromptFiles embed.FS
+
+// builtinPrompts renders prompts when no registry is attached to the context (providers used outside a chain)
+var builtinPrompts = mustNewPromptRegistry()
+
+// PromptTemplate is one version of a prompt in one language.
+// Weight is its share of traffic among the active versions of the same prompt and language.
+type PromptTemplate struct {
+	Name     PromptName `json:"name"`
+	Language string     `json:"language"`
+	Version  int        `json:"version"`
+	Body     string     `json:"body"`
+	Weight   int        `json:"weight"`
+}
+
+// PromptContext selects the prompt variant of a request
+type PromptContext struct {
+	Language string // User's language ("vi", "en", "pt-BR"...); empty = DefaultPromptLanguage
+	UserID   string // Keeps a user on the same version while an A/B test runs
+}
+
+// PromptUsage is the template version that rendered a prompt.
+// Experiment is set when the version was picked among several active versions (A/B test).
+type PromptUsage struct {
+	Name       PromptName `json:"name"`
+	Language   string     `json:"language"`
+	Version    int        `json:"version"`
+	Experiment bool       `json:"experiment"`
+}
+
+// PromptRun is the outcome of an operation whose prompt was picked by an A/B test
+type PromptRun struct {
+	PromptUsage
+	UserID  string
+	Output  string
+	Error   string
+	Latency time.Duration
+}
+
+// PromptRunRecorder stores A/B test runs so versions can be compared on their results
+type PromptRunRecorder interface {
+	RecordPromptRun(run PromptRun)
+}
+
+type promptKey struct {
+	name     PromptName
+	language string
+}
+
+type compiledPrompt struct {
+	PromptTemplate
+	tmpl *template.Template
+}
+
+// PromptRegistry renders the prompts of every provider from text/template templates.
+// Built-in templates are version 0; versions activated with SetActive (e.g. loaded from the
+// database) replace them, and several active versions of a prompt split the traffic by weight.
+type PromptRegistry struct {
+	mu       sync.RWMutex
+	builtins map[promptKey]*compiledPrompt
+	active   map[promptKey][]*compiledPrompt
+}
+
+// NewPromptRegistry loads the built-in templates. If dir is set, <dir>/<language>/<name>.tmpl
+// files override them (or add languages) without rebuilding the binary.
+func NewPromptRegistry(dir string) (*PromptRegistry, error) {
+	r := &PromptRegistry{
+		builtins: make(map[promptKey]*compiledPrompt),
+		active:   make(map[promptKey][]*compiledPrompt),
+	}
+	if err := r.loadBuiltins(builtinPromptFiles, "prompts"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := r.loadBuiltins(os.DirFS(dir), "."); err != nil {
+			return nil, fmt.Errorf("failed to load prompt templates from %s: %w", dir, err)
+		}
+	}
+	return r, nil
+}
+
+func mustNewPromptRegistry() *PromptRegistry {
+	r, err := NewPromptRegistry("")
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r *PromptRegistry) loadBuiltins(fsys fs.FS, root string) error {
+	files, err := fs.Glob(fsys, path.Join(root, "*", "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := PromptName(strings.TrimSuffix(path.Base(file), ".tmpl"))
+		language := path.Base(path.Dir(file))
+		if !IsPromptName(name) {
+			return fmt.Errorf("%s: unknown prompt %q", file, name)
+		}
+
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		prompt, err := compilePrompt(PromptTemplate{
+			Name:     name,
+			Language: normalizeLanguage(language),
+			Version:  BuiltinPromptVersion,
+			Body:     string(body),
+			Weight:   1,
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		r.builtins[promptKey{name, prompt.Language}] = prompt
+	}
+	return nil
+}
+
+// IsPromptName reports whether name is a prompt rendered by the providers
+func IsPromptName(name PromptName) bool {
+	for _, n := range PromptNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Builtins returns the built-in templates, sorted by name and language
+func (r *PromptRegistry) Builtins() []PromptTemplate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]PromptTemplate, 0, len(r.builtins))
+	for _, p := range r.builtins {
+		result = append(result, p.PromptTemplate)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Language < result[j].Language
+	})
+	return result
+}
+
+// Builtin returns the built-in template of a prompt in a language
+func (r *PromptRegistry) Builtin(name PromptName, language string) (PromptTemplate, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.builtins[promptKey{name, normalizeLanguage(language)}]
+	if !ok {
+		return PromptTemplate{}, false
+	}
+	return p.PromptTemplate, true
+}
+
+// SetActive replaces the active versions. Templates with a weight <= 0 are ignored; a template
+// with version 0 and no body activates the built-in one (to A/B-test it against a new version).
+// Prompts without active versions use their built-in template.
+func (r *PromptRegistry) SetActive(templates []PromptTemplate) error {
+	active := make(map[promptKey][]*compiledPrompt)
+	for _, t := range templates {
+		if t.Weight <= 0 {
+			continue
+		}
+		t.Language = normalizeLanguage(t.Language)
+		if t.Version == BuiltinPromptVersion && t.Body == "" {
+			builtin, ok := r.Builtin(t.Name, t.Language)
+			if !ok {
+				return fmt.Errorf("no built-in %s prompt for language %q", t.Name, t.Language)
+			}
+			t.Body = builtin.Body
+		}
+		prompt, err := compilePrompt(t)
+		if err != nil {
+			return fmt.Errorf("%s/%s v%d: %w", t.Name, t.Language, t.Version, err)
+		}
+		key := promptKey{t.Name, t.Language}
+		active[key] = append(active[key], prompt)
+	}
+	for _, prompts := range active {
+		sort.Slice(prompts, func(i, j int) bool { return prompts[i].Version < prompts[j].Version })
+	}
+
+	r.mu.Lock()
+	r.active = active
+	r.mu.Unlock()
+	return nil
+}
+
+// Active returns the versions currently serving traffic for a prompt (the built-in one if none is active)
+func (r *PromptRegistry) Active(name PromptName, language string) []PromptTemplate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key := promptKey{name, normalizeLanguage(language)}
+	var result []PromptTemplate
+	for _, p := range r.active[key] {
+		result = append(result, p.PromptTemplate)
+	}
+	if len(result) == 0 {
+		if builtin, ok := r.builtins[key]; ok {
+			result = append(result, builtin.PromptTemplate)
+		}
+	}
+	return result
+}
+
+// ServingVersions returns, per language, the versions of a prompt currently serving traffic
+func (r *PromptRegistry) ServingVersions(name PromptName) map[string][]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string][]int)
+	for key := range r.builtins {
+		if key.name == name {
+			result[key.language] = []int{BuiltinPromptVersion}
+		}
+	}
+	for key, prompts := range r.active {
+		if key.name != name || len(prompts) == 0 {
+			continue
+		}
+		versions := make([]int, 0, len(prompts))
+		for _, p := range prompts {
+			versions = append(versions, p.Version)
+		}
+		result[key.language] = versions
+	}
+	return result
+}
+
+// Render renders a prompt in the language of the request context, falling back to the base
+// language ("pt" for "pt-BR") and then DefaultPromptLanguage. The version used is added to the
+// context's PromptTrace, if any.
+func (r *PromptRegistry) Render(ctx context.Context, name PromptName, data interface{}) (string, error) {
+	pc := promptContextFrom(ctx)
+	prompt, experiment := r.selectPrompt(name, pc)
+	if prompt == nil {
+		return "", fmt.Errorf("no template for prompt %s", name)
+	}
+
+	var sb strings.Builder
+	if err := prompt.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s v%d: %w", name, prompt.Version, err)
+	}
+
+	if trace := promptTraceFrom(ctx); trace != nil {
+		trace.add(PromptUsage{Name: name, Language: prompt.Language, Version: prompt.Version, Experiment: experiment})
+	}
+	rendered := strings.TrimRightFunc(sb.String(), unicode.IsSpace)
+	if meter := usageMeterFrom(ctx); meter != nil {
+		meter.addInput(utf8.RuneCountInString(rendered))
+	}
+	return rendered, nil
+}
+
+// selectPrompt picks the template of the first language that has one
+func (r *PromptRegistry) selectPrompt(name PromptName, pc PromptContext) (*compiledPrompt, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, language := range promptLanguages(pc.Language) {
+		key := promptKey{name, language}
+		if candidates := r.active[key]; len(candidates) > 0 {
+			return pickWeighted(candidates, string(name)+"/"+pc.UserID, pc.UserID != ""), len(candidates) > 1
+		}
+		if builtin, ok := r.builtins[key]; ok {
+			return builtin, false
+		}
+	}
+	return nil, false
+}
+
+// pickWeighted picks a version by weight. With a seed the pick is stable (same user, same
+// version for the whole test); without one it is random.
+func pickWeighted(candidates []*compiledPrompt, seed string, stable bool) *compiledPrompt {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	total := 0
+	for _, c := range candidates {
+		total += c.Weight
+	}
+	var n int
+	if stable {
+		h := fnv.New32a()
+		h.Write([]byte(seed))
+		n = int(h.Sum32() % uint32(total))
+	} else {
+		n = rand.Intn(total)
+	}
+	for _, c := range candidates {
+		if n < c.Weight {
+			return c
+		}
+		n -= c.Weight
+	}
+	return candidates[len(candidates)-1]
+}
+
+// promptLanguages returns the languages to try, most specific first
+func promptLanguages(language string) []string {
+	language = normalizeLanguage(language)
+	var languages []string
+	if language != "" {
+		languages = append(languages, language)
+		if base, _, ok := strings.Cut(language, "-"); ok {
+			languages = append(languages, base)
+		}
+	}
+	if language != DefaultPromptLanguage {
+		languages = append(languages, DefaultPromptLanguage)
+	}
+	return languages
+}
+
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+}
+
+// promptFuncs are the helpers available in every template
+var promptFuncs = template.FuncMap{
+	"join":    strings.Join,
+	"quote":   func(s string) string { return fmt.Sprintf("%q", s) },
+	"message": formatDraftMessage,
+}
+
+func compilePrompt(t PromptTemplate) (*compiledPrompt, error) {
+	if !IsPromptName(t.Name) {
+		return nil, fmt.Errorf("unknown prompt %q", t.Name)
+	}
+	tmpl, err := template.New(string(t.Name)).Funcs(promptFuncs).Option("missingkey=error").Parse(t.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &compiledPrompt{PromptTemplate: t, tmpl: tmpl}, nil
+}
+
+// PreviewPrompt renders a template body with sample data. Use it to validate a template
+// before saving it: syntax errors and unknown fields are reported here instead of at call time.
+func PreviewPrompt(name PromptName, body string) (string, error) {
+	prompt, err := compilePrompt(PromptTemplate{Name: name, Body: body})
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := prompt.tmpl.Execute(&sb, samplePromptData(name)); err != nil {
+		return "", err
+	}
+	return strings.TrimRightFunc(sb.String(), unicode.IsSpace), nil
+}
+
+type promptContextKey struct{}
+type promptRegistryKey struct{}
+type promptTraceKey struct{}
+
+// WithPromptContext sets the language and user used to select prompt versions
+func WithPromptContext(ctx context.Context, pc PromptContext) context.Context {
+	return context.WithValue(ctx, promptContextKey{}, pc)
+}
+
+func promptContextFrom(ctx context.Context) PromptContext {
+	pc, _ := ctx.Value(promptContextKey{}).(PromptContext)
+	return pc
+}
+
+func withPromptRegistry(ctx context.Context, r *PromptRegistry) context.Context {
+	return context.WithValue(ctx, promptRegistryKey{}, r)
+}
+
+// promptsFrom returns the registry attached by the chain, or the built-in templates
+func promptsFrom(ctx context.Context) *PromptRegistry {
+	if r, ok := ctx.Value(promptRegistryKey{}).(*PromptRegistry); ok && r != nil {
+		return r
+	}
+	return builtinPrompts
+}
+
+// PromptTrace collects the template versions rendered during an operation,
+// and the provider and model that answered it
+type PromptTrace struct {
+	mu       sync.Mutex
+	usages   []PromptUsage
+	provider string
+	model    string
+	parent   *PromptTrace
+}
+
+// WithPromptTrace returns a context whose rendered prompts are recorded in the returned trace.
+// Traces nest: an outer trace also sees the prompts rendered under an inner one.
+func WithPromptTrace(ctx context.Context) (context.Context, *PromptTrace) {
+	trace := &PromptTrace{parent: promptTraceFrom(ctx)}
+	return context.WithValue(ctx, promptTraceKey{}, trace), trace
+}
+
+func promptTraceFrom(ctx context.Context) *PromptTrace {
+	trace, _ := ctx.Value(promptTraceKey{}).(*PromptTrace)
+	return trace
+}
+
+func (t *PromptTrace) add(usage PromptUsage) {
+	t.mu.Lock()
+	t.usages = append(t.usages, usage)
+	t.mu.Unlock()
+	if t.parent != nil {
+		t.parent.add(usage)
+	}
+}
+
+func (t *PromptTrace) setProvider(provider, model string) {
+	t.mu.Lock()
+	t.provider, t.model = provider, model
+	t.mu.Unlock()
+	if t.parent != nil {
+		t.parent.setProvider(provider, model)
+	}
+}
+
+// Provider returns the provider and model that answered the operation ("" if none did)
+func (t *PromptTrace) Provider() (provider, model string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.provider, t.model
+}
+
+// Usage returns the last version rendered for a prompt (the one of the provider that answered)
+func (t *PromptTrace) Usage(name PromptName) (PromptUsage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.usages) - 1; i >= 0; i-- {
+		if t.usages[i].Name == name {
+			return t.usages[i], true
+		}
+	}
+	return PromptUsage{}, false
+}
+
+// Usages returns the last version rendered for each prompt
+func (t *PromptTrace) Usages() []PromptUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[PromptName]bool)
+	var result []PromptUsage
+	for i := len(t.usages) - 1; i >= 0; i-- {
+		if !seen[t.usages[i].Name] {
+			seen[t.usages[i].Name] = true
+			result = append(result, t.usages[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Prompts and response parsing shared by every provider. The prompt texts are templates
+// (see prompt_registry.go and prompts/<language>/*.tmpl); this file holds the data given to them.
+
+// summaryPromptData is the data of the summary template
+type summaryPromptData struct {
+	Email string
+}
+
+// synonymsPromptData is the data of the synonyms template
+type synonymsPromptData struct {
+	Word       string
+	JSONObject bool
+}
+
+// buildSummaryPrompt renders the summary prompt
+func buildSummaryPrompt(ctx context.Context, emailText string) (string, error) {
+	return promptsFrom(ctx).Render(ctx, PromptSummary, summaryPromptData{Email: emailText})
+}
+
+// buildSynonymsPrompt renders the prompt used to expand a search keyword
+func buildSynonymsPrompt(ctx context.Context, word string, jsonObject bool) (string, error) {
+	return promptsFrom(ctx).Render(ctx, PromptSynonyms, synonymsPromptData{Word: word, JSONObject: jsonObject})
+}
+
+// buildDraftReplyPrompt renders the reply drafting prompt. The template gets the request with
+// trimmed options; the default tone is part of the template since it depends on the language.
+func buildDraftReplyPrompt(ctx context.Context, req DraftReplyRequest) (string, error) {
+	req.Tone = strings.TrimSpace(req.Tone)
+	req.Instruction = strings.TrimSpace(req.Instruction)
+	req.SenderName = strings.TrimSpace(req.SenderName)
+	return promptsFrom(ctx).Render(ctx, PromptDraftReply, req)
+}
+
+// buildClassifyPrompt renders the Kanban triage prompt: column definitions, the user's
+// hand-filed examples (few-shot) and the email to classify. The answer is a JSON object.
+func buildClassifyPrompt(ctx context.Context, req ClassifyRequest) (string, error) {
+	return promptsFrom(ctx).Render(ctx, PromptClassify, req)
+}
+
+// formatDraftMessage formats an email for a prompt (the "message" template function)
+func formatDraftMessage(msg DraftMessage) string {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	fmt.Fprintf(&sb, "From: %s\n", msg.From)
+	if len(msg.To) > 0 {
+		fmt.Fprintf(&sb, "To: %s\n", strings.Join(msg.To, ", "))
+	}
+	if !msg.Date.IsZero() {
+		fmt.Fprintf(&sb, "Date: %s\n", msg.Date.Format(time.RFC1123))
+	}
+	fmt.Fprintf(&sb, "Subject: %s\n\n%s", msg.Subject, strings.TrimSpace(msg.Body))
+	return sb.String()
+}
+
+// samplePromptData returns realistic data to preview or validate a template
+func samplePromptData(name PromptName) interface{} {
+	email := DraftMessage{
+		From:    "Nguyễn Văn A <a@example.com>",
+		To:      []string{"me@example.com"},
+		Subject: "Họp tiến độ dự án ABC",
+		Date:    time.Date(2025, 1, 13, 9, 30, 0, 0, time.UTC),
+		Body:    "Chào bạn,\n\nMình họp thứ 5 lúc 14h nhé. Bạn chuẩn bị báo cáo tiến độ trước thứ 4 giúp mình.\n\nCảm ơn!",
+	}
+	emailText := fmt.Sprintf("Subject: %s\n\nBody: %s", email.Subject, email.Body)
+
+	switch name {
+	case PromptSummary:
+		return summaryPromptData{Email: emailText}
+	case PromptTaskExtraction:
+		return taskExtractionPromptData{
+			Email:      emailText,
+			Today:      "2025-01-13",
+			Received:   "2025-01-13 09:30 (Monday)",
+			TimeZone:   "Asia/Ho_Chi_Minh",
+			JSONObject: true,
+		}
+	case PromptSynonyms:
+		return synonymsPromptData{Word: "meeting"}
+	case PromptDraftReply:
+		return DraftReplyRequest{
+			Email:       email,
+			Thread:      []DraftMessage{{From: "me@example.com", Subject: "Dự án ABC", Body: "Tuần này mình họp được không?"}},
+			Tone:        "thân thiện",
+			Instruction: "Đồng ý tham gia",
+			SenderName:  "Bình",
+		}
+	case PromptClassify:
+		return ClassifyRequest{
+			Email: email,
+			Columns: []ClassifyColumn{
+				{ID: "inbox", Name: "Inbox"},
+				{ID: "todo", Name: "To Do", Description: "Việc cần làm", Examples: []string{"báo cáo", "deadline"}},
+			},
+			Examples: []ClassifyExample{{ColumnID: "todo", Subject: "Nộp báo cáo tháng", From: "boss@example.com", Snippet: "Gửi anh trước thứ 6"}},
+		}
+	}
+	return nil
+}
+
+// parseClassificationJSON parses the classifier answer and checks the column exists
+func parseClassificationJSON(text string, columns []ClassifyColumn) (*Classification, error) {
+	responseText := strings.TrimSpace(text)
+	jsonStart := strings.Index(responseText, "{")
+	jsonEnd := strings.LastIndex(responseText, "}")
+	if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
+		responseText = responseText[jsonStart : jsonEnd+1]
+	}
+
+	var result Classification
+	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
+		return nil, responseError("failed to parse classification JSON: %v", err)
+	}
+
+	result.ColumnID = strings.TrimSpace(result.ColumnID)
+	known := false
+	for _, col := range columns {
+		if col.ID == result.ColumnID {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return nil, responseError("classifier returned unknown column %q", result.ColumnID)
+	}
+
+	// Some models answer in percent
+	if result.Confidence > 1 && result.Confidence <= 100 {
+		result.Confidence /= 100
+	}
+	if result.Confidence < 0 {
+		result.Confidence = 0
+	}
+	if result.Confidence > 1 {
+		result.Confidence = 1
+	}
+	return &result, nil
+}
+
+// parseSynonymsJSON extracts synonyms from a model response (JSON array, falling back to one item per line)
+func parseSynonymsJSON(text string) ([]string, error) {
+	// Extract JSON from response
+	responseText := strings.TrimSpace(text)
+	// Clean up markdown code blocks if present
+	if strings.HasPrefix(responseText, "```json") {
+		responseText = strings.TrimPrefix(responseText, "```json")
+		responseText = strings.TrimSuffix(responseText, "```")
+	} else if strings.HasPrefix(responseText, "```") {
+		responseText = strings.TrimPrefix(responseText, "```")
+		responseText = strings.TrimSuffix(responseText, "```")
+	}
+	responseText = strings.TrimSpace(responseText)
+
+	jsonStart := strings.Index(responseText, "[")
+	jsonEnd := strings.LastIndex(responseText, "]")
+	if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
+		responseText = responseText[jsonStart : jsonEnd+1]
+	}
+
+	var synonyms []string
+	if err := json.Unmarshal([]byte(responseText), &synonyms); err != nil {
+		// If JSON parse fails, try fallback similar to Gemini implementation
+		lines := strings.Split(responseText, "\n")
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			line = strings.TrimPrefix(line, "- ")
+			line = strings.TrimPrefix(line, "* ")
+			if line != "" {
+				synonyms = append(synonyms, line)
+			}
+		}
+		if len(synonyms) == 0 {
+			return nil, responseError("failed to parse synonyms: %v", err)
+		}
+	}
+
+	return synonyms, nil
+}
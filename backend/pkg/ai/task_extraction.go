@@ -0,0 +1,354 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Task extraction: prompt data, the JSON schema constraining the model output, and its
+// validation. Every provider goes through parseTaskExtractionJSON, schema-constrained or not.
+
+const (
+	maxTaskTitleRunes = 200
+	// defaultTaskConfidence is used when the model does not say how sure it is
+	defaultTaskConfidence = 0.5
+)
+
+// taskExtractionPromptData is the data of the task extraction template
+type taskExtractionPromptData struct {
+	Email      string
+	Today      string // YYYY-MM-DD of the email's reception in the user's time zone, anchors relative dates
+	Received   string // Reception date and time with the weekday, e.g. "2025-01-13 09:30 (Monday)"
+	TimeZone   string // IANA name of the user's time zone
+	JSONObject bool   // The provider forces a JSON object, so the array must be wrapped
+}
+
+// taskExtractionSchema is the JSON schema of the extraction output (Ollama "format", Gemini
+// "responseSchema" after geminiSchema). Go validation does not trust it: not every model honors it.
+var taskExtractionSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"tasks": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"title":       map[string]interface{}{"type": "string"},
+					"description": map[string]interface{}{"type": "string"},
+					"due_date":    map[string]interface{}{"type": "string"},
+					"priority":    map[string]interface{}{"type": "string", "enum": []string{"high", "medium", "low"}},
+					"assignee":    map[string]interface{}{"type": "string"},
+					"source":      map[string]interface{}{"type": "string"},
+					"confidence":  map[string]interface{}{"type": "number"},
+				},
+				"required": []string{"title", "priority", "source", "confidence"},
+			},
+		},
+	},
+	"required": []string{"tasks"},
+}
+
+// geminiSchema converts a JSON schema to Gemini's OpenAPI subset (upper-case type names)
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if key == "properties" {
+				props := make(map[string]interface{}, len(v))
+				for name, prop := range v {
+					props[name] = geminiSchema(prop.(map[string]interface{}))
+				}
+				result[key] = props
+			} else {
+				result[key] = geminiSchema(v)
+			}
+		case string:
+			if key == "type" {
+				v = strings.ToUpper(v)
+			}
+			result[key] = v
+		default:
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// taskExtractionAnchor returns when the email was received, in the user's time zone
+func taskExtractionAnchor(req TaskExtractionRequest) time.Time {
+	loc := req.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	anchor := req.ReceivedAt
+	if anchor.IsZero() {
+		anchor = time.Now()
+	}
+	return anchor.In(loc)
+}
+
+// taskExtractionText is the email as given to the model
+func taskExtractionText(req TaskExtractionRequest) string {
+	return "Subject: " + req.Subject + "\n\n" + req.Body
+}
+
+// buildTaskExtractionPrompt renders the task extraction prompt, anchored on the email's reception date
+func buildTaskExtractionPrompt(ctx context.Context, req TaskExtractionRequest, jsonObject bool) (string, error) {
+	anchor := taskExtractionAnchor(req)
+	return promptsFrom(ctx).Render(ctx, PromptTaskExtraction, taskExtractionPromptData{
+		Email:      taskExtractionText(req),
+		Today:      anchor.Format("2006-01-02"),
+		Received:   anchor.Format("2006-01-02 15:04 (Monday)"),
+		TimeZone:   anchor.Location().String(),
+		JSONObject: jsonObject,
+	})
+}
+
+// rawTaskExtraction is one task as answered by the model
+type rawTaskExtraction struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	DueDate     string   `json:"due_date"`
+	Priority    string   `json:"priority"`
+	Assignee    string   `json:"assignee"`
+	Source      string   `json:"source"`
+	Confidence  *float64 `json:"confidence"`
+}
+
+// parseTaskExtractionJSON validates a model response ({"tasks": [...]}, or a bare array possibly
+// wrapped in text) against the extraction schema. A response that does not match it is an error,
+// so the chain falls back; tasks without a title are dropped, other fields are normalized.
+func parseTaskExtractionJSON(text string, req TaskExtractionRequest) ([]TaskExtraction, error) {
+	responseText := strings.TrimSpace(text)
+
+	var rawTasks []rawTaskExtraction
+	if strings.HasPrefix(responseText, "{") {
+		var wrapped struct {
+			Tasks *[]rawTaskExtraction `json:"tasks"`
+		}
+		if err := json.Unmarshal([]byte(responseText), &wrapped); err != nil {
+			return nil, responseError("task JSON does not match the schema: %v", err)
+		}
+		if wrapped.Tasks == nil {
+			return nil, responseError("task JSON does not match the schema: missing tasks")
+		}
+		rawTasks = *wrapped.Tasks
+	} else {
+		jsonStart := strings.Index(responseText, "[")
+		jsonEnd := strings.LastIndex(responseText, "]")
+		if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
+			responseText = responseText[jsonStart : jsonEnd+1]
+		}
+		if err := json.Unmarshal([]byte(responseText), &rawTasks); err != nil {
+			return nil, responseError("task JSON does not match the schema: %v", err)
+		}
+	}
+
+	anchor := taskExtractionAnchor(req)
+	seen := make(map[string]bool)
+	var tasks []TaskExtraction
+	for _, rt := range rawTasks {
+		title := strings.TrimSpace(rt.Title)
+		if title == "" || seen[strings.ToLower(title)] {
+			continue
+		}
+		seen[strings.ToLower(title)] = true
+
+		task := TaskExtraction{
+			Title:       truncateTaskTitle(title),
+			Description: strings.TrimSpace(rt.Description),
+			Priority:    normalizeTaskPriority(rt.Priority),
+			Assignee:    strings.TrimSpace(rt.Assignee),
+			Confidence:  normalizeConfidence(rt.Confidence),
+			Source:      locateSourceSpan(req.Body, strings.TrimSpace(rt.Source)),
+		}
+		if rt.DueDate != "" {
+			task.DueDate = parseDueDate(strings.TrimSpace(rt.DueDate), anchor)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func truncateTaskTitle(title string) string {
+	if utf8.RuneCountInString(title) <= maxTaskTitleRunes {
+		return title
+	}
+	return string([]rune(title)[:maxTaskTitleRunes])
+}
+
+func normalizeTaskPriority(priority string) string {
+	switch p := strings.ToLower(strings.TrimSpace(priority)); p {
+	case "high", "medium", "low":
+		return p
+	}
+	return "medium"
+}
+
+// normalizeConfidence clamps a confidence to [0, 1] (some models answer in percent)
+func normalizeConfidence(confidence *float64) float64 {
+	if confidence == nil {
+		return defaultTaskConfidence
+	}
+	c := *confidence
+	if c > 1 && c <= 100 {
+		c /= 100
+	}
+	if c < 0 {
+		return 0
+	}
+	if c > 1 {
+		return 1
+	}
+	return c
+}
+
+// locateSourceSpan finds the quoted sentence in the body: exactly, else ignoring case and
+// whitespace differences (models often reflow lines)
+func locateSourceSpan(body, quote string) SourceSpan {
+	span := SourceSpan{Text: quote, Start: -1, End: -1}
+	if quote == "" {
+		return span
+	}
+
+	if i := strings.Index(body, quote); i >= 0 {
+		span.Start = utf8.RuneCountInString(body[:i])
+		span.End = span.Start + utf8.RuneCountInString(quote)
+		span.Text = quote
+		return span
+	}
+
+	normBody, offsets := normalizeForMatch(body)
+	normQuote, _ := normalizeForMatch(quote)
+	if len(normQuote) == 0 {
+		return span
+	}
+	if i := indexRunes(normBody, normQuote); i >= 0 {
+		bodyRunes := []rune(body)
+		span.Start = offsets[i]
+		span.End = offsets[i+len(normQuote)-1] + 1
+		span.Text = string(bodyRunes[span.Start:span.End])
+	}
+	return span
+}
+
+// normalizeForMatch lower-cases s and collapses whitespace runs into one space. offsets maps
+// each rune of the result to the rune index it comes from in s.
+func normalizeForMatch(s string) ([]rune, []int) {
+	var result []rune
+	var offsets []int
+	space := true // Also trims leading whitespace
+	for i, r := range []rune(s) {
+		if unicode.IsSpace(r) {
+			if !space {
+				result = append(result, ' ')
+				offsets = append(offsets, i)
+				space = true
+			}
+			continue
+		}
+		result = append(result, unicode.ToLower(r))
+		offsets = append(offsets, i)
+		space = false
+	}
+	if len(result) > 0 && result[len(result)-1] == ' ' {
+		result, offsets = result[:len(result)-1], offsets[:len(offsets)-1]
+	}
+	return result, offsets
+}
+
+func indexRunes(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseDueDate parses an absolute date (in the user's time zone unless it has an offset)
+// or a relative expression anchored on the email's reception
+func parseDueDate(value string, anchor time.Time) *time.Time {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t
+	}
+	formats := []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+	for _, format := range formats {
+		if t, err := time.ParseInLocation(format, value, anchor.Location()); err == nil {
+			return &t
+		}
+	}
+	return parseRelativeDate(value, anchor)
+}
+
+var (
+	inDaysPattern = regexp.MustCompile(`(?:in (\d+) days?|(\d+) ngày nữa|sau (\d+) ngày)`)
+
+	weekdayNames = []struct {
+		pattern *regexp.Regexp
+		day     time.Weekday
+	}{
+		{regexp.MustCompile(`\b(monday|mon)\b|thứ (2|hai)`), time.Monday},
+		{regexp.MustCompile(`\b(tuesday|tue)\b|thứ (3|ba)`), time.Tuesday},
+		{regexp.MustCompile(`\b(wednesday|wed)\b|thứ (4|tư)`), time.Wednesday},
+		{regexp.MustCompile(`\b(thursday|thu)\b|thứ (5|năm)`), time.Thursday},
+		{regexp.MustCompile(`\b(friday|fri)\b|thứ (6|sáu)`), time.Friday},
+		{regexp.MustCompile(`\b(saturday|sat)\b|thứ (7|bảy)`), time.Saturday},
+		{regexp.MustCompile(`\b(sunday|sun)\b|chủ nhật`), time.Sunday},
+	}
+)
+
+// parseRelativeDate resolves a relative date expression (English or Vietnamese) against anchor,
+// returning the start of that day in anchor's location
+func parseRelativeDate(dateStr string, anchor time.Time) *time.Time {
+	dateStr = strings.ToLower(strings.TrimSpace(dateStr))
+	day := time.Date(anchor.Year(), anchor.Month(), anchor.Day(), 0, 0, 0, 0, anchor.Location())
+	at := func(t time.Time) *time.Time { return &t }
+
+	switch {
+	case strings.Contains(dateStr, "day after tomorrow") || strings.Contains(dateStr, "ngày kia") || strings.Contains(dateStr, "ngày mốt"):
+		return at(day.AddDate(0, 0, 2))
+	case strings.Contains(dateStr, "tomorrow") || strings.Contains(dateStr, "ngày mai"):
+		return at(day.AddDate(0, 0, 1))
+	case strings.Contains(dateStr, "today") || strings.Contains(dateStr, "hôm nay") || strings.Contains(dateStr, "tonight") || strings.Contains(dateStr, "tối nay"):
+		return at(day)
+	case strings.Contains(dateStr, "next week") || strings.Contains(dateStr, "tuần sau") || strings.Contains(dateStr, "tuần tới"):
+		return at(day.AddDate(0, 0, 7))
+	case strings.Contains(dateStr, "next month") || strings.Contains(dateStr, "tháng sau") || strings.Contains(dateStr, "tháng tới"):
+		return at(day.AddDate(0, 1, 0))
+	}
+
+	if m := inDaysPattern.FindStringSubmatch(dateStr); m != nil {
+		for _, group := range m[1:] {
+			if n, err := strconv.Atoi(group); err == nil {
+				return at(day.AddDate(0, 0, n))
+			}
+		}
+	}
+
+	// A weekday is its next occurrence after the reception day
+	for _, w := range weekdayNames {
+		if w.pattern.MatchString(dateStr) {
+			days := (int(w.day) - int(day.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			return at(day.AddDate(0, 0, days))
+		}
+	}
+	return nil
+}
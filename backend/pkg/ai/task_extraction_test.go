@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRelativeDate(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*60*60)
+	anchor := time.Date(2024, 3, 13, 16, 30, 0, 0, loc) // Wednesday
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 3, d, 0, 0, 0, 0, loc)
+		return &t
+	}
+
+	tests := []struct {
+		input string
+		want  *time.Time
+	}{
+		{"today", day(13)},
+		{"Tonight", day(13)},
+		{"hôm nay", day(13)},
+		{"tomorrow", day(14)},
+		{"ngày mai", day(14)},
+		{"the day after tomorrow", day(15)},
+		{"ngày mốt", day(15)},
+		{"in 3 days", day(16)},
+		{"in 1 day", day(14)},
+		{"5 ngày nữa", day(18)},
+		{"sau 2 ngày", day(15)},
+		{"next week", day(20)},
+		{"tuần sau", day(20)},
+		{"Friday", day(15)},
+		{"by fri", day(15)},
+		{"thứ 6", day(15)},
+		{"thứ hai", day(18)},
+		{"chủ nhật", day(17)},
+		{"Wednesday", day(20)}, // The reception day itself means next week
+		{"soon", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := parseRelativeDate(tt.input, anchor)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("parseRelativeDate(%q) = %v, want nil", tt.input, got)
+			case tt.want != nil && (got == nil || !got.Equal(*tt.want)):
+				t.Errorf("parseRelativeDate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	nextMonth := parseRelativeDate("next month", anchor)
+	if want := time.Date(2024, 4, 13, 0, 0, 0, 0, loc); nextMonth == nil || !nextMonth.Equal(want) {
+		t.Errorf("parseRelativeDate(%q) = %v, want %v", "next month", nextMonth, want)
+	}
+}
+
+func TestLocateSourceSpan(t *testing.T) {
+	body := "Hi team,\n\nPlease send the  Q1 report\nby Friday.\nThanks, Đức"
+
+	tests := []struct {
+		name      string
+		quote     string
+		wantText  string
+		wantStart int
+		wantEnd   int
+	}{
+		{"empty quote", "", "", -1, -1},
+		{"exact", "Thanks, Đức", "Thanks, Đức", 48, 59},
+		{"case and reflowed whitespace", "please send the Q1 report by friday.", "Please send the  Q1 report\nby Friday.", 10, 47},
+		{"surrounding whitespace", "  q1 REPORT  ", "Q1 report", 27, 36},
+		{"not in the body", "send the Q2 report", "send the Q2 report", -1, -1},
+		{"whitespace only", " \n ", " \n ", -1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := locateSourceSpan(body, tt.quote)
+			if span.Text != tt.wantText || span.Start != tt.wantStart || span.End != tt.wantEnd {
+				t.Errorf("locateSourceSpan(%q) = %+v, want {Text:%q Start:%d End:%d}", tt.quote, span, tt.wantText, tt.wantStart, tt.wantEnd)
+			}
+			if span.Start >= 0 {
+				if got := string([]rune(body)[span.Start:span.End]); got != span.Text {
+					t.Errorf("rune offsets [%d:%d] select %q, want %q", span.Start, span.End, got, span.Text)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// charsPerToken approximates the tokens of a text from its length (providers do not all report tokens)
+const charsPerToken = 4
+
+// EstimateTokens returns the approximate number of tokens of a text of chars characters
+func EstimateTokens(chars int) int {
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+// UsageRecord is the usage of one provider by one user for one operation.
+// Characters are runes of the rendered prompts and of the output; tokens are estimated from them.
+type UsageRecord struct {
+	UserID       string
+	Provider     string
+	Model        string
+	Operation    Operation
+	Calls        int // Provider calls, including retries
+	Failures     int
+	InputChars   int
+	OutputChars  int
+	InputTokens  int
+	OutputTokens int
+}
+
+// UsageTracker accounts the AI usage of each user and enforces their quotas
+type UsageTracker interface {
+	// CheckQuota returns a *QuotaError when the user may not call the provider anymore today
+	CheckQuota(userID, provider string) error
+	// RecordUsage accounts an operation (must not block)
+	RecordUsage(record UsageRecord)
+}
+
+// ErrQuotaExceeded is matched (errors.Is) by every *QuotaError
+var ErrQuotaExceeded = errors.New("daily AI quota exceeded")
+
+// QuotaError tells which daily quota a user reached and when it resets
+type QuotaError struct {
+	Limit   string // "tokens" or "requests"
+	Used    int64
+	Quota   int64
+	ResetAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("daily AI quota exceeded: %d/%d %s used, resets at %s", e.Used, e.Quota, e.Limit, e.ResetAt.UTC().Format(time.RFC3339))
+}
+
+func (e *QuotaError) Is(target error) bool { return target == ErrQuotaExceeded }
+
+// usageMeter counts the characters sent to and received from each provider during an operation
+type usageMeter struct {
+	mu      sync.Mutex
+	records map[string]*UsageRecord // By provider
+	order   []string
+	current *UsageRecord
+}
+
+type usageMeterKey struct{}
+
+func withUsageMeter(ctx context.Context) (context.Context, *usageMeter) {
+	meter := &usageMeter{records: make(map[string]*UsageRecord)}
+	return context.WithValue(ctx, usageMeterKey{}, meter), meter
+}
+
+func usageMeterFrom(ctx context.Context) *usageMeter {
+	meter, _ := ctx.Value(usageMeterKey{}).(*usageMeter)
+	return meter
+}
+
+// begin starts a provider call; prompts rendered until end are charged to it
+func (m *usageMeter) begin(op Operation, provider, model string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[provider]
+	if !ok {
+		record = &UsageRecord{Provider: provider, Model: model, Operation: op}
+		m.records[provider] = record
+		m.order = append(m.order, provider)
+	}
+	record.Calls++
+	m.current = record
+}
+
+func (m *usageMeter) end(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current != nil && err != nil {
+		m.current.Failures++
+	}
+	m.current = nil
+}
+
+func (m *usageMeter) addInput(chars int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current != nil {
+		m.current.InputChars += chars
+	}
+}
+
+func (m *usageMeter) addOutput(provider string, chars int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if record, ok := m.records[provider]; ok {
+		record.OutputChars += chars
+	}
+}
+
+// snapshot returns the usage of every provider called, with estimated tokens
+func (m *usageMeter) snapshot(userID string) []UsageRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]UsageRecord, 0, len(m.order))
+	for _, provider := range m.order {
+		record := *m.records[provider]
+		record.UserID = userID
+		record.InputTokens = EstimateTokens(record.InputChars)
+		record.OutputTokens = EstimateTokens(record.OutputChars)
+		result = append(result, record)
+	}
+	return result
+}
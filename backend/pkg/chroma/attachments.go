@@ -0,0 +1,112 @@
+package chroma
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"ga03-backend/pkg/embedding"
+
+	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+)
+
+// UpsertAttachmentChunks embeds the passages of an attachment, replacing the ones stored before
+func (c *ChromaClient) UpsertAttachmentChunks(ctx context.Context, userID, emailID, attachmentID string, chunks []embedding.Chunk) error {
+	if c.attachments == nil {
+		return fmt.Errorf("attachment collection not available")
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	// A shorter new version of the document leaves no passages of the old one behind
+	err := c.attachments.Delete(ctx, chroma.WithWhereDelete(chroma.And(
+		chroma.EqString("user_id", userID),
+		chroma.EqString("email_id", emailID),
+		chroma.EqString("attachment_id", attachmentID),
+	)))
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment chunks: %w", err)
+	}
+
+	ids := make([]chroma.DocumentID, len(chunks))
+	texts := make([]string, len(chunks))
+	metadatas := make([]chroma.DocumentMetadata, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = chroma.DocumentID(fmt.Sprintf("%s/%s/%d", emailID, attachmentID, chunk.Index))
+		texts[i] = embedding.ChunkText(chunk)
+		metadatas[i], err = chroma.NewDocumentMetadataFromMap(map[string]interface{}{
+			"user_id":         userID,
+			"email_id":        emailID,
+			"attachment_id":   attachmentID,
+			"chunk_index":     chunk.Index,
+			"filename":        chunk.Filename,
+			"mime_type":       chunk.MimeType,
+			"embedding_model": c.embedder.Model(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create metadata: %w", err)
+		}
+	}
+
+	err = c.attachments.Upsert(
+		ctx,
+		chroma.WithIDs(ids...),
+		chroma.WithMetadatas(metadatas...),
+		chroma.WithTexts(texts...),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert attachment chunks: %w", err)
+	}
+	return nil
+}
+
+// SearchAttachmentChunks returns the user's attachment passages closest to the query, with their distances
+func (c *ChromaClient) SearchAttachmentChunks(ctx context.Context, userID, query string, limit int) ([]embedding.ChunkMatch, error) {
+	if c.attachments == nil {
+		return []embedding.ChunkMatch{}, nil
+	}
+
+	results, err := c.attachments.Query(
+		ctx,
+		chroma.WithQueryTexts(query),
+		chroma.WithNResults(limit),
+		chroma.WithWhereQuery(chroma.EqString("user_id", userID)),
+		chroma.WithIncludeQuery(chroma.IncludeDocuments, chroma.IncludeMetadatas, chroma.Include("distances")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachment collection: %w", err)
+	}
+	if results == nil || results.CountGroups() == 0 {
+		return []embedding.ChunkMatch{}, nil
+	}
+
+	documents := results.GetDocumentsGroups()[0]
+	metadatas := results.GetMetadatasGroups()[0]
+	var distances []float64
+	if groups := results.GetDistancesGroups(); len(groups) > 0 {
+		for _, d := range groups[0] {
+			distances = append(distances, float64(d))
+		}
+	}
+
+	matches := make([]embedding.ChunkMatch, 0, len(metadatas))
+	for i, metadata := range metadatas {
+		if metadata == nil || i >= len(documents) || i >= len(distances) {
+			continue
+		}
+		var chunk embedding.Chunk
+		chunk.EmailID, _ = metadata.GetString("email_id")
+		chunk.AttachmentID, _ = metadata.GetString("attachment_id")
+		chunk.Filename, _ = metadata.GetString("filename")
+		chunk.MimeType, _ = metadata.GetString("mime_type")
+		index, _ := metadata.GetInt("chunk_index")
+		chunk.Index = int(index)
+		// The stored document is the embedded text: drop the file name header
+		chunk.Text = strings.TrimPrefix(documents[i].ContentString(), embedding.ChunkHeader(chunk))
+		matches = append(matches, embedding.ChunkMatch{Chunk: chunk, Distance: distances[i]})
+	}
+	log.Printf("[SemanticSearch] Chroma returned %d attachment passages for user %s", len(matches), userID)
+	return matches, nil
+}
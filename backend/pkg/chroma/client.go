@@ -4,43 +4,91 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"regexp"
+	"strings"
 
 	"ga03-backend/pkg/config"
+	"ga03-backend/pkg/embedding"
 
 	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
-	"github.com/amikos-tech/chroma-go/pkg/embeddings/gemini"
+	"github.com/amikos-tech/chroma-go/pkg/embeddings"
 )
 
 type ChromaClient struct {
 	client     chroma.Client
-	embedFunc  *gemini.GeminiEmbeddingFunction
+	embedder   embedding.Embedder
 	config     *config.Config
 	collection chroma.Collection // Pre-created collection
+	// Passages of attachments (one document per chunk); nil when it could not be created
+	attachments chroma.Collection
+	// Passages of long email bodies (one document per chunk); nil when it could not be created
+	emailChunks chroma.Collection
 }
 
-func NewChromaClient(cfg *config.Config) (*ChromaClient, error) {
-	if cfg.ChromaAPIKey == "" {
-		return nil, fmt.Errorf("CHROMA_API_KEY is required")
-	}
+// embeddingFunction lets Chroma embed documents and queries with the application's embedder
+type embeddingFunction struct {
+	embedder embedding.Embedder
+}
 
-	// Set environment variable for Gemini API key if needed
-	if cfg.GeminiApiKey != "" {
-		os.Setenv("GEMINI_API_KEY", cfg.GeminiApiKey)
+func (f *embeddingFunction) EmbedDocuments(ctx context.Context, texts []string) ([]embeddings.Embedding, error) {
+	vectors, err := f.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, err
 	}
+	result := make([]embeddings.Embedding, len(vectors))
+	for i, vector := range vectors {
+		result[i] = embeddings.NewEmbeddingFromFloat32(vector)
+	}
+	return result, nil
+}
 
-	// Create Gemini embedding function
-	embedFunc, err := gemini.NewGeminiEmbeddingFunction(
-		gemini.WithEnvAPIKey(),
-		gemini.WithDefaultModel("text-embedding-004"),
-	)
+func (f *embeddingFunction) EmbedQuery(ctx context.Context, text string) (embeddings.Embedding, error) {
+	vector, err := f.embedder.EmbedQuery(ctx, text)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Gemini embedding function: %w", err)
+		return nil, err
+	}
+	return embeddings.NewEmbeddingFromFloat32(vector), nil
+}
+
+var invalidCollectionChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// collectionName returns the collection of a model: a Chroma collection holds vectors of one
+// dimension, so another model writes to its own collection while the corpus is re-embedded.
+// The legacy model keeps the original "emails" collection.
+func collectionName(model string) string {
+	if model == embedding.LegacyModel {
+		return "emails"
+	}
+	return modelCollectionName("emails", model)
+}
+
+// attachmentCollectionName returns the collection of the attachment passages of a model
+func attachmentCollectionName(model string) string {
+	return modelCollectionName("attachments", model)
+}
+
+// emailChunkCollectionName returns the collection of the email body passages of a model
+func emailChunkCollectionName(model string) string {
+	return modelCollectionName("email_chunks", model)
+}
+
+func modelCollectionName(prefix, model string) string {
+	name := prefix + "_" + strings.Trim(invalidCollectionChars.ReplaceAllString(model, "_"), "_-")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "_-")
+	}
+	return name
+}
+
+func NewChromaClient(cfg *config.Config, embedder embedding.Embedder) (*ChromaClient, error) {
+	if cfg.ChromaAPIKey == "" {
+		return nil, fmt.Errorf("CHROMA_API_KEY is required")
 	}
 
 	// Create Chroma Cloud client
 	// Use Chroma Cloud endpoint - https://api.trychroma.com:8000/api/v2
 	var client chroma.Client
+	var err error
 	if cfg.ChromaDatabase != "" && cfg.ChromaTenant != "" {
 		client, err = chroma.NewHTTPClient(
 			chroma.WithBaseURL(chroma.ChromaCloudEndpoint),
@@ -66,25 +114,53 @@ func NewChromaClient(cfg *config.Config) (*ChromaClient, error) {
 
 	// Create collection once during initialization
 	ctx := context.Background()
+	name := collectionName(embedder.Model())
 	collection, err := client.GetOrCreateCollection(
 		ctx,
-		"emails", // collection name - consistent with vector_search.go
-		chroma.WithEmbeddingFunctionCreate(embedFunc),
+		name,
+		chroma.WithEmbeddingFunctionCreate(&embeddingFunction{embedder: embedder}),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create collection: %w", err)
 	}
 
-	log.Printf("Initialized Chroma client with collection: emails")
+	log.Printf("Initialized Chroma client with collection: %s (%s)", name, embedder.Model())
+
+	// Attachment search is optional: email search works without it
+	attachments, err := client.GetOrCreateCollection(
+		ctx,
+		attachmentCollectionName(embedder.Model()),
+		chroma.WithEmbeddingFunctionCreate(&embeddingFunction{embedder: embedder}),
+	)
+	if err != nil {
+		log.Printf("[WARN] Failed to create attachment collection (attachment search disabled): %v", err)
+		attachments = nil
+	}
+	emailChunks, err := client.GetOrCreateCollection(
+		ctx,
+		emailChunkCollectionName(embedder.Model()),
+		chroma.WithEmbeddingFunctionCreate(&embeddingFunction{embedder: embedder}),
+	)
+	if err != nil {
+		log.Printf("[WARN] Failed to create email passage collection (passage search disabled): %v", err)
+		emailChunks = nil
+	}
 
 	return &ChromaClient{
-		client:     client,
-		embedFunc:  embedFunc,
-		config:     cfg,
-		collection: collection,
+		client:      client,
+		embedder:    embedder,
+		config:      cfg,
+		collection:  collection,
+		attachments: attachments,
+		emailChunks: emailChunks,
 	}, nil
 }
 
+// EmbeddingModel returns the model and dimension of the vectors written now
+func (c *ChromaClient) EmbeddingModel() (string, int) {
+	return c.embedder.Model(), c.embedder.Dimensions()
+}
+
 // GetCollection returns the pre-created collection
 func (c *ChromaClient) GetCollection() chroma.Collection {
 	return c.collection
@@ -105,6 +181,9 @@ func (c *ChromaClient) AddEmailEmbedding(ctx context.Context, collectionName, em
 		"user_id":  userID,
 		"email_id": emailID,
 		"subject":  subject,
+		// Model that produced the vector
+		"embedding_model": c.embedder.Model(),
+		"embedding_dim":   c.embedder.Dimensions(),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create metadata: %w", err)
@@ -141,6 +220,9 @@ func (c *ChromaClient) UpsertEmailEmbedding(ctx context.Context, collectionName,
 		"user_id":  userID,
 		"email_id": emailID,
 		"subject":  subject,
+		// Model that produced the vector
+		"embedding_model": c.embedder.Model(),
+		"embedding_dim":   c.embedder.Dimensions(),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create metadata: %w", err)
@@ -233,3 +315,60 @@ func (c *ChromaClient) DeleteEmailEmbedding(ctx context.Context, collectionName,
 
 	return nil
 }
+
+// RelatedEmails returns the user's emails closest to the stored vector of an email (the email itself
+// excluded), with their distances. Nothing is returned while the email has no vector in the collection.
+func (c *ChromaClient) RelatedEmails(ctx context.Context, userID, emailID string, limit int) ([]string, []float64, error) {
+	collection := c.GetCollection()
+	if collection == nil {
+		return nil, nil, fmt.Errorf("collection is nil")
+	}
+
+	stored, err := collection.Get(
+		ctx,
+		chroma.WithIDsGet(chroma.DocumentID(emailID)),
+		chroma.WithIncludeGet(chroma.IncludeEmbeddings, chroma.IncludeMetadatas),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get email embedding: %w", err)
+	}
+	if stored == nil || stored.Count() == 0 || len(stored.GetEmbeddings()) == 0 || len(stored.GetMetadatas()) == 0 {
+		return []string{}, []float64{}, nil
+	}
+	if owner, _ := stored.GetMetadatas()[0].GetString("user_id"); owner != userID {
+		return []string{}, []float64{}, nil
+	}
+
+	results, err := collection.Query(
+		ctx,
+		chroma.WithQueryEmbeddings(stored.GetEmbeddings()[0]),
+		chroma.WithNResults(limit),
+		chroma.WithWhereQuery(chroma.And(
+			chroma.EqString("user_id", userID),
+			chroma.NotEqString("email_id", emailID),
+		)),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query collection: %w", err)
+	}
+	if results == nil || results.CountGroups() == 0 {
+		return []string{}, []float64{}, nil
+	}
+
+	idGroups := results.GetIDGroups()
+	distanceGroups := results.GetDistancesGroups()
+	if len(idGroups) == 0 || len(distanceGroups) == 0 {
+		return []string{}, []float64{}, nil
+	}
+	emailIDs := make([]string, 0, len(idGroups[0]))
+	distances := make([]float64, 0, len(idGroups[0]))
+	for i, id := range idGroups[0] {
+		if string(id) == emailID || i >= len(distanceGroups[0]) {
+			continue
+		}
+		emailIDs = append(emailIDs, string(id))
+		distances = append(distances, float64(distanceGroups[0][i]))
+	}
+	log.Printf("[RelatedEmails] Chroma returned %d emails related to %s", len(emailIDs), emailID)
+	return emailIDs, distances, nil
+}
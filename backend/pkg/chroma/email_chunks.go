@@ -0,0 +1,106 @@
+package chroma
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"ga03-backend/pkg/embedding"
+
+	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+)
+
+// UpsertEmailChunks embeds the passages of a long email body, replacing the ones stored before
+func (c *ChromaClient) UpsertEmailChunks(ctx context.Context, userID, emailID string, chunks []embedding.Chunk) error {
+	if c.emailChunks == nil {
+		return fmt.Errorf("email passage collection not available")
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	err := c.emailChunks.Delete(ctx, chroma.WithWhereDelete(chroma.And(
+		chroma.EqString("user_id", userID),
+		chroma.EqString("email_id", emailID),
+	)))
+	if err != nil {
+		return fmt.Errorf("failed to delete email chunks: %w", err)
+	}
+
+	ids := make([]chroma.DocumentID, len(chunks))
+	texts := make([]string, len(chunks))
+	metadatas := make([]chroma.DocumentMetadata, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = chroma.DocumentID(fmt.Sprintf("%s/%d", emailID, chunk.Index))
+		texts[i] = embedding.ChunkText(chunk)
+		metadatas[i], err = chroma.NewDocumentMetadataFromMap(map[string]interface{}{
+			"user_id":         userID,
+			"email_id":        emailID,
+			"chunk_index":     chunk.Index,
+			"subject":         chunk.Subject,
+			"embedding_model": c.embedder.Model(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create metadata: %w", err)
+		}
+	}
+
+	err = c.emailChunks.Upsert(
+		ctx,
+		chroma.WithIDs(ids...),
+		chroma.WithMetadatas(metadatas...),
+		chroma.WithTexts(texts...),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert email chunks: %w", err)
+	}
+	return nil
+}
+
+// SearchEmailChunks returns the user's email body passages closest to the query, with their distances
+func (c *ChromaClient) SearchEmailChunks(ctx context.Context, userID, query string, limit int) ([]embedding.ChunkMatch, error) {
+	if c.emailChunks == nil {
+		return []embedding.ChunkMatch{}, nil
+	}
+
+	results, err := c.emailChunks.Query(
+		ctx,
+		chroma.WithQueryTexts(query),
+		chroma.WithNResults(limit),
+		chroma.WithWhereQuery(chroma.EqString("user_id", userID)),
+		chroma.WithIncludeQuery(chroma.IncludeDocuments, chroma.IncludeMetadatas, chroma.Include("distances")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email passage collection: %w", err)
+	}
+	if results == nil || results.CountGroups() == 0 {
+		return []embedding.ChunkMatch{}, nil
+	}
+
+	documents := results.GetDocumentsGroups()[0]
+	metadatas := results.GetMetadatasGroups()[0]
+	var distances []float64
+	if groups := results.GetDistancesGroups(); len(groups) > 0 {
+		for _, d := range groups[0] {
+			distances = append(distances, float64(d))
+		}
+	}
+
+	matches := make([]embedding.ChunkMatch, 0, len(metadatas))
+	for i, metadata := range metadatas {
+		if metadata == nil || i >= len(documents) || i >= len(distances) {
+			continue
+		}
+		var chunk embedding.Chunk
+		chunk.EmailID, _ = metadata.GetString("email_id")
+		chunk.Subject, _ = metadata.GetString("subject")
+		index, _ := metadata.GetInt("chunk_index")
+		chunk.Index = int(index)
+		// The stored document is the embedded text: drop the subject header
+		chunk.Text = strings.TrimPrefix(documents[i].ContentString(), embedding.ChunkHeader(chunk))
+		matches = append(matches, embedding.ChunkMatch{Chunk: chunk, Distance: distances[i]})
+	}
+	log.Printf("[SemanticSearch] Chroma returned %d email passages for user %s", len(matches), userID)
+	return matches, nil
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -29,14 +31,74 @@ type Config struct {
 	ChromaAPIKey       string
 	ChromaTenant       string
 	ChromaDatabase     string
+
+	// Vector search backend: "chroma" (Chroma Cloud) or "pgvector" (the application's Postgres)
+	VectorBackend    string
+	PgVectorEfSearch int // HNSW candidates scanned per query (per iteration with pgvector 0.8+; default 100, max 1000)
+
+	// Embedding model used by every vector backend. Changing it re-embeds each user's corpus in the background.
+	EmbeddingProvider   string // "gemini" (default), "ollama" or "openai" (OpenAI-compatible /v1/embeddings)
+	EmbeddingModel      string // Default per provider: text-embedding-004, nomic-embed-text, text-embedding-3-small
+	EmbeddingDimensions int    // Vector size (0 = known for the model or learned from the first embedding)
+	EmbeddingBaseURL    string // Server of ollama/openai embeddings (default OLLAMA_BASE_URL / OPENAI_BASE_URL)
+
+	// Attachment search: PDF, DOCX and text attachments up to this size are extracted, chunked and embedded (0 = disabled)
+	AttachmentIndexMaxBytes int
 	
 	// AI Provider config (gemini/ollama)
 	AIProvider    string // "gemini" or "ollama"
 	OllamaBaseURL string // e.g., "http://localhost:11434"
 	OllamaModel   string // e.g., "llama3", "mistral", "qwen2"
+
+	// OpenAI-compatible chat completions server (vLLM, llama.cpp server, LM Studio, LocalAI)
+	OpenAIBaseURL  string // e.g., "http://localhost:8000/v1" (empty = disabled)
+	OpenAIModel    string
+	OpenAIAPIKey   string // Optional Bearer token
+	OpenAIJSONMode bool   // Use response_format json_object (disable for servers that don't support it)
+
+	// AI provider chain: order per operation (comma-separated provider names), breakers and retries
+	AIChainSummarize    []string      // default: ollama,openai,gemini
+	AIChainExtractTasks []string      // default: gemini,openai,ollama
+	AIChainSynonyms     []string      // default: gemini,openai,ollama
+	AIChainDraftReply   []string      // default: gemini,openai,ollama
+	AIChainClassify     []string      // default: ollama,openai,gemini
+	AIBreakerFailures   int           // Consecutive failures before a provider is skipped
+	AIBreakerCooldown   time.Duration // How long a provider is skipped before a probe call
+	AIRetryAttempts     int           // Attempts per provider on connection/5xx errors
+	AIRetryBaseDelay    time.Duration // First retry delay (doubled each attempt, jittered)
+
+	// AI prompt templates
+	PromptTemplatesDir   string        // Optional directory of <language>/<name>.tmpl files overriding the built-in prompts
+	PromptReloadInterval time.Duration // How often active prompt versions are reloaded from the database (default 1m)
+	AdminEmails          []string      // Users allowed on the admin API: prompts, job queue (empty = nobody)
+
+	// Per-user daily AI quotas (0 = unlimited). Users over quota only get the exempt (local) providers.
+	AIDailyTokenQuota      int      // Estimated tokens (prompt + output) per user per day (default 200000)
+	AIDailyRequestQuota    int      // Provider calls per user per day (default 500)
+	AIQuotaExemptProviders []string // Providers not counted nor blocked (default: ollama)
 	
 	// Firebase/FCM config
 	FirebaseCredentials string // Path to Firebase Admin SDK credentials JSON (can be same as Google credentials)
+
+	// Stale summary refresh (summaries from older models or prompt versions)
+	SummaryRefreshInterval time.Duration // How often stale summaries are queued (0 = disabled, default 15m)
+	SummaryRefreshBatch    int           // Summaries queued per refresh (default 20)
+
+	// Persistent job queue (summaries, vector sync)
+	JobQueuePollInterval time.Duration // How often idle workers look for due jobs (default 1s)
+	JobMaxAttempts       int           // Attempts before a job is dead-lettered (default 5)
+	JobRetryBaseDelay    time.Duration // First retry delay, doubled each attempt (default 10s)
+	JobRetryMaxDelay     time.Duration // Cap of the retry delay (default 30m)
+	JobStuckTimeout      time.Duration // Running jobs older than this are requeued (default 10m)
+	JobTimeout           time.Duration // Deadline of one job, kept below JobStuckTimeout (default 5m)
+	JobDeadRetention     time.Duration // Dead-lettered jobs older than this are deleted (default 7 days)
+	JobMaxRunningPerUser int           // Jobs of one user running at once per queue (default 2)
+
+	// Snooze scheduler
+	SnoozeCheckInterval time.Duration // How often due snoozes are claimed (default 1m)
+
+	// Follow-up reminders for sent emails
+	FollowUpDefaultColumn string // Kanban column to resurface unanswered emails into (default "inbox")
 }
 
 func Load() *Config {
@@ -57,6 +119,13 @@ func Load() *Config {
 		}
 	}
 
+	snoozeInterval := 1 * time.Minute
+	if interval := os.Getenv("SNOOZE_CHECK_INTERVAL"); interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil {
+			snoozeInterval = parsed
+		}
+	}
+
 	return &Config{
 		Port:               getEnv("PORT", "8080"),
 		JWTSecret:          getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
@@ -79,12 +148,61 @@ func Load() *Config {
 		ChromaAPIKey:       os.Getenv("CHROMA_API_KEY"),
 		ChromaTenant:       os.Getenv("CHROMA_TENANT"),
 		ChromaDatabase:     os.Getenv("CHROMA_DATABASE"),
+		// Vector search backend config
+		VectorBackend:    strings.ToLower(getEnv("VECTOR_BACKEND", "chroma")),
+		PgVectorEfSearch: getEnvInt("PGVECTOR_EF_SEARCH", 100),
+		// Embedding config
+		EmbeddingProvider:   getEnv("EMBEDDING_PROVIDER", "gemini"),
+		EmbeddingModel:      os.Getenv("EMBEDDING_MODEL"),
+		EmbeddingDimensions: getEnvInt("EMBEDDING_DIMENSIONS", 0),
+		EmbeddingBaseURL:    os.Getenv("EMBEDDING_BASE_URL"),
+		// Attachment search config
+		AttachmentIndexMaxBytes: getEnvInt("ATTACHMENT_INDEX_MAX_BYTES", 10<<20),
 		// AI Provider config
 		AIProvider:    getEnv("AI_PROVIDER", "gemini"), // "gemini" or "ollama"
 		OllamaBaseURL: getEnv("OLLAMA_BASE_URL", "http://localhost:11434"),
 		OllamaModel:   getEnv("OLLAMA_MODEL", "llama3"),
+		// OpenAI-compatible provider config
+		OpenAIBaseURL:  os.Getenv("OPENAI_BASE_URL"),
+		OpenAIModel:    getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+		OpenAIAPIKey:   os.Getenv("OPENAI_API_KEY"),
+		OpenAIJSONMode: getEnv("OPENAI_JSON_MODE", "true") == "true",
+		// AI provider chain config
+		AIChainSummarize:    getEnvList("AI_CHAIN_SUMMARIZE", "ollama,openai,gemini"),
+		AIChainExtractTasks: getEnvList("AI_CHAIN_EXTRACT_TASKS", "gemini,openai,ollama"),
+		AIChainSynonyms:     getEnvList("AI_CHAIN_SYNONYMS", "gemini,openai,ollama"),
+		AIChainDraftReply:   getEnvList("AI_CHAIN_DRAFT_REPLY", "gemini,openai,ollama"),
+		AIChainClassify:     getEnvList("AI_CHAIN_CLASSIFY", "ollama,openai,gemini"),
+		AIBreakerFailures:   getEnvInt("AI_BREAKER_FAILURES", 3),
+		AIBreakerCooldown:   getEnvDuration("AI_BREAKER_COOLDOWN", 30*time.Second),
+		AIRetryAttempts:     getEnvInt("AI_RETRY_ATTEMPTS", 2),
+		AIRetryBaseDelay:    getEnvDuration("AI_RETRY_BASE_DELAY", 200*time.Millisecond),
+		// AI prompt templates
+		PromptTemplatesDir:   os.Getenv("PROMPT_TEMPLATES_DIR"),
+		PromptReloadInterval: getEnvDuration("PROMPT_RELOAD_INTERVAL", time.Minute),
+		AdminEmails:          getEnvList("ADMIN_EMAILS", ""),
+		// AI quota config
+		AIDailyTokenQuota:      getEnvInt("AI_DAILY_TOKEN_QUOTA", 200000),
+		AIDailyRequestQuota:    getEnvInt("AI_DAILY_REQUEST_QUOTA", 500),
+		AIQuotaExemptProviders: getEnvList("AI_QUOTA_EXEMPT_PROVIDERS", "ollama"),
 		// Firebase/FCM config (defaults to Google credentials if not set)
 		FirebaseCredentials: getEnv("FIREBASE_CREDENTIALS", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")),
+		// Stale summary refresh config
+		SummaryRefreshInterval: getEnvDuration("SUMMARY_REFRESH_INTERVAL", 15*time.Minute),
+		SummaryRefreshBatch:    getEnvInt("SUMMARY_REFRESH_BATCH", 20),
+		// Job queue config
+		JobQueuePollInterval: getEnvDuration("JOB_QUEUE_POLL_INTERVAL", time.Second),
+		JobMaxAttempts:       getEnvInt("JOB_MAX_ATTEMPTS", 5),
+		JobRetryBaseDelay:    getEnvDuration("JOB_RETRY_BASE_DELAY", 10*time.Second),
+		JobRetryMaxDelay:     getEnvDuration("JOB_RETRY_MAX_DELAY", 30*time.Minute),
+		JobStuckTimeout:      getEnvDuration("JOB_STUCK_TIMEOUT", 10*time.Minute),
+		JobTimeout:           getEnvDuration("JOB_TIMEOUT", 5*time.Minute),
+		JobDeadRetention:     getEnvDuration("JOB_DEAD_RETENTION", 7*24*time.Hour),
+		JobMaxRunningPerUser: getEnvInt("JOB_MAX_RUNNING_PER_USER", 2),
+		// Snooze scheduler config
+		SnoozeCheckInterval: snoozeInterval,
+		// Follow-up reminders config
+		FollowUpDefaultColumn: getEnv("FOLLOW_UP_DEFAULT_COLUMN", "inbox"),
 	}
 }
 
@@ -94,3 +212,32 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList reads a comma-separated list, ignoring empty items
+func getEnvList(key, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
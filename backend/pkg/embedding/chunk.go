@@ -0,0 +1,32 @@
+package embedding
+
+// Chunk is a passage of a long email body or of an email attachment embedded on its own,
+// so that long documents are searchable beyond the first page
+type Chunk struct {
+	EmailID      string
+	AttachmentID string // Empty for a passage of the email body
+	Filename     string
+	MimeType     string
+	Subject      string // Subject of the email (passages of the email body)
+	Index        int    // Position of the passage in the document
+	Text         string
+}
+
+// ChunkMatch is a chunk returned by a search with its distance to the query (metric of the vector backend)
+type ChunkMatch struct {
+	Chunk
+	Distance float64
+}
+
+// ChunkText returns the text embedded for a chunk: the file name or the email subject gives the passage its context
+func ChunkText(chunk Chunk) string {
+	return ChunkHeader(chunk) + chunk.Text
+}
+
+// ChunkHeader returns the context line prepended to the text of a chunk before embedding
+func ChunkHeader(chunk Chunk) string {
+	if chunk.AttachmentID == "" {
+		return "Subject: " + chunk.Subject + "\n\n"
+	}
+	return "Attachment: " + chunk.Filename + "\n\n"
+}
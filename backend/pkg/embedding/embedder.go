@@ -0,0 +1,133 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"ga03-backend/pkg/config"
+)
+
+// Provider names of EMBEDDING_PROVIDER
+const (
+	ProviderGemini = "gemini"
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai"
+)
+
+// LegacyModel identifies the vectors stored before the embedding model was recorded (Gemini text-embedding-004)
+const LegacyModel = "gemini:text-embedding-004"
+
+// LegacyDimensions is the vector size of LegacyModel
+const LegacyDimensions = 768
+
+// Embedder turns texts into vectors. Every vector backend embeds with it, so that documents
+// and queries of a corpus always come from the same model.
+type Embedder interface {
+	// EmbedDocuments returns one vector per text, for indexing
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+	// EmbedQuery returns the vector of a search query
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+	// Model identifies the model as "<provider>:<model>", e.g. "ollama:nomic-embed-text"
+	Model() string
+	// Dimensions is the vector size: configured, known for the model, or learned from
+	// the first embedding (0 until then)
+	Dimensions() int
+}
+
+// knownDimensions are the vector sizes of common embedding models
+var knownDimensions = map[string]int{
+	"text-embedding-004":     768,
+	"gemini-embedding-001":   3072,
+	"nomic-embed-text":       768,
+	"mxbai-embed-large":      1024,
+	"all-minilm":             384,
+	"bge-m3":                 1024,
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+}
+
+// defaultModels are the models used when EMBEDDING_MODEL is not set
+var defaultModels = map[string]string{
+	ProviderGemini: "text-embedding-004",
+	ProviderOllama: "nomic-embed-text",
+	ProviderOpenAI: "text-embedding-3-small",
+}
+
+// New creates the embedder selected by EMBEDDING_PROVIDER.
+// The model is fixed at startup (not runtime settings): changing it requires re-embedding the corpus.
+func New(cfg *config.Config) (Embedder, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.EmbeddingProvider))
+	if provider == "" {
+		provider = ProviderGemini
+	}
+	model := cfg.EmbeddingModel
+	if model == "" {
+		model = defaultModels[provider]
+	}
+
+	dims := newDimensions(model, cfg.EmbeddingDimensions)
+	switch provider {
+	case ProviderGemini:
+		if cfg.GeminiApiKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY is required for Gemini embeddings")
+		}
+		return newGeminiEmbedder(cfg.GeminiApiKey, model, dims), nil
+	case ProviderOllama:
+		baseURL := cfg.EmbeddingBaseURL
+		if baseURL == "" {
+			baseURL = cfg.OllamaBaseURL
+		}
+		return newOllamaEmbedder(baseURL, model, dims), nil
+	case ProviderOpenAI:
+		baseURL := cfg.EmbeddingBaseURL
+		if baseURL == "" {
+			baseURL = cfg.OpenAIBaseURL
+		}
+		if baseURL == "" {
+			return nil, fmt.Errorf("EMBEDDING_BASE_URL or OPENAI_BASE_URL is required for OpenAI-compatible embeddings")
+		}
+		return newOpenAIEmbedder(baseURL, cfg.OpenAIAPIKey, model, dims), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q (gemini, ollama or openai)", provider)
+	}
+}
+
+// dimensions holds the vector size of a model, learned from the first embedding when unknown
+type dimensions struct {
+	mu    sync.RWMutex
+	value int
+}
+
+func newDimensions(model string, configured int) *dimensions {
+	if configured > 0 {
+		return &dimensions{value: configured}
+	}
+	return &dimensions{value: knownDimensions[model]}
+}
+
+func (d *dimensions) get() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.value
+}
+
+// check learns the size from the first vectors and rejects vectors of another size
+func (d *dimensions) check(model string, vectors ...[]float32) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, vector := range vectors {
+		if len(vector) == 0 {
+			return fmt.Errorf("%s returned an empty embedding", model)
+		}
+		if d.value == 0 {
+			d.value = len(vector)
+		}
+		if len(vector) != d.value {
+			return fmt.Errorf("%s returned %d dimensions, expected %d (EMBEDDING_DIMENSIONS)", model, len(vector), d.value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+
+	"ga03-backend/pkg/gemini"
+)
+
+// geminiEmbedder embeds with the Gemini API (batchEmbedContents)
+type geminiEmbedder struct {
+	service *gemini.GeminiService
+	model   string
+	dims    *dimensions
+}
+
+func newGeminiEmbedder(apiKey, model string, dims *dimensions) *geminiEmbedder {
+	return &geminiEmbedder{service: gemini.NewGeminiService(apiKey), model: model, dims: dims}
+}
+
+func (g *geminiEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors, err := g.service.BatchEmbedContents(ctx, g.model, texts, gemini.TaskRetrievalDocument)
+	if err != nil {
+		return nil, fmt.Errorf("gemini embedding failed: %w", err)
+	}
+	if err := g.dims.check(g.Model(), vectors...); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+func (g *geminiEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := g.service.BatchEmbedContents(ctx, g.model, []string{text}, gemini.TaskRetrievalQuery)
+	if err != nil {
+		return nil, fmt.Errorf("gemini embedding failed: %w", err)
+	}
+	if err := g.dims.check(g.Model(), vectors...); err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (g *geminiEmbedder) Model() string { return ProviderGemini + ":" + g.model }
+
+func (g *geminiEmbedder) Dimensions() int { return g.dims.get() }
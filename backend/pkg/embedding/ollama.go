@@ -0,0 +1,90 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ollamaEmbedder embeds with a local Ollama server (/api/embeddings, one text per call)
+type ollamaEmbedder struct {
+	baseURL string
+	model   string
+	dims    *dimensions
+	client  *http.Client
+}
+
+func newOllamaEmbedder(baseURL, model string, dims *dimensions) *ollamaEmbedder {
+	return &ollamaEmbedder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		dims:    dims,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (o *ollamaEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector, err := o.embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+func (o *ollamaEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return o.embed(ctx, text)
+}
+
+func (o *ollamaEmbedder) embed(ctx context.Context, text string) ([]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":  o.model,
+		"prompt": text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/embeddings", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama embedding error (%d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if err := o.dims.check(o.Model(), result.Embedding); err != nil {
+		return nil, err
+	}
+	return result.Embedding, nil
+}
+
+func (o *ollamaEmbedder) Model() string { return ProviderOllama + ":" + o.model }
+
+func (o *ollamaEmbedder) Dimensions() int { return o.dims.get() }
@@ -0,0 +1,121 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxOpenAIBatch is the number of texts sent per /embeddings call
+const maxOpenAIBatch = 64
+
+// openAIEmbedder embeds with an OpenAI-compatible server (/v1/embeddings: vLLM, llama.cpp server, LocalAI, TEI...)
+type openAIEmbedder struct {
+	baseURL string
+	apiKey  string
+	model   string
+	dims    *dimensions
+	client  *http.Client
+}
+
+func newOpenAIEmbedder(baseURL, apiKey, model string, dims *dimensions) *openAIEmbedder {
+	return &openAIEmbedder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		dims:    dims,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (o *openAIEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxOpenAIBatch {
+		end := start + maxOpenAIBatch
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := o.embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (o *openAIEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := o.embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (o *openAIEmbedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": o.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/embeddings", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openai embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai embedding error (%d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	// Results may come in any order: place them by index
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) || vectors[item.Index] != nil {
+			return nil, fmt.Errorf("invalid embedding index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	if err := o.dims.check(o.Model(), vectors...); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+func (o *openAIEmbedder) Model() string { return ProviderOpenAI + ":" + o.model }
+
+func (o *openAIEmbedder) Dimensions() int { return o.dims.get() }
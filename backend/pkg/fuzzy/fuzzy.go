@@ -5,28 +5,6 @@ import (
 	"unicode"
 )
 
-// BuildGmailSearchQuery builds a Gmail search query from user query for pre-filtering
-// This helps reduce the number of emails that need fuzzy matching
-// Gmail search syntax: https://support.google.com/mail/answer/7190
-// IMPORTANT: Gmail search doesn't automatically remove accents, so for Vietnamese text,
-// we return empty string to skip Gmail pre-filtering and fetch all emails for fuzzy matching
-// This ensures we don't miss matches like "canh" -> "cảnh báo"
-func BuildGmailSearchQuery(userQuery string) string {
-	userQuery = strings.TrimSpace(userQuery)
-	if len(userQuery) == 0 {
-		return ""
-	}
-
-	// Check if query might contain Vietnamese characters or needs accent-insensitive matching
-	// Gmail search is accent-sensitive, so for better results with Vietnamese text,
-	// we skip Gmail pre-filtering and rely on fuzzy matching instead
-	// This is more inclusive and ensures we don't miss matches
-
-	// For now, we'll skip Gmail search query for all queries to ensure accent-insensitive matching
-	// The performance impact is acceptable since we do progressive fetching and early termination
-	return ""
-}
-
 // QuickFilter performs a simple contains check for pre-filtering
 // Returns true if email might match (for further fuzzy matching)
 // This is much faster than fuzzy matching and helps reduce the dataset
@@ -403,6 +381,20 @@ func normalizeString(s string) string {
 	return s
 }
 
+// Tokenize splits text into lowercase, accent-free words of letters and digits
+// e.g. "Cảnh báo: hóa-đơn #12" -> ["canh", "bao", "hoa", "don", "12"]
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(normalizeString(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Fold returns the words of s as Tokenize does, separated by single spaces
+// e.g. "Cảnh báo: hóa-đơn #12" -> "canh bao hoa don 12"
+func Fold(s string) string {
+	return strings.Join(Tokenize(s), " ")
+}
+
 // containsWord checks if text contains query as a whole word
 func containsWord(text, query string) bool {
 	words := strings.Fields(text)
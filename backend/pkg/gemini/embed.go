@@ -0,0 +1,90 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Embedding task types: documents and queries are embedded differently for retrieval
+const (
+	TaskRetrievalDocument = "RETRIEVAL_DOCUMENT"
+	TaskRetrievalQuery    = "RETRIEVAL_QUERY"
+)
+
+// maxBatchEmbedRequests is the maximum number of texts per batchEmbedContents call
+const maxBatchEmbedRequests = 100
+
+// BatchEmbedContents returns the embedding of each text with an embedding model (e.g. "text-embedding-004")
+func (g *GeminiService) BatchEmbedContents(ctx context.Context, model string, texts []string, taskType string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxBatchEmbedRequests {
+		end := start + maxBatchEmbedRequests
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := g.batchEmbed(ctx, model, texts[start:end], taskType)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (g *GeminiService) batchEmbed(ctx context.Context, model string, texts []string, taskType string) ([][]float32, error) {
+	url := "https://generativelanguage.googleapis.com/v1beta/models/" + model + ":batchEmbedContents?key=" + g.ApiKey
+
+	requests := make([]map[string]interface{}, 0, len(texts))
+	for _, text := range texts {
+		request := map[string]interface{}{
+			"model":   "models/" + model,
+			"content": map[string]interface{}{"parts": []map[string]string{{"text": text}}},
+		}
+		if taskType != "" {
+			request["taskType"] = taskType
+		}
+		requests = append(requests, request)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"requests": requests})
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var result struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Embeddings))
+	}
+
+	vectors := make([][]float32, len(result.Embeddings))
+	for i, embedding := range result.Embeddings {
+		vectors[i] = embedding.Values
+	}
+	return vectors, nil
+}
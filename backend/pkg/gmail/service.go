@@ -10,6 +10,7 @@ import (
 	"log"
 	"mime"
 	"mime/multipart"
+	"net/http"
 	"regexp"
 	"sort"
 	"strings"
@@ -20,6 +21,7 @@ import (
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
@@ -229,43 +231,127 @@ func (s *Service) GetEmails(ctx context.Context, accessToken, refreshToken strin
 
 	// If we had an offset and skipped some messages, we might need to adjust
 	// But since Gmail API uses pageToken, we can't go back, so we just return what we got
-	emails := make([]*emaildomain.Email, 0, len(messagesResp.Messages))
+	ids := make([]string, len(messagesResp.Messages))
+	for i, msg := range messagesResp.Messages {
+		ids[i] = msg.Id
+	}
+	emails := fetchMessages(srv, ids)
+
+	// Return total estimate from Gmail API
+	totalEstimate := int(messagesResp.ResultSizeEstimate)
+	if totalEstimate == 0 && len(emails) > 0 {
+		// If estimate is 0 but we have emails, use a reasonable estimate
+		totalEstimate = len(emails) + currentOffset
+	}
+
+	return emails, totalEstimate, nil
+}
+
+// maxExcludingScan bounds the message IDs listed by GetEmailsExcluding to count the exact total
+const maxExcludingScan = 10000
+
+// GetEmailsExcluding lists a label newest first while skipping the given email IDs.
+// Only message IDs are listed (500 per request); full messages are fetched for the page alone.
+// Pagination is computed after exclusion, so pages are always full; the total is exact unless the
+// label holds more than maxExcludingScan messages, where the rest is counted from Gmail's estimate.
+// Used for the Kanban inbox column, which must not show emails assigned to other columns.
+func (s *Service) GetEmailsExcluding(ctx context.Context, accessToken, refreshToken, labelID string, excludeIDs []string, limit, offset int, onTokenRefresh TokenUpdateFunc) ([]*emaildomain.Email, int, error) {
+	srv, err := s.GetGmailService(ctx, accessToken, refreshToken, onTokenRefresh)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	excluded := make(map[string]bool, len(excludeIDs))
+	for _, id := range excludeIDs {
+		excluded[id] = true
+	}
+
+	var kept []string
+	scanned := 0
+	estimate := 0
+	pageToken := ""
+	for {
+		listQuery := srv.Users.Messages.List("me").MaxResults(500)
+		if labelID != "" && labelID != "ALL" {
+			listQuery = listQuery.LabelIds(labelID)
+		}
+		if pageToken != "" {
+			listQuery = listQuery.PageToken(pageToken)
+		}
+		resp, err := listQuery.Do()
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to list messages: %v", err)
+		}
+		if estimate == 0 {
+			estimate = int(resp.ResultSizeEstimate)
+		}
+
+		for _, msg := range resp.Messages {
+			if !excluded[msg.Id] {
+				kept = append(kept, msg.Id)
+			}
+		}
+		scanned += len(resp.Messages)
+		pageToken = resp.NextPageToken
+
+		if pageToken == "" {
+			break
+		}
+		if scanned >= maxExcludingScan && len(kept) >= offset+limit {
+			break
+		}
+	}
+
+	total := len(kept)
+	if pageToken != "" && estimate > scanned {
+		// Unscanned messages: assume the excluded emails were among the newest ones scanned
+		total += estimate - scanned
+	}
 
-	// Get full message details for each message
-	// Use goroutines for parallel fetching to improve performance
+	if offset >= len(kept) {
+		return []*emaildomain.Email{}, total, nil
+	}
+	end := offset + limit
+	if end > len(kept) {
+		end = len(kept)
+	}
+	return fetchMessages(srv, kept[offset:end]), total, nil
+}
+
+// fetchMessages gets the full messages of the given IDs in parallel, newest first.
+// Messages that cannot be fetched are skipped.
+func fetchMessages(srv *gmail.Service, ids []string) []*emaildomain.Email {
 	type emailResult struct {
 		email *emaildomain.Email
 		err   error
 	}
 
-	emailChan := make(chan emailResult, len(messagesResp.Messages))
+	emailChan := make(chan emailResult, len(ids))
 
 	// Fetch emails in parallel (with reasonable concurrency limit)
 	semaphore := make(chan struct{}, 10) // Max 10 concurrent requests
 
-	for _, msg := range messagesResp.Messages {
+	for _, id := range ids {
 		go func(msgID string) {
 			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
 
-			fullMsg, err := srv.Users.Messages.Get(user, msgID).Format("full").Do()
+			fullMsg, err := srv.Users.Messages.Get("me", msgID).Format("full").Do()
 			if err != nil {
 				emailChan <- emailResult{nil, err}
 				return
 			}
 
-			email := convertGmailMessageToEmail(fullMsg)
-			emailChan <- emailResult{email, nil}
-		}(msg.Id)
+			emailChan <- emailResult{convertGmailMessageToEmail(fullMsg), nil}
+		}(id)
 	}
 
-	// Collect results
-	for i := 0; i < len(messagesResp.Messages); i++ {
+	emails := make([]*emaildomain.Email, 0, len(ids))
+	for range ids {
 		result := <-emailChan
 		if result.err == nil && result.email != nil {
 			emails = append(emails, result.email)
 		}
-		// Skip emails we can't fetch (already logged in Get)
 	}
 
 	// Sort emails by ReceivedAt descending (newest first)
@@ -273,15 +359,7 @@ func (s *Service) GetEmails(ctx context.Context, accessToken, refreshToken strin
 	sort.Slice(emails, func(i, j int) bool {
 		return emails[i].ReceivedAt.After(emails[j].ReceivedAt)
 	})
-
-	// Return total estimate from Gmail API
-	totalEstimate := int(messagesResp.ResultSizeEstimate)
-	if totalEstimate == 0 && len(emails) > 0 {
-		// If estimate is 0 but we have emails, use a reasonable estimate
-		totalEstimate = len(emails) + currentOffset
-	}
-
-	return emails, totalEstimate, nil
+	return emails
 }
 
 // GetAttachment retrieves an attachment from a message
@@ -345,12 +423,37 @@ func (s *Service) GetEmailByID(ctx context.Context, accessToken, refreshToken, e
 	user := "me"
 	msg, err := srv.Users.Messages.Get(user, emailID).Format("full").Do()
 	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, fmt.Errorf("unable to retrieve message: %w", emaildomain.ErrEmailNotFound)
+		}
 		return nil, fmt.Errorf("unable to retrieve message: %v", err)
 	}
 
 	return convertGmailMessageToEmail(msg), nil
 }
 
+// GetThreadEmails retrieves all messages of a thread (oldest first)
+func (s *Service) GetThreadEmails(ctx context.Context, accessToken, refreshToken, threadID string, onTokenRefresh TokenUpdateFunc) ([]*emaildomain.Email, error) {
+	srv, err := s.GetGmailService(ctx, accessToken, refreshToken, onTokenRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	user := "me"
+	thread, err := srv.Users.Threads.Get(user, threadID).Format("full").Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve thread: %v", err)
+	}
+
+	emails := make([]*emaildomain.Email, 0, len(thread.Messages))
+	for _, msg := range thread.Messages {
+		emails = append(emails, convertGmailMessageToEmail(msg))
+	}
+
+	return emails, nil
+}
+
 // MarkAsRead marks an email as read
 func (s *Service) MarkAsRead(ctx context.Context, accessToken, refreshToken, emailID string, onTokenRefresh TokenUpdateFunc) error {
 	srv, err := s.GetGmailService(ctx, accessToken, refreshToken, onTokenRefresh)
@@ -433,11 +536,11 @@ func (s *Service) ToggleStar(ctx context.Context, accessToken, refreshToken, ema
 	return nil
 }
 
-// SendEmail sends an email
-func (s *Service) SendEmail(ctx context.Context, accessToken, refreshToken, fromName, fromEmail, to, cc, bcc, subject, body string, files []*multipart.FileHeader, onTokenRefresh TokenUpdateFunc) error {
+// SendEmail sends an email and returns the Gmail message/thread IDs and the RFC 5322 Message-ID
+func (s *Service) SendEmail(ctx context.Context, accessToken, refreshToken, fromName, fromEmail, to, cc, bcc, subject, body string, files []*multipart.FileHeader, onTokenRefresh TokenUpdateFunc) (*emaildomain.SentEmail, error) {
 	srv, err := s.GetGmailService(ctx, accessToken, refreshToken, onTokenRefresh)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	user := "me"
@@ -547,6 +650,10 @@ func (s *Service) SendEmail(ctx context.Context, accessToken, refreshToken, from
 	log.Printf("Gmail Service - Encoded Subject: %s", encodedSubject)
 	emailMsg.WriteString(fmt.Sprintf("Subject: %s\r\n", encodedSubject))
 
+	// Set our own Message-ID so replies can be matched back to this message
+	messageID := emaildomain.NewMessageID(fromEmail)
+	emailMsg.WriteString(fmt.Sprintf("Message-ID: %s\r\n", messageID))
+
 	emailMsg.WriteString("MIME-Version: 1.0\r\n")
 
 	// Determine if we need multipart/related (for inline images)
@@ -581,13 +688,13 @@ func (s *Service) SendEmail(ctx context.Context, accessToken, refreshToken, from
 		for _, file := range inlineImages {
 			f, err := file.Open()
 			if err != nil {
-				return fmt.Errorf("unable to open inline image: %v", err)
+				return nil, fmt.Errorf("unable to open inline image: %v", err)
 			}
 			defer f.Close()
 
 			content, err := io.ReadAll(f)
 			if err != nil {
-				return fmt.Errorf("unable to read inline image: %v", err)
+				return nil, fmt.Errorf("unable to read inline image: %v", err)
 			}
 
 			encodedContent := base64.StdEncoding.EncodeToString(content)
@@ -661,13 +768,13 @@ func (s *Service) SendEmail(ctx context.Context, accessToken, refreshToken, from
 	for _, file := range regularAttachments {
 		f, err := file.Open()
 		if err != nil {
-			return fmt.Errorf("unable to open file: %v", err)
+			return nil, fmt.Errorf("unable to open file: %v", err)
 		}
 		defer f.Close()
 
 		content, err := io.ReadAll(f)
 		if err != nil {
-			return fmt.Errorf("unable to read file: %v", err)
+			return nil, fmt.Errorf("unable to read file: %v", err)
 		}
 
 		encodedContent := base64.StdEncoding.EncodeToString(content)
@@ -701,12 +808,16 @@ func (s *Service) SendEmail(ctx context.Context, accessToken, refreshToken, from
 		Raw: base64.URLEncoding.EncodeToString(emailMsg.Bytes()),
 	}
 
-	_, err = srv.Users.Messages.Send(user, msg).Do()
+	sent, err := srv.Users.Messages.Send(user, msg).Do()
 	if err != nil {
-		return fmt.Errorf("unable to send message: %v", err)
+		return nil, fmt.Errorf("unable to send message: %v", err)
 	}
 
-	return nil
+	return &emaildomain.SentEmail{
+		ID:        sent.Id,
+		ThreadID:  sent.ThreadId,
+		MessageID: messageID,
+	}, nil
 }
 
 // TrashEmail moves an email to trash
@@ -898,6 +1009,9 @@ func convertGmailMessageToEmail(msg *gmail.Message) *emaildomain.Email {
 
 	email := &emaildomain.Email{
 		ID:          msg.Id,
+		ThreadID:    msg.ThreadId,
+		MessageID:   getHeader(msg.Payload.Headers, "Message-ID"),
+		InReplyTo:   getHeader(msg.Payload.Headers, "In-Reply-To"),
 		Subject:     getHeader(msg.Payload.Headers, "Subject"),
 		From:        from,
 		FromName:    fromName,
@@ -917,7 +1031,8 @@ func convertGmailMessageToEmail(msg *gmail.Message) *emaildomain.Email {
 
 func getHeader(headers []*gmail.MessagePartHeader, name string) string {
 	for _, header := range headers {
-		if header.Name == name {
+		// Header names are case-insensitive (e.g. "Message-ID" vs "Message-Id")
+		if strings.EqualFold(header.Name, name) {
 			dec := new(mime.WordDecoder)
 			decoded, err := dec.DecodeHeader(header.Value)
 			if err != nil {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/smtp"
+	"sort"
 	"strings"
 
 	emaildomain "ga03-backend/internal/email/domain"
@@ -329,65 +330,236 @@ func (s *IMAPService) GetEmails(ctx context.Context, server string, port int, em
 
 	var result []*emaildomain.Email
 	for msg := range messages {
-		// Parse email
-		subject := msg.Envelope.Subject
-		from := ""
-		if len(msg.Envelope.From) > 0 {
-			from = fmt.Sprintf("%s <%s@%s>", msg.Envelope.From[0].PersonalName, msg.Envelope.From[0].MailboxName, msg.Envelope.From[0].HostName)
+		result = append(result, s.messageToEmail(msg, section, realMailboxName, mailboxID))
+	}
+
+	// Reverse result to show newest first
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return result, int(mbox.Messages), <-done
+}
+
+// messageToEmail converts a fetched IMAP message into an Email (ID = base64 "Mailbox:UID")
+func (s *IMAPService) messageToEmail(msg *imap.Message, section *imap.BodySectionName, realMailboxName, mailboxID string) *emaildomain.Email {
+	// Parse email
+	subject := msg.Envelope.Subject
+	from := ""
+	if len(msg.Envelope.From) > 0 {
+		from = fmt.Sprintf("%s <%s@%s>", msg.Envelope.From[0].PersonalName, msg.Envelope.From[0].MailboxName, msg.Envelope.From[0].HostName)
+	}
+
+	to := []string{}
+	for _, addr := range msg.Envelope.To {
+		to = append(to, fmt.Sprintf("%s <%s@%s>", addr.PersonalName, addr.MailboxName, addr.HostName))
+	}
+
+	body := ""
+	snippet := ""
+	isHTML := false
+
+	r := msg.GetBody(section)
+	if r != nil {
+		var textBody string
+		body, textBody, isHTML = s.parseBody(r)
+		if len(textBody) > 100 {
+			snippet = textBody[:100] + "..."
+		} else {
+			snippet = textBody
 		}
-		
-		to := []string{}
-		for _, addr := range msg.Envelope.To {
-			to = append(to, fmt.Sprintf("%s <%s@%s>", addr.PersonalName, addr.MailboxName, addr.HostName))
+	}
+
+	isRead := false
+	isStarred := false
+	for _, f := range msg.Flags {
+		if f == imap.SeenFlag {
+			isRead = true
 		}
-		
-		body := ""
-		snippet := ""
-		isHTML := false
-		
-		r := msg.GetBody(section)
-		if r != nil {
-			var textBody string
-			body, textBody, isHTML = s.parseBody(r)
-			if len(textBody) > 100 {
-				snippet = textBody[:100] + "..."
-			} else {
-				snippet = textBody
-			}
+		if f == imap.FlaggedFlag {
+			isStarred = true
 		}
+	}
 
-		isRead := false
-		isStarred := false
-		for _, f := range msg.Flags {
-			if f == imap.SeenFlag {
-				isRead = true
-			}
-			if f == imap.FlaggedFlag {
-				isStarred = true
-			}
+	return &emaildomain.Email{
+		ID:         base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", realMailboxName, msg.Uid))), // Encode Mailbox:UID
+		MessageID:  msg.Envelope.MessageId,
+		InReplyTo:  msg.Envelope.InReplyTo,
+		Subject:    subject,
+		From:       from,
+		To:         to,
+		Preview:    snippet,
+		Body:       body,
+		IsHTML:     isHTML,
+		ReceivedAt: msg.Envelope.Date,
+		IsRead:     isRead,
+		IsStarred:  isStarred,
+		MailboxID:  mailboxID,
+	}
+}
+
+// fetchByUIDs fetches the given UIDs from the currently selected mailbox, newest (highest UID) first
+func (s *IMAPService) fetchByUIDs(c *client.Client, realMailboxName, mailboxID string, uids []uint32) ([]*emaildomain.Email, error) {
+	if len(uids) == 0 {
+		return []*emaildomain.Email{}, nil
+	}
+
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uids...)
+
+	messages := make(chan *imap.Message, len(uids))
+	done := make(chan error, 1)
+
+	section := &imap.BodySectionName{Peek: true}
+	items := []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, imap.FetchInternalDate, imap.FetchUid, section.FetchItem()}
+
+	go func() {
+		done <- c.UidFetch(seqset, items, messages)
+	}()
+
+	byUID := make(map[uint32]*emaildomain.Email, len(uids))
+	for msg := range messages {
+		byUID[msg.Uid] = s.messageToEmail(msg, section, realMailboxName, mailboxID)
+	}
+	if err := <-done; err != nil {
+		return nil, err
+	}
+
+	// Keep the requested order (servers return UID FETCH results in ascending order)
+	result := make([]*emaildomain.Email, 0, len(uids))
+	for _, uid := range uids {
+		if email, ok := byUID[uid]; ok {
+			result = append(result, email)
 		}
+	}
+	return result, nil
+}
 
-		result = append(result, &emaildomain.Email{
-			ID:         base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", realMailboxName, msg.Uid))), // Encode Mailbox:UID
-			Subject:    subject,
-			From:       from,
-			To:         to,
-			Preview:    snippet,
-			Body:       body,
-			IsHTML:     isHTML,
-			ReceivedAt: msg.Envelope.Date,
-			IsRead:     isRead,
-			IsStarred:  isStarred,
-			MailboxID:  mailboxID,
-		})
+// GetEmailsExcluding lists a mailbox newest first while skipping the given email IDs.
+// Pagination and total are computed after exclusion, so pages are always full and total is exact.
+// Used for the Kanban inbox column, which must not show emails assigned to other columns.
+func (s *IMAPService) GetEmailsExcluding(ctx context.Context, server string, port int, emailAddr, password, mailboxID string, excludeIDs []string, limit, offset int) ([]*emaildomain.Email, int, error) {
+	c, err := s.connect(server, port, emailAddr, password)
+	if err != nil {
+		return nil, 0, err
 	}
+	defer c.Logout()
 
-	// Reverse result to show newest first
-	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
-		result[i], result[j] = result[j], result[i]
+	realMailboxName, err := s.resolveMailboxName(c, mailboxID)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return result, int(mbox.Messages), <-done
+	if _, err := c.Select(realMailboxName, true); err != nil {
+		return nil, 0, err
+	}
+
+	// UID SEARCH only returns numbers, so listing the whole mailbox is cheap
+	uids, err := c.UidSearch(imap.NewSearchCriteria())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	excluded := make(map[uint32]bool, len(excludeIDs))
+	for _, id := range excludeIDs {
+		name, uid, err := decodeEmailID(id)
+		if err == nil && name == realMailboxName {
+			excluded[uid] = true
+		}
+	}
+
+	visible := make([]uint32, 0, len(uids))
+	for _, uid := range uids {
+		if !excluded[uid] {
+			visible = append(visible, uid)
+		}
+	}
+	// Newest first
+	sort.Slice(visible, func(i, j int) bool { return visible[i] > visible[j] })
+
+	total := len(visible)
+	if offset >= total {
+		return []*emaildomain.Email{}, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	emails, err := s.fetchByUIDs(c, realMailboxName, mailboxID, visible[offset:end])
+	if err != nil {
+		return nil, 0, err
+	}
+	return emails, total, nil
+}
+
+// GetEmailsByIDs fetches several emails by ID using one connection, preserving the order of ids
+func (s *IMAPService) GetEmailsByIDs(ctx context.Context, server string, port int, emailAddr, password string, ids []string) ([]*emaildomain.Email, error) {
+	if len(ids) == 0 {
+		return []*emaildomain.Email{}, nil
+	}
+
+	c, err := s.connect(server, port, emailAddr, password)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Logout()
+
+	// Group UIDs by mailbox
+	uidsByMailbox := make(map[string][]uint32)
+	var mailboxOrder []string
+	for _, id := range ids {
+		name, uid, err := decodeEmailID(id)
+		if err != nil {
+			continue
+		}
+		if _, ok := uidsByMailbox[name]; !ok {
+			mailboxOrder = append(mailboxOrder, name)
+		}
+		uidsByMailbox[name] = append(uidsByMailbox[name], uid)
+	}
+
+	byID := make(map[string]*emaildomain.Email, len(ids))
+	for _, name := range mailboxOrder {
+		if _, err := c.Select(name, true); err != nil {
+			// Mailbox may have been renamed/deleted, skip its emails
+			continue
+		}
+		emails, err := s.fetchByUIDs(c, name, name, uidsByMailbox[name])
+		if err != nil {
+			return nil, err
+		}
+		for _, email := range emails {
+			byID[email.ID] = email
+		}
+	}
+
+	result := make([]*emaildomain.Email, 0, len(ids))
+	for _, id := range ids {
+		if email, ok := byID[id]; ok {
+			result = append(result, email)
+		}
+	}
+	return result, nil
+}
+
+// decodeEmailID splits an IMAP email ID (base64 "Mailbox:UID") into mailbox name and UID
+func decodeEmailID(id string) (string, uint32, error) {
+	decodedBytes, err := base64.URLEncoding.DecodeString(id)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid email ID format")
+	}
+	decoded := string(decodedBytes)
+	idx := strings.LastIndex(decoded, ":")
+	if idx < 0 {
+		return "", 0, fmt.Errorf("invalid email ID format")
+	}
+
+	var uid uint32
+	if _, err := fmt.Sscanf(decoded[idx+1:], "%d", &uid); err != nil {
+		return "", 0, fmt.Errorf("invalid UID format")
+	}
+	return decoded[:idx], uid, nil
 }
 
 func (s *IMAPService) GetEmailByID(ctx context.Context, server string, port int, emailAddr, password, messageID string) (*emaildomain.Email, error) {
@@ -436,7 +608,7 @@ func (s *IMAPService) GetEmailByID(ctx context.Context, server string, port int,
 
 	msg := <-messages
 	if msg == nil {
-		return nil, fmt.Errorf("email not found")
+		return nil, emaildomain.ErrEmailNotFound
 	}
 
 	if err := <-done; err != nil {
@@ -484,6 +656,8 @@ func (s *IMAPService) GetEmailByID(ctx context.Context, server string, port int,
 
 	return &emaildomain.Email{
 		ID:         messageID,
+		MessageID:  msg.Envelope.MessageId,
+		InReplyTo:  msg.Envelope.InReplyTo,
 		Subject:    subject,
 		From:       from,
 		To:         to,
@@ -497,7 +671,9 @@ func (s *IMAPService) GetEmailByID(ctx context.Context, server string, port int,
 	}, nil
 }
 
-func (s *IMAPService) SendEmail(ctx context.Context, server string, port int, emailAddr, password string, to, subject, body string) error {
+// SendEmail sends an email over SMTP and returns the generated Message-ID.
+// The provider ID is unknown at this point; use SearchByHeader on the Sent folder to resolve it later.
+func (s *IMAPService) SendEmail(ctx context.Context, server string, port int, emailAddr, password string, to, subject, body string) (*emaildomain.SentEmail, error) {
 	// Need SMTP server. Usually imap.gmail.com -> smtp.gmail.com
 	// We need to infer SMTP settings or ask user.
 	// For Gmail: smtp.gmail.com:587
@@ -513,15 +689,100 @@ func (s *IMAPService) SendEmail(ctx context.Context, server string, port int, em
 	
 	auth := smtp.PlainAuth("", emailAddr, password, smtpServer)
 	
+	messageID := emaildomain.NewMessageID(emailAddr)
 	msg := []byte(fmt.Sprintf("To: %s\r\n"+
 		"Subject: %s\r\n"+
+		"Message-ID: %s\r\n"+
 		"MIME-Version: 1.0\r\n"+
 		"Content-Type: text/html; charset=\"UTF-8\"\r\n"+
 		"\r\n"+
-		"%s\r\n", to, subject, body))
+		"%s\r\n", to, subject, messageID, body))
 		
 	addr := fmt.Sprintf("%s:%s", smtpServer, smtpPort)
-	return smtp.SendMail(addr, auth, emailAddr, []string{to}, msg)
+	if err := smtp.SendMail(addr, auth, emailAddr, []string{to}, msg); err != nil {
+		return nil, err
+	}
+
+	return &emaildomain.SentEmail{
+		ThreadID:  messageID, // IMAP has no thread IDs, the root Message-ID identifies the conversation
+		MessageID: messageID,
+	}, nil
+}
+
+// SearchByHeader returns the IDs of emails in a mailbox whose header contains the given value
+// (e.g. Message-ID in the Sent folder, or In-Reply-To in INBOX for reply detection)
+func (s *IMAPService) SearchByHeader(ctx context.Context, server string, port int, emailAddr, password, mailboxID, header, value string) ([]string, error) {
+	c, err := s.connect(server, port, emailAddr, password)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Logout()
+
+	realMailboxName, err := s.resolveMailboxName(c, mailboxID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.Select(realMailboxName, true); err != nil {
+		return nil, err
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.Header.Add(header, value)
+
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		ids = append(ids, base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", realMailboxName, uid))))
+	}
+	return ids, nil
+}
+
+// SearchEmails returns one page (newest first) of the emails of a mailbox matching the criteria, and how many match
+func (s *IMAPService) SearchEmails(ctx context.Context, server string, port int, emailAddr, password, mailboxID string, criteria *imap.SearchCriteria, limit, offset int) ([]*emaildomain.Email, int, error) {
+	c, err := s.connect(server, port, emailAddr, password)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer c.Logout()
+
+	realMailboxName, err := s.resolveMailboxName(c, mailboxID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err := c.Select(realMailboxName, true); err != nil {
+		return nil, 0, err
+	}
+
+	if criteria == nil {
+		criteria = imap.NewSearchCriteria()
+	}
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Newest first
+	sort.Slice(uids, func(i, j int) bool { return uids[i] > uids[j] })
+
+	total := len(uids)
+	if offset >= total {
+		return []*emaildomain.Email{}, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	emails, err := s.fetchByUIDs(c, realMailboxName, mailboxID, uids[offset:end])
+	if err != nil {
+		return nil, 0, err
+	}
+	return emails, total, nil
 }
 
 func (s *IMAPService) modifyFlags(ctx context.Context, server string, port int, emailAddr, password, messageID string, flags []interface{}, add bool) error {
@@ -0,0 +1,103 @@
+package jobqueue
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Status is the state of a job in the queue. Completed jobs are deleted.
+type Status string
+
+const (
+	StatusPending Status = "pending" // Waiting for a worker (or for its retry time)
+	StatusRunning Status = "running" // Claimed by a worker
+	StatusDead    Status = "dead"    // Out of attempts or failed permanently (dead letter)
+)
+
+// Priorities: higher runs first. An email on screen beats a backfill.
+const (
+	PriorityLow    = 0   // Backfills and background refreshes
+	PriorityNormal = 50  // Sync of new emails
+	PriorityHigh   = 100 // Work the user is waiting for
+)
+
+// Job is a unit of work stored in Postgres, so it survives restarts
+type Job struct {
+	ID          string     `json:"id" gorm:"primaryKey"`
+	Queue       string     `json:"queue" gorm:"not null;index:idx_queue_job_claim"`
+	Status      Status     `json:"status" gorm:"not null;index:idx_queue_job_claim"`
+	Priority    int        `json:"priority" gorm:"not null;default:0"`
+	UserID      string     `json:"user_id" gorm:"not null;default:'';index"`
+	DedupKey    string     `json:"dedup_key,omitempty" gorm:"not null;default:''"` // Unique among live jobs of a queue
+	Payload     string     `json:"payload,omitempty" gorm:"type:text"`             // JSON
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int        `json:"max_attempts" gorm:"not null"`
+	RunAt       time.Time  `json:"run_at" gorm:"not null;index:idx_queue_job_claim"`
+	LockedAt    *time.Time `json:"locked_at,omitempty"`
+	LockedBy    string     `json:"locked_by,omitempty"`
+	LastError   string     `json:"last_error,omitempty" gorm:"type:text"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Job) TableName() string {
+	return "queue_jobs"
+}
+
+// Migrate creates the jobs table and the partial unique index used to deduplicate live jobs
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Job{}); err != nil {
+		return err
+	}
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_queue_job_dedup ON queue_jobs (queue, dedup_key) WHERE dedup_key <> '' AND status <> 'dead'`).Error
+}
+
+// EnqueueOptions controls how a job is scheduled (zero values = defaults)
+type EnqueueOptions struct {
+	UserID      string        // Owner, for per-user fairness ("" = not user-specific)
+	Priority    int           // PriorityLow, PriorityNormal, PriorityHigh or anything in between
+	DedupKey    string        // A live job with the same key is kept (its priority raised if needed)
+	MaxAttempts int           // Attempts before dead-lettering (0 = queue default)
+	Delay       time.Duration // Run no sooner than this
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as not worth retrying: the job is dead-lettered right away
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter marks a handler error as temporary with a known end (e.g. a quota that resets):
+// the job is retried after delay, and the attempt is not counted
+func RetryAfter(err error, delay time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, delay: delay}
+}
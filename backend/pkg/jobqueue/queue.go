@@ -0,0 +1,401 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Config tunes the queue (zero values = defaults)
+type Config struct {
+	PollInterval      time.Duration // How often idle workers look for due jobs
+	MaxAttempts       int           // Default attempts before a job is dead-lettered
+	BaseBackoff       time.Duration // First retry delay, doubled each attempt, with jitter
+	MaxBackoff        time.Duration
+	StuckTimeout      time.Duration // Running jobs older than this (crashed worker) are requeued
+	JobTimeout        time.Duration // Deadline of the context of one job, below StuckTimeout
+	DeadRetention     time.Duration // Dead-lettered jobs older than this are deleted by the reaper
+	MaxRunningPerUser int           // Jobs of one user running at once per queue (fairness)
+}
+
+// DefaultConfig returns the default queue settings
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:      time.Second,
+		MaxAttempts:       5,
+		BaseBackoff:       10 * time.Second,
+		MaxBackoff:        30 * time.Minute,
+		StuckTimeout:      10 * time.Minute,
+		JobTimeout:        5 * time.Minute,
+		DeadRetention:     7 * 24 * time.Hour,
+		MaxRunningPerUser: 2,
+	}
+}
+
+// HandlerFunc processes one job. Returning an error retries the job with backoff
+// (or dead-letters it when wrapped with Permanent or out of attempts).
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+type registration struct {
+	handler HandlerFunc
+	workers int
+	wake    chan struct{}
+}
+
+// Queue is a Postgres-backed job queue shared by several named queues (one handler each).
+// Several instances can run against the same database: jobs are claimed with SKIP LOCKED.
+type Queue struct {
+	db       *gorm.DB
+	cfg      Config
+	workerID string
+
+	mu       sync.Mutex
+	handlers map[string]*registration
+	started  bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates a queue; register handlers with Register, then call Start
+func New(db *gorm.DB, cfg Config) *Queue {
+	defaults := DefaultConfig()
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = defaults.BaseBackoff
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = defaults.MaxBackoff
+	}
+	if cfg.StuckTimeout <= 0 {
+		cfg.StuckTimeout = defaults.StuckTimeout
+	}
+	// A job must end before the reaper considers it stuck, or it would run twice
+	if cfg.JobTimeout <= 0 || cfg.JobTimeout >= cfg.StuckTimeout {
+		cfg.JobTimeout = cfg.StuckTimeout / 2
+	}
+	if cfg.DeadRetention <= 0 {
+		cfg.DeadRetention = defaults.DeadRetention
+	}
+	if cfg.MaxRunningPerUser <= 0 {
+		cfg.MaxRunningPerUser = defaults.MaxRunningPerUser
+	}
+
+	host, _ := os.Hostname()
+	return &Queue{
+		db:       db,
+		cfg:      cfg,
+		workerID: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		handlers: make(map[string]*registration),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Register sets the handler of a named queue and how many workers run it on this instance
+func (q *Queue) Register(queue string, workers int, handler HandlerFunc) {
+	if workers <= 0 {
+		workers = 1
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[queue] = &registration{handler: handler, workers: workers, wake: make(chan struct{}, 1)}
+}
+
+// Enqueue stores a job; payload is encoded as JSON. With a DedupKey, a live job with the
+// same key is kept instead, and its priority raised to this one if higher.
+func (q *Queue) Enqueue(queue string, payload interface{}, opts EnqueueOptions) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s job: %w", queue, err)
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = q.cfg.MaxAttempts
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:          uuid.New().String(),
+		Queue:       queue,
+		Status:      StatusPending,
+		Priority:    opts.Priority,
+		UserID:      opts.UserID,
+		DedupKey:    opts.DedupKey,
+		Payload:     string(data),
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       now.Add(opts.Delay),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	db := q.db
+	if opts.DedupKey != "" {
+		db = db.Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "queue"}, {Name: "dedup_key"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "dedup_key <> '' AND status <> 'dead'"}}},
+			DoUpdates: clause.Set{{
+				Column: clause.Column{Name: "priority"},
+				Value:  gorm.Expr("GREATEST(queue_jobs.priority, EXCLUDED.priority)"),
+			}},
+		})
+	}
+	if err := db.Create(job).Error; err != nil {
+		return fmt.Errorf("failed to enqueue %s job: %w", queue, err)
+	}
+
+	q.wakeUp(queue)
+	return nil
+}
+
+// wakeUp tells an idle local worker of the queue that a job is available
+func (q *Queue) wakeUp(queue string) {
+	q.mu.Lock()
+	reg := q.handlers[queue]
+	q.mu.Unlock()
+	if reg == nil {
+		return
+	}
+	select {
+	case reg.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start starts the workers of every registered queue and the stuck job reaper
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return
+	}
+	q.started = true
+
+	for name, reg := range q.handlers {
+		for i := 0; i < reg.workers; i++ {
+			q.wg.Add(1)
+			go q.worker(name, reg, i)
+		}
+		log.Printf("[JobQueue] Started %d workers for queue %s", reg.workers, name)
+	}
+
+	q.wg.Add(1)
+	go q.reaper()
+}
+
+// Stop stops the workers, waiting for the jobs in progress
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	if !q.started {
+		q.mu.Unlock()
+		return
+	}
+	q.started = false
+	close(q.stopChan)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	log.Println("[JobQueue] All workers stopped")
+}
+
+func (q *Queue) worker(queue string, reg *registration, id int) {
+	defer q.wg.Done()
+	workerID := fmt.Sprintf("%s/%s-%d", q.workerID, queue, id)
+
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stopChan:
+			return
+		default:
+		}
+
+		job, err := q.claim(queue, workerID)
+		if err != nil {
+			log.Printf("[JobQueue] %s: failed to claim job: %v", workerID, err)
+		}
+		if job != nil {
+			q.process(reg.handler, job)
+			continue
+		}
+
+		select {
+		case <-ticker.C:
+		case <-reg.wake:
+		case <-q.stopChan:
+			return
+		}
+	}
+}
+
+// claim locks the next due job: highest priority first, then the user with the fewest
+// running jobs (users already at MaxRunningPerUser wait), then the oldest
+func (q *Queue) claim(queue, workerID string) (*Job, error) {
+	var jobs []*Job
+	err := q.db.Raw(`
+		WITH running AS (
+			SELECT user_id, COUNT(*) AS n
+			FROM queue_jobs
+			WHERE queue = @queue AND status = 'running'
+			GROUP BY user_id
+		), next AS (
+			SELECT j.id
+			FROM queue_jobs j
+			LEFT JOIN running r ON r.user_id = j.user_id
+			WHERE j.queue = @queue AND j.status = 'pending' AND j.run_at <= @now
+			  AND (j.user_id = '' OR COALESCE(r.n, 0) < @max_per_user)
+			ORDER BY j.priority DESC, COALESCE(r.n, 0), j.run_at, j.created_at
+			LIMIT 1
+			FOR UPDATE OF j SKIP LOCKED
+		)
+		UPDATE queue_jobs
+		SET status = 'running', attempts = attempts + 1, locked_at = @now, locked_by = @worker, updated_at = @now
+		FROM next
+		WHERE queue_jobs.id = next.id
+		RETURNING queue_jobs.*`,
+		map[string]interface{}{
+			"queue":        queue,
+			"now":          time.Now(),
+			"max_per_user": q.cfg.MaxRunningPerUser,
+			"worker":       workerID,
+		},
+	).Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return jobs[0], nil
+}
+
+// process runs the handler with the job deadline; a panic counts as a failed attempt
+func (q *Queue) process(handler HandlerFunc, job *Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.cfg.JobTimeout)
+	defer cancel()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[JobQueue] Panic in %s job %s: %v\n%s", job.Queue, job.ID, r, debug.Stack())
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return handler(ctx, job)
+	}()
+	if err == nil && ctx.Err() != nil {
+		// The handler ignored its deadline: the job may have been requeued meanwhile
+		log.Printf("[JobQueue] %s job %s ran past its %v deadline", job.Queue, job.ID, q.cfg.JobTimeout)
+	}
+
+	if err == nil {
+		// Only the worker holding the lock completes the job (not one whose job was requeued by the reaper)
+		if dbErr := q.owned(job).Delete(&Job{}).Error; dbErr != nil {
+			log.Printf("[JobQueue] Failed to complete %s job %s: %v", job.Queue, job.ID, dbErr)
+		}
+		return
+	}
+	q.fail(job, err)
+}
+
+// fail schedules a retry with backoff, or dead-letters the job
+func (q *Queue) fail(job *Job, jobErr error) {
+	updates := q.failure(job, jobErr, time.Now())
+	if err := q.owned(job).Model(&Job{}).Updates(updates).Error; err != nil {
+		log.Printf("[JobQueue] Failed to record failure of %s job %s: %v", job.Queue, job.ID, err)
+	}
+}
+
+// failure returns the updates recording a failed attempt at now: pending again after the
+// RetryAfter delay or a backoff, or dead when the error is permanent or attempts are exhausted
+func (q *Queue) failure(job *Job, jobErr error, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{
+		"last_error": jobErr.Error(),
+		"locked_at":  nil,
+		"locked_by":  "",
+		"updated_at": now,
+	}
+	var retryAfter *retryAfterError
+	if errors.As(jobErr, &retryAfter) {
+		updates["status"] = StatusPending
+		updates["run_at"] = now.Add(retryAfter.delay)
+		updates["attempts"] = gorm.Expr("GREATEST(attempts - 1, 0)")
+		log.Printf("[JobQueue] %s job %s postponed for %v: %v", job.Queue, job.ID, retryAfter.delay.Round(time.Second), jobErr)
+	} else if IsPermanent(jobErr) || job.Attempts >= job.MaxAttempts {
+		updates["status"] = StatusDead
+		log.Printf("[JobQueue] %s job %s dead after %d attempts: %v", job.Queue, job.ID, job.Attempts, jobErr)
+	} else {
+		delay := q.backoff(job.Attempts)
+		updates["status"] = StatusPending
+		updates["run_at"] = now.Add(delay)
+		log.Printf("[JobQueue] %s job %s failed (attempt %d/%d), retrying in %v: %v", job.Queue, job.ID, job.Attempts, job.MaxAttempts, delay.Round(time.Second), jobErr)
+	}
+	return updates
+}
+
+// owned scopes a query to a job still locked by the worker that claimed it
+func (q *Queue) owned(job *Job) *gorm.DB {
+	return q.db.Where("id = ? AND status = ? AND locked_by = ?", job.ID, StatusRunning, job.LockedBy)
+}
+
+// backoff returns the delay before retry n (1-based): base * 2^(n-1), capped, with ±20% jitter
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.cfg.BaseBackoff
+	for i := 1; i < attempt && delay < q.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.cfg.MaxBackoff {
+		delay = q.cfg.MaxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5+1)) - delay/10
+	return delay + jitter
+}
+
+// reaper requeues jobs left running by a crashed or killed worker, and deletes expired dead letters
+func (q *Queue) reaper() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.cfg.StuckTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			result := q.db.Model(&Job{}).
+				Where("status = ? AND locked_at < ?", StatusRunning, time.Now().Add(-q.cfg.StuckTimeout)).
+				Updates(map[string]interface{}{
+					"status":     gorm.Expr("CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END"),
+					"last_error": "worker timed out",
+					"locked_at":  nil,
+					"locked_by":  "",
+					"run_at":     time.Now(),
+					"updated_at": time.Now(),
+				})
+			if result.Error != nil {
+				log.Printf("[JobQueue] Failed to requeue stuck jobs: %v", result.Error)
+			} else if result.RowsAffected > 0 {
+				log.Printf("[JobQueue] Requeued %d stuck jobs", result.RowsAffected)
+			}
+
+			expired := q.db.Where("status = ? AND updated_at < ?", StatusDead, time.Now().Add(-q.cfg.DeadRetention)).Delete(&Job{})
+			if expired.Error != nil {
+				log.Printf("[JobQueue] Failed to delete expired dead jobs: %v", expired.Error)
+			} else if expired.RowsAffected > 0 {
+				log.Printf("[JobQueue] Deleted %d dead jobs older than %v", expired.RowsAffected, q.cfg.DeadRetention)
+			}
+		case <-q.stopChan:
+			return
+		}
+	}
+}
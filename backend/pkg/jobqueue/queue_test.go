@@ -0,0 +1,237 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// statement is a query built by a dry run queue
+type statement struct {
+	sql  string
+	vars []interface{}
+}
+
+// dryRunQueue returns a queue whose database builds statements without running them, and the statements built
+func dryRunQueue(t *testing.T, cfg Config) (*Queue, *[]statement) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test sslmode=disable"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statements []statement
+	capture := func(db *gorm.DB) {
+		statements = append(statements, statement{sql: db.Statement.SQL.String(), vars: db.Statement.Vars})
+	}
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().After("gorm:create").Register("test:capture", capture),
+		callbacks.Update().After("gorm:update").Register("test:capture", capture),
+		callbacks.Delete().After("gorm:delete").Register("test:capture", capture),
+		callbacks.Row().After("gorm:row").Register("test:capture", capture),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(db, cfg), &statements
+}
+
+func TestFailure(t *testing.T) {
+	q := New(nil, Config{BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute})
+	now := time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC)
+	failed := errors.New("smtp timeout")
+
+	tests := []struct {
+		name            string
+		attempts        int
+		err             error
+		wantStatus      Status
+		wantMinDelay    time.Duration
+		wantMaxDelay    time.Duration
+		wantAttemptBack bool
+	}{
+		{"first failure retries after the base backoff", 1, failed, StatusPending, 8 * time.Second, 12 * time.Second, false},
+		{"backoff doubles", 3, failed, StatusPending, 32 * time.Second, 48 * time.Second, false},
+		{"backoff is capped", 4, failed, StatusPending, 48 * time.Second, 72 * time.Second, false},
+		{"out of attempts", 5, failed, StatusDead, 0, 0, false},
+		{"permanent", 1, Permanent(failed), StatusDead, 0, 0, false},
+		{"permanent wrapped", 1, fmt.Errorf("send: %w", Permanent(failed)), StatusDead, 0, 0, false},
+		{"retry after a known delay", 5, RetryAfter(failed, time.Hour), StatusPending, time.Hour, time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{ID: "job-1", Queue: "sync", Attempts: tt.attempts, MaxAttempts: 5}
+			updates := q.failure(job, tt.err, now)
+			if updates["status"] != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", updates["status"], tt.wantStatus)
+			}
+			if updates["last_error"] != tt.err.Error() || updates["locked_by"] != "" || updates["locked_at"] != nil {
+				t.Errorf("updates = %v, want the error recorded and the lock released", updates)
+			}
+
+			runAt, scheduled := updates["run_at"].(time.Time)
+			if tt.wantStatus == StatusDead {
+				if scheduled {
+					t.Errorf("dead job scheduled at %v", runAt)
+				}
+				return
+			}
+			if delay := runAt.Sub(now); delay < tt.wantMinDelay || delay > tt.wantMaxDelay {
+				t.Errorf("retry in %v, want between %v and %v", delay, tt.wantMinDelay, tt.wantMaxDelay)
+			}
+			if _, back := updates["attempts"]; back != tt.wantAttemptBack {
+				t.Errorf("attempt given back = %v, want %v", back, tt.wantAttemptBack)
+			}
+		})
+	}
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    HandlerFunc
+		attempts   int
+		wantSQL    string
+		wantStatus Status
+	}{
+		{
+			name:     "success deletes the job",
+			handler:  func(ctx context.Context, job *Job) error { return nil },
+			attempts: 1,
+			wantSQL:  "DELETE FROM",
+		},
+		{
+			name:       "failure retries",
+			handler:    func(ctx context.Context, job *Job) error { return errors.New("temporary") },
+			attempts:   1,
+			wantSQL:    "UPDATE",
+			wantStatus: StatusPending,
+		},
+		{
+			name:       "last failed attempt dead-letters",
+			handler:    func(ctx context.Context, job *Job) error { return errors.New("temporary") },
+			attempts:   3,
+			wantSQL:    "UPDATE",
+			wantStatus: StatusDead,
+		},
+		{
+			name:       "permanent failure dead-letters",
+			handler:    func(ctx context.Context, job *Job) error { return Permanent(errors.New("bad payload")) },
+			attempts:   1,
+			wantSQL:    "UPDATE",
+			wantStatus: StatusDead,
+		},
+		{
+			name:       "panic counts as a failure",
+			handler:    func(ctx context.Context, job *Job) error { panic("nil map") },
+			attempts:   3,
+			wantSQL:    "UPDATE",
+			wantStatus: StatusDead,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, statements := dryRunQueue(t, Config{})
+			job := &Job{ID: "job-1", Queue: "sync", Status: StatusRunning, Attempts: tt.attempts, MaxAttempts: 3, LockedBy: "host-1/sync-0"}
+			q.process(tt.handler, job)
+
+			if len(*statements) != 1 {
+				t.Fatalf("statements = %+v, want one", *statements)
+			}
+			s := (*statements)[0]
+			if !strings.HasPrefix(s.sql, tt.wantSQL) || !strings.Contains(s.sql, "locked_by = ") {
+				t.Errorf("SQL = %q, want %s scoped to the worker holding the lock", s.sql, tt.wantSQL)
+			}
+			if !containsVar(s.vars, "host-1/sync-0") || !containsVar(s.vars, "job-1") {
+				t.Errorf("vars = %v, want the job ID and its worker", s.vars)
+			}
+			if tt.wantStatus != "" && !containsVar(s.vars, tt.wantStatus) {
+				t.Errorf("vars = %v, want status %v", s.vars, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestClaim(t *testing.T) {
+	q, statements := dryRunQueue(t, Config{MaxRunningPerUser: 3})
+	if _, err := q.claim("sync", "host-1/sync-0"); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatalf("claim() error: %v", err)
+	}
+	if len(*statements) != 1 {
+		t.Fatalf("statements = %+v, want one", *statements)
+	}
+	s := (*statements)[0]
+	if strings.Contains(s.sql, "@") {
+		t.Errorf("SQL has unbound parameters: %s", s.sql)
+	}
+	for _, want := range []string{"FOR UPDATE OF j SKIP LOCKED", "status = 'running', attempts = attempts + 1", "ORDER BY j.priority DESC"} {
+		if !strings.Contains(s.sql, want) {
+			t.Errorf("SQL does not contain %q", want)
+		}
+	}
+	for _, want := range []interface{}{"sync", "host-1/sync-0", 3} {
+		if !containsVar(s.vars, want) {
+			t.Errorf("vars = %v, want %v", s.vars, want)
+		}
+	}
+}
+
+func TestEnqueueDedup(t *testing.T) {
+	q, statements := dryRunQueue(t, Config{MaxAttempts: 4})
+	if err := q.Enqueue("alerts", map[string]string{"email_id": "e1"}, EnqueueOptions{UserID: "u1", DedupKey: "u1/e1", Priority: PriorityHigh}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue("alerts", map[string]string{"email_id": "e2"}, EnqueueOptions{UserID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*statements) != 2 {
+		t.Fatalf("statements = %+v, want two", *statements)
+	}
+
+	dedup, plain := (*statements)[0], (*statements)[1]
+	if !strings.Contains(dedup.sql, "WHERE dedup_key <> '' AND status <> 'dead' DO UPDATE") ||
+		!strings.Contains(dedup.sql, "GREATEST(queue_jobs.priority, EXCLUDED.priority)") {
+		t.Errorf("SQL = %q, want an upsert raising the priority of the live job", dedup.sql)
+	}
+	if !containsVar(dedup.vars, `{"email_id":"e1"}`) || !containsVar(dedup.vars, 4) || !containsVar(dedup.vars, StatusPending) {
+		t.Errorf("vars = %v, want the JSON payload, default attempts and pending status", dedup.vars)
+	}
+	if strings.Contains(plain.sql, "ON CONFLICT") {
+		t.Errorf("SQL = %q, want a plain insert without a dedup key", plain.sql)
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	q := New(nil, Config{StuckTimeout: time.Minute, JobTimeout: 2 * time.Minute, MaxBackoff: time.Second})
+	if q.cfg.JobTimeout >= q.cfg.StuckTimeout {
+		t.Errorf("JobTimeout = %v, want below StuckTimeout %v", q.cfg.JobTimeout, q.cfg.StuckTimeout)
+	}
+	if q.cfg.MaxBackoff < q.cfg.BaseBackoff {
+		t.Errorf("MaxBackoff = %v, want at least BaseBackoff %v", q.cfg.MaxBackoff, q.cfg.BaseBackoff)
+	}
+	if defaults := DefaultConfig(); q.cfg.MaxAttempts != defaults.MaxAttempts || q.cfg.MaxRunningPerUser != defaults.MaxRunningPerUser {
+		t.Errorf("config = %+v, want default attempts and per-user limit", q.cfg)
+	}
+}
+
+func containsVar(vars []interface{}, want interface{}) bool {
+	for _, v := range vars {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
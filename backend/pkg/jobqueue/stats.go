@@ -0,0 +1,181 @@
+package jobqueue
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrJobNotFound is returned when retrying a job that is not dead-lettered
+var ErrJobNotFound = errors.New("dead job not found")
+
+// QueueStats describes the depth and failures of one named queue
+type QueueStats struct {
+	Queue            string           `json:"queue"`
+	Workers          int              `json:"workers"` // On this instance
+	Pending          int64            `json:"pending"`
+	Retrying         int64            `json:"retrying"` // Pending jobs that already failed at least once
+	Running          int64            `json:"running"`
+	Dead             int64            `json:"dead"`
+	PendingBy        map[int]int64    `json:"pending_by_priority"`
+	OldestPendingAge float64          `json:"oldest_pending_seconds"` // Age of the oldest due job
+	TopUsers         []UserQueueDepth `json:"top_users,omitempty"`    // Users with the most pending jobs
+}
+
+// UserQueueDepth is the number of pending jobs of a user
+type UserQueueDepth struct {
+	UserID  string `json:"user_id"`
+	Pending int64  `json:"pending"`
+}
+
+// Stats returns the depth of every queue that has jobs or a handler
+func (q *Queue) Stats() ([]*QueueStats, error) {
+	var rows []struct {
+		Queue    string
+		Status   Status
+		Priority int
+		Retrying int64
+		Count    int64
+		Oldest   *time.Time
+	}
+	err := q.db.Raw(`
+		SELECT queue, status, priority,
+		       COUNT(*) FILTER (WHERE attempts > 0) AS retrying,
+		       COUNT(*) AS count,
+		       MIN(run_at) FILTER (WHERE run_at <= @now) AS oldest
+		FROM queue_jobs
+		GROUP BY queue, status, priority`,
+		map[string]interface{}{"now": time.Now()},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byQueue := make(map[string]*QueueStats)
+	get := func(name string) *QueueStats {
+		s, ok := byQueue[name]
+		if !ok {
+			s = &QueueStats{Queue: name, PendingBy: make(map[int]int64)}
+			byQueue[name] = s
+		}
+		return s
+	}
+
+	q.mu.Lock()
+	for name, reg := range q.handlers {
+		get(name).Workers = reg.workers
+	}
+	q.mu.Unlock()
+
+	now := time.Now()
+	for _, row := range rows {
+		s := get(row.Queue)
+		switch row.Status {
+		case StatusPending:
+			s.Pending += row.Count
+			s.Retrying += row.Retrying
+			s.PendingBy[row.Priority] += row.Count
+			if row.Oldest != nil {
+				if age := now.Sub(*row.Oldest).Seconds(); age > s.OldestPendingAge {
+					s.OldestPendingAge = age
+				}
+			}
+		case StatusRunning:
+			s.Running += row.Count
+		case StatusDead:
+			s.Dead += row.Count
+		}
+	}
+
+	var users []struct {
+		Queue   string
+		UserID  string
+		Pending int64
+	}
+	err = q.db.Raw(`
+		SELECT queue, user_id, pending
+		FROM (
+			SELECT queue, user_id, COUNT(*) AS pending,
+			       ROW_NUMBER() OVER (PARTITION BY queue ORDER BY COUNT(*) DESC) AS rank
+			FROM queue_jobs
+			WHERE status = 'pending' AND user_id <> ''
+			GROUP BY queue, user_id
+		) ranked
+		WHERE rank <= 5
+		ORDER BY queue, pending DESC`,
+	).Scan(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		s := get(u.Queue)
+		s.TopUsers = append(s.TopUsers, UserQueueDepth{UserID: u.UserID, Pending: u.Pending})
+	}
+
+	result := make([]*QueueStats, 0, len(byQueue))
+	for _, s := range byQueue {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Queue < result[j].Queue })
+	return result, nil
+}
+
+// DeadJobs returns the most recent dead-lettered jobs (empty queue = all queues).
+// Payloads are left out: they can hold email content of any user.
+func (q *Queue) DeadJobs(queue string, limit int) ([]*Job, error) {
+	query := q.db.Omit("payload").Where("status = ?", StatusDead)
+	if queue != "" {
+		query = query.Where("queue = ?", queue)
+	}
+	var jobs []*Job
+	if err := query.Order("updated_at DESC").Limit(limit).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RetryDeadJob puts a dead-lettered job back in its queue with fresh attempts
+func (q *Queue) RetryDeadJob(id string) error {
+	var job Job
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND status = ?", id, StatusDead).First(&job).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrJobNotFound
+			}
+			return err
+		}
+		// A live job with the same key replaced it meanwhile: the dead one is redundant
+		if job.DedupKey != "" {
+			var live int64
+			if err := tx.Model(&Job{}).Where("queue = ? AND dedup_key = ? AND status <> ?", job.Queue, job.DedupKey, StatusDead).Count(&live).Error; err != nil {
+				return err
+			}
+			if live > 0 {
+				return tx.Delete(&Job{}, "id = ?", id).Error
+			}
+		}
+		return tx.Model(&Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"status":     StatusPending,
+			"attempts":   0,
+			"run_at":     time.Now(),
+			"updated_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	q.wakeUp(job.Queue)
+	return nil
+}
+
+// PurgeDeadJobs deletes dead-lettered jobs (empty queue = all queues)
+func (q *Queue) PurgeDeadJobs(queue string) (int64, error) {
+	query := q.db.Where("status = ?", StatusDead)
+	if queue != "" {
+		query = query.Where("queue = ?", queue)
+	}
+	result := query.Delete(&Job{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,126 @@
+package pgvector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"ga03-backend/pkg/embedding"
+
+	"gorm.io/gorm"
+)
+
+// UpsertAttachmentChunks embeds the passages of an attachment, replacing the ones stored before
+func (c *Client) UpsertAttachmentChunks(ctx context.Context, userID, emailID, attachmentID string, chunks []embedding.Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = embedding.ChunkText(chunk)
+	}
+	vectors, err := c.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed attachment: %w", err)
+	}
+	if len(vectors) != len(chunks) {
+		return fmt.Errorf("failed to embed attachment: %d embeddings for %d chunks", len(vectors), len(chunks))
+	}
+	dims := len(vectors[0])
+	if err := c.ensureIndex("attachment_embeddings", dims); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`DELETE FROM attachment_embeddings WHERE user_id = ? AND email_id = ? AND attachment_id = ?`,
+			userID, emailID, attachmentID).Error
+		if err != nil {
+			return err
+		}
+		for i, chunk := range chunks {
+			err := tx.Exec(`
+				INSERT INTO attachment_embeddings (user_id, email_id, attachment_id, chunk_index, filename, mime_type,
+					content, embedding, embedding_model, embedding_dim, created_at)
+				VALUES (@user_id, @email_id, @attachment_id, @chunk_index, @filename, @mime_type,
+					@content, CAST(@embedding AS vector), @model, @dims, @now)`,
+				map[string]interface{}{
+					"user_id":       userID,
+					"email_id":      emailID,
+					"attachment_id": attachmentID,
+					"chunk_index":   chunk.Index,
+					"filename":      chunk.Filename,
+					"mime_type":     chunk.MimeType,
+					"content":       chunk.Text,
+					"embedding":     formatVector(vectors[i]),
+					"model":         c.embedder.Model(),
+					"dims":          dims,
+					"now":           now,
+				},
+			).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store attachment embeddings: %w", err)
+	}
+	return nil
+}
+
+// SearchAttachmentChunks returns the user's attachment passages closest to the query, with their cosine distances
+func (c *Client) SearchAttachmentChunks(ctx context.Context, userID, query string, limit int) ([]embedding.ChunkMatch, error) {
+	vector, err := c.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	dims := len(vector)
+
+	var rows []struct {
+		EmailID      string
+		AttachmentID string
+		ChunkIndex   int
+		Filename     string
+		MimeType     string
+		Content      string
+		Distance     float64
+	}
+	err = c.nearest(ctx, limit, func(tx *gorm.DB) (int, error) {
+		err := tx.Raw(fmt.Sprintf(`
+			SELECT email_id, attachment_id, chunk_index, filename, mime_type, content,
+				embedding::vector(%[1]d) <=> CAST(@query AS vector(%[1]d)) AS distance
+			FROM attachment_embeddings
+			WHERE embedding_dim = %[1]d AND user_id = @user_id AND embedding_model = @model
+			ORDER BY embedding::vector(%[1]d) <=> CAST(@query AS vector(%[1]d))
+			LIMIT @limit`, dims),
+			map[string]interface{}{"query": formatVector(vector), "user_id": userID, "model": c.embedder.Model(), "limit": limit},
+		).Scan(&rows).Error
+		return len(rows), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachment embeddings: %w", err)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Distance < rows[j].Distance })
+
+	matches := make([]embedding.ChunkMatch, 0, len(rows))
+	for _, row := range rows {
+		matches = append(matches, embedding.ChunkMatch{
+			Chunk: embedding.Chunk{
+				EmailID:      row.EmailID,
+				AttachmentID: row.AttachmentID,
+				Filename:     row.Filename,
+				MimeType:     row.MimeType,
+				Index:        row.ChunkIndex,
+				Text:         row.Content,
+			},
+			Distance: row.Distance,
+		})
+	}
+	log.Printf("[SemanticSearch] pgvector returned %d attachment passages for user %s", len(matches), userID)
+	return matches, nil
+}
@@ -0,0 +1,382 @@
+package pgvector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ga03-backend/pkg/config"
+	"ga03-backend/pkg/embedding"
+
+	"gorm.io/gorm"
+)
+
+// maxEmbeddingTextLength truncates the embedded text (embedding models have token limits)
+const maxEmbeddingTextLength = 10000
+
+// cosineDistanceThreshold is the cosine distance above which results are not relevant.
+// Chroma returns squared L2 distances (threshold 1.2), i.e. twice the cosine distance of normalized vectors.
+const cosineDistanceThreshold = 0.6
+
+// Client stores email embeddings in Postgres (pgvector extension) and searches them by cosine distance.
+// Each row records the model and dimension of its vector; searches only compare vectors of the current model.
+type Client struct {
+	db            *gorm.DB
+	embedder      embedding.Embedder
+	efSearch      int
+	iterativeScan bool // pgvector 0.8+: filtered HNSW scans continue until enough rows match
+
+	indexMu sync.Mutex
+	indexed map[string]bool // Tables and dimensions ("email_embeddings/768") whose HNSW index exists
+}
+
+// NewClient creates the pgvector tables and indexes and returns a client embedding with embedder
+func NewClient(db *gorm.DB, embedder embedding.Embedder, cfg *config.Config) (*Client, error) {
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		db:       db,
+		embedder: embedder,
+		efSearch: cfg.PgVectorEfSearch,
+		indexed:  make(map[string]bool),
+
+		iterativeScan: supportsIterativeScan(db),
+	}
+	// The index of an unknown dimension is created with the first vector
+	if dims := embedder.Dimensions(); dims > 0 {
+		for _, table := range []string{"email_embeddings", "email_chunk_embeddings", "attachment_embeddings"} {
+			if err := c.ensureIndex(table, dims); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	log.Printf("Initialized pgvector store: email_embeddings (%s, %d dimensions, iterative scan: %v)", embedder.Model(), embedder.Dimensions(), c.iterativeScan)
+	return c, nil
+}
+
+// Migrate enables the vector extension and creates the embeddings tables (emails, passages of long
+// emails, and attachment passages).
+// The vector column has no fixed dimension so that vectors of several models can coexist
+// during a re-embedding; HNSW indexes are partial, one per dimension.
+func Migrate(db *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		`CREATE TABLE IF NOT EXISTS email_embeddings (
+			user_id         text NOT NULL,
+			email_id        text NOT NULL,
+			subject         text NOT NULL DEFAULT '',
+			embedding       vector NOT NULL,
+			embedding_model text NOT NULL DEFAULT '',
+			embedding_dim   integer NOT NULL DEFAULT 0,
+			created_at      timestamptz NOT NULL DEFAULT now(),
+			updated_at      timestamptz NOT NULL DEFAULT now(),
+			PRIMARY KEY (user_id, email_id)
+		)`,
+		// Tables created with a fixed dimension (vector(768)) and without model columns
+		`ALTER TABLE email_embeddings ADD COLUMN IF NOT EXISTS embedding_model text NOT NULL DEFAULT ''`,
+		`ALTER TABLE email_embeddings ADD COLUMN IF NOT EXISTS embedding_dim integer NOT NULL DEFAULT 0`,
+		`DROP INDEX IF EXISTS idx_email_embeddings_hnsw`,
+		`ALTER TABLE email_embeddings ALTER COLUMN embedding TYPE vector`,
+		`UPDATE email_embeddings SET embedding_dim = vector_dims(embedding) WHERE embedding_dim = 0`,
+		fmt.Sprintf(`UPDATE email_embeddings SET embedding_model = '%s' WHERE embedding_model = ''`, embedding.LegacyModel),
+		`CREATE INDEX IF NOT EXISTS idx_email_embeddings_user_model ON email_embeddings (user_id, embedding_model)`,
+		`CREATE TABLE IF NOT EXISTS email_chunk_embeddings (
+			user_id         text NOT NULL,
+			email_id        text NOT NULL,
+			chunk_index     integer NOT NULL,
+			subject         text NOT NULL DEFAULT '',
+			content         text NOT NULL DEFAULT '',
+			embedding       vector NOT NULL,
+			embedding_model text NOT NULL DEFAULT '',
+			embedding_dim   integer NOT NULL DEFAULT 0,
+			created_at      timestamptz NOT NULL DEFAULT now(),
+			PRIMARY KEY (user_id, email_id, chunk_index)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_email_chunk_embeddings_user_model ON email_chunk_embeddings (user_id, embedding_model)`,
+		`CREATE TABLE IF NOT EXISTS attachment_embeddings (
+			user_id         text NOT NULL,
+			email_id        text NOT NULL,
+			attachment_id   text NOT NULL,
+			chunk_index     integer NOT NULL,
+			filename        text NOT NULL DEFAULT '',
+			mime_type       text NOT NULL DEFAULT '',
+			content         text NOT NULL DEFAULT '',
+			embedding       vector NOT NULL,
+			embedding_model text NOT NULL DEFAULT '',
+			embedding_dim   integer NOT NULL DEFAULT 0,
+			created_at      timestamptz NOT NULL DEFAULT now(),
+			PRIMARY KEY (user_id, email_id, attachment_id, chunk_index)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_attachment_embeddings_user_model ON attachment_embeddings (user_id, embedding_model)`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to migrate pgvector store: %w", err)
+		}
+	}
+	return nil
+}
+
+// ensureIndex creates the HNSW index (cosine distance) of the vectors of a dimension in a table
+func (c *Client) ensureIndex(table string, dims int) error {
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+	key := fmt.Sprintf("%s/%d", table, dims)
+	if c.indexed[key] {
+		return nil
+	}
+	stmt := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%[1]s_hnsw_%[2]d ON %[1]s
+		USING hnsw ((embedding::vector(%[2]d)) vector_cosine_ops) WHERE embedding_dim = %[2]d`, table, dims)
+	if err := c.db.Exec(stmt).Error; err != nil {
+		return fmt.Errorf("failed to create HNSW index of %s for %d dimensions: %w", table, dims, err)
+	}
+	c.indexed[key] = true
+	return nil
+}
+
+// EmbeddingModel returns the model and dimension of the vectors written now
+func (c *Client) EmbeddingModel() (string, int) {
+	return c.embedder.Model(), c.embedder.Dimensions()
+}
+
+// DistanceThreshold is the cosine distance above which results are not relevant
+func (c *Client) DistanceThreshold() float64 {
+	return cosineDistanceThreshold
+}
+
+// AddEmailEmbedding stores the embedding of an email, keeping the existing one if any
+func (c *Client) AddEmailEmbedding(ctx context.Context, collectionName, emailID, userID, subject, body string) error {
+	return c.store(ctx, emailID, userID, subject, body, false)
+}
+
+// UpsertEmailEmbedding stores the embedding of an email, replacing the existing one if any
+func (c *Client) UpsertEmailEmbedding(ctx context.Context, collectionName, emailID, userID, subject, body string) error {
+	return c.store(ctx, emailID, userID, subject, body, true)
+}
+
+func (c *Client) store(ctx context.Context, emailID, userID, subject, body string, replace bool) error {
+	// Combine subject and body for embedding (same text as the Chroma client)
+	text := fmt.Sprintf("Subject: %s\n\nBody: %s", subject, body)
+	if len(text) > maxEmbeddingTextLength {
+		text = text[:maxEmbeddingTextLength]
+	}
+
+	vectors, err := c.embedder.EmbedDocuments(ctx, []string{text})
+	if err != nil {
+		return fmt.Errorf("failed to embed email: %w", err)
+	}
+	if len(vectors) == 0 {
+		return fmt.Errorf("failed to embed email: no embedding returned")
+	}
+	dims := len(vectors[0])
+	if err := c.ensureIndex("email_embeddings", dims); err != nil {
+		return err
+	}
+
+	onConflict := `DO NOTHING`
+	if replace {
+		onConflict = `DO UPDATE SET subject = EXCLUDED.subject, embedding = EXCLUDED.embedding,
+			embedding_model = EXCLUDED.embedding_model, embedding_dim = EXCLUDED.embedding_dim, updated_at = EXCLUDED.updated_at`
+	}
+	err = c.db.WithContext(ctx).Exec(`
+		INSERT INTO email_embeddings (user_id, email_id, subject, embedding, embedding_model, embedding_dim, updated_at)
+		VALUES (@user_id, @email_id, @subject, CAST(@embedding AS vector), @model, @dims, @now)
+		ON CONFLICT (user_id, email_id) `+onConflict,
+		map[string]interface{}{
+			"user_id":   userID,
+			"email_id":  emailID,
+			"subject":   subject,
+			"embedding": formatVector(vectors[0]),
+			"model":     c.embedder.Model(),
+			"dims":      dims,
+			"now":       time.Now(),
+		},
+	).Error
+	if err != nil {
+		return fmt.Errorf("failed to store email embedding: %w", err)
+	}
+	return nil
+}
+
+// SemanticSearch returns the user's emails closest to the query, with their cosine distances (0 = same direction, 2 = opposite).
+// Emails not yet re-embedded with the current model are not returned.
+func (c *Client) SemanticSearch(ctx context.Context, collectionName, userID, query string, limit int) ([]string, []float64, error) {
+	vector, err := c.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	dims := len(vector)
+
+	var rows []struct {
+		EmailID  string
+		Distance float64
+	}
+	err = c.nearest(ctx, limit, func(tx *gorm.DB) (int, error) {
+		// The cast to vector(dims) and the embedding_dim filter match the partial index of the dimension
+		err := tx.Raw(fmt.Sprintf(`
+			SELECT email_id, embedding::vector(%[1]d) <=> CAST(@query AS vector(%[1]d)) AS distance
+			FROM email_embeddings
+			WHERE embedding_dim = %[1]d AND user_id = @user_id AND embedding_model = @model
+			ORDER BY embedding::vector(%[1]d) <=> CAST(@query AS vector(%[1]d))
+			LIMIT @limit`, dims),
+			map[string]interface{}{"query": formatVector(vector), "user_id": userID, "model": c.embedder.Model(), "limit": limit},
+		).Scan(&rows).Error
+		return len(rows), err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query embeddings: %w", err)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Distance < rows[j].Distance })
+
+	emailIDs := make([]string, 0, len(rows))
+	distances := make([]float64, 0, len(rows))
+	for _, row := range rows {
+		emailIDs = append(emailIDs, row.EmailID)
+		distances = append(distances, row.Distance)
+	}
+	log.Printf("[SemanticSearch] pgvector returned %d results for user %s", len(emailIDs), userID)
+	return emailIDs, distances, nil
+}
+
+// RelatedEmails returns the user's emails closest to the stored vector of an email (the email itself
+// excluded), with their cosine distances. Nothing is returned while the email has no vector of the current model.
+func (c *Client) RelatedEmails(ctx context.Context, userID, emailID string, limit int) ([]string, []float64, error) {
+	var dims int
+	err := c.db.WithContext(ctx).Raw(`
+		SELECT embedding_dim FROM email_embeddings
+		WHERE user_id = @user_id AND email_id = @email_id AND embedding_model = @model`,
+		map[string]interface{}{"user_id": userID, "email_id": emailID, "model": c.embedder.Model()},
+	).Scan(&dims).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read email embedding: %w", err)
+	}
+	if dims == 0 {
+		return []string{}, []float64{}, nil
+	}
+
+	var rows []struct {
+		EmailID  string
+		Distance float64
+	}
+	err = c.nearest(ctx, limit, func(tx *gorm.DB) (int, error) {
+		// The stored vector is the query: a scalar subquery still uses the HNSW index
+		err := tx.Raw(fmt.Sprintf(`
+			WITH source AS (
+				SELECT embedding::vector(%[1]d) AS embedding FROM email_embeddings
+				WHERE user_id = @user_id AND email_id = @email_id AND embedding_model = @model
+			)
+			SELECT email_id, embedding::vector(%[1]d) <=> (SELECT embedding FROM source) AS distance
+			FROM email_embeddings
+			WHERE embedding_dim = %[1]d AND user_id = @user_id AND embedding_model = @model AND email_id <> @email_id
+			ORDER BY embedding::vector(%[1]d) <=> (SELECT embedding FROM source)
+			LIMIT @limit`, dims),
+			map[string]interface{}{"user_id": userID, "email_id": emailID, "model": c.embedder.Model(), "limit": limit},
+		).Scan(&rows).Error
+		return len(rows), err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query related embeddings: %w", err)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Distance < rows[j].Distance })
+
+	emailIDs := make([]string, 0, len(rows))
+	distances := make([]float64, 0, len(rows))
+	for _, row := range rows {
+		emailIDs = append(emailIDs, row.EmailID)
+		distances = append(distances, row.Distance)
+	}
+	log.Printf("[RelatedEmails] pgvector returned %d emails related to %s", len(emailIDs), emailID)
+	return emailIDs, distances, nil
+}
+
+// nearest runs a nearest-neighbour query of the user's vectors in a transaction. The HNSW scan returns
+// ef_search candidates before the user filter, so a user with few emails among many others may get
+// fewer than limit results: with pgvector 0.8+ the scan continues until enough rows pass the filter
+// (iterative scan, relaxed order: callers sort by distance); with older versions a short result is
+// retried as an exact scan of the user's rows. run returns the number of rows it read.
+func (c *Client) nearest(ctx context.Context, limit int, run func(tx *gorm.DB) (int, error)) error {
+	return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := c.setEfSearch(tx, limit); err != nil {
+			return err
+		}
+		if c.iterativeScan {
+			if err := tx.Exec("SET LOCAL hnsw.iterative_scan = relaxed_order").Error; err != nil {
+				return err
+			}
+		}
+		n, err := run(tx)
+		if err != nil || n >= limit || c.iterativeScan {
+			return err
+		}
+		// Plain index scans (HNSW included) off: the planner reads the user's rows through the btree bitmap
+		if err := tx.Exec("SET LOCAL enable_indexscan = off").Error; err != nil {
+			return err
+		}
+		_, err = run(tx)
+		return err
+	})
+}
+
+// setEfSearch sizes the HNSW scan of a transaction: widen it so that users with few emails among many
+// others still get limit results.
+func (c *Client) setEfSearch(tx *gorm.DB, limit int) error {
+	efSearch := c.efSearch
+	if efSearch < limit {
+		efSearch = limit
+	}
+	if efSearch > 1000 {
+		efSearch = 1000 // pgvector maximum
+	}
+	return tx.Exec(fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", efSearch)).Error
+}
+
+// supportsIterativeScan reports whether the installed vector extension has iterative index scans (0.8.0+)
+func supportsIterativeScan(db *gorm.DB) bool {
+	var version string
+	if err := db.Raw(`SELECT extversion FROM pg_extension WHERE extname = 'vector'`).Scan(&version).Error; err != nil {
+		return false
+	}
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return major > 0 || minor >= 8
+}
+
+// DeleteEmailEmbedding removes the embedding of an email
+func (c *Client) DeleteEmailEmbedding(ctx context.Context, collectionName, emailID string) error {
+	err := c.db.WithContext(ctx).Exec(`DELETE FROM email_embeddings WHERE email_id = @email_id`,
+		map[string]interface{}{"email_id": emailID}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete email embedding: %w", err)
+	}
+	return nil
+}
+
+// formatVector returns the pgvector text representation of a vector, e.g. "[0.1,0.2]"
+func formatVector(vector []float32) string {
+	var sb strings.Builder
+	sb.Grow(len(vector) * 10)
+	sb.WriteByte('[')
+	for i, v := range vector {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
@@ -0,0 +1,122 @@
+package search
+
+import (
+	"math"
+	"sort"
+
+	"ga03-backend/pkg/fuzzy"
+)
+
+// Default Okapi BM25 parameters
+const (
+	DefaultK1 = 1.2
+	DefaultB  = 0.75
+)
+
+// Field is a piece of a document with its weight (e.g. subject counts more than body)
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Document is a tokenized document: weighted term frequencies and length
+type Document struct {
+	ID     string
+	terms  map[string]float64
+	length float64
+}
+
+// NewDocument tokenizes the fields of a document (accent-folded, see fuzzy.Tokenize)
+func NewDocument(id string, fields ...Field) Document {
+	doc := Document{ID: id, terms: make(map[string]float64)}
+	for _, field := range fields {
+		weight := field.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		for _, term := range fuzzy.Tokenize(field.Text) {
+			doc.terms[term] += weight
+			doc.length += weight
+		}
+	}
+	return doc
+}
+
+// Scored is a document score, higher is better
+type Scored struct {
+	ID    string
+	Score float64
+}
+
+// BM25 ranks documents against a query with Okapi BM25; document frequencies come from the scored corpus
+type BM25 struct {
+	K1 float64
+	B  float64
+}
+
+// Rank returns the documents matching at least one query term, best first (ties by ID, so the order is stable)
+func (m BM25) Rank(query string, docs []Document) []Scored {
+	terms := uniqueTerms(fuzzy.Tokenize(query))
+	if len(terms) == 0 || len(docs) == 0 {
+		return []Scored{}
+	}
+	k1, b := m.K1, m.B
+	if k1 <= 0 {
+		k1 = DefaultK1
+	}
+	if b < 0 || b > 1 {
+		b = DefaultB
+	}
+
+	var totalLength float64
+	df := make(map[string]int, len(terms))
+	for _, doc := range docs {
+		totalLength += doc.length
+		for _, term := range terms {
+			if doc.terms[term] > 0 {
+				df[term]++
+			}
+		}
+	}
+	avgLength := totalLength / float64(len(docs))
+	if avgLength == 0 {
+		return []Scored{}
+	}
+
+	n := float64(len(docs))
+	results := make([]Scored, 0)
+	for _, doc := range docs {
+		var score float64
+		for _, term := range terms {
+			tf := doc.terms[term]
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+			score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*doc.length/avgLength))
+		}
+		if score > 0 {
+			results = append(results, Scored{ID: doc.ID, Score: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	unique := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,453 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"ga03-backend/pkg/fuzzy"
+)
+
+// Operators of the query language (Gmail syntax: https://support.google.com/mail/answer/7190)
+const (
+	FieldText    = ""        // Free text: subject, sender and body, accent-insensitive and typo-tolerant
+	FieldFrom    = "from"    // from:alice, from:alice@example.com
+	FieldTo      = "to"      // to:bob (To and Cc)
+	FieldSubject = "subject" // subject:"weekly report"
+	FieldHas     = "has"     // has:attachment
+	FieldIs      = "is"      // is:unread, is:read, is:starred
+	FieldBefore  = "before"  // before:2024/01/31 (received before the start of that day)
+	FieldAfter   = "after"   // after:2024-01-01 (received on or after that day)
+	FieldLarger  = "larger"  // larger:5M (bytes, K, M or G)
+	FieldSmaller = "smaller" // smaller:100K
+	FieldIn      = "in"      // in:todo, in:"To Do" (Kanban column ID or name)
+)
+
+var queryFields = map[string]bool{
+	FieldFrom: true, FieldTo: true, FieldSubject: true, FieldHas: true, FieldIs: true,
+	FieldBefore: true, FieldAfter: true, FieldLarger: true, FieldSmaller: true, FieldIn: true,
+}
+
+// Query is a parsed search query. Terms are combined with implicit AND; OR binds tighter than AND
+// ("a b OR c" means a AND (b OR c)) as in Gmail; NOT or a leading "-" negates a term or a group.
+type Query struct {
+	root node
+}
+
+type node interface {
+	String() string
+}
+
+type andNode struct{ children []node }
+type orNode struct{ children []node }
+type notNode struct{ child node }
+
+// term is a free-text word or phrase, or an operator with its value
+type term struct {
+	field  string
+	value  string    // As typed (without quotes); lowercased for has: and is:
+	phrase bool      // Quoted
+	date   time.Time // before:/after:, start of the day in the query location
+	size   int64     // larger:/smaller: in bytes
+}
+
+func (n *andNode) String() string { return joinNodes(n.children, " ") }
+func (n *orNode) String() string  { return "(" + joinNodes(n.children, " OR ") + ")" }
+func (n *notNode) String() string { return "-" + groupString(n.child) }
+
+func (t *term) String() string {
+	value := t.value
+	if t.phrase || strings.ContainsAny(value, " ()") {
+		value = strconv.Quote(value)
+	}
+	if t.field == FieldText {
+		return value
+	}
+	return t.field + ":" + value
+}
+
+func joinNodes(nodes []node, sep string) string {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		parts[i] = groupString(n)
+	}
+	return strings.Join(parts, sep)
+}
+
+// groupString parenthesizes a nested AND group
+func groupString(n node) string {
+	if _, ok := n.(*andNode); ok {
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+// String returns the canonical form of the query, e.g. `from:alice ("q1 report" OR invoice) -is:read`
+func (q *Query) String() string {
+	if q == nil || q.root == nil {
+		return ""
+	}
+	return q.root.String()
+}
+
+// IsEmpty reports whether the query has no terms (it matches every email)
+func (q *Query) IsEmpty() bool {
+	return q == nil || q.root == nil
+}
+
+// HasField reports whether the query uses an operator (e.g. FieldIn, to know whether Kanban columns must be loaded)
+func (q *Query) HasField(field string) bool {
+	if q.IsEmpty() {
+		return false
+	}
+	found := false
+	walk(q.root, func(t *term) {
+		if t.field == field {
+			found = true
+		}
+	})
+	return found
+}
+
+func walk(n node, fn func(*term)) {
+	switch n := n.(type) {
+	case *andNode:
+		for _, child := range n.children {
+			walk(child, fn)
+		}
+	case *orNode:
+		for _, child := range n.children {
+			walk(child, fn)
+		}
+	case *notNode:
+		walk(n.child, fn)
+	case *term:
+		fn(n)
+	}
+}
+
+// Split separates the free-text words of the query (top-level, not negated) from the rest.
+// The text is what rankers (BM25, embeddings) score; the filter holds the operators, the quoted
+// phrases (also part of the text) and the free text nested in OR or NOT. Either may be empty.
+func (q *Query) Split() (text string, filter *Query) {
+	if q.IsEmpty() {
+		return "", &Query{}
+	}
+	children := []node{q.root}
+	if and, ok := q.root.(*andNode); ok {
+		children = and.children
+	}
+	var words []string
+	var rest []node
+	for _, child := range children {
+		if t, ok := child.(*term); ok && t.field == FieldText {
+			words = append(words, t.value)
+			if !t.phrase {
+				continue
+			}
+		}
+		rest = append(rest, child)
+	}
+	return strings.Join(words, " "), &Query{root: newAnd(rest)}
+}
+
+func newAnd(children []node) node {
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return children[0]
+	}
+	return &andNode{children: children}
+}
+
+// ParseQuery parses a Gmail-style query. Dates are days in loc (UTC if nil).
+// Unknown operators ("foo:bar", URLs) are free text; invalid values of known operators are errors.
+func ParseQuery(input string, loc *time.Location) (*Query, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	p := &queryParser{tokens: lexQuery(input), loc: loc}
+	root, err := p.parseAnd(false)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{root: root}, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	quoted bool // Quoted phrase, or operator with a quoted value
+	phrase bool // Quoted phrase (never an operator, even if it contains ":")
+	negate bool // Preceded by "-"
+}
+
+// lexQuery splits the input into words, quoted phrases and parentheses.
+// An operator keeps its quoted value in one token: subject:"weekly report". Unclosed quotes end the input.
+func lexQuery(input string) []token {
+	runes := []rune(input)
+	var tokens []token
+	i := 0
+	readQuoted := func() string {
+		i++ // Opening quote
+		start := i
+		for i < len(runes) && runes[i] != '"' {
+			i++
+		}
+		value := string(runes[start:i])
+		if i < len(runes) {
+			i++ // Closing quote
+		}
+		return value
+	}
+
+	for i < len(runes) {
+		r := runes[i]
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+		negate := false
+		if r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			negate = true
+			i++
+			r = runes[i]
+		}
+		switch r {
+		case '(':
+			tokens = append(tokens, token{kind: tokenOpen, negate: negate})
+			i++
+			continue
+		case ')':
+			tokens = append(tokens, token{kind: tokenClose})
+			i++
+			continue
+		case '"':
+			tokens = append(tokens, token{kind: tokenWord, text: readQuoted(), quoted: true, phrase: true, negate: negate})
+			continue
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+			i++
+		}
+		word := string(runes[start:i])
+		if i < len(runes) && runes[i] == '"' && strings.HasSuffix(word, ":") {
+			// field:"quoted value"
+			word += readQuoted()
+			tokens = append(tokens, token{kind: tokenWord, text: word, quoted: true, negate: negate})
+			continue
+		}
+		tokens = append(tokens, token{kind: tokenWord, text: word, negate: negate})
+	}
+	return tokens
+}
+
+type queryParser struct {
+	tokens []token
+	pos    int
+	loc    *time.Location
+}
+
+func (p *queryParser) peek() *token {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func isKeyword(t *token, keyword string) bool {
+	return t != nil && t.kind == tokenWord && !t.quoted && !t.negate && t.text == keyword
+}
+
+// parseAnd parses terms up to the end of the input (or of the group), with optional AND keywords
+func (p *queryParser) parseAnd(inGroup bool) (node, error) {
+	var children []node
+	for {
+		t := p.peek()
+		if t == nil {
+			break
+		}
+		if t.kind == tokenClose {
+			if inGroup {
+				break
+			}
+			p.pos++ // Stray ")"
+			continue
+		}
+		if isKeyword(t, "AND") || isKeyword(t, "OR") {
+			p.pos++ // Dangling keyword
+			continue
+		}
+		child, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			children = append(children, child)
+		}
+	}
+	return newAnd(children), nil
+}
+
+// parseOr parses unary terms separated by OR
+func (p *queryParser) parseOr() (node, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []node{}
+	if first != nil {
+		children = append(children, first)
+	}
+	for isKeyword(p.peek(), "OR") {
+		p.pos++
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			children = append(children, next)
+		}
+	}
+	switch len(children) {
+	case 0:
+		return nil, nil
+	case 1:
+		return children[0], nil
+	}
+	return &orNode{children: children}, nil
+}
+
+// parseUnary parses a term or a group, negated by NOT or "-"
+func (p *queryParser) parseUnary() (node, error) {
+	t := p.peek()
+	if t == nil || t.kind == tokenClose || isKeyword(t, "OR") || isKeyword(t, "AND") {
+		return nil, nil
+	}
+	if isKeyword(t, "NOT") {
+		p.pos++
+		child, err := p.parseUnary()
+		if err != nil || child == nil {
+			return nil, err
+		}
+		return &notNode{child: child}, nil
+	}
+
+	p.pos++
+	var n node
+	if t.kind == tokenOpen {
+		group, err := p.parseAnd(true)
+		if err != nil {
+			return nil, err
+		}
+		if next := p.peek(); next != nil && next.kind == tokenClose {
+			p.pos++
+		}
+		if group == nil {
+			return nil, nil
+		}
+		n = group
+	} else {
+		parsed, err := p.parseTerm(t)
+		if err != nil {
+			return nil, err
+		}
+		if parsed == nil {
+			return nil, nil
+		}
+		n = parsed
+	}
+	if t.negate {
+		return &notNode{child: n}, nil
+	}
+	return n, nil
+}
+
+// parseTerm parses a word: an operator with its value, or free text
+func (p *queryParser) parseTerm(t *token) (*term, error) {
+	if t.phrase {
+		if strings.TrimSpace(t.text) == "" {
+			return nil, nil
+		}
+		return &term{field: FieldText, value: t.text, phrase: true}, nil
+	}
+	field, value, found := strings.Cut(t.text, ":")
+	field = strings.ToLower(field)
+	if !found || !queryFields[field] || (value == "" && !t.quoted) {
+		if len(fuzzy.Tokenize(t.text)) == 0 {
+			return nil, nil // Punctuation only, e.g. "-" or "&"
+		}
+		return &term{field: FieldText, value: t.text}, nil
+	}
+
+	tm := &term{field: field, value: value, phrase: t.quoted}
+	switch field {
+	case FieldHas:
+		tm.value = strings.ToLower(value)
+		if tm.value != "attachment" {
+			return nil, fmt.Errorf("unsupported operator has:%s (use has:attachment)", value)
+		}
+	case FieldIs:
+		tm.value = strings.ToLower(value)
+		if tm.value != "unread" && tm.value != "read" && tm.value != "starred" {
+			return nil, fmt.Errorf("unsupported operator is:%s (use is:unread, is:read or is:starred)", value)
+		}
+	case FieldBefore, FieldAfter:
+		date, err := parseQueryDate(value, p.loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date in %s:%s (use YYYY/MM/DD)", field, value)
+		}
+		tm.date = date
+	case FieldLarger, FieldSmaller:
+		size, err := parseQuerySize(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size in %s:%s (e.g. 500K, 10M)", field, value)
+		}
+		tm.size = size
+	}
+	return tm, nil
+}
+
+// parseQueryDate parses YYYY/MM/DD or YYYY-MM-DD as the start of that day in loc
+func parseQueryDate(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"2006/1/2", "2006-1-2"} {
+		if date, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseQuerySize parses a size in bytes with an optional K, M or G suffix (KB, MB, GB also accepted)
+func parseQuerySize(value string) (int64, error) {
+	v := strings.TrimSuffix(strings.ToUpper(value), "B")
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(v, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(v, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(v, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		v = v[:len(v)-1]
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return n * multiplier, nil
+}
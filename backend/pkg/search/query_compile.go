@@ -0,0 +1,143 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+)
+
+// backend compiles the terms it supports into its own filter language
+type backend[T any] interface {
+	// term returns the filter of a term; pushed is false when the backend cannot express it,
+	// exact is false when the filter selects more than the term (the term is then also checked with Match)
+	term(t *term) (filter T, pushed, exact bool)
+	and(filters []T) T
+	or(filters []T) T
+	not(filter T) T
+}
+
+// pushDown compiles as much of n as the backend supports. The filter selects a superset of the
+// matches of n (pushed is false when nothing could be compiled: no filter); residual is the part
+// of n that must still be checked with Match, nil when the filter is exact.
+func pushDown[T any](b backend[T], n node) (filter T, pushed bool, residual node) {
+	var none T
+	switch n := n.(type) {
+	case *term:
+		f, ok, exact := b.term(n)
+		if !ok {
+			return none, false, n
+		}
+		if !exact {
+			return f, true, n
+		}
+		return f, true, nil
+	case *andNode:
+		var filters []T
+		var residuals []node
+		for _, child := range n.children {
+			f, ok, r := pushDown(b, child)
+			if ok {
+				filters = append(filters, f)
+			}
+			if r != nil {
+				residuals = append(residuals, r)
+			}
+		}
+		if len(filters) == 0 {
+			return none, false, n
+		}
+		return b.and(filters), true, newAnd(residuals)
+	case *orNode:
+		// Every alternative must be compiled, or the filter would drop the matches of the others
+		filters := make([]T, 0, len(n.children))
+		exact := true
+		for _, child := range n.children {
+			f, ok, r := pushDown(b, child)
+			if !ok {
+				return none, false, n
+			}
+			filters = append(filters, f)
+			if r != nil {
+				exact = false
+			}
+		}
+		if !exact {
+			return b.or(filters), true, n
+		}
+		return b.or(filters), true, nil
+	case *notNode:
+		// The negation of a superset is a subset: only exact filters can be negated
+		f, ok, r := pushDown(b, n.child)
+		if !ok || r != nil {
+			return none, false, n
+		}
+		return b.not(f), true, nil
+	}
+	return none, false, nil
+}
+
+// compile runs pushDown on the whole query
+func compile[T any](q *Query, b backend[T]) (filter T, pushed bool, residual *Query) {
+	if q.IsEmpty() {
+		var none T
+		return none, false, &Query{}
+	}
+	filter, pushed, r := pushDown(b, q.root)
+	return filter, pushed, &Query{root: r}
+}
+
+// Gmail compiles the query to a Gmail search string ("" when nothing can be sent to Gmail) and returns
+// the part that must still be checked with Match: free text and subject: (Gmail search is
+// accent-sensitive, "canh" would not find "cảnh báo") and in: (Kanban columns are local).
+func (q *Query) Gmail() (string, *Query) {
+	filter, _, residual := compile[string](q, gmailBackend{})
+	return filter, residual
+}
+
+type gmailBackend struct{}
+
+func (gmailBackend) term(t *term) (string, bool, bool) {
+	switch t.field {
+	case FieldFrom, FieldTo:
+		return t.field + ":" + gmailValue(t.value), true, true
+	case FieldHas, FieldIs:
+		return t.field + ":" + t.value, true, true
+	case FieldBefore, FieldAfter:
+		// Seconds since the epoch: the day starts in the user's time zone, not Gmail's (Pacific time)
+		return t.field + ":" + strconv.FormatInt(t.date.Unix(), 10), true, true
+	case FieldLarger, FieldSmaller:
+		return t.field + ":" + strconv.FormatInt(t.size, 10), true, true
+	}
+	return "", false, false
+}
+
+func (gmailBackend) and(filters []string) string {
+	return strings.Join(filters, " ")
+}
+
+func (gmailBackend) or(filters []string) string {
+	parts := make([]string, len(filters))
+	for i, f := range filters {
+		parts[i] = gmailGroup(f)
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+func (gmailBackend) not(filter string) string {
+	return "-" + gmailGroup(filter)
+}
+
+// gmailGroup parenthesizes a filter of several terms (in Gmail, OR binds tighter than AND)
+func gmailGroup(filter string) string {
+	if strings.Contains(filter, " ") {
+		return "(" + filter + ")"
+	}
+	return filter
+}
+
+// gmailValue quotes a value containing spaces or Gmail syntax characters
+func gmailValue(value string) string {
+	if strings.ContainsAny(value, " (){}\"") {
+		return `"` + strings.ReplaceAll(value, `"`, "") + `"`
+	}
+	return value
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"math"
+
+	"github.com/emersion/go-imap"
+)
+
+// IMAP compiles the query to IMAP SEARCH criteria (empty criteria match every message) and returns
+// the part that must still be checked with Match: free text and subject: (IMAP SEARCH is
+// accent-sensitive), has:attachment (no IMAP search key) and in: (Kanban columns are local).
+func (q *Query) IMAP() (*imap.SearchCriteria, *Query) {
+	criteria, pushed, residual := compile[*imap.SearchCriteria](q, imapBackend{})
+	if !pushed {
+		criteria = imap.NewSearchCriteria()
+	}
+	return criteria, residual
+}
+
+type imapBackend struct{}
+
+func (imapBackend) term(t *term) (*imap.SearchCriteria, bool, bool) {
+	c := imap.NewSearchCriteria()
+	switch t.field {
+	case FieldFrom:
+		c.Header.Add("From", t.value)
+	case FieldTo:
+		to := imap.NewSearchCriteria()
+		to.Header.Add("To", t.value)
+		cc := imap.NewSearchCriteria()
+		cc.Header.Add("Cc", t.value)
+		c.Or = append(c.Or, [2]*imap.SearchCriteria{to, cc})
+	case FieldIs:
+		switch t.value {
+		case "unread":
+			c.WithoutFlags = append(c.WithoutFlags, imap.SeenFlag)
+		case "read":
+			c.WithFlags = append(c.WithFlags, imap.SeenFlag)
+		case "starred":
+			c.WithFlags = append(c.WithFlags, imap.FlaggedFlag)
+		}
+	case FieldAfter:
+		c.Since = t.date
+	case FieldBefore:
+		c.Before = t.date
+	case FieldLarger:
+		c.Larger = imapSize(t.size)
+	case FieldSmaller:
+		if t.size == 0 {
+			return nil, false, false // SMALLER 0 would read as no limit
+		}
+		c.Smaller = imapSize(t.size)
+	default:
+		return nil, false, false
+	}
+	return c, true, true
+}
+
+func (imapBackend) and(filters []*imap.SearchCriteria) *imap.SearchCriteria {
+	c := imap.NewSearchCriteria()
+	for _, f := range filters {
+		mergeCriteria(c, f)
+	}
+	return c
+}
+
+func (b imapBackend) or(filters []*imap.SearchCriteria) *imap.SearchCriteria {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	// IMAP OR takes two keys: a OR b OR c is OR a (OR b c)
+	c := imap.NewSearchCriteria()
+	c.Or = append(c.Or, [2]*imap.SearchCriteria{filters[0], b.or(filters[1:])})
+	return c
+}
+
+func (imapBackend) not(filter *imap.SearchCriteria) *imap.SearchCriteria {
+	c := imap.NewSearchCriteria()
+	c.Not = append(c.Not, filter)
+	return c
+}
+
+// mergeCriteria adds the keys of src to dst (the keys of a criteria are ANDed)
+func mergeCriteria(dst, src *imap.SearchCriteria) {
+	for key, values := range src.Header {
+		for _, value := range values {
+			dst.Header.Add(key, value)
+		}
+	}
+	dst.Body = append(dst.Body, src.Body...)
+	dst.Text = append(dst.Text, src.Text...)
+	dst.WithFlags = append(dst.WithFlags, src.WithFlags...)
+	dst.WithoutFlags = append(dst.WithoutFlags, src.WithoutFlags...)
+	dst.Not = append(dst.Not, src.Not...)
+	dst.Or = append(dst.Or, src.Or...)
+	if src.Since.After(dst.Since) {
+		dst.Since = src.Since
+	}
+	if !src.Before.IsZero() && (dst.Before.IsZero() || src.Before.Before(dst.Before)) {
+		dst.Before = src.Before
+	}
+	if src.Larger > dst.Larger {
+		dst.Larger = src.Larger
+	}
+	if src.Smaller != 0 && (dst.Smaller == 0 || src.Smaller < dst.Smaller) {
+		dst.Smaller = src.Smaller
+	}
+}
+
+func imapSize(size int64) uint32 {
+	if size > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(size)
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"strings"
+	"time"
+
+	"ga03-backend/pkg/fuzzy"
+)
+
+// Message is what a query is evaluated against in Go (emails of backends that cannot run the whole query)
+type Message struct {
+	Subject       string
+	From          string
+	FromName      string
+	To            []string // To and Cc
+	Body          string   // Plain text or preview
+	HasAttachment bool
+	IsRead        bool
+	IsStarred     bool
+	ReceivedAt    time.Time
+	Size          int64    // Bytes (estimated when the provider does not report it)
+	Columns       []string // ID and name of the email's Kanban column, for in:
+}
+
+// Match reports whether the message matches the query. Free text is accent-insensitive and
+// typo-tolerant (fuzzy.FuzzyMatchEmail); phrases must appear as consecutive words.
+// A nil or empty query matches every message.
+func (q *Query) Match(m *Message) bool {
+	if q.IsEmpty() {
+		return true
+	}
+	return matchNode(q.root, m)
+}
+
+func matchNode(n node, m *Message) bool {
+	switch n := n.(type) {
+	case *andNode:
+		for _, child := range n.children {
+			if !matchNode(child, m) {
+				return false
+			}
+		}
+		return true
+	case *orNode:
+		for _, child := range n.children {
+			if matchNode(child, m) {
+				return true
+			}
+		}
+		return false
+	case *notNode:
+		return !matchNode(n.child, m)
+	case *term:
+		return n.match(m)
+	}
+	return false
+}
+
+func (t *term) match(m *Message) bool {
+	switch t.field {
+	case FieldText:
+		if t.phrase {
+			return containsPhrase(m.Subject, t.value) || containsPhrase(m.FromName+" "+m.From, t.value) || containsPhrase(m.Body, t.value)
+		}
+		return fuzzy.FuzzyMatchEmail(t.value, m.Subject, m.From, m.FromName, m.Body)
+	case FieldSubject:
+		if t.phrase {
+			return containsPhrase(m.Subject, t.value)
+		}
+		return fuzzy.FuzzyMatchEmail(t.value, m.Subject, "", "", "")
+	case FieldFrom:
+		return containsFolded(m.From, t.value) || containsFolded(m.FromName, t.value)
+	case FieldTo:
+		for _, to := range m.To {
+			if containsFolded(to, t.value) {
+				return true
+			}
+		}
+		return false
+	case FieldHas:
+		return m.HasAttachment
+	case FieldIs:
+		switch t.value {
+		case "unread":
+			return !m.IsRead
+		case "read":
+			return m.IsRead
+		case "starred":
+			return m.IsStarred
+		}
+	case FieldBefore:
+		return m.ReceivedAt.Before(t.date)
+	case FieldAfter:
+		return !m.ReceivedAt.Before(t.date)
+	case FieldLarger:
+		return m.Size > t.size
+	case FieldSmaller:
+		return m.Size < t.size
+	case FieldIn:
+		for _, column := range m.Columns {
+			if column != "" && fuzzy.Fold(column) == fuzzy.Fold(t.value) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// containsFolded reports whether value appears in text, ignoring case, accents and punctuation
+// (from:example.com matches "alice@example.com")
+func containsFolded(text, value string) bool {
+	v := fuzzy.Fold(value)
+	return v != "" && strings.Contains(fuzzy.Fold(text), v)
+}
+
+// containsPhrase reports whether the words of phrase appear consecutively in text
+func containsPhrase(text, phrase string) bool {
+	p := fuzzy.Fold(phrase)
+	return p != "" && strings.Contains(" "+fuzzy.Fold(text)+" ", " "+p+" ")
+}
@@ -0,0 +1,182 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"ga03-backend/pkg/fuzzy"
+)
+
+// SQLColumns maps the operators to the columns (SQL expressions) of a local table.
+// Operators without a column are not compiled and stay in the residual query.
+type SQLColumns struct {
+	Text string // Searchable text of free-text terms (subject, sender and body)
+	// Document is a tsvector ('simple' configuration) of the folded text. When set, free text is
+	// matched with PrefixTSQuery (index scan, words matched by prefix) instead of LIKE on Text.
+	Document      string
+	Subject       string
+	From          string // Sender address and name
+	To            string // Recipients (To and Cc)
+	HasAttachment string // boolean
+	IsRead        string // boolean
+	IsStarred     string // boolean
+	ReceivedAt    string // timestamp
+	Size          string // bytes
+	// Fold wraps the text columns so that they compare with the lowercase, accent-free words of the query,
+	// e.g. "unaccent(lower(%s))". Defaults to "lower(%s)", enough for columns stored without accents.
+	Fold string
+	// InColumn returns the condition of in:<column> given the named parameter holding the column ID or name,
+	// e.g. "email_id IN (SELECT email_id FROM email_kanban_columns WHERE column_id = @p)"
+	InColumn func(param string) string
+}
+
+// SQL compiles the query to a WHERE condition with named parameters ("" when nothing can be compiled)
+// and returns the part that must still be checked with Match. Text operators are matched as
+// accent-insensitive substrings of every word: free text loses its typo tolerance, and phrases
+// (whose words could be apart) are also checked with Match.
+func (q *Query) SQL(cols SQLColumns) (string, map[string]interface{}, *Query) {
+	if cols.Fold == "" {
+		cols.Fold = "lower(%s)"
+	}
+	b := &sqlBackend{cols: cols, args: make(map[string]interface{})}
+	where, _, residual := compile[string](q, b)
+	return where, b.args, residual
+}
+
+type sqlBackend struct {
+	cols SQLColumns
+	args map[string]interface{}
+}
+
+// param stores a value and returns its placeholder
+func (b *sqlBackend) param(value interface{}) string {
+	name := fmt.Sprintf("search_%d", len(b.args))
+	b.args[name] = value
+	return "@" + name
+}
+
+func (b *sqlBackend) term(t *term) (string, bool, bool) {
+	switch t.field {
+	case FieldText:
+		if b.cols.Document != "" {
+			return b.tsquery(t)
+		}
+		return b.words(b.cols.Text, t)
+	case FieldSubject:
+		return b.words(b.cols.Subject, t)
+	case FieldFrom:
+		return b.words(b.cols.From, t)
+	case FieldTo:
+		return b.words(b.cols.To, t)
+	case FieldHas:
+		return b.boolean(b.cols.HasAttachment, true)
+	case FieldIs:
+		switch t.value {
+		case "unread":
+			return b.boolean(b.cols.IsRead, false)
+		case "read":
+			return b.boolean(b.cols.IsRead, true)
+		case "starred":
+			return b.boolean(b.cols.IsStarred, true)
+		}
+	case FieldAfter:
+		return b.compare(b.cols.ReceivedAt, ">=", t.date)
+	case FieldBefore:
+		return b.compare(b.cols.ReceivedAt, "<", t.date)
+	case FieldLarger:
+		return b.compare(b.cols.Size, ">", t.size)
+	case FieldSmaller:
+		return b.compare(b.cols.Size, "<", t.size)
+	case FieldIn:
+		if b.cols.InColumn != nil {
+			return b.cols.InColumn(b.param(t.value)), true, true
+		}
+	}
+	return "", false, false
+}
+
+// words requires every folded word of the term in the column; a phrase is only approximated (exact is false)
+func (b *sqlBackend) words(column string, t *term) (string, bool, bool) {
+	words := fuzzy.Tokenize(t.value)
+	if column == "" || len(words) == 0 {
+		return "", false, false
+	}
+	folded := fmt.Sprintf(b.cols.Fold, column)
+	conditions := make([]string, len(words))
+	for i, word := range words {
+		conditions[i] = folded + " LIKE " + b.param("%"+escapeLike(word)+"%")
+	}
+	return b.and(conditions), true, !t.phrase || len(words) == 1
+}
+
+// tsquery matches the folded words of the term by prefix, consecutively for a phrase
+func (b *sqlBackend) tsquery(t *term) (string, bool, bool) {
+	query := PrefixTSQuery(t.value)
+	if query == "" {
+		return "", false, false
+	}
+	if t.phrase {
+		query = strings.Join(fuzzy.Tokenize(t.value), " <-> ")
+	}
+	return b.cols.Document + " @@ to_tsquery('simple', " + b.param(query) + ")", true, true
+}
+
+func (b *sqlBackend) boolean(column string, value bool) (string, bool, bool) {
+	if column == "" {
+		return "", false, false
+	}
+	if value {
+		return column, true, true
+	}
+	return "NOT " + column, true, true
+}
+
+func (b *sqlBackend) compare(column, op string, value interface{}) (string, bool, bool) {
+	if column == "" {
+		return "", false, false
+	}
+	return column + " " + op + " " + b.param(value), true, true
+}
+
+func (b *sqlBackend) and(filters []string) string {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return "(" + strings.Join(filters, " AND ") + ")"
+}
+
+func (b *sqlBackend) or(filters []string) string {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return "(" + strings.Join(filters, " OR ") + ")"
+}
+
+func (b *sqlBackend) not(filter string) string {
+	return "NOT (" + filter + ")"
+}
+
+// PrefixTSQuery returns a tsquery requiring every folded word of text as a word prefix,
+// e.g. "Cảnh bá" -> "canh:* & ba:*" ("" when text has no words)
+func PrefixTSQuery(text string) string {
+	words := fuzzy.Tokenize(text)
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// AnyPrefixTSQuery returns a tsquery matching any folded word of text as a word prefix,
+// e.g. "Cảnh bá" -> "canh:* | ba:*" ("" when text has no words)
+func AnyPrefixTSQuery(text string) string {
+	words := fuzzy.Tokenize(text)
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " | ")
+}
+
+// escapeLike escapes the LIKE wildcards of a value
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
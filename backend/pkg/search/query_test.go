@@ -0,0 +1,206 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQueryString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"invoice", "invoice"},
+		{"from:alice invoice", "from:alice invoice"},
+		{`subject:"weekly report"`, `subject:"weekly report"`},
+		{`"q1 report"`, `"q1 report"`},
+		{"a b OR c", "a (b OR c)"},
+		{"-is:read", "-is:read"},
+		{"NOT from:bob", "-from:bob"},
+		{"(a OR b) c", "(a OR b) c"},
+		{"-(a b)", "-(a b)"},
+		{"IS:UNREAD", "is:unread"},
+		{"foo:bar", "foo:bar"},
+		{"https://example.com", "https://example.com"},
+		{"report &", "report"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := ParseQuery(tt.input, nil)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) error: %v", tt.input, err)
+			}
+			if got := q.String(); got != tt.want {
+				t.Errorf("ParseQuery(%q).String() = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, input := range []string{
+		"has:pdf",
+		"is:important",
+		"before:yesterday",
+		"after:2024/13/01",
+		"larger:big",
+		"smaller:-5M",
+	} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := ParseQuery(input, nil); err == nil {
+				t.Errorf("ParseQuery(%q) succeeded, want an error", input)
+			}
+		})
+	}
+}
+
+func TestQuerySplit(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantText   string
+		wantFilter string
+	}{
+		{"invoice march", "invoice march", ""},
+		{"from:alice invoice", "invoice", "from:alice"},
+		{`"q1 report" budget`, "q1 report budget", `"q1 report"`},
+		{"a OR b", "", "(a OR b)"},
+		{"-spam is:unread", "", "-spam is:unread"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := ParseQuery(tt.input, nil)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) error: %v", tt.input, err)
+			}
+			text, filter := q.Split()
+			if text != tt.wantText || filter.String() != tt.wantFilter {
+				t.Errorf("Split(%q) = %q, %q; want %q, %q", tt.input, text, filter.String(), tt.wantText, tt.wantFilter)
+			}
+		})
+	}
+}
+
+func TestQueryGmail(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*60*60)
+	tests := []struct {
+		input        string
+		wantFilter   string
+		wantResidual string
+	}{
+		{"from:alice is:unread", "from:alice is:unread", ""},
+		{"from:alice invoice", "from:alice", "invoice"},
+		{"from:alice OR to:bob", "(from:alice OR to:bob)", ""},
+		// An alternative Gmail cannot run keeps the whole OR local
+		{"from:alice OR invoice", "", "(from:alice OR invoice)"},
+		{"-has:attachment", "-has:attachment", ""},
+		{"-invoice", "", "-invoice"},
+		{`to:"Bob Smith"`, `to:"Bob Smith"`, ""},
+		{"larger:1M", "larger:1048576", ""},
+		{"after:2024/01/02", "after:1704128400", ""},
+		{"in:todo is:starred", "is:starred", "in:todo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := ParseQuery(tt.input, loc)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) error: %v", tt.input, err)
+			}
+			filter, residual := q.Gmail()
+			if filter != tt.wantFilter || residual.String() != tt.wantResidual {
+				t.Errorf("Gmail(%q) = %q, %q; want %q, %q", tt.input, filter, residual.String(), tt.wantFilter, tt.wantResidual)
+			}
+		})
+	}
+}
+
+func TestQuerySQL(t *testing.T) {
+	cols := SQLColumns{From: "from_text", IsRead: "is_read", Size: "size"}
+	tests := []struct {
+		input        string
+		wantWhere    string
+		wantArgs     int
+		wantResidual string
+	}{
+		{"is:unread", "NOT is_read", 0, ""},
+		{"-is:read larger:10K", "(NOT (is_read) AND size > @search_0)", 1, ""},
+		{"from:alice invoice", "lower(from_text) LIKE @search_0", 1, "invoice"},
+		{"invoice", "", 0, "invoice"},
+		{"in:todo", "", 0, "in:todo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := ParseQuery(tt.input, nil)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) error: %v", tt.input, err)
+			}
+			where, args, residual := q.SQL(cols)
+			if where != tt.wantWhere || len(args) != tt.wantArgs || residual.String() != tt.wantResidual {
+				t.Errorf("SQL(%q) = %q (%d args), %q; want %q (%d args), %q",
+					tt.input, where, len(args), residual.String(), tt.wantWhere, tt.wantArgs, tt.wantResidual)
+			}
+		})
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	received := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	message := &Message{
+		Subject:       "Cảnh báo bảo mật: weekly report",
+		From:          "alice@example.com",
+		FromName:      "Alice Nguyễn",
+		To:            []string{"bob@example.com", "carol@example.org"},
+		Body:          "Please review the Q1 budget before Friday.",
+		HasAttachment: true,
+		IsRead:        false,
+		IsStarred:     true,
+		ReceivedAt:    received,
+		Size:          2 << 20,
+		Columns:       []string{"todo", "To Do"},
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"canh bao", true},
+		{"budgte", true},
+		{`"weekly report"`, true},
+		{`"report weekly"`, false},
+		{"from:alice", true},
+		{"from:example.com", true},
+		{"from:nguyen", true},
+		{"from:bob", false},
+		{"to:carol", true},
+		{"to:dave", false},
+		{`subject:"weekly report"`, true},
+		{"subject:budget", false},
+		{"has:attachment", true},
+		{"is:unread", true},
+		{"is:read", false},
+		{"is:starred", true},
+		{"after:2024/03/15", true},
+		{"before:2024/03/15", false},
+		{"before:2024-03-16", true},
+		{"larger:1M", true},
+		{"smaller:1M", false},
+		{`in:"to do"`, true},
+		{"in:done", false},
+		{"from:bob OR to:bob", true},
+		{"-is:starred", false},
+		{"from:alice -has:attachment", false},
+		{"(from:bob OR budget) is:unread", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query, nil)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) error: %v", tt.query, err)
+			}
+			if got := q.Match(message); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
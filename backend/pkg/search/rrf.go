@@ -0,0 +1,76 @@
+package search
+
+import "sort"
+
+// DefaultRRFK is the usual reciprocal rank fusion constant: it damps the weight of the first ranks
+const DefaultRRFK = 60
+
+// Ranking is the result list of one source (e.g. "keyword", "semantic"), best first
+type Ranking struct {
+	Source string
+	IDs    []string
+	Weight float64 // 0 = 1
+}
+
+// Fused is a document of the fused ranking with its 1-based rank in each source that returned it
+type Fused struct {
+	ID    string
+	Score float64
+	Ranks map[string]int
+}
+
+// FuseRRF merges rankings with reciprocal rank fusion: score = sum of weight / (k + rank).
+// The order is deterministic: score, then best rank in any source, then ID.
+func FuseRRF(k float64, rankings ...Ranking) []Fused {
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+
+	byID := make(map[string]*Fused)
+	order := make([]*Fused, 0)
+	for _, ranking := range rankings {
+		weight := ranking.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		for i, id := range ranking.IDs {
+			fused, ok := byID[id]
+			if !ok {
+				fused = &Fused{ID: id, Ranks: make(map[string]int)}
+				byID[id] = fused
+				order = append(order, fused)
+			}
+			if _, seen := fused.Ranks[ranking.Source]; seen {
+				continue // Duplicate in the same source: keep the best rank
+			}
+			fused.Ranks[ranking.Source] = i + 1
+			fused.Score += weight / (k + float64(i+1))
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].Score != order[j].Score {
+			return order[i].Score > order[j].Score
+		}
+		if bi, bj := bestRank(order[i]), bestRank(order[j]); bi != bj {
+			return bi < bj
+		}
+		return order[i].ID < order[j].ID
+	})
+
+	result := make([]Fused, len(order))
+	for i, fused := range order {
+		result[i] = *fused
+	}
+	return result
+}
+
+func bestRank(f *Fused) int {
+	best := 0
+	for _, rank := range f.Ranks {
+		if best == 0 || rank < best {
+			best = rank
+		}
+	}
+	return best
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFuseRRF(t *testing.T) {
+	tests := []struct {
+		name     string
+		k        float64
+		rankings []Ranking
+		wantIDs  []string
+	}{
+		{
+			name:     "no rankings",
+			rankings: nil,
+			wantIDs:  []string{},
+		},
+		{
+			name:     "one source keeps its order",
+			rankings: []Ranking{{Source: "keyword", IDs: []string{"c", "a", "b"}}},
+			wantIDs:  []string{"c", "a", "b"},
+		},
+		{
+			name: "documents in both sources come first",
+			rankings: []Ranking{
+				{Source: "keyword", IDs: []string{"a", "b", "c"}},
+				{Source: "semantic", IDs: []string{"d", "c", "e"}},
+			},
+			wantIDs: []string{"c", "a", "d", "b", "e"},
+		},
+		{
+			name: "weight favours a source",
+			rankings: []Ranking{
+				{Source: "keyword", IDs: []string{"a"}},
+				{Source: "semantic", IDs: []string{"b"}, Weight: 2},
+			},
+			wantIDs: []string{"b", "a"},
+		},
+		{
+			name: "ties break by ID",
+			rankings: []Ranking{
+				{Source: "keyword", IDs: []string{"b"}},
+				{Source: "semantic", IDs: []string{"a"}},
+			},
+			wantIDs: []string{"a", "b"},
+		},
+		{
+			name:     "duplicates in a source keep the best rank",
+			rankings: []Ranking{{Source: "keyword", IDs: []string{"a", "b", "a"}}},
+			wantIDs:  []string{"a", "b"},
+		},
+		{
+			name: "small k rewards the first rank",
+			k:    1,
+			rankings: []Ranking{
+				{Source: "keyword", IDs: []string{"a", "x", "y", "b"}},
+				{Source: "semantic", IDs: []string{"z", "w", "v", "b"}},
+			},
+			wantIDs: []string{"a", "z", "b", "w", "x", "v", "y"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fused := FuseRRF(tt.k, tt.rankings...)
+			ids := make([]string, len(fused))
+			for i, f := range fused {
+				ids[i] = f.ID
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("FuseRRF() order = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestFuseRRFScoresAndRanks(t *testing.T) {
+	fused := FuseRRF(0,
+		Ranking{Source: "keyword", IDs: []string{"a", "b"}},
+		Ranking{Source: "semantic", IDs: []string{"b"}},
+	)
+	if len(fused) != 2 || fused[0].ID != "b" {
+		t.Fatalf("FuseRRF() = %+v, want b first", fused)
+	}
+
+	wantScore := 1.0/(DefaultRRFK+2) + 1.0/(DefaultRRFK+1)
+	if math.Abs(fused[0].Score-wantScore) > 1e-12 {
+		t.Errorf("score of b = %v, want %v", fused[0].Score, wantScore)
+	}
+	if want := map[string]int{"keyword": 2, "semantic": 1}; !reflect.DeepEqual(fused[0].Ranks, want) {
+		t.Errorf("ranks of b = %v, want %v", fused[0].Ranks, want)
+	}
+	if want := map[string]int{"keyword": 1}; !reflect.DeepEqual(fused[1].Ranks, want) {
+		t.Errorf("ranks of a = %v, want %v", fused[1].Ranks, want)
+	}
+}
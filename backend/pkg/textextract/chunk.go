@@ -0,0 +1,87 @@
+package textextract
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk splits text into passages of at most maxRunes runes. Paragraphs (separated by blank lines,
+// or by line breaks when the text has none) are kept together while they fit; longer paragraphs
+// are cut after a sentence, or else between words.
+func Chunk(text string, maxRunes int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" || maxRunes <= 0 {
+		return nil
+	}
+
+	separator := "\n\n"
+	if !strings.Contains(text, separator) {
+		separator = "\n"
+	}
+
+	var chunks []string
+	var current strings.Builder
+	currentRunes := 0
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentRunes = 0
+		}
+	}
+
+	for _, paragraph := range strings.Split(text, separator) {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		for _, piece := range splitLong(paragraph, maxRunes) {
+			n := utf8.RuneCountInString(piece)
+			if currentRunes > 0 && currentRunes+len(separator)+n > maxRunes {
+				flush()
+			}
+			if currentRunes > 0 {
+				current.WriteString(separator)
+				currentRunes += len(separator)
+			}
+			current.WriteString(piece)
+			currentRunes += n
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitLong cuts a paragraph longer than maxRunes after the last sentence end that fits,
+// or at the last space, or (a single long word) at maxRunes
+func splitLong(paragraph string, maxRunes int) []string {
+	var pieces []string
+	runes := []rune(paragraph)
+	for len(runes) > maxRunes {
+		cut := -1
+		for i := maxRunes - 1; i > maxRunes/2; i-- {
+			if (runes[i] == '.' || runes[i] == '!' || runes[i] == '?') && i+1 < len(runes) && unicode.IsSpace(runes[i+1]) {
+				cut = i + 1
+				break
+			}
+		}
+		if cut < 0 {
+			for i := maxRunes; i > 0; i-- {
+				if unicode.IsSpace(runes[i]) {
+					cut = i
+					break
+				}
+			}
+		}
+		if cut <= 0 {
+			cut = maxRunes
+		}
+		pieces = append(pieces, strings.TrimSpace(string(runes[:cut])))
+		runes = []rune(strings.TrimSpace(string(runes[cut:])))
+	}
+	if len(runes) > 0 {
+		pieces = append(pieces, string(runes))
+	}
+	return pieces
+}
@@ -0,0 +1,39 @@
+package textextract
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxRunes int
+		want     []string
+	}{
+		{"empty", "  \n ", 100, nil},
+		{"no limit", "text", 0, nil},
+		{"fits", "First paragraph.\n\nSecond paragraph.", 100, []string{"First paragraph.\n\nSecond paragraph."}},
+		{"paragraphs grouped while they fit", "aaaa\n\nbbbb\n\ncccc", 10, []string{"aaaa\n\nbbbb", "cccc"}},
+		{"line breaks without blank lines", "one\ntwo\nthree", 8, []string{"one\ntwo", "three"}},
+		{"cut after a sentence", "First sentence here. Second one follows.", 25, []string{"First sentence here.", "Second one follows."}},
+		{"cut between words", "alpha beta gamma delta", 12, []string{"alpha beta", "gamma delta"}},
+		{"long word", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"runes, not bytes", "Xin chào thế giới", 8, []string{"Xin chào", "thế giới"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Chunk(tt.text, tt.maxRunes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chunk(%q, %d) = %q, want %q", tt.text, tt.maxRunes, got, tt.want)
+			}
+			for _, chunk := range got {
+				if n := utf8.RuneCountInString(chunk); n > tt.maxRunes {
+					t.Errorf("chunk %q has %d runes, more than %d", chunk, n, tt.maxRunes)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package textextract
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// reHTMLBody recognizes HTML email bodies (plain text bodies may contain "<address>" pairs)
+	reHTMLBody = regexp.MustCompile(`(?i)<(html|body|div|p|br|table|span|a)[\s/>]`)
+
+	// Headers introducing a quoted or forwarded message (Gmail, Apple Mail, Outlook, Vietnamese Gmail)
+	reReplyHeaders = []*regexp.Regexp{
+		regexp.MustCompile(`^On .+ wrote:$`),
+		regexp.MustCompile(`^Vào .+ đã viết:$`),
+		regexp.MustCompile(`(?i)^-{2,} ?(Original Message|Forwarded message|Tin nhắn đã chuyển tiếp) ?-{2,}$`),
+		regexp.MustCompile(`^_{10,}$`),
+	}
+	// reOutlookFrom starts the header block of a reply quoted by Outlook ("From: ... Sent: ...")
+	reOutlookFrom    = regexp.MustCompile(`^(From|Từ): .+`)
+	reOutlookHeaders = regexp.MustCompile(`^(Sent|Date|Đã gửi|Ngày): .+`)
+)
+
+// EmailText returns the text of an email body (HTML or plain text) with paragraphs separated by blank lines
+func EmailText(body string) string {
+	if reHTMLBody.MatchString(body) {
+		body = extractHTML(body)
+	}
+	return normalize(body)
+}
+
+// ChunkEmail splits the text of an email into passages of at most maxRunes runes. The new message and
+// each quoted reply are chunked separately (a passage never mixes two messages of a thread), then
+// by paragraphs as Chunk does. Quote markers (">") are removed.
+func ChunkEmail(text string, maxRunes int) []string {
+	var chunks []string
+	for _, section := range splitReplies(text) {
+		chunks = append(chunks, Chunk(section, maxRunes)...)
+	}
+	return chunks
+}
+
+// splitReplies splits an email at the headers of quoted replies and where ">" quoting starts or stops
+func splitReplies(text string) []string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+
+	var sections []string
+	var current []string
+	flush := func() {
+		if section := strings.TrimSpace(strings.Join(current, "\n")); section != "" {
+			sections = append(sections, section)
+		}
+		current = nil
+	}
+
+	quoted := false
+	afterHeader := false // The section only holds a reply header so far
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		isQuoted := strings.HasPrefix(trimmed, ">")
+		if isQuoted {
+			trimmed = strings.TrimSpace(strings.TrimLeft(trimmed, "> "))
+		}
+		if trimmed == "" {
+			current = append(current, "")
+			continue
+		}
+
+		if isReplyHeader(lines, i, trimmed) {
+			flush()
+			afterHeader = true
+		} else {
+			// The header ("On ... wrote:") stays with the quote it introduces
+			if isQuoted != quoted && !afterHeader {
+				flush()
+			}
+			afterHeader = false
+		}
+		quoted = isQuoted
+		current = append(current, trimmed)
+	}
+	flush()
+	return sections
+}
+
+// isReplyHeader reports whether line i (trimmed) introduces a quoted message
+func isReplyHeader(lines []string, i int, trimmed string) bool {
+	for _, re := range reReplyHeaders {
+		if re.MatchString(trimmed) {
+			return true
+		}
+	}
+	if !reOutlookFrom.MatchString(trimmed) {
+		return false
+	}
+	// "From:" alone is too common in a message: Outlook follows it with "Sent:" or "Date:"
+	for j := i + 1; j < len(lines) && j <= i+3; j++ {
+		if reOutlookHeaders.MatchString(strings.TrimSpace(lines[j])) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package textextract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmailText(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"plain text", "Hi  Bob,\r\n\r\n\r\nSee you <bob@example.com>", "Hi Bob,\n\nSee you <bob@example.com>"},
+		{"html", "<div>Hi Bob,</div><div><br></div><p>Thanks&nbsp;a lot</p>", "Hi Bob,\n\nThanks a lot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EmailText(tt.body); got != tt.want {
+				t.Errorf("EmailText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "no quote",
+			text: "Hi Bob,\n\nThe report is attached.",
+			want: []string{"Hi Bob,\n\nThe report is attached."},
+		},
+		{
+			name: "Gmail reply header stays with its quote",
+			text: "Sure, sending it today.\n\nOn Mon, Mar 4, 2024 at 10:00 AM Alice <alice@example.com> wrote:\n> Can you send the report?\n> Thanks",
+			want: []string{
+				"Sure, sending it today.",
+				"On Mon, Mar 4, 2024 at 10:00 AM Alice <alice@example.com> wrote:\nCan you send the report?\nThanks",
+			},
+		},
+		{
+			name: "Vietnamese Gmail header",
+			text: "Đã nhận.\n\nVào Th 2, 4 thg 3, 2024 lúc 10:00 Alice đã viết:\n> Gửi báo cáo nhé",
+			want: []string{"Đã nhận.", "Vào Th 2, 4 thg 3, 2024 lúc 10:00 Alice đã viết:\nGửi báo cáo nhé"},
+		},
+		{
+			name: "Outlook header block",
+			text: "Agreed.\n\nFrom: Bob\nSent: Monday, March 4, 2024\nSubject: Budget\n\nPlease approve the budget.",
+			want: []string{"Agreed.", "From: Bob\nSent: Monday, March 4, 2024\nSubject: Budget\n\nPlease approve the budget."},
+		},
+		{
+			name: "From: without Outlook headers is text",
+			text: "From: the finance team\nPlease approve the budget.",
+			want: []string{"From: the finance team\nPlease approve the budget."},
+		},
+		{
+			name: "interleaved quoting",
+			text: "> What time?\n3pm works.\n> And where?\nRoom 2.",
+			want: []string{"What time?", "3pm works.", "And where?", "Room 2."},
+		},
+		{
+			name: "forwarded message",
+			text: "FYI\n\n---------- Forwarded message ---------\nFrom: Alice\nHello team",
+			want: []string{"FYI", "---------- Forwarded message ---------\nFrom: Alice\nHello team"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChunkEmail(tt.text, 500); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChunkEmail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Each section is chunked on its own
+	got := ChunkEmail("One two three four.\n\nOn Mon Alice wrote:\n> Five", 12)
+	want := []string{"One two", "three four.", "On Mon Alice", "wrote:\nFive"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChunkEmail() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,130 @@
+// Package textextract extracts the plain text of attachments (PDF, DOCX, text files) and email bodies,
+// and splits long documents into passages small enough to embed.
+package textextract
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupported is returned for attachment types without a text extractor
+var ErrUnsupported = errors.New("unsupported attachment type")
+
+// Version identifies the extractors: bump it when they change so attachments without text (failed or
+// empty) are extracted again
+const Version = 2
+
+// Document kinds
+const (
+	kindPDF  = "pdf"
+	kindDOCX = "docx"
+	kindText = "text"
+	kindHTML = "html"
+)
+
+// textExtensions are plain text files sometimes sent as application/octet-stream
+var textExtensions = map[string]bool{
+	".txt": true, ".csv": true, ".tsv": true, ".md": true, ".log": true, ".json": true, ".xml": true,
+}
+
+// kind returns the document kind from the MIME type, or the file extension when the type is generic
+func kind(mimeType, filename string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	switch {
+	case mimeType == "application/pdf":
+		return kindPDF
+	case mimeType == "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return kindDOCX
+	case mimeType == "text/html":
+		return kindHTML
+	case strings.HasPrefix(mimeType, "text/"), mimeType == "application/json", mimeType == "application/xml":
+		return kindText
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case ext == ".pdf":
+		return kindPDF
+	case ext == ".docx":
+		return kindDOCX
+	case ext == ".html" || ext == ".htm":
+		return kindHTML
+	case textExtensions[ext]:
+		return kindText
+	}
+	return ""
+}
+
+// Supported reports whether the text of an attachment can be extracted
+func Supported(mimeType, filename string) bool {
+	return kind(mimeType, filename) != ""
+}
+
+// Extract returns the text of an attachment with paragraphs separated by blank lines
+// (ErrUnsupported for other types, "" when the document has no text layer, e.g. a scanned PDF)
+func Extract(mimeType, filename string, data []byte) (string, error) {
+	var text string
+	var err error
+	switch kind(mimeType, filename) {
+	case kindPDF:
+		text, err = extractPDF(data)
+	case kindDOCX:
+		text, err = extractDOCX(data)
+	case kindHTML:
+		text = extractHTML(string(data))
+	case kindText:
+		text = string(data)
+	default:
+		return "", ErrUnsupported
+	}
+	if err != nil {
+		return "", err
+	}
+	return normalize(text), nil
+}
+
+var (
+	reHTMLBlock = regexp.MustCompile(`(?is)<(style|script|head)[^>]*>.*?</(style|script|head)>`)
+	reHTMLBreak = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])[^>]*>`)
+	reHTMLTag   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// extractHTML keeps the text of an HTML file, with block elements as line breaks
+func extractHTML(html string) string {
+	html = reHTMLBlock.ReplaceAllString(html, " ")
+	html = reHTMLBreak.ReplaceAllString(html, "\n")
+	html = reHTMLTag.ReplaceAllString(html, " ")
+	return strings.NewReplacer("&nbsp;", " ", "&lt;", "<", "&gt;", ">", "&amp;", "&", "&quot;", `"`, "&#39;", "'").Replace(html)
+}
+
+// normalize fixes invalid UTF-8, collapses spaces within lines and keeps at most one blank line between paragraphs
+func normalize(text string) string {
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "")
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var sb strings.Builder
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			blank = sb.Len() > 0
+			continue
+		}
+		if sb.Len() > 0 {
+			if blank {
+				sb.WriteString("\n\n")
+			} else {
+				sb.WriteByte('\n')
+			}
+		}
+		blank = false
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
@@ -0,0 +1,291 @@
+package textextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSupported(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		filename string
+		want     bool
+	}{
+		{"application/pdf", "report", true},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "", true},
+		{"text/plain; charset=utf-8", "notes", true},
+		{"application/octet-stream", "data.CSV", true},
+		{"application/octet-stream", "scan.docx", true},
+		{"image/png", "photo.png", false},
+		{"application/octet-stream", "archive.zip", false},
+	}
+	for _, tt := range tests {
+		if got := Supported(tt.mimeType, tt.filename); got != tt.want {
+			t.Errorf("Supported(%q, %q) = %v, want %v", tt.mimeType, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimeType string
+		data     string
+		want     string
+	}{
+		{"plain text", "text/plain", "Line   one\r\nLine two\n\n\n\nLast", "Line one\nLine two\n\nLast"},
+		{"html", "text/html", "<html><head><title>x</title></head><body><p>Hello&nbsp;&amp; welcome</p><p>Bye</p></body></html>", "Hello & welcome\nBye"},
+		{"invalid utf-8", "text/plain", "caf\xe9 ok", "caf ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(tt.mimeType, "", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Extract() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Extract("image/png", "photo.png", nil); err != ErrUnsupported {
+		t.Errorf("Extract(image/png) error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestExtractDOCX(t *testing.T) {
+	const document = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Báo cáo </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>quý 1</w:t></w:r></w:p>
+<w:p><w:r><w:t>Name</w:t><w:tab/><w:t>Total</w:t><w:br/><w:t>Alice</w:t></w:r></w:p>
+<w:p><w:r><w:instrText>PAGE</w:instrText></w:r></w:p>
+</w:body></w:document>`
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "paragraphs, runs, tabs and breaks",
+			files: map[string]string{"word/document.xml": document, "word/styles.xml": "<w:styles/>"},
+			want:  "Báo cáo quý 1\n\nName Total\nAlice",
+		},
+		{name: "no document part", files: map[string]string{"word/styles.xml": "<w:styles/>"}, wantErr: true},
+		{name: "malformed xml", files: map[string]string{"word/document.xml": "<w:document><w:p>"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract("", "report.docx", buildDOCX(t, tt.files))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Extract() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Extract("", "report.docx", []byte("not a zip")); err == nil {
+		t.Error("Extract() of an invalid archive succeeded, want an error")
+	}
+}
+
+func TestExtractPDF(t *testing.T) {
+	helvetica := "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>"
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <0043>
+<0002> <0068>
+endbfchar
+1 beginbfrange
+<0003> <0004> [<00E0> <006F>]
+endbfrange
+endcmap
+end end`
+
+	tests := []struct {
+		name    string
+		pdf     []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "text operators",
+			pdf: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+				"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+				pdfStream("", "BT /F1 12 Tf 72 720 Td (Hello ) Tj [(wor) -20 (ld) -300 (again)] TJ 0 -14 Td (Second \\(line\\)) Tj ET"),
+				helvetica,
+			),
+			want: "Hello world again\nSecond (line)",
+		},
+		{
+			name: "page tree order and inherited resources",
+			pdf: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [4 0 R 3 0 R] /Count 2 /Resources << /Font << /F1 7 0 R >> >> >>",
+				"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+				"<< /Type /Page /Parent 2 0 R /Contents [6 0 R] >>",
+				pdfStream("", "BT /F1 12 Tf (Page two) Tj ET"),
+				pdfStream("/Filter /FlateDecode", flate(t, "BT /F1 12 Tf (Page one) Tj ET")),
+				helvetica,
+			),
+			want: "Page one\n\nPage two",
+		},
+		{
+			name: "composite font with a ToUnicode map in an object stream",
+			pdf: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [6 0 R] /Count 1 >>",
+				pdfStream("/Filter /FlateDecode", flate(t, cmap)),
+				pdfStream("/Filter /FlateDecode", flate(t, "BT /F1 12 Tf <0001000200030004> Tj ET")),
+				pdfObjectStream(t, map[int]string{
+					6: "<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 7 0 R >> >> /Contents 4 0 R >>",
+					7: "<< /Type /Font /Subtype /Type0 /BaseFont /Arial /Encoding /Identity-H /ToUnicode 3 0 R >>",
+				}, 6, 7),
+			),
+			want: "Chào",
+		},
+		{
+			name: "ToUnicode range with an empty array",
+			pdf: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+				"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+				pdfStream("", "BT /F1 12 Tf <000100030002> Tj ET"),
+				"<< /Type /Font /Subtype /Type0 /BaseFont /Arial /Encoding /Identity-H /ToUnicode 6 0 R >>",
+				pdfStream("", "begincmap\n1 beginbfchar\n<0001> <004F>\nendbfchar\n2 beginbfrange\n<0003> <0004> []\n<0002> <0002> <004B>\nendbfrange\nendcmap"),
+			),
+			want: "OK",
+		},
+		{
+			name: "composite font without a ToUnicode map",
+			pdf: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+				"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+				pdfStream("", "BT /F1 12 Tf <00240025> Tj ET"),
+				"<< /Type /Font /Subtype /Type0 /BaseFont /Arial /Encoding /Identity-H >>",
+			),
+			want: "",
+		},
+		{
+			name: "ASCIIHex stream without a page tree",
+			pdf: buildPDF(
+				pdfStream("/Filter /ASCIIHexDecode", []byte(fmt.Sprintf("%x>", "BT (Loose text) Tj ET"))),
+			),
+			want: "Loose text",
+		},
+		{
+			name:    "encrypted",
+			pdf:     append(buildPDF("<< /Type /Catalog /Pages 2 0 R >>"), "trailer << /Root 1 0 R /Encrypt 9 0 R >>"...),
+			wantErr: true,
+		},
+		{
+			name:    "missing header",
+			pdf:     []byte("BT (Hello) Tj ET"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract("application/pdf", "", tt.pdf)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Extract() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func buildDOCX(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildPDF numbers the objects from 1 in the order given
+func buildPDF(objects ...string) []byte {
+	var sb strings.Builder
+	sb.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&sb, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	return []byte(sb.String())
+}
+
+func pdfStream(dict string, data interface{}) string {
+	var raw string
+	switch d := data.(type) {
+	case string:
+		raw = d
+	case []byte:
+		raw = string(d)
+	}
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(raw), raw)
+}
+
+// pdfObjectStream stores objects (in the order of nums) in a compressed object stream
+func pdfObjectStream(t *testing.T, objects map[int]string, nums ...int) string {
+	var header, body strings.Builder
+	for _, num := range nums {
+		fmt.Fprintf(&header, "%d %d ", num, body.Len())
+		body.WriteString(objects[num])
+		body.WriteByte('\n')
+	}
+	dict := fmt.Sprintf("/Type /ObjStm /N %d /First %d /Filter /FlateDecode", len(nums), header.Len())
+	return pdfStream(dict, flate(t, header.String()+body.String()))
+}
+
+func flate(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
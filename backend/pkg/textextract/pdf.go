@@ -0,0 +1,473 @@
+package textextract
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// maxPDFStreamSize bounds a decompressed stream (zip bombs)
+const maxPDFStreamSize = 20 << 20
+
+// minPrintableRatio is the share of letters, digits, punctuation and spaces below which the text of a
+// content is dropped when some of it was drawn in fonts without a ToUnicode map: their codes may be glyph
+// IDs of a custom encoding rather than characters
+const minPrintableRatio = 0.8
+
+// extractPDF returns the text drawn by the pages of a PDF (text operators between BT and ET), in page
+// order, followed by the text of form XObjects. Strings are decoded through the ToUnicode map of their
+// font (composite CID fonts included); streams may be Flate, ASCIIHex or ASCII85 encoded and stored in
+// object streams. Scanned pages (images) have no text; encrypted documents are an error.
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF")) {
+		return "", fmt.Errorf("invalid pdf: missing header")
+	}
+	if isEncryptedPDF(data) {
+		return "", fmt.Errorf("unsupported pdf: encrypted")
+	}
+
+	var sb strings.Builder
+	for _, content := range parsePDF(data).contents() {
+		text, mapped := pdfContentText(content.data, content.fonts)
+		if text == "" || (!mapped && printableRatio(text) < minPrintableRatio) {
+			continue
+		}
+		sb.WriteString(text)
+		sb.WriteString("\n\n")
+	}
+	return sb.String(), nil
+}
+
+// pdfContent is a content stream (all the streams of a page, or a form XObject) with the fonts its
+// resources name
+type pdfContent struct {
+	data  []byte
+	fonts map[string]*pdfFont
+}
+
+// contents returns the content of each page, then of each form XObject. Contents without font resources
+// of their own use the fonts of the whole document by name. When the page tree cannot be read, every
+// stream that draws text is a content.
+func (doc *pdfDocument) contents() []pdfContent {
+	var contents []pdfContent
+	used := make(map[int]bool)
+	for _, page := range doc.pages() {
+		content := pdfContent{fonts: doc.fontNames(doc.inherited(page, "/Resources"))}
+		for _, num := range doc.contentStreams(pdfValue(page, "/Contents")) {
+			if obj := doc.objects[num]; obj != nil && obj.stream != nil {
+				content.data = append(content.data, obj.stream...)
+				content.data = append(content.data, '\n')
+				used[num] = true
+			}
+		}
+		contents = append(contents, content)
+	}
+
+	pages := len(contents)
+	for _, num := range doc.order {
+		obj := doc.objects[num]
+		if used[num] || !isContentStream(obj) {
+			continue
+		}
+		form := pdfValue(obj.dict, "/Subtype") == "/Form"
+		if pages > 0 && !form {
+			continue
+		}
+		content := pdfContent{data: obj.stream}
+		if form {
+			content.fonts = doc.fontNames(pdfValue(obj.dict, "/Resources"))
+		}
+		contents = append(contents, content)
+	}
+
+	all := make(map[string]*pdfFont)
+	for _, content := range contents {
+		for name, font := range content.fonts {
+			if _, ok := all[name]; !ok {
+				all[name] = font
+			}
+		}
+	}
+	for i := range contents {
+		if len(contents[i].fonts) == 0 {
+			contents[i].fonts = all
+		}
+	}
+	return contents
+}
+
+// pages returns the page dictionaries in page order (the page tree of the catalog), or in file order
+// when the tree cannot be read
+func (doc *pdfDocument) pages() []string {
+	var pages []string
+	for _, num := range doc.order {
+		if obj := doc.objects[num]; pdfValue(obj.dict, "/Type") == "/Catalog" {
+			doc.walkPages(pdfValue(obj.dict, "/Pages"), 0, make(map[int]bool), &pages)
+			break
+		}
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+	for _, num := range doc.order {
+		if obj := doc.objects[num]; pdfValue(obj.dict, "/Type") == "/Page" {
+			pages = append(pages, obj.dict)
+		}
+	}
+	return pages
+}
+
+func (doc *pdfDocument) walkPages(value string, depth int, seen map[int]bool, pages *[]string) {
+	if depth > maxPDFTreeDepth {
+		return
+	}
+	for _, num := range pdfReferences(value) {
+		obj := doc.objects[num]
+		if obj == nil || seen[num] {
+			continue
+		}
+		seen[num] = true
+		switch pdfValue(obj.dict, "/Type") {
+		case "/Pages":
+			doc.walkPages(pdfValue(obj.dict, "/Kids"), depth+1, seen, pages)
+		case "/Page":
+			*pages = append(*pages, obj.dict)
+		}
+	}
+}
+
+// inherited returns a page attribute, looking up the page tree when the page does not set it
+func (doc *pdfDocument) inherited(dict, key string) string {
+	for depth := 0; depth <= maxPDFTreeDepth; depth++ {
+		if value := pdfValue(dict, key); value != "" {
+			return value
+		}
+		parent := doc.resolve(pdfValue(dict, "/Parent"))
+		if parent == nil {
+			return ""
+		}
+		dict = parent.dict
+	}
+	return ""
+}
+
+// contentStreams returns the streams of a /Contents value: a stream, an array of streams, or a
+// reference to such an array
+func (doc *pdfDocument) contentStreams(value string) []int {
+	nums := pdfReferences(value)
+	if len(nums) == 1 {
+		if obj := doc.objects[nums[0]]; obj != nil && !obj.hasStream {
+			return pdfReferences(obj.dict)
+		}
+	}
+	return nums
+}
+
+// isContentStream reports whether a stream draws text: not an image, font program, CMap, metadata or
+// cross-reference data
+func isContentStream(obj *pdfObject) bool {
+	if obj.stream == nil || !bytes.Contains(obj.stream, []byte("BT")) {
+		return false
+	}
+	switch pdfValue(obj.dict, "/Type") {
+	case "/XRef", "/ObjStm", "/Metadata", "/EmbeddedFile", "/CMap":
+		return false
+	}
+	switch pdfValue(obj.dict, "/Subtype") {
+	case "", "/Form":
+		return pdfValue(obj.dict, "/Length1") == "" && pdfValue(obj.dict, "/CMapName") == ""
+	}
+	return false
+}
+
+// pdfContentText interprets the text operators of a content stream. mapped reports whether every string
+// was decoded through a ToUnicode map.
+func pdfContentText(content []byte, fonts map[string]*pdfFont) (text string, mapped bool) {
+	if !bytes.Contains(content, []byte("BT")) {
+		return "", false
+	}
+
+	var sb strings.Builder
+	var operands []interface{} // string ([]byte), number (float64), name (string) or array ([]interface{})
+	var array []interface{}
+	var font *pdfFont
+	inArray := false
+	inText := false
+	mapped = true
+
+	push := func(v interface{}) {
+		if inArray {
+			array = append(array, v)
+		} else {
+			operands = append(operands, v)
+		}
+	}
+	newline := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+	space := func() {
+		s := sb.String()
+		if sb.Len() > 0 && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+			sb.WriteByte(' ')
+		}
+	}
+	decode := func(s []byte) string {
+		if font == nil {
+			mapped = false
+			return decodePDFString(s)
+		}
+		text, ok := font.decode(s)
+		if !ok {
+			mapped = false
+		}
+		return text
+	}
+
+	i := 0
+	for i < len(content) {
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, next := pdfLiteralString(content, i)
+			push(s)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2 // Inline dictionaries (marked content properties) are ignored
+		case c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			s, next := pdfHexString(content, i)
+			push(s)
+			i = next
+		case c == '[':
+			inArray = true
+			array = nil
+			i++
+		case c == ']':
+			inArray = false
+			operands = append(operands, array)
+			i++
+		default:
+			start := i
+			if c == '/' {
+				i++ // Name
+			}
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			if i == start {
+				i++ // Stray delimiter
+				continue
+			}
+			token := string(content[start:i])
+			if n, err := strconv.ParseFloat(token, 64); err == nil {
+				push(n)
+				continue
+			}
+			if token[0] == '/' {
+				push(token)
+				continue
+			}
+
+			switch token {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				newline()
+			case "Tf":
+				if len(operands) == 2 {
+					if name, ok := operands[0].(string); ok {
+						font = fonts[name]
+					}
+				}
+			}
+			if inText {
+				switch token {
+				case "Tj":
+					writePDFStrings(&sb, operands, decode)
+				case "'", "\"":
+					newline()
+					writePDFStrings(&sb, operands, decode)
+				case "TJ":
+					for _, op := range operands {
+						elements, ok := op.([]interface{})
+						if !ok {
+							continue
+						}
+						for _, element := range elements {
+							switch v := element.(type) {
+							case []byte:
+								sb.WriteString(decode(v))
+							case float64:
+								// Large negative adjustments move to the next word
+								if v < -250 {
+									space()
+								}
+							}
+						}
+					}
+				case "Td", "TD":
+					if len(operands) == 2 {
+						if ty, ok := operands[1].(float64); ok && ty != 0 {
+							newline()
+						} else {
+							space()
+						}
+					}
+				case "T*", "Tm":
+					newline()
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	return sb.String(), mapped
+}
+
+func writePDFStrings(sb *strings.Builder, operands []interface{}, decode func([]byte) string) {
+	for _, op := range operands {
+		if s, ok := op.([]byte); ok {
+			sb.WriteString(decode(s))
+		}
+	}
+}
+
+// pdfLiteralString reads a (string) with nested parentheses and escapes; returns the bytes and the next position
+func pdfLiteralString(content []byte, i int) ([]byte, int) {
+	var out []byte
+	depth := 0
+	for i < len(content) {
+		c := content[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+			i++
+		case ')':
+			depth--
+			i++
+			if depth == 0 {
+				return out, i
+			}
+			out = append(out, c)
+		case '\\':
+			i++
+			if i >= len(content) {
+				return out, i
+			}
+			e := content[i]
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+				if e == '\r' && i+1 < len(content) && content[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					j := 0
+					for j < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7' {
+						n = n*8 + int(content[i]-'0')
+						i++
+						j++
+					}
+					out = append(out, byte(n))
+					continue
+				}
+				out = append(out, e)
+			}
+			i++
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+	return out, i
+}
+
+// pdfHexString reads a <hex string>; returns the bytes and the next position
+func pdfHexString(content []byte, i int) ([]byte, int) {
+	i++ // <
+	var digits []byte
+	for i < len(content) && content[i] != '>' {
+		if unicode.Is(unicode.ASCII_Hex_Digit, rune(content[i])) {
+			digits = append(digits, content[i])
+		}
+		i++
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for j := range out {
+		v, _ := strconv.ParseUint(string(digits[2*j:2*j+2]), 16, 8)
+		out[j] = byte(v)
+	}
+	return out, i + 1
+}
+
+// decodePDFString decodes a text string: UTF-16BE with a byte order mark, otherwise one byte per character
+// (PDFDocEncoding and WinAnsiEncoding agree with Latin-1 on letters)
+func decodePDFString(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF {
+		units := make([]uint16, 0, len(s)/2)
+		for j := 2; j+1 < len(s); j += 2 {
+			units = append(units, uint16(s[j])<<8|uint16(s[j+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, 0, len(s))
+	for _, b := range s {
+		if b < 0x20 && b != '\t' && b != '\n' {
+			// Control codes come from two-byte font encodings: keep them so printableRatio rejects the content
+			runes = append(runes, unicode.ReplacementChar)
+			continue
+		}
+		runes = append(runes, rune(b))
+	}
+	return string(runes)
+}
+
+// printableRatio returns the share of letters, digits, punctuation and spaces in text
+func printableRatio(text string) float64 {
+	total, printable := 0, 0
+	for _, r := range text {
+		total++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r) {
+			printable++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(printable) / float64(total)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
@@ -0,0 +1,316 @@
+package textextract
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfFont decodes the strings drawn with a font
+type pdfFont struct {
+	toUnicode *pdfCMap // ToUnicode CMap (nil when absent)
+	composite bool     // Type0 font: multi-byte codes (CIDs), meaningless without ToUnicode
+}
+
+// decode returns the text of a string drawn with the font, and whether it came from the ToUnicode map
+func (f *pdfFont) decode(s []byte) (string, bool) {
+	if f.toUnicode != nil {
+		return f.toUnicode.decode(s, f.composite), true
+	}
+	if f.composite {
+		return "", false // Glyph IDs of an embedded font: no way to map them to characters
+	}
+	return decodePDFString(s), false
+}
+
+// font returns the font of a font dictionary value (reference or inline dictionary), cached by value
+func (doc *pdfDocument) font(value string) *pdfFont {
+	if font, ok := doc.fonts[value]; ok {
+		return font
+	}
+	dict := doc.dictionary(value)
+	font := &pdfFont{composite: pdfValue(dict, "/Subtype") == "/Type0"}
+	if cmap := doc.resolve(pdfValue(dict, "/ToUnicode")); cmap != nil && cmap.stream != nil {
+		font.toUnicode = parseCMap(cmap.stream)
+	}
+	doc.fonts[value] = font
+	return font
+}
+
+// fontNames returns the fonts of a resource dictionary by resource name ("/F1")
+func (doc *pdfDocument) fontNames(resources string) map[string]*pdfFont {
+	names := make(map[string]*pdfFont)
+	fonts := doc.dictionary(pdfValue(doc.dictionary(resources), "/Font"))
+	for _, entry := range pdfEntries(fonts) {
+		names[entry.key] = doc.font(entry.value)
+	}
+	return names
+}
+
+// pdfCMap maps character codes to text (ToUnicode CMap)
+type pdfCMap struct {
+	codespaces []pdfCodespace
+	chars      map[string]string // Code bytes -> text (bfchar)
+	ranges     []pdfCMapRange    // bfrange
+}
+
+// pdfCodespace is a range of codes of one length: a code matches when each byte is within the bounds
+type pdfCodespace struct {
+	low, high []byte
+}
+
+// pdfCMapRange maps consecutive codes to consecutive characters, or to the texts of an array
+type pdfCMapRange struct {
+	low, high uint32
+	length    int
+	start     []uint16 // UTF-16 of the first code; the last unit is incremented along the range
+	texts     []string
+}
+
+// parseCMap reads the codespace ranges, bfchar and bfrange sections of a ToUnicode CMap
+func parseCMap(data []byte) *pdfCMap {
+	cmap := &pdfCMap{chars: make(map[string]string)}
+	tokens := cmapTokens(data)
+	section := ""
+	var operands []cmapToken
+	for _, token := range tokens {
+		if token.kind != cmapKeyword {
+			operands = append(operands, token)
+			continue
+		}
+		switch token.text {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = token.text
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				low, high := operands[i].data, operands[i+1].data
+				if len(low) > 0 && len(low) == len(high) {
+					cmap.codespaces = append(cmap.codespaces, pdfCodespace{low: low, high: high})
+				}
+			}
+			section = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				if operands[i].kind == cmapHex {
+					cmap.chars[string(operands[i].data)] = operands[i+1].unicode()
+				}
+			}
+			section = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				cmap.addRange(operands[i], operands[i+1], operands[i+2])
+			}
+			section = ""
+		}
+		if section == "" || strings.HasPrefix(token.text, "begin") {
+			operands = operands[:0]
+		}
+	}
+	return cmap
+}
+
+func (c *pdfCMap) addRange(low, high, target cmapToken) {
+	if low.kind != cmapHex || high.kind != cmapHex || len(low.data) == 0 || len(low.data) != len(high.data) || len(low.data) > 4 {
+		return
+	}
+	r := pdfCMapRange{low: codeValue(low.data), high: codeValue(high.data), length: len(low.data)}
+	if r.high < r.low {
+		return
+	}
+	switch target.kind {
+	case cmapHex:
+		r.start = utf16Units(target.data)
+		if len(r.start) == 0 {
+			return
+		}
+	case cmapArray:
+		if len(target.elements) == 0 {
+			return
+		}
+		for _, element := range target.elements {
+			r.texts = append(r.texts, element.unicode())
+		}
+	default:
+		return
+	}
+	c.ranges = append(c.ranges, r)
+}
+
+// decode maps the codes of a string to text. Code lengths come from the codespace ranges;
+// unmapped codes are dropped.
+func (c *pdfCMap) decode(s []byte, composite bool) string {
+	defaultLength := 1
+	if composite {
+		defaultLength = 2
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		n := c.codeLength(s[i:], defaultLength)
+		if i+n > len(s) {
+			break
+		}
+		if text, ok := c.lookup(s[i : i+n]); ok {
+			sb.WriteString(text)
+		}
+		i += n
+	}
+	return sb.String()
+}
+
+// codeLength returns the length of the code at the start of s
+func (c *pdfCMap) codeLength(s []byte, defaultLength int) int {
+	for _, space := range c.codespaces {
+		n := len(space.low)
+		if n > len(s) {
+			continue
+		}
+		within := true
+		for j := 0; j < n; j++ {
+			if s[j] < space.low[j] || s[j] > space.high[j] {
+				within = false
+				break
+			}
+		}
+		if within {
+			return n
+		}
+	}
+	return defaultLength
+}
+
+func (c *pdfCMap) lookup(code []byte) (string, bool) {
+	if text, ok := c.chars[string(code)]; ok {
+		return text, true
+	}
+	value := codeValue(code)
+	for _, r := range c.ranges {
+		if r.length != len(code) || value < r.low || value > r.high {
+			continue
+		}
+		offset := value - r.low
+		if r.texts != nil {
+			if int(offset) < len(r.texts) {
+				return r.texts[offset], true
+			}
+			return "", false
+		}
+		if len(r.start) == 0 {
+			return "", false
+		}
+		units := append([]uint16(nil), r.start...)
+		units[len(units)-1] += uint16(offset)
+		return string(utf16.Decode(units)), true
+	}
+	return "", false
+}
+
+func codeValue(code []byte) uint32 {
+	var v uint32
+	for _, b := range code {
+		v = v<<8 | uint32(b)
+	}
+	return v
+}
+
+// utf16Units reads UTF-16BE code units (a lone trailing byte is a unit of its own)
+func utf16Units(data []byte) []uint16 {
+	units := make([]uint16, 0, (len(data)+1)/2)
+	for j := 0; j < len(data); j += 2 {
+		if j+1 < len(data) {
+			units = append(units, uint16(data[j])<<8|uint16(data[j+1]))
+		} else {
+			units = append(units, uint16(data[j]))
+		}
+	}
+	return units
+}
+
+type cmapTokenKind int
+
+const (
+	cmapKeyword cmapTokenKind = iota
+	cmapHex
+	cmapName
+	cmapArray
+	cmapOther
+)
+
+type cmapToken struct {
+	kind     cmapTokenKind
+	text     string      // Keyword or name
+	data     []byte      // Hex string bytes
+	elements []cmapToken // Array elements
+}
+
+// unicode returns the text a bfchar or bfrange destination stands for (UTF-16BE hex, or a glyph name)
+func (t cmapToken) unicode() string {
+	switch t.kind {
+	case cmapHex:
+		return string(utf16.Decode(utf16Units(t.data)))
+	case cmapName:
+		if t.text == "/space" {
+			return " "
+		}
+	}
+	return ""
+}
+
+// cmapTokens splits a CMap into keywords, hex strings, names and arrays (dictionaries and strings are skipped)
+func cmapTokens(data []byte) []cmapToken {
+	var tokens []cmapToken
+	var array []cmapToken
+	inArray := false
+	push := func(t cmapToken) {
+		if inArray {
+			array = append(array, t)
+		} else {
+			tokens = append(tokens, t)
+		}
+	}
+
+	i := 0
+	for i < len(data) {
+		c := data[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c == '<' && i+1 < len(data) && data[i+1] == '<', c == '>' && i+1 < len(data) && data[i+1] == '>':
+			i += 2
+		case c == '<':
+			s, next := pdfHexString(data, i)
+			push(cmapToken{kind: cmapHex, data: s})
+			i = next
+		case c == '(':
+			_, i = pdfLiteralString(data, i)
+			push(cmapToken{kind: cmapOther})
+		case c == '[':
+			inArray = true
+			array = nil
+			i++
+		case c == ']':
+			inArray = false
+			tokens = append(tokens, cmapToken{kind: cmapArray, elements: array})
+			i++
+		default:
+			start := i
+			i++
+			for i < len(data) && !isPDFSpace(data[i]) && !isPDFDelimiter(data[i]) {
+				i++
+			}
+			word := string(data[start:i])
+			switch {
+			case c == '/':
+				push(cmapToken{kind: cmapName, text: word})
+			case bytes.ContainsAny([]byte(word), "0123456789") && strings.Trim(word, "0123456789.-") == "":
+				push(cmapToken{kind: cmapOther, text: word})
+			default:
+				push(cmapToken{kind: cmapKeyword, text: word})
+			}
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,382 @@
+package textextract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	rePDFObject    = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	rePDFReference = regexp.MustCompile(`(\d+)\s+\d+\s+R\b`)
+	rePDFLength    = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	rePDFEncrypt   = regexp.MustCompile(`/Encrypt\s*(\d+\s+\d+\s+R|<<)`)
+)
+
+// maxPDFTreeDepth bounds the walk of the page tree and of inherited resources (cycles)
+const maxPDFTreeDepth = 32
+
+// pdfObject is an indirect object: its text up to the stream, and the decoded stream
+type pdfObject struct {
+	dict      string // Text of the object before its stream (the whole object when it has none)
+	hasStream bool
+	stream    []byte // Decoded stream (nil when a filter is not supported)
+}
+
+// pdfDocument holds the objects of a PDF, including those stored in object streams (PDF 1.5)
+type pdfDocument struct {
+	objects map[int]*pdfObject
+	order   []int // Object numbers in file order
+	fonts   map[string]*pdfFont
+}
+
+// parsePDF reads every object of a PDF. Later definitions (incremental updates) replace earlier ones.
+func parsePDF(data []byte) *pdfDocument {
+	doc := &pdfDocument{objects: make(map[int]*pdfObject), fonts: make(map[string]*pdfFont)}
+	pos := 0
+	for _, m := range rePDFObject.FindAllSubmatchIndex(data, -1) {
+		if m[0] < pos {
+			continue // Inside the stream of the previous object
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		obj, next := readPDFObject(data, m[1])
+		doc.add(num, obj)
+		pos = next
+	}
+
+	for _, num := range append([]int(nil), doc.order...) {
+		if obj := doc.objects[num]; obj.stream != nil && pdfValue(obj.dict, "/Type") == "/ObjStm" {
+			doc.addObjectStream(obj)
+		}
+	}
+	return doc
+}
+
+func (doc *pdfDocument) add(num int, obj *pdfObject) {
+	if _, seen := doc.objects[num]; !seen {
+		doc.order = append(doc.order, num)
+	}
+	doc.objects[num] = obj
+}
+
+// readPDFObject reads the object starting at start (after "obj"); returns it and the position after it
+func readPDFObject(data []byte, start int) (*pdfObject, int) {
+	endObj := bytes.Index(data[start:], []byte("endobj"))
+	keyword := bytes.Index(data[start:], []byte("stream"))
+	if keyword < 0 || (endObj >= 0 && keyword > endObj) {
+		if endObj < 0 {
+			return &pdfObject{dict: string(data[start:])}, len(data)
+		}
+		return &pdfObject{dict: string(data[start : start+endObj])}, start + endObj + len("endobj")
+	}
+
+	dict := string(data[start : start+keyword])
+	from := start + keyword + len("stream")
+	if from < len(data) && data[from] == '\r' {
+		from++
+	}
+	if from < len(data) && data[from] == '\n' {
+		from++
+	}
+
+	// A direct /Length is trusted when endstream follows it; otherwise the stream ends at endstream
+	var raw []byte
+	next := -1
+	if m := rePDFLength.FindStringSubmatch(dict); m != nil && m[2] == "" {
+		if length, err := strconv.Atoi(m[1]); err == nil && from+length <= len(data) &&
+			bytes.HasPrefix(bytes.TrimLeft(data[from+length:], "\r\n \t"), []byte("endstream")) {
+			raw = data[from : from+length]
+			next = from + length
+		}
+	}
+	if next < 0 {
+		end := bytes.Index(data[from:], []byte("endstream"))
+		if end < 0 {
+			return &pdfObject{dict: dict, hasStream: true}, len(data)
+		}
+		raw = bytes.TrimRight(data[from:from+end], "\r\n")
+		next = from + end
+	}
+	if idx := bytes.Index(data[next:], []byte("endobj")); idx >= 0 {
+		next += idx + len("endobj")
+	}
+	return &pdfObject{dict: dict, hasStream: true, stream: decodePDFStream(dict, raw)}, next
+}
+
+// addObjectStream adds the objects stored in an object stream (objects defined directly take precedence)
+func (doc *pdfDocument) addObjectStream(obj *pdfObject) {
+	count, _ := strconv.Atoi(pdfValue(obj.dict, "/N"))
+	first, _ := strconv.Atoi(pdfValue(obj.dict, "/First"))
+	if first <= 0 || first > len(obj.stream) {
+		return
+	}
+	header := strings.Fields(string(obj.stream[:first]))
+	for i := 0; i+1 < len(header) && i/2 < count; i += 2 {
+		num, err1 := strconv.Atoi(header[i])
+		offset, err2 := strconv.Atoi(header[i+1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		start, end := first+offset, len(obj.stream)
+		if i+3 < len(header) {
+			if nextOffset, err := strconv.Atoi(header[i+3]); err == nil {
+				end = first + nextOffset
+			}
+		}
+		if start < first || start > end || end > len(obj.stream) {
+			continue
+		}
+		if _, exists := doc.objects[num]; !exists {
+			doc.add(num, &pdfObject{dict: string(obj.stream[start:end])})
+		}
+	}
+}
+
+// decodePDFStream applies the filters of a stream (nil when one is not supported, e.g. image codecs)
+func decodePDFStream(dict string, raw []byte) []byte {
+	data := raw
+	for _, filter := range pdfFilters(dict) {
+		switch filter {
+		case "/FlateDecode", "/Fl":
+			decoded, err := io.ReadAll(io.LimitReader(zlibReader(data), maxPDFStreamSize))
+			// Streams with trailing garbage still decode up to the error
+			if len(decoded) == 0 && err != nil {
+				return nil
+			}
+			data = decoded
+		case "/ASCIIHexDecode", "/AHx":
+			data = asciiHexDecode(data)
+		case "/ASCII85Decode", "/A85":
+			data = ascii85Decode(data)
+		default:
+			return nil
+		}
+		if data == nil {
+			return nil
+		}
+	}
+	return data
+}
+
+// pdfFilters returns the filter names of a stream dictionary, in the order they apply
+func pdfFilters(dict string) []string {
+	value := pdfValue(dict, "/Filter")
+	if value == "" {
+		return nil
+	}
+	if strings.HasPrefix(value, "[") {
+		return strings.Fields(strings.NewReplacer("[", " ", "]", " ", "/", " /").Replace(value))
+	}
+	return []string{value}
+}
+
+// zlibReader returns a reader of a Flate stream (empty when the header is invalid)
+func zlibReader(raw []byte) io.Reader {
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return bytes.NewReader(nil)
+	}
+	return r
+}
+
+func asciiHexDecode(data []byte) []byte {
+	if end := bytes.IndexByte(data, '>'); end >= 0 {
+		data = data[:end]
+	}
+	digits := make([]byte, 0, len(data))
+	for _, c := range data {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(out, digits); err != nil {
+		return nil
+	}
+	return out
+}
+
+func ascii85Decode(data []byte) []byte {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	out, err := io.ReadAll(io.LimitReader(ascii85.NewDecoder(bytes.NewReader(data)), maxPDFStreamSize))
+	if len(out) == 0 && err != nil {
+		return nil
+	}
+	return out
+}
+
+// isEncryptedPDF reports whether a PDF has an encryption dictionary (its strings and streams are ciphered)
+func isEncryptedPDF(data []byte) bool {
+	return rePDFEncrypt.Match(data)
+}
+
+// resolve returns the object a reference ("5 0 R") points to, or nil
+func (doc *pdfDocument) resolve(value string) *pdfObject {
+	m := rePDFReference.FindStringSubmatch(value)
+	if m == nil {
+		return nil
+	}
+	num, _ := strconv.Atoi(m[1])
+	return doc.objects[num]
+}
+
+// dictionary returns a dictionary value, following a reference
+func (doc *pdfDocument) dictionary(value string) string {
+	if strings.HasPrefix(value, "<<") {
+		return value
+	}
+	if obj := doc.resolve(value); obj != nil {
+		return obj.dict
+	}
+	return ""
+}
+
+// pdfReferences returns the object numbers of the references in a value ("[4 0 R 9 0 R]")
+func pdfReferences(value string) []int {
+	var nums []int
+	for _, m := range rePDFReference.FindAllStringSubmatch(value, -1) {
+		if num, err := strconv.Atoi(m[1]); err == nil {
+			nums = append(nums, num)
+		}
+	}
+	return nums
+}
+
+// pdfEntry is a key of a dictionary with the text of its value
+type pdfEntry struct {
+	key   string
+	value string
+}
+
+// pdfEntries returns the entries of the first dictionary in text (nested values are kept as text)
+func pdfEntries(text string) []pdfEntry {
+	start := strings.Index(text, "<<")
+	if start < 0 {
+		return nil
+	}
+	var entries []pdfEntry
+	i := start + 2
+	for i < len(text) {
+		i = skipPDFSpace(text, i)
+		if i >= len(text) || strings.HasPrefix(text[i:], ">>") {
+			break
+		}
+		if text[i] != '/' {
+			i++ // Malformed entry
+			continue
+		}
+		key, next := pdfName(text, i)
+		value, end := pdfValueAt(text, next)
+		entries = append(entries, pdfEntry{key: key, value: value})
+		i = end
+	}
+	return entries
+}
+
+// pdfValue returns the value of a key of the first dictionary in text ("" when absent)
+func pdfValue(text, key string) string {
+	for _, entry := range pdfEntries(text) {
+		if entry.key == key {
+			return entry.value
+		}
+	}
+	return ""
+}
+
+// pdfValueAt reads the value starting at i: a dictionary, array, string, name, reference or number.
+// Returns its text and the position after it.
+func pdfValueAt(s string, i int) (string, int) {
+	i = skipPDFSpace(s, i)
+	if i >= len(s) {
+		return "", i
+	}
+	switch {
+	case strings.HasPrefix(s[i:], "<<"):
+		end := pdfClosing(s, i)
+		return s[i:end], end
+	case s[i] == '[':
+		end := pdfClosing(s, i)
+		return s[i:end], end
+	case s[i] == '(':
+		_, end := pdfLiteralString([]byte(s), i)
+		return s[i:end], end
+	case s[i] == '<':
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			return s[i:], len(s)
+		}
+		return s[i : i+end+1], i + end + 1
+	case s[i] == '/':
+		return pdfName(s, i)
+	}
+	if m := rePDFReference.FindStringIndex(s[i:]); m != nil && m[0] == 0 {
+		return s[i : i+m[1]], i + m[1]
+	}
+	end := i
+	for end < len(s) && !isPDFSpace(s[end]) && !isPDFDelimiter(s[end]) {
+		end++
+	}
+	if end == i {
+		end++ // Stray delimiter
+	}
+	return s[i:end], end
+}
+
+// pdfClosing returns the position after the dictionary or array starting at i
+func pdfClosing(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "<<"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], ">>"):
+			depth--
+			i += 2
+		case s[i] == '[':
+			depth++
+			i++
+		case s[i] == ']':
+			depth--
+			i++
+		case s[i] == '(':
+			_, i = pdfLiteralString([]byte(s), i)
+		default:
+			i++
+		}
+		if depth == 0 {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// pdfName reads a /Name starting at i; returns it (with the slash) and the position after it
+func pdfName(s string, i int) (string, int) {
+	end := i + 1
+	for end < len(s) && !isPDFSpace(s[end]) && !isPDFDelimiter(s[end]) {
+		end++
+	}
+	return s[i:end], end
+}
+
+func skipPDFSpace(s string, i int) int {
+	for i < len(s) && isPDFSpace(s[i]) {
+		i++
+	}
+	return i
+}
@@ -1,4 +1,4 @@
-import { useState, useCallback, useEffect } from "react";
+import { useState, useCallback, useEffect, useRef } from "react";
 import { emailService } from "@/services/email.service";
 import { getAllSummariesFromCache, saveSummaryToCache, saveSummariesToCache } from "@/lib/db";
 
@@ -20,6 +20,8 @@ export interface UseKanbanSummariesReturn {
   queueSummaries: (emailIds: string[]) => Promise<void>;
   /** Handle SSE summary update */
   handleSummaryUpdate: (emailId: string, summary: string) => void;
+  /** Handle a streamed SSE summary chunk */
+  handleSummaryDelta: (emailId: string, delta: string) => void;
   /** Load summary for detail view */
   loadDetailSummary: (emailId: string) => Promise<void>;
   /** Set of already-requested email IDs */
@@ -125,8 +127,22 @@ export function useKanbanSummaries(): UseKanbanSummariesReturn {
     }
   }, []);
 
+  // Text streamed so far per email; the final summary_update replaces it and is the one cached
+  const streamingSummariesRef = useRef<Record<string, string>>({});
+
+  // Handle streamed summary chunk from SSE
+  const handleSummaryDelta = useCallback((emailId: string, delta: string) => {
+    const partial = (streamingSummariesRef.current[emailId] || "") + delta;
+    streamingSummariesRef.current[emailId] = partial;
+    setSummaryStates((prev) => ({
+      ...prev,
+      [emailId]: { summary: partial, loading: false },
+    }));
+  }, []);
+
   // Handle SSE summary update
   const handleSummaryUpdate = useCallback((emailId: string, newSummary: string) => {
+    delete streamingSummariesRef.current[emailId];
     setSummaryStates((prev) => ({
       ...prev,
       [emailId]: { summary: newSummary, loading: false },
@@ -169,6 +185,7 @@ export function useKanbanSummaries(): UseKanbanSummariesReturn {
     requestSummary,
     queueSummaries,
     handleSummaryUpdate,
+    handleSummaryDelta,
     loadDetailSummary,
     requestedSummaries,
   };
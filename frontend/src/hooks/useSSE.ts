@@ -19,6 +19,8 @@ export interface SSEEventHandlers {
   onNewEmail?: (email: NewEmailInfo) => void;
   /** Called when an AI summary is ready */
   onSummaryUpdate?: (emailId: string, summary: string) => void;
+  /** Called for each streamed chunk of an AI summary (summary_update follows with the final text) */
+  onSummaryDelta?: (emailId: string, delta: string) => void;
   /** Called on SSE connection error */
   onError?: (error: Event) => void;
   /** Called when SSE connection opens */
@@ -57,7 +59,7 @@ const RECONNECT_BASE_DELAY_MS = 1000;
  * - Connection to /events endpoint with authentication
  * - Automatic reconnection with exponential backoff
  * - email_update events for real-time inbox updates
- * - summary_update events for AI-generated summaries
+ * - summary_delta / summary_update events for AI-generated summaries
  * - Debouncing to prevent conflicts with user actions
  * 
  * @example
@@ -135,6 +137,15 @@ export function useSSE({
       try {
         const data = JSON.parse(event.data);
 
+        // Handle streamed summary chunks from AI worker
+        if (data.type === "summary_delta") {
+          const { email_id, delta } = data.payload || {};
+          if (email_id && delta) {
+            handlersRef.current.onSummaryDelta?.(email_id, delta);
+          }
+          return;
+        }
+
         // Handle summary updates from AI worker
         if (data.type === "summary_update") {
           const { email_id, summary } = data.payload || {};
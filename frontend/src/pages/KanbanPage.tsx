@@ -73,6 +73,7 @@ export default function KanbanPage() {
     isSummaryLoading,
     requestSummary: handleRequestSummary,
     handleSummaryUpdate,
+    handleSummaryDelta,
     loadDetailSummary,
     queueSummaries,
   } = useKanbanSummaries();
@@ -261,6 +262,9 @@ export default function KanbanPage() {
       onSummaryUpdate: (emailId, summary) => {
         handleSummaryUpdate(emailId, summary);
       },
+      onSummaryDelta: (emailId, delta) => {
+        handleSummaryDelta(emailId, delta);
+      },
     },
   });
 